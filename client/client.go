@@ -1,8 +1,10 @@
 package client
 
 import (
+	"crypto/cipher"
 	"log"
 	"o2/udpclient"
+	"sync"
 )
 
 type Client struct {
@@ -11,6 +13,15 @@ type Client struct {
 	// model state:
 	group    [20]byte
 	hostName string
+
+	password string
+	aead     cipher.AEAD
+
+	sealedLock    sync.Mutex
+	sealedPlayers map[uint32]*sealedPlayer
+
+	reliable *ReliableGroup
+	recorder *Recorder
 }
 
 func NewClient() *Client {
@@ -27,6 +38,8 @@ func (c *Client) SetGroup(group string) {
 	for ; n < 20; n++ {
 		c.group[n] = ' '
 	}
+	// the group key is salted by the group name:
+	c.deriveKey()
 	log.Printf("client: actual group name '%s'\n", c.group[:])
 }
 
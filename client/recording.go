@@ -0,0 +1,202 @@
+package client
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"log"
+	"o2/client/protocol03"
+	"sync"
+	"time"
+)
+
+// recordingMagic identifies a session recording file and its format version:
+var recordingMagic = [8]byte{'O', '2', 'R', 'E', 'C', 0, 0, 1}
+
+var ErrNotRecording = errors.New("client: not a session recording")
+
+type Direction uint8
+
+const (
+	Inbound Direction = iota
+	Outbound
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Inbound:
+		return "inbound"
+	case Outbound:
+		return "outbound"
+	default:
+		return fmt.Sprintf("Direction(%d)", uint8(d))
+	}
+}
+
+// Recording is a single protocol 0x03 message captured by a Recorder
+type Recording struct {
+	Direction Direction
+	Time      time.Time
+	Message   *protocol03.GroupMessage
+}
+
+// Recorder captures inbound and outbound protocol 0x03 messages with timestamps. Messages are recorded
+// unsealed so that recordings of password-protected groups can be replayed without the password.
+type Recorder struct {
+	lock sync.Mutex
+	w    *bufio.Writer
+	c    io.Closer
+
+	// Now returns the recording clock; replaceable for tests
+	Now func() time.Time
+}
+
+// NewRecorder writes a recording to w; if w is an io.Closer it is closed by Close
+func NewRecorder(w io.Writer) (r *Recorder, err error) {
+	r = &Recorder{
+		w:   bufio.NewWriter(w),
+		Now: time.Now,
+	}
+	if c, ok := w.(io.Closer); ok {
+		r.c = c
+	}
+
+	if _, err = r.w.Write(recordingMagic[:]); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Record appends the message to the recording
+func (r *Recorder) Record(dir Direction, gm *protocol03.GroupMessage) (err error) {
+	var b []byte
+	b, err = proto.Marshal(gm)
+	if err != nil {
+		return
+	}
+
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	var hdr [13]byte
+	hdr[0] = byte(dir)
+	binary.LittleEndian.PutUint64(hdr[1:9], uint64(r.Now().UnixNano()))
+	binary.LittleEndian.PutUint32(hdr[9:13], uint32(len(b)))
+	if _, err = r.w.Write(hdr[:]); err != nil {
+		return
+	}
+	_, err = r.w.Write(b)
+	return
+}
+
+// Close flushes the recording and closes the underlying writer
+func (r *Recorder) Close() (err error) {
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	err = r.w.Flush()
+	if r.c != nil {
+		if cerr := r.c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return
+}
+
+// RecordingReader reads messages back from a recording written by a Recorder
+type RecordingReader struct {
+	r *bufio.Reader
+}
+
+func NewRecordingReader(r io.Reader) (rr *RecordingReader, err error) {
+	rr = &RecordingReader{r: bufio.NewReader(r)}
+
+	var magic [8]byte
+	if _, err = io.ReadFull(rr.r, magic[:]); err != nil {
+		return nil, ErrNotRecording
+	}
+	if magic != recordingMagic {
+		return nil, ErrNotRecording
+	}
+	return
+}
+
+// Next returns the next recorded message or io.EOF at the end of the recording
+func (rr *RecordingReader) Next() (rec *Recording, err error) {
+	var hdr [13]byte
+	if _, err = io.ReadFull(rr.r, hdr[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			// a truncated recording, e.g. from a crash; treat as the end:
+			err = io.EOF
+		}
+		return
+	}
+
+	b := make([]byte, binary.LittleEndian.Uint32(hdr[9:13]))
+	if _, err = io.ReadFull(rr.r, b); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = io.EOF
+		}
+		return
+	}
+
+	rec = &Recording{
+		Direction: Direction(hdr[0]),
+		Time:      time.Unix(0, int64(binary.LittleEndian.Uint64(hdr[1:9]))),
+		Message:   &protocol03.GroupMessage{},
+	}
+	if err = proto.Unmarshal(b, rec.Message); err != nil {
+		return nil, fmt.Errorf("p3: unmarshal: %w", err)
+	}
+	return
+}
+
+// Replay calls deliver for each recorded message, pacing them by their recorded timestamps divided by speed.
+// A speed <= 0 replays as fast as possible.
+func (rr *RecordingReader) Replay(speed float64, deliver func(rec *Recording) error) (err error) {
+	var first time.Time
+	start := time.Now()
+
+	for {
+		var rec *Recording
+		rec, err = rr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return
+		}
+
+		if speed > 0 {
+			if first.IsZero() {
+				first = rec.Time
+			}
+			due := start.Add(time.Duration(float64(rec.Time.Sub(first)) / speed))
+			if wait := time.Until(due); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+
+		if err = deliver(rec); err != nil {
+			return
+		}
+	}
+}
+
+// SetRecorder starts recording the client's protocol 0x03 messages; nil stops recording
+func (c *Client) SetRecorder(r *Recorder) { c.recorder = r }
+func (c *Client) Recorder() *Recorder     { return c.recorder }
+
+// Record captures the unsealed message if a recording is in progress
+func (c *Client) Record(dir Direction, gm *protocol03.GroupMessage) {
+	r := c.recorder
+	if r == nil {
+		return
+	}
+	if err := r.Record(dir, gm); err != nil {
+		log.Printf("client: record: %v\n", err)
+	}
+}
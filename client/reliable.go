@@ -0,0 +1,108 @@
+package client
+
+import (
+	"google.golang.org/protobuf/proto"
+	"o2/client/protocol03"
+	"o2/udpclient"
+	"time"
+)
+
+// ReliableGroup delivers data reliably and in order to all players in the group using protocol 0x03
+// ReliableData and ReliableAck messages.
+type ReliableGroup struct {
+	*udpclient.Reliable
+
+	c *Client
+
+	// ReadOnly suppresses all sending, e.g. for spectators whose acks nobody waits on
+	ReadOnly bool
+
+	// StampHeader fills in the local player's fields (e.g. PlayerIndex, PlayerInSector) of outgoing messages
+	StampHeader func(gm *protocol03.GroupMessage)
+
+	// Routed is set from the server's JoinGroup reply when it routes ReliableData and ReliableAck; other servers
+	// (e.g. alttp.online) drop them so senders should fall back to unreliable messages
+	Routed bool
+}
+
+// Reliable returns the client's reliable delivery channel, creating it on first use
+func (c *Client) Reliable() *ReliableGroup {
+	if c.reliable == nil {
+		r := &ReliableGroup{c: c}
+		r.Reliable = udpclient.NewReliable(r)
+		c.reliable = r
+	}
+	return c.reliable
+}
+
+// HandleMessage processes any ReliableData or ReliableAck in gm and returns the data now deliverable in order
+func (r *ReliableGroup) HandleMessage(gm *protocol03.GroupMessage) (deliveries []udpclient.ReliableDelivery, err error) {
+	if rd := gm.GetReliableData(); rd != nil {
+		return r.ReceiveData(gm.PlayerIndex, uint8(rd.Channel), rd.Sequence, rd.Base, rd.Data)
+	}
+	if ra := gm.GetReliableAck(); ra != nil {
+		r.ReceiveAck(gm.PlayerIndex, uint8(ra.Channel), ra.Sequence)
+	}
+	return
+}
+
+// Reset discards all send and receive state and forgets whether the server routes reliable messages
+func (r *ReliableGroup) Reset() {
+	r.Routed = false
+	r.Reliable.Reset()
+}
+
+func (r *ReliableGroup) SendData(channel uint8, seq uint32, base uint32, data []byte) error {
+	gm := r.newMessage()
+	gm.ReliableData = &protocol03.ReliableData{
+		Channel:  uint32(channel),
+		Sequence: seq,
+		Base:     base,
+		Data:     data,
+	}
+	return r.send(gm)
+}
+
+func (r *ReliableGroup) SendAck(peer uint32, channel uint8, seq uint32) error {
+	gm := r.newMessage()
+	gm.ReliableAck = &protocol03.ReliableAck{
+		TargetPlayerIndex: peer,
+		Channel:           uint32(channel),
+		Sequence:          seq,
+	}
+	return r.send(gm)
+}
+
+func (r *ReliableGroup) newMessage() *protocol03.GroupMessage {
+	gm := &protocol03.GroupMessage{
+		Group:      string(r.c.Group()),
+		PlayerTime: time.Now().UnixNano(),
+	}
+	if r.StampHeader != nil {
+		r.StampHeader(gm)
+	}
+	return gm
+}
+
+func (r *ReliableGroup) send(gm *protocol03.GroupMessage) (err error) {
+	if r.ReadOnly {
+		return
+	}
+	if !r.c.IsConnected() {
+		return
+	}
+
+	r.c.Record(Outbound, gm)
+	if err = r.c.Seal(gm); err != nil {
+		return
+	}
+
+	pkt := MakePacket(0x03)
+	var b []byte
+	b, err = proto.MarshalOptions{}.MarshalAppend(pkt.Bytes(), gm)
+	if err != nil {
+		return
+	}
+
+	return r.c.WriteTimeout(b, time.Second)
+}
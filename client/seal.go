@@ -0,0 +1,198 @@
+package client
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"golang.org/x/crypto/pbkdf2"
+	"o2/client/protocol03"
+	"time"
+)
+
+const (
+	// number of PBKDF2 iterations used to derive a group key from its password:
+	groupKeyIterations = 4096
+
+	// sealed messages stamped further than this from our clock are rejected as replays:
+	maxSealedClockSkew = 2 * time.Minute
+	// a player index silent for this long is forgotten so the next player to take it may have a clock behind:
+	sealedPlayerIdle = 15 * time.Second
+)
+
+var (
+	ErrNotSealed    = errors.New("client: message not sealed with the group password")
+	ErrSealed       = errors.New("client: message sealed but no group password set")
+	ErrUnauthentic  = errors.New("client: message failed authentication")
+	ErrReplayed     = errors.New("client: message stale or replayed")
+	errSealedFormat = errors.New("client: sealed data too short")
+)
+
+// sealedPlayer tracks the newest PlayerTime opened from a player index to reject replays:
+type sealedPlayer struct {
+	playerTime int64
+	seen       time.Time
+}
+
+// SetPassword sets the shared group password; an empty password disables sealing
+func (c *Client) SetPassword(password string) {
+	c.password = password
+	c.deriveKey()
+}
+
+func (c *Client) HasPassword() bool { return c.aead != nil }
+
+func (c *Client) deriveKey() {
+	c.sealedLock.Lock()
+	c.sealedPlayers = nil
+	c.sealedLock.Unlock()
+
+	c.aead = nil
+	if c.password == "" {
+		return
+	}
+
+	// salt with the group name so the same password yields different keys per group:
+	key := pbkdf2.Key([]byte(c.password), append([]byte("o2 group key:"), c.group[:]...), groupKeyIterations, 32, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	c.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Seal encrypts the payload data of gm and authenticates it along with the fields the server routes by.
+// JoinGroup messages are answered only by the server and are never sealed.
+func (c *Client) Seal(gm *protocol03.GroupMessage) (err error) {
+	if c.aead == nil || gm.GetJoinGroup() != nil {
+		return
+	}
+
+	var plaintext []byte
+	data := payloadData(gm)
+	if data != nil {
+		plaintext = *data
+	}
+
+	sealed := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err = rand.Read(sealed); err != nil {
+		return
+	}
+	gm.Sealed = c.aead.Seal(sealed, sealed, plaintext, sealedAdditionalData(gm))
+	if data != nil {
+		*data = nil
+	}
+	return
+}
+
+// Open verifies and decrypts a message sealed by Seal, restoring its payload data. Messages that are not
+// sealed with our group password are rejected when a password is set and vice versa, as are messages whose
+// PlayerTime is stale or not newer than the last one opened from the same player.
+func (c *Client) Open(gm *protocol03.GroupMessage) (err error) {
+	if gm.GetJoinGroup() != nil {
+		return
+	}
+	if c.aead == nil {
+		if len(gm.Sealed) > 0 {
+			return ErrSealed
+		}
+		return
+	}
+	if len(gm.Sealed) == 0 {
+		return ErrNotSealed
+	}
+
+	n := c.aead.NonceSize()
+	if len(gm.Sealed) < n+c.aead.Overhead() {
+		return errSealedFormat
+	}
+
+	var b []byte
+	b, err = c.aead.Open(nil, gm.Sealed[:n], gm.Sealed[n:], sealedAdditionalData(gm))
+	if err != nil {
+		return ErrUnauthentic
+	}
+	if err = c.checkReplay(gm.PlayerIndex, gm.PlayerTime, time.Now()); err != nil {
+		return
+	}
+
+	if data := payloadData(gm); data != nil {
+		*data = b
+	}
+	gm.Sealed = nil
+	return
+}
+
+// checkReplay rejects an authentic message stamped too far from now or not after the last one from the player
+func (c *Client) checkReplay(playerIndex uint32, playerTime int64, now time.Time) error {
+	if d := now.Sub(time.Unix(0, playerTime)); d > maxSealedClockSkew || d < -maxSealedClockSkew {
+		return ErrReplayed
+	}
+
+	defer c.sealedLock.Unlock()
+	c.sealedLock.Lock()
+
+	if c.sealedPlayers == nil {
+		c.sealedPlayers = make(map[uint32]*sealedPlayer)
+	}
+	p, ok := c.sealedPlayers[playerIndex]
+	if !ok || now.Sub(p.seen) >= sealedPlayerIdle {
+		p = &sealedPlayer{playerTime: playerTime - 1}
+		c.sealedPlayers[playerIndex] = p
+	}
+	if playerTime <= p.playerTime {
+		return ErrReplayed
+	}
+
+	p.playerTime = playerTime
+	p.seen = now
+	return nil
+}
+
+// payloadData points to the data field of whichever payload gm carries, if any
+func payloadData(gm *protocol03.GroupMessage) *[]byte {
+	if gm.BroadcastAll != nil {
+		return &gm.BroadcastAll.Data
+	} else if gm.BroadcastSector != nil {
+		return &gm.BroadcastSector.Data
+	} else if gm.Echo != nil {
+		return &gm.Echo.Data
+	} else if gm.ReliableData != nil {
+		return &gm.ReliableData.Data
+	}
+	return nil
+}
+
+// sealedAdditionalData binds the sealed data to the sending player and to the fields the server routes by
+// so that packets cannot be replayed from another player or redirected:
+func sealedAdditionalData(gm *protocol03.GroupMessage) []byte {
+	ad := &bytes.Buffer{}
+	ad.WriteString(gm.Group)
+	_ = binary.Write(ad, binary.LittleEndian, gm.PlayerTime)
+	_ = binary.Write(ad, binary.LittleEndian, gm.PlayerIndex)
+
+	if gm.BroadcastAll != nil {
+		ad.WriteByte(11)
+	} else if bs := gm.BroadcastSector; bs != nil {
+		ad.WriteByte(12)
+		_ = binary.Write(ad, binary.LittleEndian, bs.TargetSector)
+	} else if gm.Echo != nil {
+		ad.WriteByte(13)
+	} else if rd := gm.ReliableData; rd != nil {
+		ad.WriteByte(14)
+		_ = binary.Write(ad, binary.LittleEndian, rd.Channel)
+		_ = binary.Write(ad, binary.LittleEndian, rd.Sequence)
+	} else if ra := gm.ReliableAck; ra != nil {
+		ad.WriteByte(15)
+		_ = binary.Write(ad, binary.LittleEndian, ra.TargetPlayerIndex)
+		_ = binary.Write(ad, binary.LittleEndian, ra.Channel)
+		_ = binary.Write(ad, binary.LittleEndian, ra.Sequence)
+	}
+	return ad.Bytes()
+}
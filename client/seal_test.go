@@ -0,0 +1,134 @@
+package client
+
+import (
+	"bytes"
+	"google.golang.org/protobuf/proto"
+	"o2/client/protocol03"
+	"testing"
+	"time"
+)
+
+func newTestClient(group, password string) *Client {
+	c := &Client{}
+	c.SetGroup(group)
+	c.SetPassword(password)
+	return c
+}
+
+func TestClient_Seal(t *testing.T) {
+	sender := newTestClient("race", "hunter2")
+	receiver := newTestClient("race", "hunter2")
+
+	data := []byte("sram")
+	gm := &protocol03.GroupMessage{
+		Group:        string(sender.Group()),
+		PlayerTime:   time.Now().UnixNano(),
+		PlayerIndex:  3,
+		BroadcastAll: &protocol03.BroadcastAll{Data: append([]byte(nil), data...)},
+	}
+	if err := sender.Seal(gm); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(gm.Sealed, data) || gm.BroadcastAll.Data != nil {
+		t.Fatal("data not encrypted")
+	}
+
+	if err := receiver.Open(gm); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gm.BroadcastAll.Data, data) {
+		t.Fatalf("data = %q, expected %q", gm.BroadcastAll.Data, data)
+	}
+}
+
+func TestClient_Open_Rejects(t *testing.T) {
+	sender := newTestClient("race", "hunter2")
+
+	tests := []struct {
+		name     string
+		receiver *Client
+		sealed   bool
+		age      time.Duration
+		tamper   func(gm *protocol03.GroupMessage)
+		wantErr  error
+	}{
+		{"wrong password", newTestClient("race", "letmein"), true, 0, nil, ErrUnauthentic},
+		{"unsealed", newTestClient("race", "hunter2"), false, 0, nil, ErrNotSealed},
+		{"no password", newTestClient("race", ""), true, 0, nil, ErrSealed},
+		{
+			"replayed by another player",
+			newTestClient("race", "hunter2"),
+			true,
+			0,
+			func(gm *protocol03.GroupMessage) { gm.PlayerIndex = 7 },
+			ErrUnauthentic,
+		},
+		{
+			"retimed",
+			newTestClient("race", "hunter2"),
+			true,
+			0,
+			func(gm *protocol03.GroupMessage) { gm.PlayerTime++ },
+			ErrUnauthentic,
+		},
+		{"stale", newTestClient("race", "hunter2"), true, -10 * time.Minute, nil, ErrReplayed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gm := &protocol03.GroupMessage{
+				Group:        string(sender.Group()),
+				PlayerTime:   time.Now().Add(tt.age).UnixNano(),
+				PlayerIndex:  3,
+				BroadcastAll: &protocol03.BroadcastAll{Data: []byte("fake sram")},
+			}
+			if tt.sealed {
+				if err := sender.Seal(gm); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.tamper != nil {
+				tt.tamper(gm)
+			}
+			if err := tt.receiver.Open(gm); err != tt.wantErr {
+				t.Errorf("Open() = %v, expected %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_Open_Replayed(t *testing.T) {
+	sender := newTestClient("race", "hunter2")
+	receiver := newTestClient("race", "hunter2")
+
+	now := time.Now()
+	seal := func(playerTime time.Time) *protocol03.GroupMessage {
+		gm := &protocol03.GroupMessage{
+			Group:        string(sender.Group()),
+			PlayerTime:   playerTime.UnixNano(),
+			PlayerIndex:  3,
+			BroadcastAll: &protocol03.BroadcastAll{Data: []byte("sram")},
+		}
+		if err := sender.Seal(gm); err != nil {
+			t.Fatal(err)
+		}
+		return gm
+	}
+
+	older := seal(now.Add(-time.Second))
+	newer := seal(now)
+	replay := proto.Clone(newer).(*protocol03.GroupMessage)
+
+	if err := receiver.Open(newer); err != nil {
+		t.Fatal(err)
+	}
+	if err := receiver.Open(replay); err != ErrReplayed {
+		t.Errorf("Open(replay) = %v, expected %v", err, ErrReplayed)
+	}
+	if err := receiver.Open(older); err != ErrReplayed {
+		t.Errorf("Open(older) = %v, expected %v", err, ErrReplayed)
+	}
+	// other players' times are tracked separately:
+	if err := receiver.checkReplay(4, now.Add(-time.Second).UnixNano(), now); err != nil {
+		t.Errorf("checkReplay(other player) = %v, expected nil", err)
+	}
+}
@@ -0,0 +1,317 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"log"
+	"o2/games"
+	"o2/snes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LibraryEntry describes a ROM stored in the Library
+type LibraryEntry struct {
+	SHA1  string `json:"sha1"`
+	CRC32 string `json:"crc32"`
+	// Name is the display name, initially the filename the ROM was loaded from
+	Name string `json:"name"`
+	Size int    `json:"size"`
+
+	// parsed from the header:
+	Title    string `json:"title"`
+	Region   string `json:"region"`
+	Version  string `json:"version"`
+	MapMode  uint8  `json:"mapMode"`
+	ROMSize  uint8  `json:"romSize"`
+	RAMSize  uint8  `json:"ramSize"`
+	CheckSum uint16 `json:"checksum"`
+
+	// Games lists the names of the game factories that claim the ROM
+	Games []string `json:"games"`
+	// IsPatched is set if the ROM is already patched for o2
+	IsPatched bool `json:"isPatched"`
+	// PatchVersion is the version of the o2 patch; zero if unpatched or patched by a version of o2 that did not
+	// record it
+	PatchVersion int `json:"patchVersion"`
+
+	Added time.Time `json:"added"`
+}
+
+func newLibraryEntry(name string, contents []byte) (e *LibraryEntry, rom *snes.ROM, err error) {
+	rom, err = snes.NewROM(name, contents)
+	if err != nil {
+		return
+	}
+
+	sum := sha1.Sum(rom.Contents)
+	e = &LibraryEntry{
+		SHA1:     hex.EncodeToString(sum[:]),
+		CRC32:    fmt.Sprintf("%08x", crc32.ChecksumIEEE(rom.Contents)),
+		Name:     name,
+		Size:     len(rom.Contents),
+		Title:    strings.TrimRight(string(rom.Header.Title[:]), " \x00"),
+		Region:   snes.RegionNames[rom.Header.DestinationCode],
+		Version:  fmt.Sprintf("1.%d", rom.Header.MaskROMVersion),
+		MapMode:  rom.Header.MapMode,
+		ROMSize:  rom.Header.ROMSize,
+		RAMSize:  rom.Header.RAMSize,
+		CheckSum: rom.Header.CheckSum,
+		Games:    make([]string, 0, 1),
+		Added:    time.Now(),
+	}
+
+	for _, gameName := range games.FactoryNames() {
+		f, ok := games.FactoryByName(gameName)
+		if !ok || !f.IsROMSupported(rom) {
+			continue
+		}
+		e.Games = append(e.Games, gameName)
+		if d, ok := f.Patcher(rom).(games.PatchDetector); ok && d.IsPatched() {
+			e.IsPatched = true
+			e.PatchVersion = d.PatchVersion()
+		}
+	}
+
+	return
+}
+
+// Library is a persistent collection of ROMs indexed by SHA-1 and CRC32. The ROMs are stored in its directory
+// named by their SHA-1 alongside an index.json of their entries.
+type Library struct {
+	dir string
+
+	lock    sync.Mutex
+	loaded  bool
+	entries map[string]*LibraryEntry
+}
+
+// NewLibrary creates a Library stored in dir; the index is loaded on first use
+func NewLibrary(dir string) *Library {
+	return &Library{
+		dir:     dir,
+		entries: make(map[string]*LibraryEntry),
+	}
+}
+
+func (l *Library) indexPath() string {
+	return filepath.Join(l.dir, "index.json")
+}
+
+func (l *Library) romPath(id string) string {
+	return filepath.Join(l.dir, id+".sfc")
+}
+
+// load reads the index if not already loaded; lock must be held
+func (l *Library) load() {
+	if l.loaded {
+		return
+	}
+	l.loaded = true
+
+	b, err := ioutil.ReadFile(l.indexPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("library: could not read index: %v\n", err)
+		}
+		return
+	}
+
+	var entries []*LibraryEntry
+	if err = json.Unmarshal(b, &entries); err != nil {
+		log.Printf("library: could not json unmarshal index: %v\n", err)
+		return
+	}
+	for _, e := range entries {
+		l.entries[e.SHA1] = e
+	}
+}
+
+// save writes the index; lock must be held
+func (l *Library) save() error {
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return fmt.Errorf("library: could not make directory '%s': %w", l.dir, err)
+	}
+
+	b, err := json.MarshalIndent(l.sorted(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("library: could not json marshal index: %w", err)
+	}
+	if err = ioutil.WriteFile(l.indexPath(), b, 0644); err != nil {
+		return fmt.Errorf("library: could not write index: %w", err)
+	}
+	return nil
+}
+
+// sorted lists the entries by name then SHA-1; lock must be held
+func (l *Library) sorted() []*LibraryEntry {
+	entries := make([]*LibraryEntry, 0, len(l.entries))
+	for _, e := range l.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].SHA1 < entries[j].SHA1
+	})
+	return entries
+}
+
+// Entries returns copies of all entries sorted by name
+func (l *Library) Entries() []LibraryEntry {
+	defer l.lock.Unlock()
+	l.lock.Lock()
+	l.load()
+
+	entries := make([]LibraryEntry, 0, len(l.entries))
+	for _, e := range l.sorted() {
+		entries = append(entries, *e)
+	}
+	return entries
+}
+
+// Get finds the entry with the given SHA-1 in hex
+func (l *Library) Get(id string) (LibraryEntry, bool) {
+	defer l.lock.Unlock()
+	l.lock.Lock()
+	l.load()
+
+	e, ok := l.entries[id]
+	if !ok {
+		return LibraryEntry{}, false
+	}
+	return *e, true
+}
+
+// FindCRC32 finds the entries with the given CRC32
+func (l *Library) FindCRC32(crc uint32) []LibraryEntry {
+	defer l.lock.Unlock()
+	l.lock.Lock()
+	l.load()
+
+	crcString := fmt.Sprintf("%08x", crc)
+	entries := make([]LibraryEntry, 0, 1)
+	for _, e := range l.sorted() {
+		if e.CRC32 == crcString {
+			entries = append(entries, *e)
+		}
+	}
+	return entries
+}
+
+// FindSource finds the unpatched ROM in the library that the factory's patcher turns into patched, e.g. to create
+// a BPS patch for a ROM that was selected already patched; patched must be patched by the current version of o2
+func (l *Library) FindSource(f games.Factory, patched []byte) ([]byte, bool) {
+	for _, e := range l.Entries() {
+		if e.IsPatched || e.Size != len(patched) {
+			continue
+		}
+
+		contents, err := l.Read(e.SHA1)
+		if err != nil {
+			continue
+		}
+		source := make([]byte, len(contents))
+		copy(source, contents)
+
+		rom, err := snes.NewROM(e.Name, contents)
+		if err != nil || !f.IsROMSupported(rom) {
+			continue
+		}
+		if err = f.Patcher(rom).Patch(); err != nil {
+			continue
+		}
+		if bytes.Equal(rom.Contents, patched) {
+			return source, true
+		}
+	}
+	return nil, false
+}
+
+// Add stores the ROM in the library; adding a ROM already in the library keeps its existing entry
+func (l *Library) Add(name string, contents []byte) (LibraryEntry, error) {
+	e, rom, err := newLibraryEntry(name, contents)
+	if err != nil {
+		return LibraryEntry{}, err
+	}
+
+	defer l.lock.Unlock()
+	l.lock.Lock()
+	l.load()
+
+	if existing, ok := l.entries[e.SHA1]; ok {
+		return *existing, nil
+	}
+
+	if err = os.MkdirAll(l.dir, 0755); err != nil {
+		return LibraryEntry{}, fmt.Errorf("library: could not make directory '%s': %w", l.dir, err)
+	}
+	// store the contents without any copier header so the SHA-1 matches:
+	if err = ioutil.WriteFile(l.romPath(e.SHA1), rom.Contents, 0644); err != nil {
+		return LibraryEntry{}, fmt.Errorf("library: could not write rom: %w", err)
+	}
+
+	l.entries[e.SHA1] = e
+	if err = l.save(); err != nil {
+		return LibraryEntry{}, err
+	}
+
+	log.Printf("library: added '%s' sha1=%s\n", e.Name, e.SHA1)
+	return *e, nil
+}
+
+// Read returns the contents of the ROM with the given SHA-1
+func (l *Library) Read(id string) ([]byte, error) {
+	defer l.lock.Unlock()
+	l.lock.Lock()
+	l.load()
+
+	if _, ok := l.entries[id]; !ok {
+		return nil, fmt.Errorf("library: rom %s not found", id)
+	}
+	return ioutil.ReadFile(l.romPath(id))
+}
+
+// Rename changes the display name of the entry with the given SHA-1
+func (l *Library) Rename(id string, name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("library: name cannot be empty")
+	}
+
+	defer l.lock.Unlock()
+	l.lock.Lock()
+	l.load()
+
+	e, ok := l.entries[id]
+	if !ok {
+		return fmt.Errorf("library: rom %s not found", id)
+	}
+	e.Name = name
+	return l.save()
+}
+
+// Delete removes the entry with the given SHA-1 and its stored ROM
+func (l *Library) Delete(id string) error {
+	defer l.lock.Unlock()
+	l.lock.Lock()
+	l.load()
+
+	if _, ok := l.entries[id]; !ok {
+		return fmt.Errorf("library: rom %s not found", id)
+	}
+	if err := os.Remove(l.romPath(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("library: could not delete rom: %w", err)
+	}
+	delete(l.entries, id)
+	return l.save()
+}
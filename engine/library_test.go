@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"bytes"
+	"o2/games"
+	"o2/snes"
+	"o2/snes/emulator"
+	"path/filepath"
+	"testing"
+)
+
+// libraryTestFactory claims ROMs titled "LIBTEST" and considers them patched if their first byte is $01
+type libraryTestFactory struct{}
+
+type libraryTestPatcher struct{ rom *snes.ROM }
+
+func (p libraryTestPatcher) Patch() error      { p.rom.Contents[0] = 0x01; return nil }
+func (p libraryTestPatcher) IsPatched() bool   { return p.rom.Contents[0] == 0x01 }
+func (p libraryTestPatcher) PatchVersion() int { return int(p.rom.Contents[0]) }
+
+func (libraryTestFactory) IsROMSupported(rom *snes.ROM) bool {
+	return string(rom.Header.Title[:7]) == "LIBTEST"
+}
+func (libraryTestFactory) CanPlay(rom *snes.ROM) (bool, string) { return true, "" }
+func (libraryTestFactory) Patcher(rom *snes.ROM) games.Patcher  { return libraryTestPatcher{rom} }
+func (libraryTestFactory) NewGame(rom *snes.ROM) games.Game     { return nil }
+
+func init() {
+	games.Register("LIBTEST", libraryTestFactory{})
+}
+
+func makeLibraryTestROM(t *testing.T, title string) []byte {
+	rom, err := emulator.MakeTestROM(title)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rom.Contents
+}
+
+func TestLibrary(t *testing.T) {
+	defer withTempHome(t)()
+
+	vm := NewViewModel()
+	vm.Init()
+	v := vm.libraryViewModel
+
+	e, err := v.Add("test.sfc", makeLibraryTestROM(t, "LIBTEST"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.SHA1) != 40 || len(e.CRC32) != 8 {
+		t.Errorf("sha1 = '%s', crc32 = '%s'", e.SHA1, e.CRC32)
+	}
+	if e.Title != "LIBTEST" || e.RAMSize != 5 {
+		t.Errorf("title = '%s', ramSize = %d", e.Title, e.RAMSize)
+	}
+	if len(e.Games) != 1 || e.Games[0] != "LIBTEST" || e.IsPatched {
+		t.Errorf("games = %v, isPatched = %v", e.Games, e.IsPatched)
+	}
+
+	// the same ROM is only stored once:
+	if _, err = v.Add("copy.sfc", makeLibraryTestROM(t, "LIBTEST")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(vm.library.Entries()); n != 1 {
+		t.Errorf("len(Entries()) = %d, want 1", n)
+	}
+
+	if err = v.Rename(e.SHA1, "renamed.sfc"); err != nil {
+		t.Fatal(err)
+	}
+	if err = v.Rename(e.SHA1, " "); err == nil {
+		t.Errorf("Rename() should fail with an empty name")
+	}
+
+	patched, err := v.Repatch(e.SHA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !patched.IsPatched || patched.PatchVersion != 1 || patched.Name != "o2-renamed.sfc" {
+		t.Errorf("patched = '%s', isPatched = %v, patchVersion = %d", patched.Name, patched.IsPatched, patched.PatchVersion)
+	}
+	// re-patching a patched ROM that is up to date does not change it:
+	again, err := v.Repatch(patched.SHA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.SHA1 != patched.SHA1 {
+		t.Errorf("Repatch() of a patched ROM = %s, want %s", again.SHA1, patched.SHA1)
+	}
+
+	// the original of a patched ROM is found to create patches from:
+	patchedContents, err := vm.library.Read(patched.SHA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, ok := vm.library.FindSource(libraryTestFactory{}, patchedContents)
+	if !ok || !bytes.Equal(source, makeLibraryTestROM(t, "LIBTEST")) {
+		t.Errorf("FindSource() = %v, want the unpatched rom", ok)
+	}
+
+	// the library persists:
+	dir := vm.library.dir
+	restored := NewLibrary(dir)
+	entries := restored.Entries()
+	if len(entries) != 2 || entries[0].Name != "o2-renamed.sfc" || entries[1].Name != "renamed.sfc" {
+		t.Fatalf("restored entries = %v", entries)
+	}
+	if found := restored.FindCRC32(0); len(found) != 0 {
+		t.Errorf("FindCRC32(0) = %v", found)
+	}
+	if _, err = restored.Read(e.SHA1); err != nil {
+		t.Error(err)
+	}
+
+	if err = v.Delete(e.SHA1); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := vm.library.Get(e.SHA1); ok {
+		t.Errorf("Get() found deleted rom")
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, e.SHA1+"*")); len(matches) != 0 {
+		t.Errorf("deleted rom still stored: %v", matches)
+	}
+	if err = v.Delete(e.SHA1); err == nil {
+		t.Errorf("Delete() should fail on a missing rom")
+	}
+}
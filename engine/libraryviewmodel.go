@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"o2/games"
+	"o2/interfaces"
+	"o2/snes"
+)
+
+// name of the view that lists and manages the ROM library:
+const libraryView = "library"
+
+// LibraryViewModel lists the ROMs in the library and selects, renames, deletes and re-patches them
+type LibraryViewModel struct {
+	commands map[string]interfaces.Command
+
+	root    *ViewModel
+	library *Library
+
+	isDirty bool
+
+	Entries []LibraryEntry `json:"entries"`
+}
+
+func NewLibraryViewModel(root *ViewModel, library *Library) *LibraryViewModel {
+	v := &LibraryViewModel{
+		root:    root,
+		library: library,
+		isDirty: true,
+	}
+
+	v.commands = map[string]interfaces.Command{
+		"select":  &LibrarySelectCommand{v},
+		"rename":  &LibraryRenameCommand{v},
+		"delete":  &LibraryDeleteCommand{v},
+		"repatch": &LibraryRepatchCommand{v},
+	}
+
+	return v
+}
+
+func (v *LibraryViewModel) IsDirty() bool {
+	return v.isDirty
+}
+
+func (v *LibraryViewModel) ClearDirty() {
+	v.isDirty = false
+}
+
+func (v *LibraryViewModel) MarkDirty() {
+	v.isDirty = true
+}
+
+func (v *LibraryViewModel) Update() {
+	v.Entries = v.library.Entries()
+}
+
+func (v *LibraryViewModel) CommandFor(command string) (ce interfaces.Command, err error) {
+	var ok bool
+	ce, ok = v.commands[command]
+	if !ok {
+		err = fmt.Errorf("libraryviewmodel: no command '%s' found", command)
+	}
+	return
+}
+
+// Add stores a ROM in the library and marks the view dirty if it is new
+func (v *LibraryViewModel) Add(name string, contents []byte) (LibraryEntry, error) {
+	e, err := v.library.Add(name, contents)
+	if err != nil {
+		return e, err
+	}
+	v.MarkDirty()
+	return e, nil
+}
+
+// Commands:
+
+type LibraryEntryCommandArgs struct {
+	SHA1 string `json:"sha1"`
+	// Session names the session to load the ROM into; empty is the default session
+	Session string `json:"session"`
+}
+
+type LibrarySelectCommand struct{ v *LibraryViewModel }
+
+func (ce *LibrarySelectCommand) CreateArgs() interfaces.CommandArgs {
+	return &LibraryEntryCommandArgs{}
+}
+func (ce *LibrarySelectCommand) Execute(args interfaces.CommandArgs) error {
+	f := args.(*LibraryEntryCommandArgs)
+	return ce.v.Select(f.SHA1, f.Session)
+}
+
+// Select loads the library's ROM into the session as if it were provided by the user
+func (v *LibraryViewModel) Select(id string, session string) error {
+	s := v.root.Session(session)
+	if s == nil {
+		return fmt.Errorf("libraryviewmodel: session '%s' not found", session)
+	}
+	e, ok := v.library.Get(id)
+	if !ok {
+		return fmt.Errorf("libraryviewmodel: rom %s not found", id)
+	}
+
+	contents, err := v.library.Read(id)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("libraryviewmodel: select '%s' sha1=%s\n", e.Name, e.SHA1)
+	if err = s.romViewModel.NameProvided(&ROMNameCommandArgs{Name: e.Name}); err != nil {
+		return err
+	}
+	return s.romViewModel.DataProvided(contents)
+}
+
+type LibraryRenameCommandArgs struct {
+	SHA1 string `json:"sha1"`
+	Name string `json:"name"`
+}
+
+type LibraryRenameCommand struct{ v *LibraryViewModel }
+
+func (ce *LibraryRenameCommand) CreateArgs() interfaces.CommandArgs {
+	return &LibraryRenameCommandArgs{}
+}
+func (ce *LibraryRenameCommand) Execute(args interfaces.CommandArgs) error {
+	f := args.(*LibraryRenameCommandArgs)
+	return ce.v.Rename(f.SHA1, f.Name)
+}
+
+func (v *LibraryViewModel) Rename(id string, name string) error {
+	if err := v.library.Rename(id, name); err != nil {
+		return err
+	}
+
+	v.MarkDirty()
+	v.root.UpdateAndNotifyView()
+	return nil
+}
+
+type LibraryDeleteCommand struct{ v *LibraryViewModel }
+
+func (ce *LibraryDeleteCommand) CreateArgs() interfaces.CommandArgs {
+	return &LibraryEntryCommandArgs{}
+}
+func (ce *LibraryDeleteCommand) Execute(args interfaces.CommandArgs) error {
+	return ce.v.Delete(args.(*LibraryEntryCommandArgs).SHA1)
+}
+
+func (v *LibraryViewModel) Delete(id string) error {
+	if err := v.library.Delete(id); err != nil {
+		return err
+	}
+
+	v.MarkDirty()
+	v.root.UpdateAndNotifyView()
+	return nil
+}
+
+type LibraryRepatchCommand struct{ v *LibraryViewModel }
+
+func (ce *LibraryRepatchCommand) CreateArgs() interfaces.CommandArgs {
+	return &LibraryEntryCommandArgs{}
+}
+func (ce *LibraryRepatchCommand) Execute(args interfaces.CommandArgs) error {
+	_, err := ce.v.Repatch(args.(*LibraryEntryCommandArgs).SHA1)
+	return err
+}
+
+// Repatch patches a ROM from the library with the current version of o2 and adds the patched ROM to the library,
+// e.g. to share it or to upgrade a patched copy after upgrading o2
+func (v *LibraryViewModel) Repatch(id string) (LibraryEntry, error) {
+	e, ok := v.library.Get(id)
+	if !ok {
+		return LibraryEntry{}, fmt.Errorf("libraryviewmodel: rom %s not found", id)
+	}
+	if len(e.Games) != 1 {
+		return LibraryEntry{}, fmt.Errorf("libraryviewmodel: rom '%s' is claimed by %d game providers", e.Name, len(e.Games))
+	}
+	f, ok := games.FactoryByName(e.Games[0])
+	if !ok {
+		return LibraryEntry{}, fmt.Errorf("libraryviewmodel: game provider '%s' not found", e.Games[0])
+	}
+
+	contents, err := v.library.Read(id)
+	if err != nil {
+		return LibraryEntry{}, err
+	}
+	rom, err := snes.NewROM(e.Name, contents)
+	if err != nil {
+		return LibraryEntry{}, err
+	}
+	if err = f.Patcher(rom).Patch(); err != nil {
+		return LibraryEntry{}, fmt.Errorf("libraryviewmodel: error patching rom '%s': %w", e.Name, err)
+	}
+
+	name := e.Name
+	if !e.IsPatched {
+		name = "o2-" + name
+	}
+	patched, err := v.Add(name, rom.Contents)
+	if err != nil {
+		return LibraryEntry{}, err
+	}
+	v.root.UpdateAndNotifyView()
+	return patched, nil
+}
@@ -2,19 +2,23 @@ package engine
 
 import (
 	"fmt"
+	"hash/crc32"
 	"io/ioutil"
 	"log"
 	"o2/interfaces"
 	"o2/snes"
+	"o2/snes/patch"
 	"o2/util"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 type ROMViewModel struct {
 	commands map[string]interfaces.Command
 
-	root *ViewModel
+	root *Session
 
 	// public fields for JSON:
 	IsLoaded bool   `json:"isLoaded"`
@@ -22,6 +26,8 @@ type ROMViewModel struct {
 	Title    string `json:"title"`
 	Region   string `json:"region"`
 	Version  string `json:"version"`
+	// PatchVersion describes the o2 patch the ROM had when selected, e.g. "none" or "1"
+	PatchVersion string `json:"patchVersion"`
 
 	// inputs:
 	Folder   string `json:"folder"`   // folder to store in on device
@@ -101,8 +107,13 @@ func (v *ROMViewModel) SaveConfiguration(config *ROMConfiguration) {
 		log.Printf("romviewmodel: saveConfiguration: could not make directories along the path '%s': %v\n", dir, err)
 	}
 
+	// an already patched ROM whose original is unknown is saved patched; it is verified when loaded again:
+	contents := v.root.unpatchedRomContents
+	if contents == nil && v.root.nextRom != nil {
+		contents = v.root.nextRom.Contents
+	}
 	path := filepath.Join(dir, v.Name)
-	err = ioutil.WriteFile(path, v.root.unpatchedRomContents, 0644)
+	err = ioutil.WriteFile(path, contents, 0644)
 	if err != nil {
 		log.Printf("romviewmodel: saveConfiguration: could not write unpatched rom to '%s': %v\n", path, err)
 		return
@@ -119,10 +130,19 @@ func (v *ROMViewModel) Update() {
 		v.Title = string(rom.Header.Title[:])
 		v.Region = snes.RegionNames[rom.Header.DestinationCode]
 		v.Version = fmt.Sprintf("1.%d", rom.Header.MaskROMVersion)
+		switch patchVersion := v.root.detectedPatchVersion; {
+		case patchVersion == 0:
+			v.PatchVersion = "none"
+		case patchVersion < 0:
+			v.PatchVersion = "unknown"
+		default:
+			v.PatchVersion = strconv.Itoa(patchVersion)
+		}
 	} else {
 		v.Title = ""
 		v.Region = ""
 		v.Version = ""
+		v.PatchVersion = ""
 	}
 }
 
@@ -135,7 +155,7 @@ func (v *ROMViewModel) CommandFor(command string) (ce interfaces.Command, err er
 	return
 }
 
-func NewROMViewModel(c *ViewModel) *ROMViewModel {
+func NewROMViewModel(c *Session) *ROMViewModel {
 	v := &ROMViewModel{
 		root: c,
 	}
@@ -145,8 +165,11 @@ func NewROMViewModel(c *ViewModel) *ROMViewModel {
 		"data":     &ROMDataCommand{v},
 		"boot":     &ROMBootCommand{v},
 		"setField": &ROMsetFieldCmd{v},
+		"patch":    &ROMPatchCommand{v},
 		// get contents of patched rom; used internally for /rom/patched.sfc download endpoint:
 		"patched": &ROMGetDataCommand{v},
+		// get a BPS patch of the o2 changes; used internally for /rom/o2.bps download endpoint:
+		"patchBPS": &ROMGetPatchCommand{v},
 	}
 
 	return v
@@ -214,6 +237,12 @@ func (v *ROMViewModel) DataProvided(romImage []byte) error {
 	if err != nil {
 		return err
 	}
+
+	// remember every ROM in the library:
+	if _, err := v.root.root.libraryViewModel.Add(v.Name, romImage); err != nil {
+		log.Printf("romviewmodel: dataProvided: could not add rom to library: %v\n", err)
+	}
+
 	err = v.root.ROMSelected(rom)
 	if err != nil {
 		return err
@@ -222,6 +251,37 @@ func (v *ROMViewModel) DataProvided(romImage []byte) error {
 	return nil
 }
 
+type ROMPatchCommand struct{ v *ROMViewModel }
+
+func (ce *ROMPatchCommand) CreateArgs() interfaces.CommandArgs {
+	panic("this is a binary command")
+}
+func (ce *ROMPatchCommand) Execute(args interfaces.CommandArgs) error {
+	return ce.v.PatchProvided(args.([]byte))
+}
+
+// PatchProvided applies an IPS, BPS or UPS patch to the last selected unpatched ROM and selects the result
+func (v *ROMViewModel) PatchProvided(p []byte) error {
+	source := v.root.unpatchedRomContents
+	if source == nil {
+		if v.root.nextRom != nil {
+			return fmt.Errorf("the selected ROM is already patched for o2; select its original ROM to apply the patch to first")
+		}
+		return fmt.Errorf("select a ROM to apply the patch to first")
+	}
+
+	romImage, err := patch.Apply(source, p)
+	if err != nil {
+		return err
+	}
+
+	// name the result after the patch so it does not replace the unpatched ROM in the config folder:
+	ext := filepath.Ext(v.Name)
+	v.Name = fmt.Sprintf("%s-%08x%s", strings.TrimSuffix(v.Name, ext), crc32.ChecksumIEEE(p), ext)
+
+	return v.DataProvided(romImage)
+}
+
 // ROMGetDataCommand This command should only be used by the web server
 type ROMGetDataCommand struct{ v *ROMViewModel }
 
@@ -279,3 +339,23 @@ func (ce *ROMBootCommand) Execute(_ interfaces.CommandArgs) (err error) {
 
 	return nil
 }
+
+// ROMGetPatchCommand This command should only be used by the web server
+type ROMGetPatchCommand struct{ v *ROMViewModel }
+
+func (ce *ROMGetPatchCommand) CreateArgs() interfaces.CommandArgs { return nil }
+func (ce *ROMGetPatchCommand) Execute(args interfaces.CommandArgs) error {
+	rom := ce.v.root.nextRom
+	if rom == nil || ce.v.root.unpatchedRomContents == nil {
+		// no ROM or the original of an already patched ROM is unknown:
+		return nil
+	}
+
+	p, ok := args.(*[]byte)
+	if !ok {
+		return nil
+	}
+
+	*p = patch.CreateBPS(ce.v.root.unpatchedRomContents, rom.Contents, "o2")
+	return nil
+}
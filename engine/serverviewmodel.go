@@ -4,14 +4,20 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"o2/client"
 	"o2/interfaces"
+	"o2/util"
 	"o2/util/env"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 type ServerViewModel struct {
 	commands map[string]interfaces.Command
 
-	root *ViewModel
+	root *Session
 
 	isDirty bool
 
@@ -20,6 +26,11 @@ type ServerViewModel struct {
 	GroupName   string `json:"groupName"`
 	Team        uint8  `json:"team"`
 	PlayerName  string `json:"playerName"`
+	// the group secret is never sent to views; they only learn whether one is set:
+	Password    string `json:"-"`
+	HasPassword bool   `json:"hasPassword"`
+	// record the network traffic of each session to ~/.o2/recordings for later replay:
+	RecordSessions bool `json:"recordSessions"`
 }
 
 type ServerConfiguration struct {
@@ -27,6 +38,10 @@ type ServerConfiguration struct {
 	GroupName  string `json:"groupName"`
 	Team       uint8  `json:"team"`
 	PlayerName string `json:"playerName"`
+	// optional shared secret that authenticates all messages within the group:
+	Password string `json:"password"`
+	// record the network traffic of each session to ~/.o2/recordings for later replay:
+	RecordSessions bool `json:"recordSessions"`
 }
 
 func (v *ServerViewModel) LoadConfiguration(config *ServerConfiguration) {
@@ -36,15 +51,19 @@ func (v *ServerViewModel) LoadConfiguration(config *ServerConfiguration) {
 	}
 
 	args := &setFieldArgs{
-		HostName:   new(string),
-		GroupName:  new(string),
-		Team:       new(uint8),
-		PlayerName: new(string),
+		HostName:       new(string),
+		GroupName:      new(string),
+		Team:           new(uint8),
+		PlayerName:     new(string),
+		Password:       new(string),
+		RecordSessions: new(bool),
 	}
 	*args.HostName = config.HostName
 	*args.GroupName = config.GroupName
 	*args.Team = config.Team
 	*args.PlayerName = config.PlayerName
+	*args.Password = config.Password
+	*args.RecordSessions = config.RecordSessions
 
 	cmd := setFieldCmd{v}
 	err := cmd.Execute(args)
@@ -64,6 +83,8 @@ func (v *ServerViewModel) SaveConfiguration(config *ServerConfiguration) {
 	config.GroupName = v.GroupName
 	config.PlayerName = v.PlayerName
 	config.Team = v.Team
+	config.Password = v.Password
+	config.RecordSessions = v.RecordSessions
 }
 
 func (v *ServerViewModel) Update() {
@@ -74,7 +95,7 @@ func (v *ServerViewModel) Update() {
 	}
 }
 
-func NewServerViewModel(root *ViewModel) *ServerViewModel {
+func NewServerViewModel(root *Session) *ServerViewModel {
 	v := &ServerViewModel{
 		root:        root,
 		IsConnected: false,
@@ -161,12 +182,65 @@ func (ce *ServerConnectCommand) Execute(_ interfaces.CommandArgs) error {
 
 	log.Printf("client: set group '%s'\n", v.GroupName)
 	vm.client.SetGroup(v.GroupName)
+	vm.client.SetPassword(v.Password)
 
 	vm.client.SetHostName(v.HostName)
 
+	if v.RecordSessions {
+		startRecording(vm.client)
+	}
+
 	return nil
 }
 
+// startRecording records the session's network traffic to a new file in ~/.o2/recordings for later replay
+func startRecording(c *client.Client) {
+	if c.Recorder() != nil {
+		return
+	}
+
+	dir, err := util.ConfigDir()
+	if err != nil {
+		log.Printf("serverviewmodel: record: could not find configuration directory: %v\n", err)
+		return
+	}
+	dir = filepath.Join(dir, "recordings")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("serverviewmodel: record: %v\n", err)
+		return
+	}
+
+	ts := strings.NewReplacer(":", "-", ".", "-").Replace(time.Now().Format("2006-01-02T15:04:05.000Z"))
+	path := filepath.Join(dir, fmt.Sprintf("o2-session-%s.o2rec", ts))
+
+	f, err := os.Create(path)
+	if err != nil {
+		log.Printf("serverviewmodel: record: %v\n", err)
+		return
+	}
+	r, err := client.NewRecorder(f)
+	if err != nil {
+		log.Printf("serverviewmodel: record: %v\n", err)
+		_ = f.Close()
+		return
+	}
+
+	log.Printf("serverviewmodel: recording session to '%s'\n", path)
+	c.SetRecorder(r)
+}
+
+func stopRecording(c *client.Client) {
+	r := c.Recorder()
+	if r == nil {
+		return
+	}
+
+	c.SetRecorder(nil)
+	if err := r.Close(); err != nil {
+		log.Printf("serverviewmodel: record: %v\n", err)
+	}
+}
+
 type ServerDisconnectCommand struct{ v *ServerViewModel }
 
 func (ce *ServerDisconnectCommand) CreateArgs() interfaces.CommandArgs { return nil }
@@ -177,6 +251,7 @@ func (ce *ServerDisconnectCommand) Execute(_ interfaces.CommandArgs) error {
 	log.Println("serverviewmodel: disconnect()")
 
 	vm.client.Disconnect()
+	stopRecording(vm.client)
 	vm.serverViewModel.IsConnected = vm.client.IsConnected()
 
 	vm.serverViewModel.MarkDirty()
@@ -192,6 +267,9 @@ type setFieldArgs struct {
 	GroupName  *string `json:"groupName"`
 	Team       *uint8  `json:"team"`
 	PlayerName *string `json:"playerName"`
+	Password   *string `json:"password"`
+
+	RecordSessions *bool `json:"recordSessions"`
 }
 
 func (c *setFieldCmd) CreateArgs() interfaces.CommandArgs { return &setFieldArgs{} }
@@ -217,6 +295,15 @@ func (c *setFieldCmd) Execute(args interfaces.CommandArgs) error {
 		}
 		c.v.MarkDirty()
 	}
+	if f.Password != nil {
+		c.v.Password = *f.Password
+		c.v.HasPassword = c.v.Password != ""
+		client := vm.client
+		if client != nil {
+			client.SetPassword(c.v.Password)
+		}
+		c.v.MarkDirty()
+	}
 	if f.Team != nil {
 		c.v.Team = *f.Team
 		if game != nil {
@@ -231,6 +318,18 @@ func (c *setFieldCmd) Execute(args interfaces.CommandArgs) error {
 		}
 		c.v.MarkDirty()
 	}
+	if f.RecordSessions != nil {
+		c.v.RecordSessions = *f.RecordSessions
+		// start or stop recording a session in progress:
+		if client := vm.client; client != nil && client.IsConnected() {
+			if c.v.RecordSessions {
+				startRecording(client)
+			} else {
+				stopRecording(client)
+			}
+		}
+		c.v.MarkDirty()
+	}
 
 	vm.UpdateAndNotifyView()
 	vm.SaveConfiguration()
@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestServerViewModel_Password(t *testing.T) {
+	defer withTempHome(t)()
+
+	vm := NewViewModel()
+	vm.Init()
+
+	ce, err := vm.CommandFor("server", "setField")
+	if err != nil {
+		t.Fatal(err)
+	}
+	password := "hunter2"
+	if err = ce.Execute(&setFieldArgs{Password: &password}); err != nil {
+		t.Fatal(err)
+	}
+
+	v, _ := vm.GetViewModel("server")
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), password) {
+		t.Errorf("view exposes the group password: %s", b)
+	}
+	if !strings.Contains(string(b), `"hasPassword":true`) {
+		t.Errorf("view does not report hasPassword: %s", b)
+	}
+
+	// the password is still kept in the configuration:
+	config := &ServerConfiguration{}
+	vm.DefaultSession().serverViewModel.SaveConfiguration(config)
+	if actual, expected := config.Password, password; actual != expected {
+		t.Errorf("config password = '%s', expected '%s'", actual, expected)
+	}
+}
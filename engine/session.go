@@ -0,0 +1,452 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"o2/client"
+	"o2/games"
+	"o2/snes"
+	"strings"
+	"sync"
+)
+
+// Session is an independent SNES device session with its own device queue, game and server client. Its view
+// models are addressed in the root view model namespace as "<session>.<view>", except for the default session
+// whose views are unprefixed, e.g. "snes", "rom", "server", "game" and "status".
+type Session struct {
+	name string
+	root *ViewModel
+
+	// state:
+	driverDevice snes.NamedDriverDevicePair
+	dev          snes.Queue
+	devLock      sync.Mutex
+
+	unpatchedRomContents []byte
+	rom                  *snes.ROM
+	nextRom              *snes.ROM
+	// version of the o2 patch the selected ROM already had; 0 if none and -1 if patched by a version of o2
+	// that did not record it:
+	detectedPatchVersion int
+
+	factory     games.Factory
+	nextFactory games.Factory
+
+	game   games.Game
+	client *client.Client
+
+	// games configuration for this session keyed by game name:
+	gamesConfig map[string]json.RawMessage
+
+	// View Models:
+	snesViewModel   *SNESViewModel
+	romViewModel    *ROMViewModel
+	serverViewModel *ServerViewModel
+}
+
+// SessionConfig is the saved configuration of a single session
+type SessionConfig struct {
+	SNES   *SNESConfiguration         `json:"snes"`
+	ROM    *ROMConfiguration          `json:"rom"`
+	Server *ServerConfiguration       `json:"server"`
+	Games  map[string]json.RawMessage `json:"games"`
+}
+
+func newSession(root *ViewModel, name string) *Session {
+	s := &Session{
+		name:        name,
+		root:        root,
+		client:      client.NewClient(),
+		gamesConfig: make(map[string]json.RawMessage),
+	}
+
+	// instantiate each child view model:
+	s.snesViewModel = NewSNESViewModel(s)
+	s.romViewModel = NewROMViewModel(s)
+	s.serverViewModel = NewServerViewModel(s)
+
+	return s
+}
+
+func (s *Session) Name() string { return s.name }
+
+// ViewName returns the name of the session's view in the root view model namespace
+func (s *Session) ViewName(view string) string {
+	if s.name == "" {
+		return view
+	}
+	return s.name + "." + view
+}
+
+// views returns the session's initial view models keyed by their names in the root namespace
+func (s *Session) views() map[string]interface{} {
+	return map[string]interface{}{
+		s.ViewName("status"): "Not connected",
+		s.ViewName("snes"):   s.snesViewModel,
+		s.ViewName("rom"):    s.romViewModel,
+		s.ViewName("server"): s.serverViewModel,
+	}
+}
+
+// ownsView determines if the named view in the root namespace belongs to this session
+func (s *Session) ownsView(view string) bool {
+	if s.name == "" {
+		return !strings.Contains(view, ".") && view != sessionsView && view != libraryView
+	}
+	return strings.HasPrefix(view, s.name+".")
+}
+
+func (s *Session) LoadSessionConfiguration(config *SessionConfig) {
+	if config == nil {
+		log.Printf("session(%s): loadConfiguration: no config\n", s.name)
+		return
+	}
+
+	if config.Games != nil {
+		s.gamesConfig = config.Games
+	}
+	s.snesViewModel.LoadConfiguration(config.SNES)
+	// loading ROM will instantiate Game instance which should load its own configuration
+	s.romViewModel.LoadConfiguration(config.ROM)
+	s.serverViewModel.LoadConfiguration(config.Server)
+}
+
+func (s *Session) SaveSessionConfiguration(config *SessionConfig) bool {
+	config.SNES = new(SNESConfiguration)
+	config.ROM = new(ROMConfiguration)
+	config.Server = new(ServerConfiguration)
+
+	s.snesViewModel.SaveConfiguration(config.SNES)
+	s.romViewModel.SaveConfiguration(config.ROM)
+	s.serverViewModel.SaveConfiguration(config.Server)
+
+	game := s.game
+	if game != nil {
+		gameName := game.Name()
+		// gameConfig must be json.Marshal-able:
+		gameConfig := game.ConfigurationModel()
+		marshaled, err := json.MarshalIndent(gameConfig, "    ", "  ")
+		if err != nil {
+			log.Printf("session(%s): saveConfiguration: could not json marshal game '%s' configuration: %v\n", s.name, gameName, err)
+			return false
+		}
+		// override named game configuration; keep other games' configuration as-is:
+		s.gamesConfig[gameName] = marshaled
+	}
+	config.Games = s.gamesConfig
+
+	return true
+}
+
+// Implements ViewModelContainer for the session's game by prefixing view names:
+
+func (s *Session) NotifyView(view string, viewModel interface{}) {
+	s.root.NotifyView(s.ViewName(view), viewModel)
+}
+
+func (s *Session) SetViewModel(view string, viewModel interface{}) {
+	s.root.SetViewModel(s.ViewName(view), viewModel)
+}
+
+func (s *Session) GetViewModel(view string) (interface{}, bool) {
+	return s.root.GetViewModel(s.ViewName(view))
+}
+
+func (s *Session) NotifyViewOf(view string, model interface{}) {
+	s.root.NotifyViewOf(s.ViewName(view), model)
+}
+
+func (s *Session) UpdateAndNotifyView() {
+	s.root.UpdateAndNotifyView()
+}
+
+// all sessions share the root's configuration file:
+
+func (s *Session) LoadConfiguration() bool {
+	return s.root.LoadConfiguration()
+}
+
+func (s *Session) SaveConfiguration() bool {
+	return s.root.SaveConfiguration()
+}
+
+func (s *Session) setStatus(msg string) {
+	if s.name != "" {
+		log.Printf("notify: session(%s): %s\n", s.name, msg)
+	} else {
+		log.Printf("notify: %s\n", msg)
+	}
+	s.root.SetViewModel(s.ViewName("status"), msg)
+}
+
+func (s *Session) tryCreateGame() bool {
+	defer s.UpdateAndNotifyView()
+
+	if s.nextRom == nil {
+		log.Println("viewmodel: tryCreateGame: rom is nil")
+		return false
+	}
+	if s.game != nil {
+		log.Println("viewmodel: tryCreateGame: stop game")
+		s.game.Stop()
+	}
+
+	s.rom = s.nextRom
+	s.factory = s.nextFactory
+
+	log.Println("viewmodel: tryCreateGame: create new game")
+	game := s.factory.NewGame(s.rom)
+	s.game = game
+
+	// provide the game with its deps:
+	game.ProvideQueue(s.dev)
+	game.ProvideClient(s.client)
+	// intercept root.viewNotifier to let us cache viewModel updates from the game:
+	// game will notify us of its viewModel on Start()/Reset():
+	game.ProvideViewModelContainer(s)
+	game.ProvideConfigurationSystem(s)
+
+	// initialize the game state:
+	game.Reset()
+
+	// load configuration:
+	if gameConfig, ok := s.gamesConfig[game.Name()]; ok {
+		game.LoadConfiguration(gameConfig)
+	}
+
+	go func() {
+		// wait until the game is stopped:
+		<-game.Stopped()
+		if s.game == game {
+			s.game = nil
+		}
+		s.root.deleteViewModel(s.ViewName("game"))
+		s.UpdateAndNotifyView()
+	}()
+
+	// start the game instance:
+	log.Println("viewmodel: tryCreateGame: start game")
+	game.Start()
+
+	return true
+}
+
+func (s *Session) IsConnected() bool {
+	return s.dev != nil
+}
+
+func (s *Session) IsConnectedToDriver(driver snes.NamedDriver) bool {
+	if s.dev == nil {
+		return false
+	}
+
+	return s.driverDevice.NamedDriver == driver
+}
+
+func (s *Session) ROMSelected(rom *snes.ROM) error {
+	defer s.UpdateAndNotifyView()
+
+	// the user has selected a ROM file:
+	log.Printf(`ROM selected
+title:   '%s'
+region:  %s (code %02X)
+version: 1.%d
+`,
+		string(rom.Header.Title[:]),
+		snes.RegionNames[rom.Header.DestinationCode],
+		rom.Header.DestinationCode,
+		rom.Header.MaskROMVersion)
+
+	// validate the header so that unsupported ROMs can explain why:
+	report := rom.Validate()
+	if report.CopierHeaderStripped {
+		log.Printf("ROM selected: stripped %d-byte copier header\n", len(rom.CopierHeader))
+	}
+	log.Printf("ROM selected: %s\n", report)
+
+	// determine if ROM is recognizable as a game we provide support for:
+	s.nextFactory = nil
+
+	allFactories := games.Factories()
+	factories := make([]games.Factory, 0, len(allFactories))
+	for _, f := range allFactories {
+		if !f.IsROMSupported(rom) {
+			continue
+		}
+		factories = append(factories, f)
+		break
+	}
+
+	if len(factories) == 0 {
+		// unrecognized ROM
+		if !report.IsValid() {
+			s.setStatus(fmt.Sprintf("ROM is not compatible with any game providers; %s", report))
+			return nil
+		}
+		s.setStatus("ROM is not compatible with any game providers")
+		return nil
+	} else if len(factories) > 1 {
+		// more than one game type matches ROM
+		// TODO: could loop through factories and filter by CanPlay
+		s.setStatus("ROM matches more than one game provider")
+		s.nextFactory = nil
+		return nil
+	}
+
+	s.nextFactory = factories[0]
+
+	// check if the ROM is supported:
+	ok, reason := s.nextFactory.CanPlay(rom)
+	if !ok {
+		s.setStatus(fmt.Sprintf("ROM not supported: %s", reason))
+		return nil
+	}
+
+	// make a backup copy of the unpatched ROM contents for saving later and to create patches from:
+	s.unpatchedRomContents = nil
+	patcher := s.nextFactory.Patcher(rom)
+	s.detectedPatchVersion = 0
+	if d, ok := patcher.(games.PatchDetector); ok && d.IsPatched() {
+		s.detectedPatchVersion = d.PatchVersion()
+		if s.detectedPatchVersion == 0 {
+			s.detectedPatchVersion = -1
+		}
+		log.Printf("viewmodel: romselected: ROM is already patched with o2 patch version %d\n", s.detectedPatchVersion)
+	} else {
+		s.unpatchedRomContents = make([]byte, len(rom.Contents))
+		copy(s.unpatchedRomContents, rom.Contents)
+	}
+
+	// attempt to patch the ROM file; an already patched ROM is verified or upgraded:
+	if err := patcher.Patch(); err != nil {
+		err = fmt.Errorf("error patching ROM: %w", err)
+		log.Printf("viewmodel: romselected: patcher: %v\n", err)
+		s.setStatus(err.Error())
+		return nil
+	}
+
+	// the unpatched contents of an already patched ROM are only known if its original is in the library:
+	if s.unpatchedRomContents == nil {
+		if source, ok := s.root.library.FindSource(s.nextFactory, rom.Contents); ok {
+			s.unpatchedRomContents = source
+		} else {
+			log.Printf("viewmodel: romselected: original of the patched ROM is not in the library\n")
+		}
+	}
+
+	s.nextRom = rom
+	s.tryCreateGame()
+
+	return nil
+}
+
+func (s *Session) SNESConnected(pair snes.NamedDriverDevicePair) {
+	defer func() {
+		s.UpdateAndNotifyView()
+		s.SaveConfiguration()
+	}()
+
+	if pair == s.driverDevice && s.dev != nil {
+		// no change
+		return
+	}
+
+	// a device can only be driven by one session at a time:
+	if other := s.root.sessionUsingDevice(pair); other != nil && other != s {
+		log.Printf("viewmodel: snesconnected: driver='%s', device='%s' already in use by session '%s'\n", pair.NamedDriver.Name, pair.Device.GetId(), other.name)
+		s.setStatus(fmt.Sprintf("SNES is already in use by session '%s'", other.name))
+		return
+	}
+
+	var err error
+	log.Printf("viewmodel: snesconnected: open: driver='%s', device='%s'\n", pair.NamedDriver.Name, pair.Device.GetId())
+	s.dev, err = pair.NamedDriver.Driver.Open(pair.Device)
+	if err != nil {
+		log.Printf("viewmodel: snesconnected: open: %v\n", err)
+		s.setStatus("Could not connect to the SNES")
+		s.dev = nil
+		s.driverDevice = snes.NamedDriverDevicePair{}
+		return
+	}
+
+	if s.game != nil {
+		// inform the game of the new device:
+		s.game.ProvideQueue(s.dev)
+	}
+
+	dev := s.dev
+	go func() {
+		// wait for the SNES to be closed:
+		<-dev.Closed()
+		log.Printf("viewmodel: snesconnected: closed: driver='%s', device='%s'\n", pair.NamedDriver.Name, pair.Device.GetId())
+		s.SNESDisconnected()
+	}()
+
+	s.driverDevice = pair
+	s.setStatus("Connected to SNES")
+}
+
+func (s *Session) SNESDisconnected() {
+	defer s.devLock.Unlock()
+	s.devLock.Lock()
+
+	if s.dev == nil {
+		if s.game != nil {
+			s.game.ProvideQueue(nil)
+		}
+		s.driverDevice = snes.NamedDriverDevicePair{}
+		return
+	}
+
+	defer func() {
+		s.UpdateAndNotifyView()
+		s.SaveConfiguration()
+	}()
+
+	// enqueue the close operation:
+	snesClosed := make(chan error)
+	lastDev := s.driverDevice
+	log.Printf("viewmodel: snesdisconnected: closing driver='%s', device='%s'\n", lastDev.NamedDriver.Name, lastDev.Device.GetId())
+	err := s.dev.Enqueue(snes.CommandWithCompletion{
+		Command: &snes.CloseCommand{},
+		Completion: func(cmd snes.Command, err error) {
+			snesClosed <- err
+			close(snesClosed)
+		},
+	})
+
+	s.dev = nil
+	if s.game != nil {
+		s.game.ProvideQueue(nil)
+	}
+	s.driverDevice = snes.NamedDriverDevicePair{}
+	s.setStatus("Disconnecting from SNES...")
+	s.UpdateAndNotifyView()
+
+	if err != nil {
+		log.Printf("viewmodel: snesdisconnected: enqueue closecommand: %v\n", err)
+		return
+	}
+
+	// wait until snes is closed:
+	err = <-snesClosed
+	if err != nil {
+		log.Println(err)
+	}
+	log.Printf("viewmodel: snesdisconnected: closed device '%s'\n", lastDev.Device.GetDisplayName())
+
+	lastDev = snes.NamedDriverDevicePair{}
+	s.setStatus("Disconnected from SNES")
+}
+
+// close stops the session's game and disconnects it from its SNES and server
+func (s *Session) close() {
+	if s.game != nil {
+		s.game.Stop()
+	}
+	s.SNESDisconnected()
+	s.client.Disconnect()
+	stopRecording(s.client)
+	close(s.snesViewModel.stopDetect)
+}
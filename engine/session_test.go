@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func withTempHome(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "o2-engine-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	home := os.Getenv("HOME")
+	_ = os.Setenv("HOME", dir)
+	return func() {
+		_ = os.Setenv("HOME", home)
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func setGroupName(t *testing.T, vm *ViewModel, view, groupName string) {
+	ce, err := vm.CommandFor(view, "setField")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ce.Execute(&setFieldArgs{GroupName: &groupName}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestViewModel_Sessions(t *testing.T) {
+	defer withTempHome(t)()
+
+	vm := NewViewModel()
+	vm.Init()
+
+	if err := vm.sessionsViewModel.Add("console2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.sessionsViewModel.Add("console2"); err == nil {
+		t.Error("expected error adding duplicate session")
+	}
+	if err := vm.sessionsViewModel.Add("bad.name"); err == nil {
+		t.Error("expected error adding session with '.' in name")
+	}
+
+	for _, view := range []string{"status", "snes", "rom", "server", "console2.status", "console2.snes", "console2.rom", "console2.server"} {
+		if _, ok := vm.GetViewModel(view); !ok {
+			t.Errorf("view '%s' not found", view)
+		}
+	}
+
+	// sessions are configured independently:
+	setGroupName(t, vm, "server", "one")
+	setGroupName(t, vm, "console2.server", "two")
+	if actual, expected := vm.DefaultSession().serverViewModel.GroupName, "one"; actual != expected {
+		t.Errorf("default group = '%s', expected '%s'", actual, expected)
+	}
+	if actual, expected := vm.Session("console2").serverViewModel.GroupName, "two"; actual != expected {
+		t.Errorf("console2 group = '%s', expected '%s'", actual, expected)
+	}
+
+	// sessions are restored from config:
+	restored := NewViewModel()
+	restored.Init()
+	s := restored.Session("console2")
+	if s == nil {
+		t.Fatal("session 'console2' not restored from config")
+	}
+	if actual, expected := s.serverViewModel.GroupName, "two"; actual != expected {
+		t.Errorf("restored console2 group = '%s', expected '%s'", actual, expected)
+	}
+	if actual, expected := restored.DefaultSession().serverViewModel.GroupName, "one"; actual != expected {
+		t.Errorf("restored default group = '%s', expected '%s'", actual, expected)
+	}
+
+	if err := vm.sessionsViewModel.Remove("console2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := vm.GetViewModel("console2.server"); ok {
+		t.Error("view 'console2.server' not removed")
+	}
+	if err := vm.sessionsViewModel.Remove(""); err == nil {
+		t.Error("expected error removing default session")
+	}
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"o2/interfaces"
+	"strings"
+)
+
+// name of the view that lists and manages sessions:
+const sessionsView = "sessions"
+
+// SessionsViewModel lists the named device sessions and adds or removes them
+type SessionsViewModel struct {
+	commands map[string]interfaces.Command
+
+	root *ViewModel
+
+	isDirty bool
+
+	// names of all sessions; the default session is named "":
+	Sessions []string `json:"sessions"`
+}
+
+func NewSessionsViewModel(root *ViewModel) *SessionsViewModel {
+	v := &SessionsViewModel{
+		root:    root,
+		isDirty: true,
+	}
+
+	v.commands = map[string]interfaces.Command{
+		"add":    &SessionAddCommand{v},
+		"remove": &SessionRemoveCommand{v},
+	}
+
+	return v
+}
+
+func (v *SessionsViewModel) IsDirty() bool {
+	return v.isDirty
+}
+
+func (v *SessionsViewModel) ClearDirty() {
+	v.isDirty = false
+}
+
+func (v *SessionsViewModel) MarkDirty() {
+	v.isDirty = true
+}
+
+func (v *SessionsViewModel) Update() {
+	v.Sessions = v.root.SessionNames()
+}
+
+func (v *SessionsViewModel) CommandFor(command string) (ce interfaces.Command, err error) {
+	var ok bool
+	ce, ok = v.commands[command]
+	if !ok {
+		err = fmt.Errorf("sessionsviewmodel: no command '%s' found", command)
+	}
+	return
+}
+
+// Commands:
+
+type SessionCommandArgs struct {
+	Name string `json:"name"`
+}
+
+type SessionAddCommand struct{ v *SessionsViewModel }
+
+func (ce *SessionAddCommand) CreateArgs() interfaces.CommandArgs { return &SessionCommandArgs{} }
+func (ce *SessionAddCommand) Execute(args interfaces.CommandArgs) error {
+	return ce.v.Add(args.(*SessionCommandArgs).Name)
+}
+
+// Add creates a new named session with its own SNES, ROM and server view models
+func (v *SessionsViewModel) Add(name string) error {
+	if name == "" || strings.ContainsAny(name, "./") {
+		return fmt.Errorf("sessionsviewmodel: invalid session name '%s'", name)
+	}
+
+	vm := v.root
+	if vm.Session(name) != nil {
+		return fmt.Errorf("sessionsviewmodel: session '%s' already exists", name)
+	}
+
+	log.Printf("sessionsviewmodel: add session '%s'\n", name)
+	s := newSession(vm, name)
+	s.snesViewModel.Init()
+	vm.addSession(s)
+
+	v.MarkDirty()
+	vm.UpdateAndNotifyView()
+	vm.SaveConfiguration()
+
+	return nil
+}
+
+type SessionRemoveCommand struct{ v *SessionsViewModel }
+
+func (ce *SessionRemoveCommand) CreateArgs() interfaces.CommandArgs { return &SessionCommandArgs{} }
+func (ce *SessionRemoveCommand) Execute(args interfaces.CommandArgs) error {
+	return ce.v.Remove(args.(*SessionCommandArgs).Name)
+}
+
+// Remove disconnects the named session and removes its view models; the default session cannot be removed
+func (v *SessionsViewModel) Remove(name string) error {
+	if name == "" {
+		return fmt.Errorf("sessionsviewmodel: cannot remove the default session")
+	}
+
+	vm := v.root
+	s := vm.Session(name)
+	if s == nil {
+		return fmt.Errorf("sessionsviewmodel: session '%s' not found", name)
+	}
+
+	log.Printf("sessionsviewmodel: remove session '%s'\n", name)
+	s.close()
+	vm.removeSession(s)
+
+	v.MarkDirty()
+	vm.UpdateAndNotifyView()
+	vm.SaveConfiguration()
+
+	return nil
+}
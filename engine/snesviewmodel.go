@@ -13,9 +13,12 @@ import (
 type SNESViewModel struct {
 	commands map[string]interfaces.Command
 
-	c       *ViewModel
+	c       *Session
 	isClean bool
 
+	// closed to stop device detection:
+	stopDetect chan struct{}
+
 	Drivers     []*DriverViewModel `json:"drivers"`
 	IsConnected bool               `json:"isConnected"`
 }
@@ -98,8 +101,11 @@ func (v *SNESViewModel) SaveConfiguration(config *SNESConfiguration) {
 	config.Device = drv.SelectedDevice
 }
 
-func NewSNESViewModel(c *ViewModel) *SNESViewModel {
-	v := &SNESViewModel{c: c}
+func NewSNESViewModel(c *Session) *SNESViewModel {
+	v := &SNESViewModel{
+		c:          c,
+		stopDetect: make(chan struct{}),
+	}
 
 	// supported commands:
 	v.commands = map[string]interfaces.Command{
@@ -161,7 +167,16 @@ func (v *SNESViewModel) Init() {
 
 	// background goroutine to auto-detect new devices every 2 seconds:
 	go func() {
-		for range time.NewTicker(time.Second * 2).C {
+		ticker := time.NewTicker(time.Second * 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-v.stopDetect:
+				return
+			case <-ticker.C:
+			}
+
 			needUpdate := false
 
 			for _, dvm := range v.Drivers {
@@ -5,32 +5,16 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
-	"o2/client"
-	"o2/games"
 	"o2/interfaces"
 	"o2/snes"
 	"o2/util"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 )
 
 type ViewModel struct {
-	// state:
-	driverDevice snes.NamedDriverDevicePair
-	dev          snes.Queue
-	devLock      sync.Mutex
-
-	unpatchedRomContents []byte
-	rom                  *snes.ROM
-	nextRom              *snes.ROM
-
-	factory     games.Factory
-	nextFactory games.Factory
-
-	game   games.Game
-	client *client.Client
-
 	isLoadingConfig bool
 
 	// dependency that notifies view of updated view model:
@@ -40,41 +24,137 @@ type ViewModel struct {
 	viewModels     map[string]interface{}
 	viewModelsLock sync.Mutex
 
-	snesViewModel   *SNESViewModel
-	romViewModel    *ROMViewModel
-	serverViewModel *ServerViewModel
+	// independent device sessions keyed by name; the default session is named "":
+	sessions     map[string]*Session
+	sessionsLock sync.Mutex
+
+	sessionsViewModel *SessionsViewModel
+
+	// ROMs known to o2 and its view model:
+	library          *Library
+	libraryViewModel *LibraryViewModel
 
 	config Config
 }
 
 type Config struct {
-	SNES   *SNESConfiguration         `json:"snes"`
-	ROM    *ROMConfiguration          `json:"rom"`
-	Server *ServerConfiguration       `json:"server"`
-	Games  map[string]json.RawMessage `json:"games"`
+	// the default session's configuration is kept at the top level:
+	SessionConfig
+
+	// additional named sessions:
+	Sessions map[string]*SessionConfig `json:"sessions,omitempty"`
 }
 
 func NewViewModel() *ViewModel {
 	vm := &ViewModel{
-		client: client.NewClient(),
+		viewModels: make(map[string]interface{}),
+		sessions:   make(map[string]*Session),
 	}
 
-	// instantiate each child view model:
-	vm.snesViewModel = NewSNESViewModel(vm)
-	vm.romViewModel = NewROMViewModel(vm)
-	vm.serverViewModel = NewServerViewModel(vm)
+	vm.sessionsViewModel = NewSessionsViewModel(vm)
+	vm.viewModels[sessionsView] = vm.sessionsViewModel
 
-	// assign unique names to each view for easy binding with html/js UI:
-	vm.viewModels = map[string]interface{}{
-		"status": "Not connected",
-		"snes":   vm.snesViewModel,
-		"rom":    vm.romViewModel,
-		"server": vm.serverViewModel,
+	dir, err := util.ConfigDir()
+	if err != nil {
+		log.Printf("viewmodel: could not find configuration directory for rom library: %v\n", err)
 	}
+	vm.library = NewLibrary(filepath.Join(dir, "library"))
+	vm.libraryViewModel = NewLibraryViewModel(vm, vm.library)
+	vm.viewModels[libraryView] = vm.libraryViewModel
+
+	// assign unique names to each view for easy binding with html/js UI:
+	vm.addSession(newSession(vm, ""))
 
 	return vm
 }
 
+// DefaultSession returns the session whose views are unprefixed
+func (vm *ViewModel) DefaultSession() *Session {
+	return vm.Session("")
+}
+
+// Session returns the named session or nil if it does not exist
+func (vm *ViewModel) Session(name string) *Session {
+	defer vm.sessionsLock.Unlock()
+	vm.sessionsLock.Lock()
+
+	return vm.sessions[name]
+}
+
+// SessionNames returns the names of all sessions in sorted order
+func (vm *ViewModel) SessionNames() []string {
+	defer vm.sessionsLock.Unlock()
+	vm.sessionsLock.Lock()
+
+	names := make([]string, 0, len(vm.sessions))
+	for name := range vm.sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (vm *ViewModel) addSession(s *Session) {
+	vm.sessionsLock.Lock()
+	vm.sessions[s.name] = s
+	vm.sessionsLock.Unlock()
+
+	vm.viewModelsLock.Lock()
+	for view, model := range s.views() {
+		vm.viewModels[view] = model
+	}
+	vm.viewModelsLock.Unlock()
+}
+
+func (vm *ViewModel) removeSession(s *Session) {
+	vm.sessionsLock.Lock()
+	delete(vm.sessions, s.name)
+	vm.sessionsLock.Unlock()
+
+	vm.viewModelsLock.Lock()
+	for view := range vm.viewModels {
+		if s.ownsView(view) {
+			delete(vm.viewModels, view)
+		}
+	}
+	vm.viewModelsLock.Unlock()
+}
+
+// sessionUsingDevice finds the session currently connected to the same driver and device, if any
+func (vm *ViewModel) sessionUsingDevice(pair snes.NamedDriverDevicePair) *Session {
+	defer vm.sessionsLock.Unlock()
+	vm.sessionsLock.Lock()
+
+	for _, s := range vm.sessions {
+		if s.dev == nil || s.driverDevice.NamedDriver.Name != pair.NamedDriver.Name {
+			continue
+		}
+		if s.driverDevice.Device.GetId() == pair.Device.GetId() {
+			return s
+		}
+	}
+	return nil
+}
+
+func (vm *ViewModel) deleteViewModel(view string) {
+	defer vm.viewModelsLock.Unlock()
+	vm.viewModelsLock.Lock()
+
+	delete(vm.viewModels, view)
+}
+
+// snapshot copies the view models map so it can be iterated while view models are added or removed
+func (vm *ViewModel) snapshot() map[string]interface{} {
+	defer vm.viewModelsLock.Unlock()
+	vm.viewModelsLock.Lock()
+
+	viewModels := make(map[string]interface{}, len(vm.viewModels))
+	for view, model := range vm.viewModels {
+		viewModels[view] = model
+	}
+	return viewModels
+}
+
 func (vm *ViewModel) GetViewModel(view string) (interface{}, bool) {
 	defer vm.viewModelsLock.Unlock()
 	vm.viewModelsLock.Lock()
@@ -114,7 +194,7 @@ func (vm *ViewModel) NotifyView(view string, model interface{}) {
 
 // initializes all view models:
 func (vm *ViewModel) Init() {
-	for _, model := range vm.viewModels {
+	for _, model := range vm.snapshot() {
 		if i, ok := model.(interfaces.Initializable); ok {
 			i.Init()
 		}
@@ -155,13 +235,23 @@ func (vm *ViewModel) LoadConfiguration() bool {
 		return false
 	}
 
-	if vm.config.Games == nil {
-		vm.config.Games = make(map[string]json.RawMessage)
+	vm.DefaultSession().LoadSessionConfiguration(&vm.config.SessionConfig)
+
+	names := make([]string, 0, len(vm.config.Sessions))
+	for name := range vm.config.Sessions {
+		names = append(names, name)
 	}
-	vm.snesViewModel.LoadConfiguration(vm.config.SNES)
-	// loading ROM will instantiate Game instance which should load its own configuration
-	vm.romViewModel.LoadConfiguration(vm.config.ROM)
-	vm.serverViewModel.LoadConfiguration(vm.config.Server)
+	sort.Strings(names)
+	for _, name := range names {
+		s := vm.Session(name)
+		if s == nil {
+			s = newSession(vm, name)
+			s.snesViewModel.Init()
+			vm.addSession(s)
+		}
+		s.LoadSessionConfiguration(vm.config.Sessions[name])
+	}
+	vm.sessionsViewModel.MarkDirty()
 
 	return true
 }
@@ -173,30 +263,27 @@ func (vm *ViewModel) SaveConfiguration() bool {
 
 	log.Printf("viewmodel: saveConfiguration: saving configuration...\n")
 
-	vm.config.SNES = new(SNESConfiguration)
-	vm.config.ROM = new(ROMConfiguration)
-	vm.config.Server = new(ServerConfiguration)
-	if vm.config.Games == nil {
-		vm.config.Games = make(map[string]json.RawMessage)
-	}
+	sessions := make(map[string]*SessionConfig)
+	for _, name := range vm.SessionNames() {
+		s := vm.Session(name)
+		if s == nil {
+			continue
+		}
 
-	vm.snesViewModel.SaveConfiguration(vm.config.SNES)
-	vm.romViewModel.SaveConfiguration(vm.config.ROM)
-	vm.serverViewModel.SaveConfiguration(vm.config.Server)
-
-	game := vm.game
-	if game != nil {
-		gameName := game.Name()
-		// gameConfig must be json.Marshal-able:
-		gameConfig := game.ConfigurationModel()
-		marshaled, err := json.MarshalIndent(gameConfig, "    ", "  ")
-		if err != nil {
-			log.Printf("viewmodel: saveConfiguration: could not json marshal game '%s' configuration: %v\n", gameName, err)
+		if name == "" {
+			if !s.SaveSessionConfiguration(&vm.config.SessionConfig) {
+				return false
+			}
+			continue
+		}
+
+		config := new(SessionConfig)
+		if !s.SaveSessionConfiguration(config) {
 			return false
 		}
-		// override named game configuration; keep other games' configuration as-is:
-		vm.config.Games[gameName] = marshaled
+		sessions[name] = config
 	}
+	vm.config.Sessions = sessions
 
 	b, err := json.MarshalIndent(&vm.config, "", "  ")
 	if err != nil {
@@ -230,7 +317,7 @@ func (vm *ViewModel) SaveConfiguration() bool {
 
 // updates all view models:
 func (vm *ViewModel) Update() {
-	for _, model := range vm.viewModels {
+	for _, model := range vm.snapshot() {
 		if i, ok := model.(interfaces.Updateable); ok {
 			i.Update()
 		}
@@ -243,14 +330,14 @@ func (vm *ViewModel) NotifyViewTo(viewNotifier interfaces.ViewNotifier) {
 	}
 
 	// send all view models to this notifier regardless of dirty state:
-	for view, model := range vm.viewModels {
+	for view, model := range vm.snapshot() {
 		viewNotifier.NotifyView(view, model)
 	}
 }
 
 // updates all view models and notifies view:
 func (vm *ViewModel) UpdateAndNotifyView() {
-	for view, model := range vm.viewModels {
+	for view, model := range vm.snapshot() {
 		if i, ok := model.(interfaces.Updateable); ok {
 			i.Update()
 		}
@@ -276,7 +363,7 @@ func (vm *ViewModel) CommandFor(view, command string) (ce interfaces.Command, er
 	var svm interface{}
 	var ok bool
 
-	svm, ok = vm.viewModels[view]
+	svm, ok = vm.GetViewModel(view)
 	if !ok {
 		return nil, fmt.Errorf("view=%s,cmd=%s: no view model found to handle command", view, command)
 	}
@@ -293,231 +380,6 @@ func (vm *ViewModel) CommandFor(view, command string) (ce interfaces.Command, er
 	return
 }
 
-func (vm *ViewModel) setStatus(msg string) {
-	log.Printf("notify: %s\n", msg)
-	vm.viewModels["status"] = msg
-}
-
-func (vm *ViewModel) tryCreateGame() bool {
-	defer vm.UpdateAndNotifyView()
-
-	if vm.nextRom == nil {
-		log.Println("viewmodel: tryCreateGame: rom is nil")
-		return false
-	}
-	if vm.game != nil {
-		log.Println("viewmodel: tryCreateGame: stop game")
-		vm.game.Stop()
-	}
-
-	vm.rom = vm.nextRom
-	vm.factory = vm.nextFactory
-
-	log.Println("viewmodel: tryCreateGame: create new game")
-	game := vm.factory.NewGame(vm.rom)
-	vm.game = game
-
-	// provide the game with its deps:
-	game.ProvideQueue(vm.dev)
-	game.ProvideClient(vm.client)
-	// intercept root.viewNotifier to let us cache viewModel updates from the game:
-	// game will notify us of its viewModel on Start()/Reset():
-	game.ProvideViewModelContainer(vm)
-	game.ProvideConfigurationSystem(vm)
-
-	// initialize the game state:
-	game.Reset()
-
-	// load configuration:
-	if gameConfig, ok := vm.config.Games[game.Name()]; ok {
-		game.LoadConfiguration(gameConfig)
-	}
-
-	go func() {
-		// wait until the game is stopped:
-		<-vm.game.Stopped()
-		vm.game = nil
-		delete(vm.viewModels, "game")
-		vm.UpdateAndNotifyView()
-	}()
-
-	// start the game instance:
-	log.Println("viewmodel: tryCreateGame: start game")
-	game.Start()
-
-	return true
-}
-
-func (vm *ViewModel) IsConnected() bool {
-	return vm.dev != nil
-}
-
-func (vm *ViewModel) IsConnectedToDriver(driver snes.NamedDriver) bool {
-	if vm.dev == nil {
-		return false
-	}
-
-	return vm.driverDevice.NamedDriver == driver
-}
-
-func (vm *ViewModel) ROMSelected(rom *snes.ROM) error {
-	defer vm.UpdateAndNotifyView()
-
-	// the user has selected a ROM file:
-	log.Printf(`ROM selected
-title:   '%s'
-region:  %s (code %02X)
-version: 1.%d
-`,
-		string(rom.Header.Title[:]),
-		snes.RegionNames[rom.Header.DestinationCode],
-		rom.Header.DestinationCode,
-		rom.Header.MaskROMVersion)
-
-	// determine if ROM is recognizable as a game we provide support for:
-	vm.nextFactory = nil
-
-	allFactories := games.Factories()
-	factories := make([]games.Factory, 0, len(allFactories))
-	for _, f := range allFactories {
-		if !f.IsROMSupported(rom) {
-			continue
-		}
-		factories = append(factories, f)
-		break
-	}
-
-	if len(factories) == 0 {
-		// unrecognized ROM
-		vm.setStatus("ROM is not compatible with any game providers")
-		return nil
-	} else if len(factories) > 1 {
-		// more than one game type matches ROM
-		// TODO: could loop through factories and filter by CanPlay
-		vm.setStatus("ROM matches more than one game provider")
-		vm.nextFactory = nil
-		return nil
-	}
-
-	vm.nextFactory = factories[0]
-
-	// check if the ROM is supported:
-	ok, reason := vm.nextFactory.CanPlay(rom)
-	if !ok {
-		vm.setStatus(fmt.Sprintf("ROM not supported: %s", reason))
-		return nil
-	}
-
-	// make a backup copy of the unpatched ROM contents for saving later:
-	vm.unpatchedRomContents = make([]byte, len(rom.Contents))
-	copy(vm.unpatchedRomContents, rom.Contents)
-
-	// attempt to patch the ROM file:
-	patcher := vm.nextFactory.Patcher(rom)
-	if err := patcher.Patch(); err != nil {
-		err = fmt.Errorf("error patching ROM: %w", err)
-		log.Printf("viewmodel: romselected: patcher: %v\n", err)
-		vm.setStatus(err.Error())
-		return nil
-	}
-
-	vm.nextRom = rom
-	vm.tryCreateGame()
-
-	return nil
-}
-
-func (vm *ViewModel) SNESConnected(pair snes.NamedDriverDevicePair) {
-	defer func() {
-		vm.UpdateAndNotifyView()
-		vm.SaveConfiguration()
-	}()
-
-	if pair == vm.driverDevice && vm.dev != nil {
-		// no change
-		return
-	}
-
-	var err error
-	log.Printf("viewmodel: snesconnected: open: driver='%s', device='%s'\n", pair.NamedDriver.Name, pair.Device.GetId())
-	vm.dev, err = pair.NamedDriver.Driver.Open(pair.Device)
-	if err != nil {
-		log.Printf("viewmodel: snesconnected: open: %v\n", err)
-		vm.setStatus("Could not connect to the SNES")
-		vm.dev = nil
-		vm.driverDevice = snes.NamedDriverDevicePair{}
-		return
-	}
-
-	if vm.game != nil {
-		// inform the game of the new device:
-		vm.game.ProvideQueue(vm.dev)
-	}
-
-	go func() {
-		// wait for the SNES to be closed:
-		<-vm.dev.Closed()
-		log.Printf("viewmodel: snesconnected: closed: driver='%s', device='%s'\n", pair.NamedDriver.Name, pair.Device.GetId())
-		vm.SNESDisconnected()
-	}()
-
-	vm.driverDevice = pair
-	vm.setStatus("Connected to SNES")
-}
-
-func (vm *ViewModel) SNESDisconnected() {
-	defer vm.devLock.Unlock()
-	vm.devLock.Lock()
-
-	if vm.dev == nil {
-		if vm.game != nil {
-			vm.game.ProvideQueue(nil)
-		}
-		vm.driverDevice = snes.NamedDriverDevicePair{}
-		return
-	}
-
-	defer func() {
-		vm.UpdateAndNotifyView()
-		vm.SaveConfiguration()
-	}()
-
-	// enqueue the close operation:
-	snesClosed := make(chan error)
-	lastDev := vm.driverDevice
-	log.Printf("viewmodel: snesdisconnected: closing driver='%s', device='%s'\n", lastDev.NamedDriver.Name, lastDev.Device.GetId())
-	err := vm.dev.Enqueue(snes.CommandWithCompletion{
-		Command: &snes.CloseCommand{},
-		Completion: func(cmd snes.Command, err error) {
-			snesClosed <- err
-			close(snesClosed)
-		},
-	})
-
-	vm.dev = nil
-	if vm.game != nil {
-		vm.game.ProvideQueue(nil)
-	}
-	vm.driverDevice = snes.NamedDriverDevicePair{}
-	vm.setStatus("Disconnecting from SNES...")
-	vm.UpdateAndNotifyView()
-
-	if err != nil {
-		log.Printf("viewmodel: snesdisconnected: enqueue closecommand: %v\n", err)
-		return
-	}
-
-	// wait until snes is closed:
-	err = <-snesClosed
-	if err != nil {
-		log.Println(err)
-	}
-	log.Printf("viewmodel: snesdisconnected: closed device '%s'\n", lastDev.Device.GetDisplayName())
-
-	lastDev = snes.NamedDriverDevicePair{}
-	vm.setStatus("Disconnected from SNES")
-}
-
 func (vm *ViewModel) ProvideViewNotifier(viewNotifier interfaces.ViewNotifier) {
 	vm.viewNotifier = viewNotifier
 }
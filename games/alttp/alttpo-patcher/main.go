@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"o2/games/alttp"
 	"o2/snes"
+	"o2/snes/patch"
 	"os"
 	"path/filepath"
 )
@@ -44,6 +45,10 @@ func main() {
 		return
 	}
 
+	// keep the unpatched contents to create a BPS patch from:
+	unpatched := make([]byte, len(rom.Contents))
+	copy(unpatched, rom.Contents)
+
 	// patch the ROM:
 	patcher := alttp.NewPatcher(rom)
 	err = patcher.Patch()
@@ -57,4 +62,11 @@ func main() {
 		panic(err)
 	}
 	fmt.Println("wrote to patched.smc")
+
+	// and as a patch to share:
+	err = ioutil.WriteFile("o2.bps", patch.CreateBPS(unpatched, rom.Contents, "o2"), 0644)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("wrote to o2.bps")
 }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"o2/client"
+	"o2/games/alttp"
+	"o2/games/zelda3"
+	"o2/snes"
+	"os"
+	"path/filepath"
+)
+
+// replays a session recorded with "Record Sessions" (or `o2 -headless -record`) into a fresh game and prints
+// what each player looked like at the end of it
+func main() {
+	var (
+		romPath string
+		speed   float64
+		dump    bool
+	)
+	flag.StringVar(&romPath, "rom", "", "ROM the session was played with")
+	flag.Float64Var(&speed, "speed", 0, "replay speed relative to the recording; 0 replays as fast as possible")
+	flag.BoolVar(&dump, "sram", false, "dump each player's items and progress SRAM ($340-$38F)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s -rom <rom> [-speed n] [-sram] <recording.o2rec>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if romPath == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	contents, err := ioutil.ReadFile(romPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, name := filepath.Split(romPath)
+	rom, err := snes.NewROM(name, contents)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !alttp.FactoryInstance().IsROMSupported(rom) {
+		// only the ROM's randomizer flavor matters to how messages are deserialized:
+		log.Printf("replay: '%s' does not look like an ALTTP ROM; replaying anyway\n", romPath)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	rr, err := client.NewRecordingReader(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	g := alttp.FactoryInstance().NewGame(rom).(*zelda3.Game)
+	g.Reset()
+	if err = g.Replay(rr, speed); err != nil {
+		log.Fatal(err)
+	}
+
+	local := g.LocalPlayer()
+	for _, p := range g.ActivePlayers() {
+		who := ""
+		if p == local {
+			who = " (recorded)"
+		}
+		fmt.Printf("player %2d team %3d '%s'%s\n", p.Index(), p.Team(), p.Name(), who)
+		if dump {
+			fmt.Print(hex.Dump(p.SRAM[0x340:0x390]))
+		}
+	}
+}
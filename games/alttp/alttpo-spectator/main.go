@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"o2/client"
+	"o2/games/zelda3"
+	"o2/util/env"
+	"sync"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+// feedHub fans out each spectator feed snapshot to all connected websockets:
+type feedHub struct {
+	lock sync.Mutex
+	last []byte
+	subs map[chan []byte]struct{}
+}
+
+func (h *feedHub) publish(feed *zelda3.SpectatorFeed) {
+	b, err := json.Marshal(feed)
+	if err != nil {
+		log.Printf("spectator: json: %v\n", err)
+		return
+	}
+
+	defer h.lock.Unlock()
+	h.lock.Lock()
+
+	h.last = b
+	for sub := range h.subs {
+		select {
+		case sub <- b:
+		default:
+			// slow consumer; drop this snapshot, the next one supersedes it anyway
+		}
+	}
+}
+
+func (h *feedHub) subscribe() chan []byte {
+	defer h.lock.Unlock()
+	h.lock.Lock()
+
+	sub := make(chan []byte, 4)
+	h.subs[sub] = struct{}{}
+	if h.last != nil {
+		sub <- h.last
+	}
+	return sub
+}
+
+func (h *feedHub) unsubscribe(sub chan []byte) {
+	defer h.lock.Unlock()
+	h.lock.Lock()
+
+	delete(h.subs, sub)
+}
+
+func (h *feedHub) latest() []byte {
+	defer h.lock.Unlock()
+	h.lock.Lock()
+
+	return h.last
+}
+
+func main() {
+	var (
+		serverAddr string
+		group      string
+		password   string
+		listen     string
+	)
+	flag.StringVar(&serverAddr, "server", net.JoinHostPort("alttp.online", env.GetOrDefault("O2_DEFAULT_SERVER_PORT", "4590")), "O2 server to connect to")
+	flag.StringVar(&group, "group", "", "group name to spectate")
+	flag.StringVar(&password, "password", "", "group password, if any")
+	flag.StringVar(&listen, "listen", "127.0.0.1:27638", "address to serve the feed on")
+	flag.Parse()
+
+	if group == "" {
+		log.Fatal("spectator: -group is required")
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", serverAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	c := client.NewClient()
+	if err = c.Connect(addr); err != nil {
+		log.Fatal(err)
+	}
+	c.SetGroup(group)
+	c.SetPassword(password)
+
+	hub := &feedHub{subs: make(map[chan []byte]struct{})}
+
+	spectator := zelda3.NewSpectator(c)
+	spectator.OnUpdate = hub.publish
+
+	// latest snapshot for polling:
+	http.HandleFunc("/feed.json", func(w http.ResponseWriter, r *http.Request) {
+		b := hub.latest()
+		if b == nil {
+			http.Error(w, "no feed yet", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		_, _ = w.Write(b)
+	})
+
+	// live feed; every snapshot is sent as a JSON text message:
+	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _, err := ws.UpgradeHTTP(r, w)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		log.Printf("spectator: feed connection from %s\n", conn.RemoteAddr())
+
+		sub := hub.subscribe()
+		closed := make(chan struct{})
+		var writeLock sync.Mutex
+
+		go func() {
+			for {
+				select {
+				case b := <-sub:
+					writeLock.Lock()
+					err := wsutil.WriteServerText(conn, b)
+					writeLock.Unlock()
+					if err != nil {
+						return
+					}
+				case <-closed:
+					return
+				}
+			}
+		}()
+
+		// the reader is in control of the lifetime of the connection:
+		go func() {
+			defer func() {
+				close(closed)
+				hub.unsubscribe(sub)
+				_ = conn.Close()
+				log.Printf("spectator: feed connection from %s closed\n", conn.RemoteAddr())
+			}()
+
+			for {
+				f, err := ws.ReadFrame(conn)
+				if err != nil {
+					return
+				}
+				if f.Header.Masked {
+					ws.Cipher(f.Payload, f.Header.Mask, 0)
+				}
+
+				switch f.Header.OpCode {
+				case ws.OpPing:
+					writeLock.Lock()
+					err = ws.WriteFrame(conn, ws.NewPongFrame(f.Payload))
+					writeLock.Unlock()
+					if err != nil {
+						return
+					}
+				case ws.OpClose:
+					// echo the close frame back to complete the closing handshake:
+					writeLock.Lock()
+					_ = ws.WriteFrame(conn, ws.NewCloseFrame(f.Payload))
+					writeLock.Unlock()
+					return
+				}
+				// anything else a feed client sends is ignored
+			}
+		}()
+	})
+
+	go func() {
+		log.Printf("spectator: serving feed on http://%s/ws and http://%s/feed.json\n", listen, listen)
+		log.Fatal(http.ListenAndServe(listen, nil))
+	}()
+
+	if err = spectator.Run(nil); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("spectator: disconnected")
+}
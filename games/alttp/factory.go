@@ -1,19 +1,29 @@
 package alttp
 
 import (
-	"o2/games"
+	"o2/games/zelda3"
 	"o2/snes"
+	"strings"
 )
 
-var gameName = "ALTTP"
+// Layout describes A Link to the Past and its randomizers for the shared zelda3 engine
+var Layout = &zelda3.Layout{
+	Name:           "ALTTP",
+	ResetHook:      0x00802F,
+	InitHook:       0x1BB1D7,
+	FrameHook:      0x008056,
+	IsROMSupported: isROMSupported,
+}
 
-type Factory struct{}
+var factory *zelda3.Factory
 
-var factory *Factory
+func FactoryInstance() *zelda3.Factory { return factory }
 
-func FactoryInstance() *Factory { return factory }
+func NewPatcher(rom *snes.ROM) *zelda3.Patcher {
+	return zelda3.NewPatcher(Layout, rom)
+}
 
-func (f *Factory) IsROMSupported(rom *snes.ROM) bool {
+func isROMSupported(rom *snes.ROM) bool {
 	if rom.Header.HeaderVersion() != 1 {
 		return false
 	}
@@ -26,19 +36,13 @@ func (f *Factory) IsROMSupported(rom *snes.ROM) bool {
 	if rom.Header.OldMakerCode != 0x01 {
 		return false
 	}
+	// Super Metroid's header looks the same but it is provided by games/sm:
+	if strings.HasPrefix(string(rom.Header.Title[:]), "Super Metroid") {
+		return false
+	}
 	return true
 }
 
-func (f *Factory) CanPlay(rom *snes.ROM) (ok bool, whyNot string) {
-	// TODO: read header of ROM to determine what variants are supported or not
-	return true, ""
-}
-
-func (f *Factory) Patcher(rom *snes.ROM) games.Patcher {
-	return &Patcher{rom: rom}
-}
-
 func init() {
-	factory = &Factory{}
-	games.Register(gameName, factory)
+	factory = zelda3.Register(Layout)
 }
@@ -0,0 +1,133 @@
+// Package base implements the game-independent lifecycle of a synced game: reading a watch list of memory from
+// the SNES, injecting generated update routines into SRAM and broadcasting memory to other players. A game
+// plugs in its hooks, watch lists and sync table with a Definition.
+package base
+
+import (
+	"fmt"
+	"o2/games"
+	"o2/snes"
+	"o2/snes/asm"
+)
+
+// Definition describes everything the base Game needs to know about a specific game
+type Definition struct {
+	// Name identifies the game for registration and configuration, e.g. "SM"
+	Name string
+	// Title is displayed to the player; empty uses Name
+	Title string
+
+	// IsROMSupported recognizes the game from the ROM header
+	IsROMSupported func(rom *snes.ROM) bool
+
+	// Hooks describes where to patch the ROM
+	Hooks Hooks
+	// Mapping translates SRAM addresses for the ROM's memory map; nil uses the map the ROM header declares
+	Mapping Mapping
+
+	// WatchWRAM lists the WRAM ranges read every frame, as offsets from $7E:0000; they are read in order so
+	// the range IsInGame depends on should be last
+	WatchWRAM []Range
+	// WatchSRAM lists the cartridge SRAM ranges read periodically, as linear offsets into SRAM
+	WatchSRAM []Range
+
+	// FrameCounter is the WRAM offset of an 8-bit counter that advances with every game frame
+	FrameCounter uint32
+	// IsInGame determines from the watched WRAM whether the game is in a state where memory is valid and safe
+	// to update; nil always assumes so
+	IsInGame func(wram []byte) bool
+	// UpdateGuard optionally emits asm at the start of each update routine that executes RTS when it is not
+	// yet safe to update, e.g. during a cutscene. It runs with 8-bit registers.
+	UpdateGuard func(a *asm.Emitter)
+
+	// SyncTable describes the syncable memory; each distinct "enable" name becomes a sync option
+	SyncTable *games.SyncTable
+}
+
+// Range is a contiguous range of memory to watch
+type Range struct {
+	Offset uint32
+	Size   uint8
+}
+
+// Mapping translates between SNES bus addresses and FX Pak Pro address space for a ROM's memory map
+type Mapping interface {
+	BusAddressToPak(busAddr uint32) uint32
+	PakAddressToBus(pakAddr uint32) uint32
+}
+
+// LoROM is the Mapping for LoROM games with SRAM at $70:0000
+var LoROM Mapping = snes.LoROM
+
+func (d *Definition) mapping(rom *snes.ROM) Mapping {
+	if d.Mapping == nil {
+		return rom.Mapping()
+	}
+	return d.Mapping
+}
+
+func (d *Definition) title() string {
+	if d.Title == "" {
+		return d.Name
+	}
+	return d.Title
+}
+
+// Validate checks that the definition is complete and that every syncable is covered by a watched range
+func (d *Definition) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("base: definition has no name")
+	}
+	if d.IsROMSupported == nil {
+		return fmt.Errorf("base: %s: IsROMSupported is required", d.Name)
+	}
+	if len(d.WatchWRAM) == 0 {
+		return fmt.Errorf("base: %s: WRAM watch list is empty", d.Name)
+	}
+	if !isWatched(d.WatchWRAM, d.FrameCounter, 1) {
+		return fmt.Errorf("base: %s: frame counter $%04x is not in the WRAM watch list", d.Name, d.FrameCounter)
+	}
+	if d.SyncTable == nil {
+		return nil
+	}
+
+	for i := range d.SyncTable.Syncables {
+		e := &d.SyncTable.Syncables[i]
+		var size uint32 = 1
+		switch e.Strategy {
+		case games.SyncStrategyBits, games.SyncStrategyMax, games.SyncStrategyScript:
+		case games.SyncStrategyBits16:
+			size = 2
+		default:
+			return fmt.Errorf("base: %s: sync table: offset $%x: strategy '%s' is not supported", d.Name, uint32(e.Offset), e.Strategy)
+		}
+		if e.OnUpdated != "" || e.When != "" {
+			return fmt.Errorf("base: %s: sync table: offset $%x: onUpdated and when are not supported", d.Name, uint32(e.Offset))
+		}
+		if e.Enable == "" {
+			return fmt.Errorf("base: %s: sync table: offset $%x: enable is required", d.Name, uint32(e.Offset))
+		}
+
+		kind, err := e.MemoryKind()
+		if err != nil {
+			return err
+		}
+		watch := d.WatchSRAM
+		if kind == games.WRAM {
+			watch = d.WatchWRAM
+		}
+		if !isWatched(watch, uint32(e.Offset), size) {
+			return fmt.Errorf("base: %s: sync table: offset $%x is not in a watched range", d.Name, uint32(e.Offset))
+		}
+	}
+	return nil
+}
+
+func isWatched(watch []Range, offset uint32, size uint32) bool {
+	for _, r := range watch {
+		if offset >= r.Offset && offset+size <= r.Offset+uint32(r.Size) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package base
+
+import (
+	"o2/games"
+	"o2/snes"
+)
+
+type Factory struct {
+	def *Definition
+}
+
+// NewFactory creates a games.Factory for ROMs described by def; it panics if def is invalid
+func NewFactory(def *Definition) *Factory {
+	if err := def.Validate(); err != nil {
+		panic(err)
+	}
+	return &Factory{def: def}
+}
+
+// Register creates a Factory for the definition and registers it under the definition's name
+func Register(def *Definition) *Factory {
+	f := NewFactory(def)
+	games.Register(def.Name, f)
+	return f
+}
+
+func (f *Factory) Definition() *Definition { return f.def }
+
+func (f *Factory) IsROMSupported(rom *snes.ROM) bool {
+	return f.def.IsROMSupported(rom)
+}
+
+func (f *Factory) CanPlay(rom *snes.ROM) (ok bool, whyNot string) {
+	return true, ""
+}
+
+func (f *Factory) Patcher(rom *snes.ROM) games.Patcher {
+	return NewPatcher(&f.def.Hooks, rom)
+}
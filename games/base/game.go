@@ -1,7 +1,8 @@
-package smz3
+package base
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"o2/client"
 	"o2/engine"
@@ -16,8 +17,9 @@ import (
 // MaxPlayers can extend to 65536 theoretical max due to use of uint16 for player indexes in protocol
 const MaxPlayers = 256
 
-// Game implements game.Game
+// Game implements games.Game for any game described by a Definition
 type Game struct {
+	def *Definition
 	// rom cannot be nil
 	rom *snes.ROM
 
@@ -30,8 +32,6 @@ type Game struct {
 	// viewModels can be nil at any time
 	viewModels interfaces.ViewModelContainer
 
-	deserTable []DeserializeFunc
-
 	// Notifications publishes notifications about game events intended for the player to see
 	Notifications interfaces.ObservableImpl
 
@@ -50,100 +50,72 @@ type Game struct {
 	readComplete chan []snes.Response
 
 	lastReadCompleted time.Time
-	notFirstWRAMRead  bool
 
 	nextUpdateA      bool
 	updateLock       sync.Mutex
 	updateStage      int
 	lastUpdateTarget uint32
 
-	customAsmLock sync.Mutex
-	customAsm     []byte
-
-	locHashTTL int
-	locHash    uint64
-
 	// staging area to read data into first before validating e.g. not in reset state or in SD2SNES menu, etc.:
 	wramStaging [0x20000]byte
 	// game-valid memory:
 	wram [0x20000]byte
-	sram [0x10000]byte
 
 	invalid bool
 
-	syncableItems  map[uint16]games.SyncStrategy
-	underworld     [0x128]games.SyncableBitU16
-	overworld      [0xC0]games.SyncableBitU8
-	syncableBitU16 map[uint16]*games.SyncableBitU16
+	// syncables are created from the sync table in table order:
+	syncables []games.SyncStrategy
 
-	romFunctions map[romFunction]uint32
-
-	lastGameFrame      uint8  // copy of wram[$001A] in-game frame counter of vanilla ALTTP game
-	localFrame         uint64 // total frame count since start of local game
-	serverFrame        uint64 // total frame count according to server (taken from first player to enter group)
-	monotonicFrameTime uint8  // always increments by 1 whenever game frame increases by any amount N
+	lastGameFrame      uint8 // copy of the definition's frame counter
+	monotonicFrameTime uint8 // always increments by 1 whenever game frame increases by any amount N
 
 	shouldUpdatePlayersList bool
 
-	colorPendingUpdate int
-	colorUpdatedTo     uint16
-	last15             uint8
-
 	// serializable ViewModel:
-	clean            bool
-	IsCreated        bool   `json:"isCreated"`
-	GameName         string `json:"gameName"`
-	PlayerColor      uint16 `json:"playerColor"`
-	SyncItems        bool   `json:"syncItems"`
-	SyncDungeonItems bool   `json:"syncDungeonItems"`
-	SyncProgress     bool   `json:"syncProgress"`
-	SyncHearts       bool   `json:"syncHearts"`
-	SyncSmallKeys    bool   `json:"syncSmallKeys"`
-	SyncUnderworld   bool   `json:"syncUnderworld"`
-	SyncOverworld    bool   `json:"syncOverworld"`
-	SyncChests       bool   `json:"syncChests"`
-	lastSyncChests   bool
-	SyncTunicColor   bool `json:"syncTunicColor"`
+	clean     bool
+	IsCreated bool   `json:"isCreated"`
+	GameName  string `json:"gameName"`
+	// SyncOptions are the sync table's "enable" names mapped to whether they are enabled
+	SyncOptions map[string]*bool `json:"syncOptions"`
 }
 
 func (f *Factory) NewGame(rom *snes.ROM) games.Game {
 	if rom == nil {
-		panic("smz3: rom cannot be nil")
+		panic("base: rom cannot be nil")
 	}
 
 	g := &Game{
+		def:              f.def,
 		rom:              rom,
 		running:          false,
 		stopped:          make(chan struct{}),
 		readComplete:     make(chan []snes.Response, 256),
-		romFunctions:     make(map[romFunction]uint32),
 		lastUpdateTarget: 0xFFFFFF,
 		// ViewModel:
-		IsCreated:        true,
-		GameName:         gameName,
-		SyncItems:        true,
-		SyncDungeonItems: true,
-		SyncProgress:     true,
-		SyncHearts:       true,
-		SyncSmallKeys:    true,
-		SyncUnderworld:   true,
-		SyncOverworld:    true,
-		SyncChests:       true,
-		lastSyncChests:   false,
+		IsCreated:   true,
+		GameName:    f.def.Name,
+		SyncOptions: make(map[string]*bool),
 	}
 
-	g.initSerde()
-	g.fillRomFunctions()
+	if table := f.def.SyncTable; table != nil {
+		for i := range table.Syncables {
+			enable := table.Syncables[i].Enable
+			if g.SyncOptions[enable] == nil {
+				enabled := true
+				g.SyncOptions[enable] = &enabled
+			}
+		}
+	}
 
 	return g
 }
 
 func (g *Game) Name() string {
-	return gameName
+	return g.GameName
 }
 
 func (g *Game) Title() string {
-	return "SMZ3"
+	return g.def.title()
 }
 
 func (g *Game) Description() string {
@@ -151,18 +123,24 @@ func (g *Game) Description() string {
 }
 
 func (g *Game) LoadConfiguration(config json.RawMessage) {
-	// kind of dirty to just unmarshal the public `json` tagged fields, but it works:
-	err := json.Unmarshal(config, g)
+	var c struct {
+		SyncOptions map[string]bool `json:"syncOptions"`
+	}
+	err := json.Unmarshal(config, &c)
 	if err != nil {
-		log.Printf("smz3: loadConfiguration: %v\n", err)
+		log.Printf("base: %s: loadConfiguration: %v\n", g.def.Name, err)
 		return
 	}
 	g.IsCreated = true
-	g.local.PlayerColor = g.PlayerColor
+	// only options the sync table knows about are kept:
+	for name, enabled := range c.SyncOptions {
+		if p := g.SyncOptions[name]; p != nil {
+			*p = enabled
+		}
+	}
 }
 
 func (g *Game) ConfigurationModel() interface{} {
-	// kind of dirty to just marshal the public `json` tagged fields, but it works:
 	return g
 }
 
@@ -171,7 +149,6 @@ func (g *Game) ProvideQueue(queue snes.Queue) {
 
 	// must reset any state waiting on connected device:
 	g.updateStage = 0
-	g.colorPendingUpdate = 0
 	g.lastUpdateTarget = 0xFFFFFF
 }
 func (g *Game) ProvideClient(client *client.Client) {
@@ -186,10 +163,9 @@ func (g *Game) ProvideConfigurationSystem(configurationSystem interfaces.Configu
 
 // Notify is called by root ViewModel
 func (g *Game) Notify(key string, value interface{}) {
-	//log.Printf("alttp: notify('%s', '%+v')\n", key, value)
 	switch key {
 	case "team":
-		g.local.Team = value.(uint8)
+		g.local.TeamF = value.(uint8)
 		g.updatePlayersList()
 		break
 	case "playerName":
@@ -206,18 +182,18 @@ func (g *Game) IsRunning() bool {
 func (g *Game) Reset() {
 	g.clean = false
 
-	// an impossible color in 15-bit BGR:
-	g.colorUpdatedTo = 0xffff
+	// must reset any state waiting on connected device:
+	g.updateStage = 0
+	g.lastUpdateTarget = 0xFFFFFF
 
 	// clear out players array:
 	for i := range g.players {
-		g.players[i] = Player{IndexF: -1, PlayerColor: 0x12ef}
+		g.players[i] = g.newPlayer()
 	}
 
 	// create a temporary Player instance until we get our Index assigned from the server:
-	g.local = &Player{IndexF: -1, PlayerColor: 0x12ef}
-	local := g.local
-	local.WRAM = make(map[uint16]*SyncableWRAM)
+	local := g.newPlayer()
+	g.local = &local
 
 	if g.viewModels != nil {
 		// preserve last-set info:
@@ -225,7 +201,7 @@ func (g *Game) Reset() {
 		if ok && serverViewModelIntf != nil {
 			serverViewModel := serverViewModelIntf.(*engine.ServerViewModel)
 			local.NameF = serverViewModel.PlayerName
-			local.Team = serverViewModel.Team
+			local.TeamF = serverViewModel.Team
 		}
 	}
 
@@ -234,7 +210,67 @@ func (g *Game) Reset() {
 		g.wram[i] = 0xFF
 	}
 
-	g.initSync()
+	if err := g.initSync(); err != nil {
+		log.Printf("base: %s: %v\n", g.def.Name, err)
+	}
+}
+
+// initSync creates the syncables described by the definition's sync table
+func (g *Game) initSync() error {
+	g.syncables = nil
+	table := g.def.SyncTable
+	if table == nil {
+		return nil
+	}
+
+	for i := range table.Syncables {
+		e := &table.Syncables[i]
+		enabled := g.SyncOptions[e.Enable]
+		if enabled == nil {
+			return fmt.Errorf("sync table: offset $%x: unknown enable flag '%s'", uint32(e.Offset), e.Enable)
+		}
+		kind, err := e.MemoryKind()
+		if err != nil {
+			return err
+		}
+
+		offset := uint32(e.Offset)
+		var s games.SyncStrategy
+		switch e.Strategy {
+		case games.SyncStrategyBits:
+			b := games.NewSyncableBitU8(g, offset, enabled, e.Names, nil)
+			b.MemoryKind = kind
+			if e.Mask != 0 {
+				b.SyncMask = uint8(e.Mask)
+			}
+			b.NotificationFormat = e.Notification
+			s = b
+		case games.SyncStrategyBits16:
+			b := games.NewSyncableBitU16(g, offset, enabled, e.Names, nil)
+			b.MemoryKind = kind
+			if e.Mask != 0 {
+				b.SyncMask = uint16(e.Mask)
+			}
+			b.NotificationFormat = e.Notification
+			s = b
+		case games.SyncStrategyMax:
+			m := games.NewSyncableMaxU8(g, offset, enabled, e.Names, nil)
+			m.MemoryKind = kind
+			if e.AbsMax != 0 {
+				m.AbsMax = uint8(e.AbsMax)
+			}
+			m.NotificationFormat = e.Notification
+			s = m
+		case games.SyncStrategyScript:
+			sc := games.NewSyncableScript(g, offset, enabled, e.SyncScript())
+			sc.MemoryKind = kind
+			s = sc
+		default:
+			return fmt.Errorf("sync table: offset $%x: strategy '%s' is not supported", uint32(e.Offset), e.Strategy)
+		}
+		g.syncables = append(g.syncables, s)
+	}
+	return nil
 }
 
 func (g *Game) Start() {
@@ -274,7 +310,8 @@ func (g *Game) ActivePlayers() []*Player {
 	if !g.activePlayersClean {
 		g.activePlayers = make([]*Player, 0, len(g.activePlayers))
 
-		for i, p := range g.players {
+		for i := range g.players {
+			p := &g.players[i]
 			if p.Index() < 0 {
 				continue
 			}
@@ -282,7 +319,7 @@ func (g *Game) ActivePlayers() []*Player {
 				continue
 			}
 
-			g.activePlayers = append(g.activePlayers, &g.players[i])
+			g.activePlayers = append(g.activePlayers, p)
 		}
 
 		g.activePlayersClean = true
@@ -291,18 +328,6 @@ func (g *Game) ActivePlayers() []*Player {
 	return g.activePlayers
 }
 
-func (g *Game) RemotePlayers() []*Player {
-	activePlayers := g.ActivePlayers()
-	remotePlayers := make([]*Player, 0, len(activePlayers))
-	for _, p := range activePlayers {
-		if p == g.LocalPlayer() {
-			continue
-		}
-		remotePlayers = append(remotePlayers, p)
-	}
-	return remotePlayers
-}
-
 func (g *Game) LocalSyncablePlayer() games.SyncablePlayer {
 	return g.local
 }
@@ -316,5 +341,6 @@ func (g *Game) RemoteSyncablePlayers() []games.SyncablePlayer {
 		}
 		remotePlayers = append(remotePlayers, p)
 	}
-	return remotePlayers
+	// only sync with teammates:
+	return games.PlayersOnTeam(remotePlayers, g.LocalPlayer().Team())
 }
@@ -0,0 +1,154 @@
+package base
+
+import (
+	"bytes"
+	"o2/games"
+	"o2/snes"
+	"o2/snes/asm"
+	"strings"
+	"testing"
+)
+
+const testSyncTable = `
+syncables:
+  - {offset: 0x09A4, memory: wram, strategy: bits, names: [Morph Ball, Bombs], enable: items}
+  - {offset: 0x0020, strategy: max, enable: progress}
+`
+
+func newTestDefinition(t *testing.T) *Definition {
+	table, err := games.ParseSyncTable([]byte(testSyncTable), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Definition{
+		Name:           "test",
+		IsROMSupported: func(rom *snes.ROM) bool { return true },
+		Hooks:          Hooks{ResetHook: 0x008100, InitHook: 0x0FF000, FrameHook: 0x008200},
+		WatchWRAM:      []Range{{Offset: 0x09A0, Size: 0x10}, {Offset: 0x05B0, Size: 0x08}},
+		WatchSRAM:      []Range{{Offset: 0x0000, Size: 0x40}},
+		FrameCounter:   0x05B6,
+		SyncTable:      table,
+	}
+}
+
+func newTestGame(t *testing.T, def *Definition) *Game {
+	rom, err := snes.NewROM("test.sfc", make([]byte, 0x10_0000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewFactory(def).NewGame(rom).(*Game)
+	g.Reset()
+
+	// pretend the server assigned player indexes:
+	g.local = &g.players[0]
+	g.local.IndexF = 0
+	g.players[1].IndexF = 1
+	g.SetTTL(g.local, 255)
+	g.SetTTL(&g.players[1], 255)
+	return g
+}
+
+func TestDefinition_Validate(t *testing.T) {
+	if err := newTestDefinition(t).Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := map[string]func(d *Definition){
+		"unwatched syncable": func(d *Definition) { d.SyncTable.Syncables[0].Offset = 0x0A00 },
+		"unwatched frame":    func(d *Definition) { d.FrameCounter = 0x1000 },
+		"custom strategy":    func(d *Definition) { d.SyncTable.Syncables[1].Strategy = games.SyncStrategyCustom },
+		"no ROM recognition": func(d *Definition) { d.IsROMSupported = nil },
+		"16-bit across boundary": func(d *Definition) {
+			d.SyncTable.Syncables[0].Offset, d.SyncTable.Syncables[0].Strategy = 0x09AF, "bits16"
+		},
+	}
+	for name, mutate := range invalid {
+		d := newTestDefinition(t)
+		mutate(d)
+		if err := d.Validate(); err == nil {
+			t.Errorf("%s: Validate() should fail", name)
+		}
+	}
+}
+
+func TestGame_GenerateUpdateAsm(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		g := newTestGame(t, newTestDefinition(t))
+		if len(g.syncables) != 2 {
+			t.Fatalf("len(syncables) = %d, want 2", len(g.syncables))
+		}
+		*g.SyncOptions["items"] = enabled
+		*g.SyncOptions["progress"] = enabled
+
+		local, remote := g.local, &g.players[1]
+		local.WRAM.write(0x09A4, []byte{0x01})
+		remote.WRAM.write(0x09A4, []byte{0x03})
+		local.SRAM.write(0x0020, []byte{0x02})
+		remote.SRAM.write(0x0020, []byte{0x04})
+
+		a := asm.Emitter{Code: &bytes.Buffer{}, Text: &strings.Builder{}}
+		a.SetBase(LoROMRoutines.UpdateA)
+		a.AssumeSEP(0x30)
+		if updated := g.generateUpdateAsm(&a); updated != enabled {
+			t.Fatalf("generateUpdateAsm() = %v with sync options enabled = %v", updated, enabled)
+		}
+		if !enabled {
+			continue
+		}
+
+		code := a.Code.Bytes()
+		// STA $7E09A4
+		if !bytes.Contains(code, []byte{0x8F, 0xA4, 0x09, 0x7E}) {
+			t.Errorf("expected a write to $7E09A4:\n%s", a.Text.String())
+		}
+		// STA $700020
+		if !bytes.Contains(code, []byte{0x8F, 0x20, 0x00, 0x70}) {
+			t.Errorf("expected a write to $700020:\n%s", a.Text.String())
+		}
+	}
+}
+
+func TestGame_Serialize(t *testing.T) {
+	def := newTestDefinition(t)
+	// enough watched memory to need more than one message:
+	for i := uint32(0); i < 8; i++ {
+		def.WatchWRAM = append(def.WatchWRAM, Range{Offset: 0x1000 + i*0x100, Size: 0xFF})
+	}
+	g := newTestGame(t, def)
+	g.local.NameF = "samus"
+
+	for _, rng := range def.WatchWRAM {
+		for i := uint32(0); i < uint32(rng.Size); i++ {
+			g.local.WRAM.write(rng.Offset+i, []byte{uint8(rng.Offset + i)})
+		}
+	}
+	g.local.SRAM.write(0x0020, []byte{0x04})
+
+	msgs := g.makeMemoryMessages()
+	if len(msgs) < 2 {
+		t.Fatalf("len(messages) = %d, want at least 2", len(msgs))
+	}
+	msgs = append(msgs, g.makePlayerNameMessage())
+
+	p := &g.players[2]
+	for _, m := range msgs {
+		if m.Len() > maxMessageLen {
+			t.Errorf("message length %d exceeds %d", m.Len(), maxMessageLen)
+		}
+		if err := g.Deserialize(bytes.NewReader(m.Bytes()), p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if p.Name() != "samus" {
+		t.Errorf("name = %q, want %q", p.Name(), "samus")
+	}
+	for _, offs := range []uint32{0x09A4, 0x05B6, 0x1000, 0x17FE} {
+		if actual, expected := p.WRAM.ReadU8(offs), g.local.WRAM.ReadU8(offs); actual != expected {
+			t.Errorf("wram[$%04x] = %02x, want %02x", offs, actual, expected)
+		}
+	}
+	if p.SRAM.ReadU8(0x0020) != 0x04 {
+		t.Errorf("sram[$0020] = %02x, want 04", p.SRAM.ReadU8(0x0020))
+	}
+}
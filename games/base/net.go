@@ -0,0 +1,230 @@
+package base
+
+import (
+	"bytes"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"log"
+	"o2/client"
+	"o2/client/protocol03"
+	"time"
+)
+
+// maxMessageLen keeps broadcast messages well under a typical MTU
+const maxMessageLen = 1024
+
+type gameMessage interface {
+	SendToClient(c *client.Client)
+}
+
+type gameBroadcastMessage struct {
+	bytes.Buffer
+
+	g *Game
+}
+
+func (g *Game) groupMessage(c *client.Client) *protocol03.GroupMessage {
+	return &protocol03.GroupMessage{
+		Group:       string(c.Group()),
+		PlayerTime:  time.Now().UnixNano(),
+		PlayerIndex: uint32(g.LocalPlayer().IndexF),
+	}
+}
+
+func writeGroupMessage(c *client.Client, gm *protocol03.GroupMessage) {
+	pkt := client.MakePacket(0x03)
+	b, err := proto.MarshalOptions{}.MarshalAppend(pkt.Bytes(), gm)
+	if err != nil {
+		log.Printf("base: send: proto.Marshal: %v\n", err)
+		return
+	}
+
+	c.Write() <- b
+}
+
+func (m *gameBroadcastMessage) SendToClient(c *client.Client) {
+	gm := m.g.groupMessage(c)
+	gm.BroadcastAll = &protocol03.BroadcastAll{Data: m.Bytes()}
+	c.Record(client.Outbound, gm)
+
+	// authenticate with the group password, if any:
+	if err := c.Seal(gm); err != nil {
+		log.Printf("base: send: seal: %v\n", err)
+		return
+	}
+
+	writeGroupMessage(c, gm)
+}
+
+type gameJoinMessage struct {
+	g *Game
+}
+
+func (m *gameJoinMessage) SendToClient(c *client.Client) {
+	gm := m.g.groupMessage(c)
+	gm.JoinGroup = &protocol03.JoinGroup{}
+	c.Record(client.Outbound, gm)
+
+	writeGroupMessage(c, gm)
+}
+
+func (g *Game) makeBroadcastMessage() (m *gameBroadcastMessage) {
+	m = &gameBroadcastMessage{g: g}
+
+	m.WriteByte(SerializationVersion)
+	// protocol starts with team number:
+	m.WriteByte(g.LocalPlayer().Team())
+	// frame number to correlate separate packets together:
+	m.WriteByte(g.lastGameFrame)
+
+	return
+}
+
+func (g *Game) makePlayerNameMessage() (m *gameBroadcastMessage) {
+	m = g.makeBroadcastMessage()
+	if err := g.SerializePlayerName(g.LocalPlayer(), m); err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (g *Game) makeJoinMessage() (m *gameJoinMessage) {
+	return &gameJoinMessage{g: g}
+}
+
+func (g *Game) send(m gameMessage) {
+	c := g.client
+	if c == nil {
+		return
+	}
+	if !c.IsConnected() {
+		return
+	}
+
+	m.SendToClient(c)
+}
+
+// sendPackets broadcasts the watched memory to all players every 16 frames
+func (g *Game) sendPackets() {
+	// don't send out any network updates until we're connected:
+	if g.local.Index() < 0 {
+		return
+	}
+	if g.monotonicFrameTime&15 != 0 {
+		return
+	}
+
+	for _, m := range g.makeMemoryMessages() {
+		g.send(m)
+	}
+}
+
+// makeMemoryMessages serializes the watch lists into as few broadcast messages as fit in maxMessageLen
+func (g *Game) makeMemoryMessages() (msgs []*gameBroadcastMessage) {
+	local := g.LocalPlayer()
+	m := g.makeBroadcastMessage()
+	headerLen := m.Len()
+
+	add := func(msgType MessageType, mem *Memory, rng Range) {
+		if m.Len() > headerLen && m.Len()+5+int(rng.Size) > maxMessageLen {
+			msgs = append(msgs, m)
+			m = g.makeBroadcastMessage()
+		}
+		if err := g.SerializeMemory(msgType, mem, rng, m); err != nil {
+			panic(err)
+		}
+	}
+	for _, rng := range g.def.WatchWRAM {
+		add(MsgWRAM, &local.WRAM, rng)
+	}
+	for _, rng := range g.def.WatchSRAM {
+		add(MsgSRAM, &local.SRAM, rng)
+	}
+
+	if m.Len() > headerLen {
+		msgs = append(msgs, m)
+	}
+	return
+}
+
+func (g *Game) handleNetMessage(msg []byte) (err error) {
+	var protocol uint8
+
+	r, err := client.ParseHeader(msg, &protocol)
+	if err != nil {
+		return fmt.Errorf("error parsing message header: %w", err)
+	}
+	if protocol != 0x03 {
+		return nil
+	}
+
+	gm := &protocol03.GroupMessage{}
+	var b []byte
+	b, err = io.ReadAll(r)
+	if err != nil {
+		return
+	}
+	if err = proto.Unmarshal(b, gm); err != nil {
+		return
+	}
+
+	// silently drop anything not authenticated by our group password:
+	if g.client.Open(gm) != nil {
+		return nil
+	}
+	g.client.Record(client.Inbound, gm)
+
+	index := int(gm.PlayerIndex)
+
+	// pre-emptively avoid panics in accessing players array out of bounds:
+	if index >= MaxPlayers {
+		return fmt.Errorf("player index %v received in packet beyond max player count %v", gm.PlayerIndex, MaxPlayers)
+	}
+
+	p := &g.players[index]
+	p.IndexF = index
+
+	// handle which kind of message it is:
+	if gm.GetJoinGroup() != nil {
+		// track local player index:
+		if (g.local.Index() < 0) || (g.local.Index() != index) {
+			if p != g.local {
+				// copy local player data into players array at the appropriate index:
+				g.players[index] = *g.local
+				// clear out old Player:
+				g.local.IndexF = -1
+				g.local.Ttl = 0
+			}
+			// repoint local into the array:
+			g.local = p
+			g.activePlayersClean = false
+			p.IndexF = index
+		}
+	} else if ba := gm.GetBroadcastAll(); ba != nil {
+		err = g.Deserialize(bytes.NewReader(ba.Data), p)
+	} else if bs := gm.GetBroadcastSector(); bs != nil {
+		err = g.Deserialize(bytes.NewReader(bs.Data), p)
+	}
+	if err != nil {
+		return fmt.Errorf("deserialize: %w", err)
+	}
+
+	// reset player Ttl:
+	g.SetTTL(p, 255)
+
+	// wait until we see a name packet to announce:
+	if p.showJoinMessage && p.Name() != "" {
+		log.Printf("base: %s: player[%02x]: %s joined\n", g.def.Name, uint8(p.Index()), p.Name())
+		g.PushNotification(fmt.Sprintf("%s joined", p.Name()))
+		p.showJoinMessage = false
+		g.activePlayersClean = false
+		g.shouldUpdatePlayersList = true
+	}
+
+	if g.shouldUpdatePlayersList {
+		g.updatePlayersList()
+	}
+
+	return nil
+}
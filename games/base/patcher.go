@@ -0,0 +1,408 @@
+package base
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"o2/snes"
+	"o2/snes/asm"
+	"strings"
+)
+
+// PreMainLen is the size of the preMain routine called every frame from the frame hook
+const PreMainLen = 8
+
+// AltPreMainLen is the size of the altPreMain routine called every frame from the alternate frame hook; it
+// saves the processor flags and switches to 8-bit mode around the `JSR` whose target high byte is at +5
+const AltPreMainLen = 12
+
+// Routines are the bus addresses of the routines the patcher installs in SRAM. All three must be in the same
+// bank since preMain calls the update routines with `JSR abs`.
+type Routines struct {
+	// PreMain calls the current update routine followed by the original frame hook's JSL target
+	PreMain uint32
+	// AltPreMain does the same for the alternate frame hook; only installed if there is one
+	AltPreMain uint32
+	// UpdateA and UpdateB are the two alternating update routines which are rewritten while the other runs
+	UpdateA uint32
+	UpdateB uint32
+}
+
+// RoutinesAt lays out the routines in the $400 bytes of SRAM starting at base:
+// update A at base+$000, update B at base+$200, preMain in the last PreMainLen bytes and altPreMain just
+// before it
+func RoutinesAt(base uint32) Routines {
+	return Routines{
+		PreMain:    base + 0x400 - PreMainLen,
+		AltPreMain: base + 0x400 - PreMainLen - AltPreMainLen,
+		UpdateA:    base,
+		UpdateB:    base + 0x200,
+	}
+}
+
+// LoROMRoutines are at the end of the first 32KiB bank of LoROM SRAM, $70:7C00-7FFF
+var LoROMRoutines = RoutinesAt(0x707C00)
+
+// HiROMRoutines are at the end of the first 8KiB bank of HiROM SRAM, $30:7C00-7FFF
+var HiROMRoutines = RoutinesAt(0x307C00)
+
+// vanillaResetCode is `LDA #$81 : STA $4200` which enables NMI and auto-joypad read in most games:
+var vanillaResetCode = []byte{0xA9, 0x81, 0x8D, 0x00, 0x42}
+
+// Hooks describes where a ROM is patched to call our routines
+type Hooks struct {
+	// ResetHook is the bus address of relocatable code in the reset routine, e.g. where NMI is enabled;
+	// it is replaced with a JSL to our init routine which then executes the original code
+	ResetHook uint32
+	// ResetCode is the original code expected at ResetHook, at least 4 bytes of whole instructions;
+	// nil expects `LDA #$81 : STA $4200`
+	ResetCode []byte
+	// InitHook is the bus address of free ROM space to write our Signature and init routine to
+	InitHook uint32
+	// FrameHook is the bus address of a `JSL` executed every frame while the game runs
+	FrameHook uint32
+	// AltFrameHook is the bus address of a second `JSL` executed every frame, for ROMs combining two games
+	// like SMZ3 where FrameHook only runs while the first game does; zero if none
+	AltFrameHook uint32
+
+	// Routines are where to install our routines in SRAM; zero uses LoROMRoutines
+	Routines Routines
+	// RAMSize is the minimum SRAM size to set in the header as 1024 << RAMSize bytes; zero means 5 (32KiB)
+	RAMSize uint8
+}
+
+func (h *Hooks) resetCode() []byte {
+	if h.ResetCode == nil {
+		return vanillaResetCode
+	}
+	return h.ResetCode
+}
+
+func (h *Hooks) routines() Routines {
+	if h.Routines == (Routines{}) {
+		return LoROMRoutines
+	}
+	return h.Routines
+}
+
+func (h *Hooks) ramSize() uint8 {
+	if h.RAMSize == 0 {
+		return 5
+	}
+	return h.RAMSize
+}
+
+type Patcher struct {
+	hooks *Hooks
+	rom   *snes.ROM
+	r     io.Reader
+	w     io.Writer
+}
+
+func NewPatcher(hooks *Hooks, rom *snes.ROM) *Patcher {
+	return &Patcher{hooks: hooks, rom: rom}
+}
+
+// Patch patches the ROM for O2 support. A ROM already patched by this version is verified to be unchanged by
+// patching again; one patched by an older version is upgraded by restoring the original code at the hooks from
+// its signature and patching it again.
+func (p *Patcher) Patch() (err error) {
+	sig, signed := p.Signature()
+	if !signed {
+		if p.IsPatched() {
+			return fmt.Errorf("ROM was patched by an older version of o2 which cannot be upgraded; patch the original ROM instead")
+		}
+		return p.patch()
+	}
+
+	// patch again from the original code, keeping the patched contents in case that fails:
+	patched := make([]byte, len(p.rom.Contents))
+	copy(patched, p.rom.Contents)
+	defer func() {
+		if err != nil {
+			copy(p.rom.Contents, patched)
+			_ = p.rom.ReadHeader()
+		}
+	}()
+
+	if err = p.restore(sig); err != nil {
+		return
+	}
+	if err = p.patch(); err != nil {
+		return
+	}
+
+	switch {
+	case sig.Version != PatchVersion:
+		log.Printf("base: patch: upgraded o2 patch from version %d to %d\n", sig.Version, PatchVersion)
+	case !bytes.Equal(patched, p.rom.Contents):
+		log.Printf("base: patch: repaired o2 patch version %d\n", sig.Version)
+	default:
+		log.Printf("base: patch: verified o2 patch version %d\n", sig.Version)
+	}
+	return
+}
+
+// patch patches an unpatched ROM
+func (p *Patcher) patch() (err error) {
+	routines := p.hooks.routines()
+
+	// patch header to expand SRAM size:
+	hdr := &p.rom.Header
+	if ramSize := p.hooks.ramSize(); hdr.RAMSize < ramSize {
+		// e.g. 1024 << 5 = 32768 bytes, aka $70:0000-7FFF
+		hdr.RAMSize = ramSize
+		if err = p.rom.WriteHeader(); err != nil {
+			return
+		}
+	}
+
+	// read from the reset hook (e.g. $00:802F) which is where NMI should be enabled in the reset routine:
+	resetHook := p.hooks.ResetHook
+	expectedReset := p.hooks.resetCode()
+	if len(expectedReset) < 4 {
+		return fmt.Errorf("reset hook $%06X needs at least 4 bytes of code to replace with a JSL", resetHook)
+	}
+	p.readAt(resetHook)
+	var resetCode []byte
+	resetCode, err = p.read(len(expectedReset))
+	if err != nil {
+		return
+	}
+
+	if !bytes.Equal(resetCode, expectedReset) {
+		// let's at least check that it's a JSL followed by NOPs:
+		if resetCode[0] != 0x22 || !isNOPs(resetCode[4:]) {
+			// it's not vanilla code nor is it a JSL / NOP combo:
+			return fmt.Errorf("unexpected code at $%06X: %s", resetHook, hex.Dump(resetCode))
+		}
+	}
+
+	// overwrite the reset hook with `JSL initCode`; the init code follows our signature at the init hook:
+	p.writeAt(resetHook)
+	initHook := p.hooks.InitHook
+	initCode := initHook + uint32(signatureLen(len(resetCode), p.hooks.AltFrameHook != 0))
+	b := &bytes.Buffer{}
+	textBuf := &strings.Builder{}
+	defer func() {
+		log.Print(textBuf.String())
+	}()
+
+	var a asm.Emitter
+	a.Code = b
+	a.Text = textBuf
+	a.SetBase(resetHook)
+	a.JSL(initCode)
+	for b.Len() < len(expectedReset) {
+		a.NOP()
+	}
+	if b.Len() != len(expectedReset) {
+		return fmt.Errorf("assembler failed to produce exactly %d bytes to patch", len(expectedReset))
+	}
+	if _, err = b.WriteTo(p.w); err != nil {
+		return
+	}
+
+	// frame hook, e.g. $00:8056 is 22 B5 80 00   JSL GameModes
+	frameHook := p.hooks.FrameHook
+	p.readAt(frameHook)
+	var frameJSL []byte
+	frameJSL, err = p.read(4)
+	if err != nil {
+		return
+	}
+	if frameJSL[0] != 0x22 {
+		return fmt.Errorf("frame hook $%06X does not contain a JSL instruction: %s", frameHook, hex.Dump(frameJSL))
+	}
+	gameModes := frameJSL[1:]
+
+	// the alternate frame hook, if any, must also be a JSL:
+	var altGameModes, altFrameJSL []byte
+	if altFrameHook := p.hooks.AltFrameHook; altFrameHook != 0 {
+		p.readAt(altFrameHook)
+		altFrameJSL, err = p.read(4)
+		if err != nil {
+			return
+		}
+		if altFrameJSL[0] != 0x22 {
+			return fmt.Errorf("alternate frame hook $%06X does not contain a JSL instruction: %s", altFrameHook, hex.Dump(altFrameJSL))
+		}
+		altGameModes = altFrameJSL[1:]
+	}
+
+	// we can't write to SRAM from this program because we only have access to the ROM contents,
+	// so we have to write an ASM routine to initialize what we want in SRAM before we call it.
+	// initialize the end of SRAM with the original JSL from the frameHook followed by RTL:
+
+	// Build a temporary assembler to write the routine that gets written to SRAM:
+	var ta asm.Emitter
+	ta.Text = textBuf
+
+	// assemble #`PreMainLen` bytes of code:
+	preMainBuf := &bytes.Buffer{}
+	ta.Code = preMainBuf
+	ta.SetBase(routines.PreMain)
+	ta.JSR_abs(uint16(routines.UpdateA))
+	ta.JSL_lhb(gameModes[0], gameModes[1], gameModes[2])
+	ta.RTL()
+	if preMainBuf.Len() != PreMainLen {
+		panic(fmt.Errorf("SRAM preMain assembled code length: %02x (actual) != %02x (expected)", preMainBuf.Len(), PreMainLen))
+	}
+
+	// assemble #`AltPreMainLen` bytes of code; the other game may run in 16-bit mode but our update routines
+	// assume 8-bit mode:
+	altPreMainBuf := &bytes.Buffer{}
+	if altGameModes != nil {
+		ta.Code = altPreMainBuf
+		ta.SetBase(routines.AltPreMain)
+		ta.PHP()
+		ta.SEP(0x30)
+		ta.JSR_abs(uint16(routines.UpdateA))
+		ta.PLP()
+		ta.JSL_lhb(altGameModes[0], altGameModes[1], altGameModes[2])
+		ta.RTL()
+		if altPreMainBuf.Len() != AltPreMainLen {
+			panic(fmt.Errorf("SRAM altPreMain assembled code length: %02x (actual) != %02x (expected)", altPreMainBuf.Len(), AltPreMainLen))
+		}
+	}
+
+	// assemble the RTS instructions at the two A/B update routine locations:
+	preMainUpdateABuf := &bytes.Buffer{}
+	ta.Code = preMainUpdateABuf
+	ta.SetBase(routines.UpdateA)
+	ta.RTS()
+	ta.NOP() // to make an even number of code bytes so that 16-bit copies work nicely
+	bufUpdateB := &bytes.Buffer{}
+	ta.Code = bufUpdateB
+	ta.SetBase(routines.UpdateB)
+	ta.RTS()
+	ta.NOP() // to make an even number of code bytes so that 16-bit copies work nicely
+
+	// write our signature and init routine into free ROM space:
+	p.writeAt(initHook)
+	sig := &Signature{
+		Version:     PatchVersion,
+		ResetCode:   resetCode,
+		FrameJSL:    frameJSL,
+		AltFrameJSL: altFrameJSL,
+	}
+	if err = p.write(sig.bytes()); err != nil {
+		return
+	}
+	a.SetBase(initCode)
+	a.REP(0x20)
+	p.asmCopyRoutine(preMainUpdateABuf.Bytes(), &a, routines.UpdateA)
+	p.asmCopyRoutine(bufUpdateB.Bytes(), &a, routines.UpdateB)
+	p.asmCopyRoutine(preMainBuf.Bytes(), &a, routines.PreMain)
+	if altGameModes != nil {
+		p.asmCopyRoutine(altPreMainBuf.Bytes(), &a, routines.AltPreMain)
+	}
+	a.SEP(0x20)
+	// emit asm code:
+	if _, err = b.WriteTo(p.w); err != nil {
+		return
+	}
+	// append the original reset code to our custom init hook:
+	if err = p.write(resetCode); err != nil {
+		return
+	}
+	// follow by `RTL`
+	a.RTL()
+	if _, err = b.WriteTo(p.w); err != nil {
+		return
+	}
+
+	// overwrite the frame hook with a JSL to preMain in SRAM:
+	p.writeAt(frameHook)
+	a.SetBase(frameHook)
+	a.JSL(routines.PreMain)
+	// emit asm code:
+	if _, err = b.WriteTo(p.w); err != nil {
+		return
+	}
+
+	if altGameModes != nil {
+		// overwrite the alternate frame hook with a JSL to altPreMain in SRAM:
+		p.writeAt(p.hooks.AltFrameHook)
+		a.SetBase(p.hooks.AltFrameHook)
+		a.JSL(routines.AltPreMain)
+		if _, err = b.WriteTo(p.w); err != nil {
+			return
+		}
+	}
+
+	// the code changes above invalidate the header checksum:
+	return p.rom.FixChecksum()
+}
+
+// IsPatched determines if the ROM is already patched by looking for `JSL preMain` at the frame hook
+func (p *Patcher) IsPatched() bool {
+	routines := p.hooks.routines()
+	p.readAt(p.hooks.FrameHook)
+	frameJSL, err := p.read(4)
+	if err != nil {
+		return false
+	}
+
+	var a asm.Emitter
+	a.Code = &bytes.Buffer{}
+	a.SetBase(p.hooks.FrameHook)
+	a.JSL(routines.PreMain)
+	return bytes.Equal(frameJSL, a.Code.Bytes())
+}
+
+func isNOPs(code []byte) bool {
+	for _, c := range code {
+		if c != 0xEA {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Patcher) asmCopyRoutine(tc []byte, a *asm.Emitter, addr uint32) uint32 {
+	// copy the assembled routine using LDA.w and STA.l instruction pairs, 16-bits at a time:
+	for i := 0; i < len(tc); i += 2 {
+		a.LDA_imm16_lh(tc[i], tc[i+1])
+		a.STA_long(addr)
+		addr += 2
+	}
+	return addr
+}
+
+func (p *Patcher) readAt(busAddr uint32) {
+	p.r = p.rom.BusReader(busAddr)
+}
+
+func (p *Patcher) writeAt(busAddr uint32) {
+	p.w = p.rom.BusWriter(busAddr)
+}
+
+func (p *Patcher) read(length int) (d []byte, err error) {
+	d = make([]byte, length)
+	t := 0
+	for t < len(d) {
+		var n int
+		n, err = p.r.Read(d[t:])
+		if err != nil {
+			return
+		}
+		t += n
+	}
+	return
+}
+
+func (p *Patcher) write(d []byte) (err error) {
+	t := 0
+	for t < len(d) {
+		var n int
+		n, err = p.w.Write(d[t:])
+		if err != nil {
+			return
+		}
+		t += n
+	}
+	return
+}
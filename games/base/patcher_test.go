@@ -0,0 +1,191 @@
+package base
+
+import (
+	"bytes"
+	"io"
+	"o2/snes"
+	"testing"
+)
+
+func TestPatcher_Hooks(t *testing.T) {
+	// a 6-byte reset hook and HiROM-style SRAM routines:
+	hooks := &Hooks{
+		ResetHook: 0x008100,
+		ResetCode: []byte{0x9C, 0x00, 0x42, 0xA9, 0x01, 0xEA}, // STZ $4200 : LDA #$01 : NOP
+		InitHook:  0x0FF000,
+		FrameHook: 0x008200,
+		Routines:  HiROMRoutines,
+		RAMSize:   3,
+	}
+
+	rom, err := snes.NewROM("test.sfc", make([]byte, 0x10_0000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(addr uint32, b []byte) {
+		if _, err := rom.BusWriter(addr).Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(addr uint32, n int) []byte {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(rom.BusReader(addr), b); err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	write(hooks.ResetHook, hooks.ResetCode)
+	// JSL MainLoop
+	write(hooks.FrameHook, []byte{0x22, 0x00, 0x90, 0x00})
+
+	if NewPatcher(hooks, rom).IsPatched() {
+		t.Errorf("IsPatched() = true before patching")
+	}
+	if err = NewPatcher(hooks, rom).Patch(); err != nil {
+		t.Fatal(err)
+	}
+	if !NewPatcher(hooks, rom).IsPatched() {
+		t.Errorf("IsPatched() = false after patching")
+	}
+
+	// JSL $0FF014 : NOP : NOP past the signature
+	if actual, expected := read(hooks.ResetHook, 6), []byte{0x22, 0x14, 0xF0, 0x0F, 0xEA, 0xEA}; !bytes.Equal(actual, expected) {
+		t.Errorf("reset hook = % x, expected % x", actual, expected)
+	}
+	// JSL $30:7FF8
+	if actual, expected := read(hooks.FrameHook, 4), []byte{0x22, 0xF8, 0x7F, 0x30}; !bytes.Equal(actual, expected) {
+		t.Errorf("frame hook = % x, expected % x", actual, expected)
+	}
+	if rom.Header.RAMSize != 3 {
+		t.Errorf("RAMSize = %d, expected 3", rom.Header.RAMSize)
+	}
+
+	// unexpected code at the reset hook must not be patched over:
+	unexpected, err := snes.NewROM("test.sfc", make([]byte, 0x10_0000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = unexpected.BusWriter(hooks.ResetHook).Write([]byte{0xA9, 0x81, 0x8D, 0x00, 0x42, 0x60}); err != nil {
+		t.Fatal(err)
+	}
+	if err = NewPatcher(hooks, unexpected).Patch(); err == nil {
+		t.Errorf("Patch() should fail on unexpected reset code")
+	}
+}
+
+func TestPatcher_AltFrameHook(t *testing.T) {
+	// a combo ROM whose second game has its own main loop:
+	hooks := &Hooks{
+		ResetHook:    0x008100,
+		InitHook:     0x0FF000,
+		FrameHook:    0x008200,
+		AltFrameHook: 0x018000,
+	}
+
+	rom, err := snes.NewROM("test.sfc", make([]byte, 0x10_0000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(addr uint32, b []byte) {
+		if _, err := rom.BusWriter(addr).Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(addr uint32, n int) []byte {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(rom.BusReader(addr), b); err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	write(hooks.ResetHook, vanillaResetCode)
+	write(hooks.FrameHook, []byte{0x22, 0x00, 0x90, 0x00})
+	// RTS where a JSL is expected:
+	write(hooks.AltFrameHook, []byte{0x60, 0x00, 0x00, 0x00})
+
+	if err = NewPatcher(hooks, rom).Patch(); err == nil {
+		t.Errorf("Patch() should fail without a JSL at the alternate frame hook")
+	}
+
+	// JSL MainLoop
+	write(hooks.AltFrameHook, []byte{0x22, 0x59, 0x94, 0x80})
+	if err = NewPatcher(hooks, rom).Patch(); err != nil {
+		t.Fatal(err)
+	}
+
+	// JSL $70:7FEC (altPreMain)
+	if actual, expected := read(hooks.AltFrameHook, 4), []byte{0x22, 0xEC, 0x7F, 0x70}; !bytes.Equal(actual, expected) {
+		t.Errorf("alternate frame hook = % x, expected % x", actual, expected)
+	}
+	// JSL $70:7FF8 (preMain)
+	if actual, expected := read(hooks.FrameHook, 4), []byte{0x22, 0xF8, 0x7F, 0x70}; !bytes.Equal(actual, expected) {
+		t.Errorf("frame hook = % x, expected % x", actual, expected)
+	}
+}
+
+func TestPatcher_Repatch(t *testing.T) {
+	hooks := &Hooks{
+		ResetHook: 0x008100,
+		InitHook:  0x0FF000,
+		FrameHook: 0x008200,
+	}
+
+	rom, err := snes.NewROM("test.sfc", make([]byte, 0x10_0000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(addr uint32, b []byte) {
+		if _, err := rom.BusWriter(addr).Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(hooks.ResetHook, vanillaResetCode)
+	write(hooks.FrameHook, []byte{0x22, 0x00, 0x90, 0x00})
+
+	if v := NewPatcher(hooks, rom).PatchVersion(); v != 0 {
+		t.Errorf("PatchVersion() = %d before patching, expected 0", v)
+	}
+	if err = NewPatcher(hooks, rom).Patch(); err != nil {
+		t.Fatal(err)
+	}
+	patched := append([]byte(nil), rom.Contents...)
+
+	sig, ok := NewPatcher(hooks, rom).Signature()
+	if !ok {
+		t.Fatal("Signature() not found after patching")
+	}
+	if sig.Version != PatchVersion || !bytes.Equal(sig.ResetCode, vanillaResetCode) || !bytes.Equal(sig.FrameJSL, []byte{0x22, 0x00, 0x90, 0x00}) {
+		t.Errorf("Signature() = %+v", sig)
+	}
+
+	// patching again is a no-op:
+	if err = NewPatcher(hooks, rom).Patch(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rom.Contents, patched) {
+		t.Errorf("patching a patched ROM changed it")
+	}
+
+	// an older version is upgraded:
+	write(hooks.InitHook+uint32(len(signatureMagic)), []byte{PatchVersion - 1})
+	write(hooks.InitHook+0x20, []byte{0xFF, 0xFF})
+	if v := NewPatcher(hooks, rom).PatchVersion(); v != PatchVersion-1 {
+		t.Errorf("PatchVersion() = %d, expected %d", v, PatchVersion-1)
+	}
+	if err = NewPatcher(hooks, rom).Patch(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rom.Contents, patched) {
+		t.Errorf("upgraded ROM differs from a freshly patched one")
+	}
+
+	// a patch without a signature cannot be upgraded:
+	write(hooks.InitHook, []byte("nopatch"))
+	unsigned := append([]byte(nil), rom.Contents...)
+	if err = NewPatcher(hooks, rom).Patch(); err == nil {
+		t.Errorf("Patch() should fail on a ROM patched without a signature")
+	}
+	if !bytes.Equal(rom.Contents, unsigned) {
+		t.Errorf("failed Patch() changed the ROM")
+	}
+}
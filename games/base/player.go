@@ -0,0 +1,146 @@
+package base
+
+import (
+	"fmt"
+	"log"
+	"o2/games"
+)
+
+// Memory is a player's copy of the watched ranges of one kind of memory
+type Memory struct {
+	kind    games.MemoryKind
+	mapping Mapping
+	data    map[uint32]uint8
+}
+
+func newMemory(kind games.MemoryKind, mapping Mapping) Memory {
+	return Memory{kind: kind, mapping: mapping, data: make(map[uint32]uint8)}
+}
+
+func (m *Memory) BusAddress(offs uint32) uint32 {
+	if m.kind == games.WRAM {
+		return 0x7E0000 + offs
+	}
+	return m.mapping.PakAddressToBus(0xE00000 + offs)
+}
+
+// ReadU8 reads a byte; memory never watched or received reads as zero
+func (m *Memory) ReadU8(offs uint32) uint8 {
+	return m.data[offs]
+}
+
+func (m *Memory) ReadU16(offs uint32) uint16 {
+	return uint16(m.data[offs]) | uint16(m.data[offs+1])<<8
+}
+
+func (m *Memory) write(offs uint32, b []byte) {
+	if m.data == nil {
+		m.data = make(map[uint32]uint8)
+	}
+	for i, v := range b {
+		m.data[offs+uint32(i)] = v
+	}
+}
+
+func (m *Memory) read(offs uint32, size uint8) []byte {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = m.data[offs+uint32(i)]
+	}
+	return b
+}
+
+type Player struct {
+	IndexF int
+	Ttl    int
+
+	TeamF uint8
+	NameF string
+
+	Frame uint8
+
+	WRAM Memory
+	SRAM Memory
+
+	showJoinMessage bool
+}
+
+func (p *Player) Index() int {
+	return p.IndexF
+}
+
+func (p *Player) Name() string {
+	return p.NameF
+}
+
+func (p *Player) Team() uint8 {
+	return p.TeamF
+}
+
+func (p *Player) TTL() int {
+	return p.Ttl
+}
+
+func (p *Player) ReadableMemory(kind games.MemoryKind) games.ReadableMemory {
+	switch kind {
+	case games.SRAM:
+		return &p.SRAM
+	case games.WRAM:
+		return &p.WRAM
+	}
+	panic(fmt.Errorf("ReadableMemory kind %v not supported", kind))
+}
+
+func (g *Game) newPlayer() Player {
+	mapping := g.def.mapping(g.rom)
+	return Player{
+		IndexF: -1,
+		WRAM:   newMemory(games.WRAM, mapping),
+		SRAM:   newMemory(games.SRAM, mapping),
+	}
+}
+
+func (g *Game) SetTTL(p *Player, ttl int) {
+	joined := false
+	if p.Ttl <= 0 && ttl > 0 {
+		joined = true
+	}
+
+	p.Ttl = ttl
+	if joined {
+		g.PlayerJoined(p)
+	}
+}
+
+func (g *Game) DecTTL(p *Player, amount int) {
+	if p.Ttl <= 0 {
+		return
+	}
+
+	p.Ttl -= amount
+	if p.Ttl <= 0 {
+		g.PlayerLeft(p)
+	}
+}
+
+func (g *Game) PlayerJoined(p *Player) {
+	// Activating new player:
+	p.showJoinMessage = true
+	g.activePlayersClean = false
+	g.shouldUpdatePlayersList = true
+}
+
+func (g *Game) PlayerLeft(p *Player) {
+	// Player left the game:
+	p.Ttl = 0
+	p.showJoinMessage = false
+
+	log.Printf("base: %s: player[%02x]: %s left\n", g.def.Name, uint8(p.IndexF), p.NameF)
+	g.PushNotification(fmt.Sprintf("%s left", p.NameF))
+
+	// refresh the ActivePlayers():
+	g.activePlayersClean = false
+
+	// refresh the players list
+	g.shouldUpdatePlayersList = true
+}
@@ -0,0 +1,280 @@
+package base
+
+import (
+	"log"
+	"o2/snes"
+	"time"
+)
+
+// FX Pak Pro address space of WRAM and SRAM:
+const (
+	pakWRAM = uint32(0xF50000)
+	pakSRAM = uint32(0xE00000)
+)
+
+func (g *Game) readEnqueue(q []snes.Read, addr uint32, size uint8, extra interface{}) []snes.Read {
+	q = append(q, snes.Read{
+		Address: addr,
+		Size:    size,
+		Extra:   extra,
+		Completion: func(rsp snes.Response) {
+			defer g.readResponseLock.Unlock()
+			g.readResponseLock.Lock()
+			// append to response queue:
+			g.readResponse = append(g.readResponse, rsp)
+		},
+	})
+
+	return q
+}
+
+func (g *Game) readSubmit(readQueue []snes.Read) {
+	if len(readQueue) == 0 {
+		return
+	}
+
+	q := g.queue
+	if q == nil {
+		return
+	}
+
+	sequence := q.MakeReadCommands(
+		readQueue,
+		func(cmd snes.Command, err error) {
+			g.readResponseLock.Lock()
+			// copy out read responses and clear that queue:
+			rsps := g.readResponse[:]
+			g.readResponse = nil
+			g.readResponseLock.Unlock()
+
+			if err != nil {
+				log.Printf("base: %s: readSubmit: complete: %s\n", g.def.Name, err)
+			}
+
+			// inform the main loop:
+			g.readComplete <- rsps
+		},
+	)
+
+	err := sequence.EnqueueTo(q)
+	if err != nil {
+		log.Printf("base: %s: readSubmit: enqueue: %s\n", g.def.Name, err)
+		return
+	}
+}
+
+// run in a separate goroutine
+func (g *Game) run() {
+	g.readSubmit(g.enqueueWatchReads(make([]snes.Read, 0, 8)))
+
+	fastbeat := time.NewTicker(120 * time.Millisecond)
+	slowbeat := time.NewTicker(500 * time.Millisecond)
+
+	defer func() {
+		fastbeat.Stop()
+		slowbeat.Stop()
+		log.Printf("base: %s: run loop exited\n", g.def.Name)
+	}()
+
+	for g.running {
+		select {
+		// wait for reads to complete:
+		case rsps := <-g.readComplete:
+			if !g.IsRunning() {
+				return
+			}
+
+			// process the last read data:
+			q := g.readMainComplete(rsps)
+			g.lastReadCompleted = time.Now()
+
+			g.readSubmit(q)
+			break
+
+		// wait for network message from server:
+		case msg := <-g.client.Read():
+			if msg == nil {
+				// disconnected?
+				for i := range g.players {
+					p := &g.players[i]
+					// reset Ttl for all players to make them inactive:
+					g.DecTTL(p, 255)
+					p.IndexF = -1
+				}
+				if g.shouldUpdatePlayersList {
+					g.updatePlayersList()
+				}
+				break
+			}
+			if !g.IsRunning() {
+				return
+			}
+
+			if err := g.handleNetMessage(msg); err != nil {
+				log.Printf("base: %s: net: %v\n", g.def.Name, err)
+			}
+			break
+
+		// periodically send basic messages to the server to maintain our connection:
+		case <-fastbeat.C:
+			if !g.IsRunning() {
+				return
+			}
+
+			if g.queue != nil {
+				// make sure a read request is always in flight to keep our main loop running:
+				if time.Now().Sub(g.lastReadCompleted) >= time.Millisecond*512 {
+					g.readSubmit(g.enqueueWatchReads(make([]snes.Read, 0, 8)))
+				} else {
+					q := g.enqueueSRAMReads(make([]snes.Read, 0, 8), 1)
+					// must always read the WRAM watch list LAST to validate the prior reads:
+					q = g.enqueueWRAMReads(q, nil)
+					g.readSubmit(q)
+				}
+			}
+
+			if g.LocalPlayer().Index() < 0 && g.client != nil {
+				// request our player index:
+				g.send(g.makeJoinMessage())
+			}
+			break
+
+		case <-slowbeat.C:
+			if !g.IsRunning() {
+				return
+			}
+
+			if g.LocalPlayer().Index() < 0 {
+				break
+			}
+
+			// broadcast player name:
+			g.send(g.makePlayerNameMessage())
+			break
+		}
+	}
+}
+
+// enqueueWatchReads reads the whole watch list and re-enqueues the WRAM reads every time they complete
+func (g *Game) enqueueWatchReads(q []snes.Read) []snes.Read {
+	q = g.enqueueSRAMReads(q, 1)
+	// must always read the WRAM watch list LAST to validate the prior reads:
+	q = g.enqueueWRAMReads(q, 0)
+	return q
+}
+
+func (g *Game) enqueueWRAMReads(q []snes.Read, extra interface{}) []snes.Read {
+	for _, r := range g.def.WatchWRAM {
+		q = g.readEnqueue(q, pakWRAM+r.Offset, r.Size, extra)
+	}
+	return q
+}
+
+func (g *Game) enqueueSRAMReads(q []snes.Read, extra interface{}) []snes.Read {
+	for _, r := range g.def.WatchSRAM {
+		q = g.readEnqueue(q, pakSRAM+r.Offset, r.Size, extra)
+	}
+	return q
+}
+
+func isReadWRAM(rsp snes.Response) (start, end uint32, ok bool) {
+	ok = rsp.Address >= pakWRAM && rsp.Address < pakWRAM+0x20000
+	if !ok {
+		return
+	}
+
+	start = rsp.Address - pakWRAM
+	end = start + uint32(rsp.Size)
+	return
+}
+
+func isReadSRAM(rsp snes.Response) (start, end uint32, ok bool) {
+	ok = rsp.Address >= pakSRAM && rsp.Address < pakSRAM+0x100000
+	if !ok {
+		return
+	}
+
+	start = rsp.Address - pakSRAM
+	end = start + uint32(rsp.Size)
+	return
+}
+
+// called when all reads are completed:
+func (g *Game) readMainComplete(rsps []snes.Response) []snes.Read {
+	q := make([]snes.Read, 0, 8)
+
+	for _, rsp := range rsps {
+		// check WRAM reads:
+		if start, end, ok := isReadWRAM(rsp); ok {
+			copy(g.wramStaging[start:end], rsp.Data)
+		}
+
+		// handle update routine check:
+		q = g.checkUpdateComplete(q, rsp)
+
+		// 0 indicates to re-enqueue the read every time:
+		if rsp.Extra == 0 {
+			q = g.readEnqueue(q, rsp.Address, rsp.Size, rsp.Extra)
+		}
+	}
+
+	// validate new reads in staging area before copying to wram/sram:
+	if g.def.IsInGame != nil && !g.def.IsInGame(g.wramStaging[:]) {
+		if !g.invalid {
+			log.Printf("base: %s: game now in invalid state\n", g.def.Name)
+		}
+		g.invalid = true
+		return q
+	}
+
+	if g.invalid {
+		log.Printf("base: %s: game now in valid state\n", g.def.Name)
+		g.invalid = false
+	}
+
+	// copy the read data into our view of memory:
+	local := g.LocalPlayer()
+	for _, rsp := range rsps {
+		if start, end, ok := isReadWRAM(rsp); ok {
+			copy(g.wram[start:end], rsp.Data)
+			local.WRAM.write(start, g.wram[start:end])
+		}
+		if start, _, ok := isReadSRAM(rsp); ok {
+			local.SRAM.write(start, rsp.Data)
+		}
+	}
+
+	g.SetTTL(local, 255)
+
+	if g.shouldUpdatePlayersList {
+		g.updatePlayersList()
+	}
+
+	// did game frame change?
+	frame := g.wram[g.def.FrameCounter]
+	if frame == g.lastGameFrame {
+		return q
+	}
+	g.lastGameFrame = frame
+
+	// should wrap around 255 to 0:
+	g.monotonicFrameTime++
+
+	g.frameAdvanced()
+
+	return q
+}
+
+// called when the local game frame advances:
+func (g *Game) frameAdvanced() {
+	// tick down TTLs of remote players:
+	for _, p := range g.ActivePlayers() {
+		g.DecTTL(p, 1)
+	}
+
+	// generate any update code and send it to the SNES:
+	g.updateRoutine()
+
+	// send out any network updates:
+	g.sendPackets()
+}
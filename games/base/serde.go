@@ -0,0 +1,138 @@
+package base
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// NOTE: increment this when the serialization code changes in an incompatible way
+const SerializationVersion = 0x01
+
+type MessageType uint8
+
+const (
+	_                         = iota
+	MsgPlayerName MessageType = iota
+	MsgWRAM
+	MsgSRAM
+
+	MsgMaxMessageType
+)
+
+// Deserialize reads a broadcast message from player p: a header of serialization version, team and frame
+// followed by any number of messages each prefixed with their MessageType
+func (g *Game) Deserialize(r io.Reader, p *Player) (err error) {
+	var header [3]uint8
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return fmt.Errorf("error deserializing header: %w", err)
+	}
+
+	if header[0] != SerializationVersion {
+		return fmt.Errorf("serializationVersion mismatch: %#02x != %#02x", header[0], SerializationVersion)
+	}
+
+	if p.TeamF != header[1] {
+		p.TeamF = header[1]
+		g.shouldUpdatePlayersList = true
+	}
+
+	// discard stale frame data:
+	frame := header[2]
+	nextFrame := int(frame)
+	lastFrame := int(p.Frame)
+	if lastFrame-nextFrame >= 128 {
+		lastFrame -= 256
+	}
+	if nextFrame < lastFrame {
+		return
+	}
+	p.Frame = frame
+
+	for {
+		// read message type or expect an EOF:
+		var msgType MessageType
+		if err = binary.Read(r, binary.LittleEndian, &msgType); err != nil {
+			break
+		}
+
+		switch msgType {
+		case MsgPlayerName:
+			err = g.DeserializePlayerName(p, r)
+		case MsgWRAM:
+			err = g.DeserializeMemory(&p.WRAM, r)
+		case MsgSRAM:
+			err = g.DeserializeMemory(&p.SRAM, r)
+		default:
+			// no good recourse to be able to skip over the message
+			err = fmt.Errorf("msgType %#02x out of bounds", msgType)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return
+}
+
+func (g *Game) DeserializePlayerName(p *Player, r io.Reader) (err error) {
+	var name [20]byte
+	if _, err = io.ReadFull(r, name[:]); err != nil {
+		return fmt.Errorf("error deserializing name: %w", err)
+	}
+	lastName := p.NameF
+	p.NameF = strings.Trim(string(name[:]), " \t\n\r\000")
+	if lastName != p.NameF {
+		p.showJoinMessage = true
+		// refresh the players list
+		g.shouldUpdatePlayersList = true
+	}
+	return
+}
+
+// DeserializeMemory reads a range of memory as a 24-bit offset, an 8-bit size and the data
+func (g *Game) DeserializeMemory(m *Memory, r io.Reader) (err error) {
+	var header [4]uint8
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return fmt.Errorf("error deserializing memory: %w", err)
+	}
+	offs := uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16
+	data := make([]byte, header[3])
+	if _, err = io.ReadFull(r, data); err != nil {
+		return fmt.Errorf("error deserializing memory: %w", err)
+	}
+	m.write(offs, data)
+	return
+}
+
+func (g *Game) SerializePlayerName(p *Player, w io.Writer) (err error) {
+	var b [21]byte
+	b[0] = byte(MsgPlayerName)
+	n := copy(b[1:], p.Name())
+	for n++; n < len(b); n++ {
+		b[n] = ' '
+	}
+	_, err = w.Write(b[:])
+	return
+}
+
+// SerializeMemory writes a watched range of the player's memory
+func (g *Game) SerializeMemory(msgType MessageType, m *Memory, rng Range, w io.Writer) (err error) {
+	header := [5]uint8{
+		byte(msgType),
+		uint8(rng.Offset),
+		uint8(rng.Offset >> 8),
+		uint8(rng.Offset >> 16),
+		rng.Size,
+	}
+	if _, err = w.Write(header[:]); err != nil {
+		return
+	}
+	_, err = w.Write(m.read(rng.Offset, rng.Size))
+	return
+}
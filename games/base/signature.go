@@ -0,0 +1,114 @@
+package base
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// PatchVersion is the version of the patch Patch writes; increment it whenever the patched code changes so
+// that ROMs patched by older versions are upgraded
+const PatchVersion = 1
+
+// signatureMagic starts the Signature at the init hook
+var signatureMagic = []byte("o2patch")
+
+// Signature identifies an o2-patched ROM and keeps the original code the patch replaced at the hooks so that it
+// can be patched again. It is written at the init hook, just before the init routine, as "o2patch" followed
+// by the version, the length of the reset code, the reset code, the frame hook JSL, the length of the
+// alternate frame hook JSL and that JSL.
+type Signature struct {
+	Version uint8
+	// ResetCode is the original code at the reset hook
+	ResetCode []byte
+	// FrameJSL is the original `JSL` at the frame hook
+	FrameJSL []byte
+	// AltFrameJSL is the original `JSL` at the alternate frame hook; nil if none
+	AltFrameJSL []byte
+}
+
+func signatureLen(resetLen int, hasAlt bool) int {
+	n := len(signatureMagic) + 1 + 1 + resetLen + 4 + 1
+	if hasAlt {
+		n += 4
+	}
+	return n
+}
+
+func (s *Signature) bytes() []byte {
+	b := &bytes.Buffer{}
+	b.Write(signatureMagic)
+	b.WriteByte(s.Version)
+	b.WriteByte(uint8(len(s.ResetCode)))
+	b.Write(s.ResetCode)
+	b.Write(s.FrameJSL)
+	b.WriteByte(uint8(len(s.AltFrameJSL)))
+	b.Write(s.AltFrameJSL)
+	return b.Bytes()
+}
+
+// Signature reads the signature at the init hook if the ROM is patched
+func (p *Patcher) Signature() (sig *Signature, ok bool) {
+	p.readAt(p.hooks.InitHook)
+	magic, err := p.read(len(signatureMagic))
+	if err != nil || !bytes.Equal(magic, signatureMagic) {
+		return nil, false
+	}
+
+	header, err := p.read(2)
+	if err != nil {
+		return nil, false
+	}
+	sig = &Signature{Version: header[0]}
+	if sig.ResetCode, err = p.read(int(header[1])); err != nil {
+		return nil, false
+	}
+	if sig.FrameJSL, err = p.read(4); err != nil {
+		return nil, false
+	}
+	altLen, err := p.read(1)
+	if err != nil {
+		return nil, false
+	}
+	if altLen[0] > 0 {
+		if sig.AltFrameJSL, err = p.read(int(altLen[0])); err != nil {
+			return nil, false
+		}
+	}
+	return sig, true
+}
+
+// PatchVersion returns the version of the o2 patch the ROM has; zero if it is unpatched or patched by a version
+// of o2 that did not sign its patch
+func (p *Patcher) PatchVersion() int {
+	sig, ok := p.Signature()
+	if !ok {
+		return 0
+	}
+	return int(sig.Version)
+}
+
+// restore writes the original code from the signature back to the hooks
+func (p *Patcher) restore(sig *Signature) (err error) {
+	if len(sig.ResetCode) != len(p.hooks.resetCode()) {
+		return fmt.Errorf("o2 patch signature has %d bytes of reset code; expected %d", len(sig.ResetCode), len(p.hooks.resetCode()))
+	}
+	if (sig.AltFrameJSL != nil) != (p.hooks.AltFrameHook != 0) {
+		return fmt.Errorf("o2 patch signature does not match the alternate frame hook")
+	}
+
+	p.writeAt(p.hooks.ResetHook)
+	if err = p.write(sig.ResetCode); err != nil {
+		return
+	}
+	p.writeAt(p.hooks.FrameHook)
+	if err = p.write(sig.FrameJSL); err != nil {
+		return
+	}
+	if sig.AltFrameJSL != nil {
+		p.writeAt(p.hooks.AltFrameHook)
+		if err = p.write(sig.AltFrameJSL); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,202 @@
+package base
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"o2/snes"
+	"o2/snes/asm"
+	"strings"
+)
+
+// maxUpdateLen is the most code an update routine may hold; routines are written with a single write command
+const maxUpdateLen = 255
+
+// updateRoutine generates asm for all syncables that need updating into whichever of the A/B SRAM routines
+// is not running, then points preMain's JSR at it. The routine disables itself with an RTS when done.
+func (g *Game) updateRoutine() {
+	if g.invalid {
+		return
+	}
+
+	q := g.queue
+	if q == nil {
+		return
+	}
+
+	defer g.updateLock.Unlock()
+	g.updateLock.Lock()
+
+	if g.updateStage > 0 {
+		return
+	}
+
+	// select target SRAM routine:
+	routines := g.def.Hooks.routines()
+	var targetSNES uint32
+	if g.nextUpdateA {
+		targetSNES = routines.UpdateA
+	} else {
+		targetSNES = routines.UpdateB
+	}
+
+	// create an assembler:
+	a := asm.Emitter{
+		Code: &bytes.Buffer{},
+		Text: &strings.Builder{},
+	}
+	a.SetBase(targetSNES)
+
+	// assume 8-bit mode for accumulator and index registers:
+	a.AssumeSEP(0x30)
+
+	if g.def.UpdateGuard != nil {
+		g.def.UpdateGuard(&a)
+	}
+
+	if !g.generateUpdateAsm(&a) {
+		// nothing to emit:
+		return
+	}
+
+	// clear out our routine with an RTS instruction at the start:
+	a.Comment("disable update routine with RTS instruction:")
+	a.LDA_imm8_b(0x60) // RTS
+	a.STA_long(targetSNES)
+	a.SEP(0x30)
+	a.RTS()
+
+	// dump asm:
+	log.Print(a.Text.String())
+
+	if a.Code.Len() > maxUpdateLen {
+		panic(fmt.Errorf("base: %s: generated update ASM larger than %d bytes: %d", g.def.Name, maxUpdateLen, a.Code.Len()))
+	}
+
+	// prevent more updates until the upcoming write completes:
+	g.updateStage = 1
+	log.Printf("base: %s: update: write started\n", g.def.Name)
+
+	mapping := g.def.mapping(g.rom)
+	target := mapping.BusAddressToPak(targetSNES)
+	g.lastUpdateTarget = target
+
+	writes := []snes.Write{
+		{
+			Address: target,
+			Size:    uint8(a.Code.Len()),
+			Data:    a.Code.Bytes(),
+		},
+	}
+	if g.def.Hooks.AltFrameHook != 0 {
+		// update the high byte of the JSR instruction in altPreMain too:
+		writes = append(writes, snes.Write{
+			Address: mapping.BusAddressToPak(routines.AltPreMain + 5),
+			Size:    1,
+			Data:    []byte{uint8(targetSNES >> 8)},
+		})
+	}
+	// finally, update the high byte of the JSR instruction in preMain to point to the updated routine:
+	writes = append(writes, snes.Write{
+		Address: mapping.BusAddressToPak(routines.PreMain + 2),
+		Size:    1,
+		Data:    []byte{uint8(targetSNES >> 8)},
+	})
+
+	// write generated asm routine to SRAM:
+	err := q.MakeWriteCommands(
+		writes,
+		func(cmd snes.Command, err error) {
+			log.Printf("base: %s: update: write completed\n", g.def.Name)
+
+			defer g.updateLock.Unlock()
+			g.updateLock.Lock()
+
+			if g.updateStage != 1 {
+				log.Printf("base: %s: update: write complete but updateStage = %d (should be 1)\n", g.def.Name, g.updateStage)
+			}
+
+			g.updateStage = 2
+
+			q := g.enqueueUpdateCheckRead(make([]snes.Read, 0, 8))
+			// must always read the WRAM watch list LAST to validate the prior reads:
+			q = g.enqueueWRAMReads(q, nil)
+			g.readSubmit(q)
+		},
+	).EnqueueTo(q)
+	if err != nil {
+		log.Println(fmt.Errorf("base: %s: update: error enqueuing snes write for update routine: %w", g.def.Name, err))
+		return
+	}
+}
+
+func (g *Game) enqueueUpdateCheckRead(q []snes.Read) []snes.Read {
+	// read the first instruction of the last update routine to check if it completed (if it's a RTS):
+	addr := g.lastUpdateTarget
+	if addr != 0xFFFFFF {
+		q = g.readEnqueue(q, addr, 0x01, nil)
+	}
+	return q
+}
+
+// checkUpdateComplete allows the next update once the last routine has replaced its first instruction with RTS
+func (g *Game) checkUpdateComplete(q []snes.Read, rsp snes.Response) []snes.Read {
+	defer g.updateLock.Unlock()
+	g.updateLock.Lock()
+
+	if rsp.Address != g.lastUpdateTarget {
+		return q
+	}
+
+	if rsp.Data[0] == 0x60 {
+		// allow next update:
+		log.Printf("base: %s: update: complete: $%06x\n", g.def.Name, rsp.Address)
+		if g.updateStage == 2 {
+			g.updateStage = 0
+			g.nextUpdateA = !g.nextUpdateA
+			g.lastUpdateTarget = 0xFFFFFF
+		}
+		return q
+	}
+
+	// check again:
+	q = g.enqueueUpdateCheckRead(q)
+	q = g.enqueueWRAMReads(q, nil)
+	return q
+}
+
+func (g *Game) generateUpdateAsm(a *asm.Emitter) bool {
+	updated := false
+
+	for _, s := range g.syncables {
+		if !s.IsEnabled() {
+			continue
+		}
+		if !s.CanUpdate() {
+			continue
+		}
+
+		// clone the assembler to a temporary:
+		ta := a.Clone()
+		if s.Size() == 2 {
+			ta.Comment("switch to 16-bit mode:")
+			ta.REP(0x30)
+		}
+		// generate the update asm routine in the temporary assembler:
+		if !s.GenerateUpdate(ta) {
+			continue
+		}
+		if s.Size() == 2 {
+			ta.Comment("switch back to 8-bit mode:")
+			ta.SEP(0x30)
+		}
+
+		// don't emit the routine if it pushes us over the code size limit:
+		if ta.Code.Len()+a.Code.Len()+10 <= maxUpdateLen {
+			a.Append(ta)
+			updated = true
+		}
+	}
+
+	return updated
+}
@@ -0,0 +1,120 @@
+package base
+
+import (
+	"fmt"
+	"o2/interfaces"
+)
+
+func (g *Game) NotifyView() {
+	if g.shouldUpdatePlayersList {
+		g.updatePlayersList()
+		g.clean = false
+	}
+	if g.clean {
+		return
+	}
+
+	// update the public serializable ViewModel:
+	g.clean = true
+
+	// notify view of changes:
+	if g.viewModels != nil {
+		g.viewModels.NotifyView("game", g)
+	}
+}
+
+func (g *Game) PushNotification(notification string) {
+	g.Notifications.Publish(notification)
+
+	if viewModels := g.viewModels; viewModels != nil {
+		// record history of Notifications:
+		historyVM, ok := viewModels.GetViewModel("game/notification/history")
+		if !ok {
+			historyVM = make([]string, 0, 200)
+		}
+
+		history, ok := historyVM.([]string)
+		if !ok {
+			history = make([]string, 0, 200)
+		}
+
+		// append the notification:
+		history = append(history, notification)
+		viewModels.NotifyView("game/notification/history", history)
+	}
+}
+
+type PlayerViewModel struct {
+	Index int    `json:"index"`
+	Team  int    `json:"team"`
+	Name  string `json:"name"`
+}
+
+func (g *Game) updatePlayersList() {
+	g.shouldUpdatePlayersList = false
+
+	activePlayers := g.ActivePlayers()
+	playerViewModels := make([]*PlayerViewModel, 0, len(activePlayers))
+	for _, p := range activePlayers {
+		// give the player a sensible name:
+		name := p.Name()
+		if name == "" {
+			name = fmt.Sprintf("player #%02x", p.Index())
+		}
+
+		playerViewModels = append(playerViewModels, &PlayerViewModel{
+			Index: p.Index(),
+			Team:  int(p.Team()),
+			Name:  name,
+		})
+	}
+
+	// send the players list:
+	if viewModels := g.viewModels; viewModels != nil {
+		viewModels.NotifyView("game/players", playerViewModels)
+	}
+}
+
+func (g *Game) CommandFor(command string) (interfaces.Command, error) {
+	switch command {
+	case "setField":
+		return &setFieldCmd{g}, nil
+	default:
+		return nil, fmt.Errorf("no handler for command=%s", command)
+	}
+}
+
+type setFieldCmd struct{ g *Game }
+type setFieldArgs struct {
+	// SyncOptions toggles the named sync options:
+	SyncOptions map[string]bool `json:"syncOptions"`
+}
+
+func (c *setFieldCmd) CreateArgs() interfaces.CommandArgs { return &setFieldArgs{} }
+
+func (c *setFieldCmd) Execute(args interfaces.CommandArgs) error {
+	f, ok := args.(*setFieldArgs)
+	if !ok {
+		return fmt.Errorf("invalid args type for command")
+	}
+
+	g := c.g
+	for name, enabled := range f.SyncOptions {
+		p := g.SyncOptions[name]
+		if p == nil {
+			return fmt.Errorf("unknown sync option '%s'", name)
+		}
+		*p = enabled
+		g.clean = false
+	}
+
+	// save configuration:
+	configurationSystem := g.configurationSystem
+	if configurationSystem != nil {
+		configurationSystem.SaveConfiguration()
+	}
+	// notify view of new values:
+	g.NotifyView()
+
+	return nil
+}
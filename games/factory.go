@@ -22,6 +22,13 @@ type Patcher interface {
 	Patch() error
 }
 
+// PatchDetector is optionally implemented by a Patcher that can tell if its ROM is already patched
+type PatchDetector interface {
+	IsPatched() bool
+	// PatchVersion returns the version of the o2 patch the ROM has; zero if unpatched or unknown
+	PatchVersion() int
+}
+
 var (
 	factoriesMu sync.RWMutex
 	factories   = make(map[string]Factory)
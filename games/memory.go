@@ -5,6 +5,9 @@ type MemoryKind int
 const (
 	SRAM MemoryKind = iota
 	WRAM
+	// SM is Super Metroid's half of SMZ3 addressed as an offset into its save data, which holds a copy of
+	// WRAM $7E:09A2 at offset $000 and of WRAM $7E:D820 onward at offset $060
+	SM
 	// TODO: other kinds of RAM
 )
 
@@ -4,6 +4,20 @@ type SyncablePlayer interface {
 	Index() int
 	Name() string
 	TTL() int
+	// Team is the team number the player syncs with; players only sync with others on the same team
+	Team() uint8
 
 	ReadableMemory(kind MemoryKind) ReadableMemory
 }
+
+// PlayersOnTeam filters players down to those on the given team
+func PlayersOnTeam(players []SyncablePlayer, team uint8) []SyncablePlayer {
+	teammates := make([]SyncablePlayer, 0, len(players))
+	for _, p := range players {
+		if p.Team() != team {
+			continue
+		}
+		teammates = append(teammates, p)
+	}
+	return teammates
+}
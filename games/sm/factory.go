@@ -0,0 +1,77 @@
+// Package sm syncs standalone Super Metroid with the game-independent lifecycle in games/base
+package sm
+
+import (
+	_ "embed"
+	"o2/games"
+	"o2/games/base"
+	"o2/snes"
+	"strings"
+)
+
+//go:embed synctable.yaml
+var syncTableYAML []byte
+
+// gameStateMainGameplay is the value of the game state at $7E:0998 while Samus is in control
+const gameStateMainGameplay = 0x08
+
+// Definition describes Super Metroid for the base lifecycle
+var Definition = &base.Definition{
+	Name:           "SM",
+	Title:          "Super Metroid",
+	IsROMSupported: isROMSupported,
+	// the hooks are in the slow ROM mirrors of banks $80 and $82 that Super Metroid runs from:
+	Hooks: base.Hooks{
+		// `REP #$30 : LDX #$1FFE` in the boot routine, once Super Metroid runs in native mode:
+		ResetHook: 0x008423,
+		ResetCode: []byte{0xC2, 0x30, 0xA2, 0xFE, 0x1F},
+		// free space at the end of bank $80:
+		InitHook: 0x00CD8E,
+		// `JSL $809459` reading controller input at the top of the main game loop:
+		FrameHook: 0x02894B,
+		// between the three save slots and the checksum complements at $70:1FF0 so that the 8KiB of SRAM
+		// the header declares is enough and the copier check never sees more:
+		Routines: base.RoutinesAt(0x701400),
+		RAMSize:  3,
+	},
+	WatchWRAM: []base.Range{
+		// collected items and beams:
+		{Offset: 0x09A4, Size: 0x06},
+		// boss flags and map stations per area:
+		{Offset: 0xD828, Size: 0x05},
+		{Offset: 0xD908, Size: 0x06},
+		// frame counter:
+		{Offset: 0x05B6, Size: 0x02},
+		// game state, last for IsInGame:
+		{Offset: 0x0998, Size: 0x02},
+	},
+	FrameCounter: 0x05B6,
+	IsInGame:     func(wram []byte) bool { return wram[0x0998] == gameStateMainGameplay },
+	SyncTable:    mustParseSyncTable(),
+}
+
+func mustParseSyncTable() *games.SyncTable {
+	table, err := games.ParseSyncTable(syncTableYAML, "yaml")
+	if err != nil {
+		panic(err)
+	}
+	return table
+}
+
+var factory *base.Factory
+
+func FactoryInstance() *base.Factory { return factory }
+
+func isROMSupported(rom *snes.ROM) bool {
+	if rom.Header.HeaderVersion() != 1 {
+		return false
+	}
+	if rom.Header.MapMode != 0x20 && rom.Header.MapMode != 0x30 {
+		return false
+	}
+	return strings.HasPrefix(string(rom.Header.Title[:]), "Super Metroid")
+}
+
+func init() {
+	factory = base.Register(Definition)
+}
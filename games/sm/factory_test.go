@@ -0,0 +1,68 @@
+package sm
+
+import (
+	"bytes"
+	"io"
+	"o2/games"
+	"o2/snes"
+	"testing"
+
+	_ "o2/games/alttp"
+	_ "o2/games/smz3"
+)
+
+func newTestROM(t *testing.T) *snes.ROM {
+	contents := make([]byte, 0x30_0000)
+	copy(contents[0x7FC0:], "Super Metroid        ")
+	contents[0x7FD5] = 0x30 // FastROM LoROM
+	contents[0x7FD7] = 0x0C
+	contents[0x7FD8] = 0x03 // 8KiB SRAM
+	contents[0x7FDA] = 0x01
+
+	rom, err := snes.NewROM("sm.sfc", contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = rom.BusWriter(Definition.Hooks.ResetHook).Write(Definition.Hooks.ResetCode); err != nil {
+		t.Fatal(err)
+	}
+	// JSL $809459
+	if _, err = rom.BusWriter(Definition.Hooks.FrameHook).Write([]byte{0x22, 0x59, 0x94, 0x80}); err != nil {
+		t.Fatal(err)
+	}
+	return rom
+}
+
+func TestFactory_IsROMSupported(t *testing.T) {
+	rom := newTestROM(t)
+
+	var supported []games.Factory
+	for _, f := range games.Factories() {
+		if f.IsROMSupported(rom) {
+			supported = append(supported, f)
+		}
+	}
+	if len(supported) != 1 || supported[0] != games.Factory(FactoryInstance()) {
+		t.Fatalf("Super Metroid is supported by %d factories, expected only games/sm", len(supported))
+	}
+}
+
+func TestFactory_Patch(t *testing.T) {
+	rom := newTestROM(t)
+	if err := FactoryInstance().Patcher(rom).Patch(); err != nil {
+		t.Fatal(err)
+	}
+
+	// JSL $70:17F8, the preMain routine in the unused SRAM after the save slots:
+	frameJSL := make([]byte, 4)
+	if _, err := io.ReadFull(rom.BusReader(Definition.Hooks.FrameHook), frameJSL); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte{0x22, 0xF8, 0x17, 0x70}; !bytes.Equal(frameJSL, expected) {
+		t.Errorf("frame hook = % x, expected % x", frameJSL, expected)
+	}
+	// the SRAM size must not grow past what Super Metroid's copier check expects:
+	if rom.Header.RAMSize != 3 {
+		t.Errorf("RAMSize = %d, expected 3", rom.Header.RAMSize)
+	}
+}
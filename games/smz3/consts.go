@@ -1,394 +0,0 @@
-package smz3
-
-var (
-	underworldNames = map[uint16]string{
-		0x0000: "Ganon",
-		0x0001: "HC, N Corridor",
-		0x0002: "HC, Switch",
-		0x0003: "Houlihan",
-		0x0004: "TR, Crysta-roller",
-		0x0005: "Empty Clone",
-		0x0006: "Swamp, Arrghus[Boss]",
-		0x0007: "Hera, Moldorm[Boss]",
-		0x0008: "Cave, Healing Fairy",
-		0x0009: "PoD",
-		0x000a: "PoD, Stalfos Trap",
-		0x000b: "PoD, Turtle",
-		0x000c: "GT, Entrance",
-		0x000d: "GT, Agahnim2[Boss]",
-		0x000e: "IP, Entrance",
-		0x000f: "Empty Clone",
-		0x0010: "Ganon Evacuation Route",
-		0x0011: "HC, Bombable Stock",
-		0x0012: "Sanctuary",
-		0x0013: "TR, Hokku-Bokku Key Room 2",
-		0x0014: "TR, Big Key",
-		0x0015: "TR",
-		0x0016: "Swamp, Swimming Treadmill",
-		0x0017: "Hera, Moldorm Fall",
-		0x0018: "Cave",
-		0x0019: "PoD, Dark Maze",
-		0x001a: "PoD, Big Chest",
-		0x001b: "PoD, Mimics",
-		0x001c: "GT, Ice Armos",
-		0x001d: "GT, Final Hallway",
-		0x001e: "IP, Bomb Floor",
-		0x001f: "IP, Big Key",
-		0x0020: "ATower, Agahnim[Boss]",
-		0x0021: "HC, Key-rat",
-		0x0022: "HC, Sewer Text Trigger",
-		0x0023: "TR, W Exit to Balcony",
-		0x0024: "TR, Big chest",
-		0x0025: "Empty Clone",
-		0x0026: "Swamp, Statue",
-		0x0027: "Hera, Big Chest",
-		0x0028: "Swamp, Entrance",
-		0x0029: "SW, Mothula[Boss]",
-		0x002a: "PoD, Big Hub",
-		0x002b: "PoD, Fairy",
-		0x002c: "Cave",
-		0x002d: "Empty Clone",
-		0x002e: "IP, Compass",
-		0x002f: "Cave, Kakariko Well HP",
-		0x0030: "ATower, Maiden Sacrifice Chamber",
-		0x0031: "Hera, Hardhat Beetles",
-		0x0032: "HC, Sewer Key Chest",
-		0x0033: "DP, Lanmolas[Boss]",
-		0x0034: "Swamp, Pre-Big Key",
-		0x0035: "Swamp, Big Key",
-		0x0036: "Swamp, Big Chest",
-		0x0037: "Swamp, Water Fill",
-		0x0038: "Swamp, Key Pot",
-		0x0039: "SW, Mothula Hole",
-		0x003a: "PoD, Bombable Floor",
-		0x003b: "PoD, Conveyor",
-		0x003c: "Hookshot Cave",
-		0x003d: "GT, Torch Room 2",
-		0x003e: "IP, Conveyor Hellway",
-		0x003f: "IP, Map Chest",
-		0x0040: "ATower, Final Bridge",
-		0x0041: "HC, First Dark",
-		0x0042: "HC, 6 Ropes",
-		0x0043: "DP, Moving Wall",
-		0x0044: "TT, Big Chest",
-		0x0045: "TT, Jail Cells",
-		0x0046: "Swamp, Compass Chest",
-		0x0047: "Empty Clone",
-		0x0048: "Empty Clone",
-		0x0049: "SW, Gibdo Torch Puzzle",
-		0x004a: "PoD, Entrance",
-		0x004b: "PoD, S Mimics",
-		0x004c: "GT, Mini-Helmasaur Conveyor",
-		0x004d: "GT, Moldorm",
-		0x004e: "IP, Bomb-Jump",
-		0x004f: "IP Clone Room, Fairy",
-		0x0050: "HC, W Corridor",
-		0x0051: "HC, Throne",
-		0x0052: "HC, East Corridor",
-		0x0053: "DP, Popos 2",
-		0x0054: "Swamp, Upstairs Pits",
-		0x0055: "Secret Passage",
-		0x0056: "SW, Key Pot",
-		0x0057: "SW, Big Key",
-		0x0058: "SW, Big Chest",
-		0x0059: "SW, Final Section Entrance",
-		0x005a: "PoD, Helmasaur King[Boss]",
-		0x005b: "GT, Spike Pit",
-		0x005c: "GT, Ganon-Ball Z",
-		0x005d: "GT, Gauntlet 1/2/3",
-		0x005e: "IP, Lonely Firebar",
-		0x005f: "IP, Spike Floor",
-		0x0060: "HC, W Entrance",
-		0x0061: "HC, Main Entrance",
-		0x0062: "HC, East Entrance",
-		0x0063: "DP, Final Section Entrance",
-		0x0064: "TT, W Attic",
-		0x0065: "TT, East Attic",
-		0x0066: "Swamp, Hidden Chest",
-		0x0067: "SW, Compass Chest",
-		0x0068: "SW, Key Chest",
-		0x0069: "Empty Clone",
-		0x006a: "PoD, Rupee",
-		0x006b: "GT, Mimics Rooms",
-		0x006c: "GT, Lanmolas",
-		0x006d: "GT, Gauntlet 4/5",
-		0x006e: "IP, Pengators",
-		0x006f: "Empty Clone",
-		0x0070: "HC, Pre Jail Cells",
-		0x0071: "HC, Boomerang Chest",
-		0x0072: "HC, Map Chest",
-		0x0073: "DP, Big Chest",
-		0x0074: "DP, Map Chest",
-		0x0075: "DP, Big Key Chest",
-		0x0076: "Swamp, Water Drain",
-		0x0077: "Hera, Entrance",
-		0x0078: "Empty Clone",
-		0x0079: "Empty Clone",
-		0x007a: "Empty Clone",
-		0x007b: "GT",
-		0x007c: "GT, Exploding Wall",
-		0x007d: "GT, Warp Maze",
-		0x007e: "IP, Bombable Floor",
-		0x007f: "IP,  Big Spike Traps",
-		0x0080: "HC, Jail Cell",
-		0x0081: "HC",
-		0x0082: "HC, Basement Chasm",
-		0x0083: "DP, W Entrance",
-		0x0084: "DP, Main Entrance",
-		0x0085: "DP, East Entrance",
-		0x0086: "Empty Clone",
-		0x0087: "Hera, Tile",
-		0x0088: "Empty Clone",
-		0x0089: "EP, Fairy",
-		0x008a: "Empty Clone",
-		0x008b: "GT, Spike Skip",
-		0x008c: "GT, Big Chest",
-		0x008d: "GT, Torches 2",
-		0x008e: "IP",
-		0x008f: "Empty Clone",
-		0x0090: "Mire, Vitreous[Boss]",
-		0x0091: "Mire, Final Switch",
-		0x0092: "Mire, Switches",
-		0x0093: "Mire, Floor Switch Puzzle",
-		0x0094: "Empty Clone",
-		0x0095: "GT, Final Collapsing Bridge",
-		0x0096: "GT, Torches 1",
-		0x0097: "Mire, Torch Puzzle",
-		0x0098: "Mire, Entrance",
-		0x0099: "EP, Eyegore Key",
-		0x009a: "Empty Clone",
-		0x009b: "GT, Warp Maze",
-		0x009c: "GT, Invisible Floor Maze",
-		0x009d: "GT, Invisible Floor",
-		0x009e: "IP, Big Chest",
-		0x009f: "IP",
-		0x00a0: "Mire, Pre-Vitreous",
-		0x00a1: "Mire, Fish",
-		0x00a2: "Mire, Bridge Key Chest",
-		0x00a3: "Mire",
-		0x00a4: "TR, Trinexx[Boss]",
-		0x00a5: "GT, Wizzrobes Rooms",
-		0x00a6: "GT, Moldorm Fall",
-		0x00a7: "Hera, Fairy",
-		0x00a8: "EP, Stalfos Spawn",
-		0x00a9: "EP, Big Chest",
-		0x00aa: "EP, Map Chest",
-		0x00ab: "TT, Key Pot",
-		0x00ac: "TT, Blind The Thief[Boss]",
-		0x00ad: "Empty Clone",
-		0x00ae: "IP",
-		0x00af: "IP, Ice Bridge",
-		0x00b0: "ATower, Circle of Pots",
-		0x00b1: "Mire, Hourglass",
-		0x00b2: "Mire, Slug",
-		0x00b3: "Mire, Spike Key Chest",
-		0x00b4: "TR, Pre-Trinexx",
-		0x00b5: "TR, Dark Maze",
-		0x00b6: "TR, Chain Chomps",
-		0x00b7: "TR, Roller",
-		0x00b8: "EP, Big Key",
-		0x00b9: "EP, Lobby Cannonballs",
-		0x00ba: "EP, Key Pot",
-		0x00bb: "TT, Hellway",
-		0x00bc: "TT, Conveyor Toilet",
-		0x00bd: "Empty Clone",
-		0x00be: "IP, Block Puzzle",
-		0x00bf: "IP Clone Room, Switch",
-		0x00c0: "ATower, Dark Bridge",
-		0x00c1: "Mire, Tile",
-		0x00c2: "Mire, Big Hub",
-		0x00c3: "Mire, Big Chest",
-		0x00c4: "TR, Last Switch Puzzle",
-		0x00c5: "TR, Laser Bridge",
-		0x00c6: "TR",
-		0x00c7: "TR, Torch Puzzle",
-		0x00c8: "EP, Armos Knights[Boss]",
-		0x00c9: "EP, Entrance",
-		0x00ca: "??",
-		0x00cb: "TT, NW Entrance",
-		0x00cc: "TT, NE Entrance",
-		0x00cd: "Empty Clone",
-		0x00ce: "IP, Hole to Kholdstare",
-		0x00cf: "Empty Clone",
-		0x00d0: "ATower, Dark Maze",
-		0x00d1: "Mire, Big Key",
-		0x00d2: "Mire, Mire02",
-		0x00d3: "Empty Clone",
-		0x00d4: "Empty Clone",
-		0x00d5: "TR, Laser Key",
-		0x00d6: "TR, Entrance",
-		0x00d7: "Empty Clone",
-		0x00d8: "EP, Zeldagamer",
-		0x00d9: "EP, Canonball",
-		0x00da: "EP",
-		0x00db: "TT, Main SW Entrance",
-		0x00dc: "TT, SE Entrance",
-		0x00dd: "Empty Clone",
-		0x00de: "IP, Kholdstare[Boss]",
-		0x00df: "Cave",
-		0x00e0: "ATower, Entrance",
-		0x00e1: "Cave, Lost Woods HP",
-		0x00e2: "Cave, Lumberjack's Tree HP",
-		0x00e3: "Cave, 1/2 Magic",
-		0x00e4: "Cave, Old Man Cave",
-		0x00e5: "Cave, Old Man Cave",
-		0x00e6: "Cave",
-		0x00e7: "Cave",
-		0x00e8: "Cave",
-		0x00e9: "Empty Clone",
-		0x00ea: "Cave, Spectacle Rock HP",
-		0x00eb: "Cave",
-		0x00ec: "Empty Clone",
-		0x00ed: "Cave",
-		0x00ee: "Cave, Spiral Cave",
-		0x00ef: "Cave, 5 Chests",
-		0x00f0: "Cave, Old Man Starting Cave",
-		0x00f1: "Cave, Old Man Starting Cave",
-		0x00f2: "House",
-		0x00f3: "House, Old Woman",
-		0x00f4: "House, Angry Brothers",
-		0x00f5: "House, Angry Brothers",
-		0x00f6: "Empty Clone",
-		0x00f7: "Empty Clone",
-		0x00f8: "Cave",
-		0x00f9: "Cave",
-		0x00fa: "Cave",
-		0x00fb: "Cave",
-		0x00fc: "Empty Clone",
-		0x00fd: "Cave",
-		0x00fe: "Cave",
-		0x00ff: "Cave",
-		0x0100: "Forest Chest Game",
-		0x0101: "House",
-		0x0102: "Sick Kid",
-		0x0103: "Kakariko Tavern",
-		0x0104: "Link's House",
-		0x0105: "Sahasrahla's Hut",
-		0x0106: "Brewery or Chest Game",
-		0x0107: "Library",
-		0x0108: "Chicken House",
-		0x0109: "Magic Shop",
-		0x010a: "Aginah's Cave",
-		0x010b: "Floodgate",
-		0x010c: "Mimic Cave",
-		0x010d: "Mire Shed",
-		0x010e: "Cave, Bird Guy",
-		0x010f: "House",
-		0x0110: "House",
-		0x0111: "Arrow Game",
-		0x0112: "Dark Sanctuary",
-		0x0113: "King's Tomb",
-		0x0114: "Waterfall Fairy",
-		0x0115: "Cave, Healing Fairy",
-		0x0116: "Pyramid Fairy",
-		0x0117: "Spike Cave",
-		0x0118: "Chest Game",
-		0x0119: "Blind's Hideout",
-		0x011a: "House, Dark Hedge Maze",
-		0x011b: "Cave 45 or Graveyard Cave",
-		0x011c: "C-Shaped House",
-		0x011d: "Blind's Hideout Basement",
-		0x011e: "Hype Cave",
-		0x011f: "Lumberjack House",
-		0x0120: "Ice Rod Cave",
-		0x0121: "Dwarves House",
-		0x0123: "Mini Moldorm Cave",
-		0x0124: "Bonk Rock Cave",
-		0x0125: "Desert Cave",
-		0x0126: "Checkerboard Cave",
-		0x0127: "Peg Cave",
-	}
-	overworldNames = map[uint16]string{
-		0x0000: "Lost Woods",
-		0x0002: "NE House",
-		0x0003: "Spectacle Rock",
-		0x0005: "Death Mountain East",
-		0x0007: "TR",
-		0x000a: "Death Mountain Cave",
-		0x000f: "Waterfall Near Zora's Domain",
-		0x0010: "Lost Woods Entrance",
-		0x0011: "Fortune Teller's House",
-		0x0012: "Teleport Lake",
-		0x0013: "Sanctuary",
-		0x0014: "Cemetary",
-		0x0015: "River Area",
-		0x0016: "Witch's Hut",
-		0x0017: "Another Waterfall",
-		0x0018: "Kakariko Village",
-		0x001a: "Another Forest",
-		0x001b: "HC",
-		0x001d: "Bridge Near Castle",
-		0x001e: "EP",
-		0x0022: "Blacksmiths' House",
-		0x0025: "Octorok Area",
-		0x0028: "Fencepost Maze",
-		0x0029: "Kakariko Library",
-		0x002a: "Haunted Grove",
-		0x002b: "Before Flute Area",
-		0x002c: "Link's House",
-		0x002d: "Bridge to HC",
-		0x002e: "S of EP",
-		0x002f: "Peg Circle",
-		0x0030: "Desert of Mystery",
-		0x0032: "Bluffs Near Desert",
-		0x0033: "Near the Swamp",
-		0x0034: "Great Swamp N",
-		0x0035: "Lake Hylia",
-		0x0037: "Ice Rod Cave",
-		0x003a: "Sleeping Man Area",
-		0x003b: "Great Swamp S",
-		0x003c: "Great Swamp SE",
-		0x003f: "Lake Hylia SE",
-		0x0040: "SW",
-		0x0042: "NE House",
-		0x0043: "GT",
-		0x0045: "Death Mountain East",
-		0x0047: "TR",
-		0x004a: "Magic Cape Cave",
-		0x004f: "Mysterious Pond",
-		0x0050: "Outside SW",
-		0x0051: "Fortune Teller's House",
-		0x0052: "Small Lake",
-		0x0053: "Dark Sanctuary",
-		0x0054: "Dark Graveyard",
-		0x0055: "Dark Waterway",
-		0x0056: "Dark Witch's Hut",
-		0x0057: "Dark Lake Hylia Shore",
-		0x0058: "TT",
-		0x005a: "House I've Never Seen",
-		0x005b: "Pyramid of Power",
-		0x005d: "Broken Bridge",
-		0x005e: "Hedge Maze",
-		0x0062: "Locked Chest House",
-		0x0065: "Octorok Area",
-		0x0068: "Shovel Game",
-		0x0069: "Arrow Game",
-		0x006a: "Haunted Grove",
-		0x006b: "Outside Haunted Grove",
-		0x006c: "Dark Link's House",
-		0x006d: "Peg Bridge",
-		0x006e: "Outside Hedge Maze",
-		0x006f: "Dark Peg Circle",
-		0x0070: "Mire",
-		0x0072: "Outside Mire",
-		0x0073: "Outside Dark Swamp",
-		0x0074: "Dark Swamp N",
-		0x0075: "Frozen Lake Hylia",
-		0x0077: "Black Ice Cave",
-		0x007a: "Dark Sleeping Man",
-		0x007b: "Swamp",
-		0x007c: "Dark Swamp SE",
-		0x007f: "Dark Waterfall",
-		0x0080: "Unknown 1, Master Sword Area",
-		0x0088: "Unknown 2",
-		0x0093: "Unknown 3",
-		0x0094: "Unknown 4, Master Sword Area",
-		0x0095: "Unknown 5, Zora's Domain",
-		0x0096: "Unknown 6",
-		0x0097: "Unknown 7",
-		0x009c: "Unknown 8",
-		0x009d: "Unknown 9",
-		0x009e: "Unknown 10, Lost Woods Overlay",
-		0x009f: "Unknown 11, Rain",
-	}
-)
@@ -1,49 +1,60 @@
 package smz3
 
 import (
-  "log"
-	"o2/games"
+	"log"
+	"o2/games/zelda3"
 	"o2/snes"
 )
 
-var gameName = "SMZ3"
+// Layout describes the SMZ3 combo randomizer for the shared zelda3 engine
+var Layout = &zelda3.Layout{
+	Name: "SMZ3",
+	// free banks in smz3:
+	// $43, $4c, $4d, $4e, $4f, $5b, $5c, $5d, $f9, $fa, $fb, $fc
+	ResetHook:      0x008312,
+	InitHook:       0x1BB1D7,
+	FrameHook:      0xC7FA00,
+	IsROMSupported: isROMSupported,
+	// SMZ3 always uses VT randomizer semantics:
+	IsVTRandomizer: func(rom *snes.ROM) bool { return true },
+	SM: &zelda3.SMLayout{
+		// `JSL $809459` reading controller input at the top of Super Metroid's main game loop:
+		FrameHook: 0x82894B,
+		// $A1:73FE:
+		CurrentGame: 0x33FE,
+		// $A1:6000:
+		SaveData: 0x2000,
+	},
+}
 
-type Factory struct{}
+var factory *zelda3.Factory
 
-var factory *Factory
+func FactoryInstance() *zelda3.Factory { return factory }
 
-func FactoryInstance() *Factory { return factory }
+func NewPatcher(rom *snes.ROM) *zelda3.Patcher {
+	return zelda3.NewPatcher(Layout, rom)
+}
 
-func (f *Factory) IsROMSupported(rom *snes.ROM) bool {
+func isROMSupported(rom *snes.ROM) bool {
 	if rom.Header.HeaderVersion() != 1 {
-    log.Printf("HeaderVersion Failed", rom.Header.HeaderVersion())
+		log.Printf("smz3: HeaderVersion %d failed\n", rom.Header.HeaderVersion())
 		return false
 	}
-	if rom.Header.MapMode != 0x35{
-    log.Printf("MapMode Failed")
+	if rom.Header.MapMode != 0x35 {
+		log.Printf("smz3: MapMode failed\n")
 		return false
 	}
 	if rom.Header.ROMSize < 0x0D {
-    log.Printf("ROMSize Failed")
+		log.Printf("smz3: ROMSize failed\n")
 		return false
 	}
 	if rom.Header.OldMakerCode != 0x01 {
-    log.Printf("OldMakerCode Failed")
+		log.Printf("smz3: OldMakerCode failed\n")
 		return false
 	}
 	return true
 }
 
-func (f *Factory) CanPlay(rom *snes.ROM) (ok bool, whyNot string) {
-	// TODO: read header of ROM to determine what variants are supported or not
-	return true, ""
-}
-
-func (f *Factory) Patcher(rom *snes.ROM) games.Patcher {
-	return &Patcher{rom: rom}
-}
-
 func init() {
-	factory = &Factory{}
-	games.Register(gameName, factory)
+	factory = zelda3.Register(Layout)
 }
@@ -8,6 +8,7 @@ import (
 
 type SyncableGame interface {
 	LocalSyncablePlayer() SyncablePlayer
+	// RemoteSyncablePlayers returns the remote players on the local player's team
 	RemoteSyncablePlayers() []SyncablePlayer
 
 	PushNotification(notification string)
@@ -41,6 +42,22 @@ type (
 
 func PlayerPredicateIdentity(_ SyncablePlayer) bool { return true }
 
+// FormatReceived formats a single received item for a notification; an empty format yields "{name} from {player}"
+func FormatReceived(format, name, player string) string {
+	if format == "" {
+		return fmt.Sprintf("%s from %s", name, player)
+	}
+	return strings.NewReplacer("{name}", name, "{player}", player).Replace(format)
+}
+
+// joinReceived joins received items into a notification, prefixed with "got " only for the default format
+func joinReceived(format string, received []string) string {
+	if format == "" {
+		return fmt.Sprintf("got %s", strings.Join(received, ", "))
+	}
+	return strings.Join(received, ", ")
+}
+
 type SyncableBitU8 struct {
 	SyncableGame
 
@@ -56,6 +73,9 @@ type SyncableBitU8 struct {
 	GenerateAsm SyncableBitU8GenerateAsm
 	OnUpdated   SyncableBitU8OnUpdated
 
+	// NotificationFormat formats each received item with {name} and {player} placeholders; empty uses the default
+	NotificationFormat string
+
 	PendingUpdate bool
 	UpdatingTo    uint8
 	Notification  string
@@ -84,7 +104,7 @@ func (s *SyncableBitU8) CanUpdate() bool {
 
 	// wait until we see the desired update:
 	g := s.SyncableGame
-	if g.LocalSyncablePlayer().ReadableMemory(SRAM).ReadU8(s.Offset) != s.UpdatingTo {
+	if g.LocalSyncablePlayer().ReadableMemory(s.MemoryKind).ReadU8(s.Offset) != s.UpdatingTo {
 		return false
 	}
 
@@ -154,14 +174,13 @@ func (s *SyncableBitU8) GenerateUpdate(asm *asm.Emitter) bool {
 		for i := 0; i < len(s.BitNames); i++ {
 			if initial&k == 0 && updated&k == k {
 				if s.BitNames[i] != "" {
-					item := fmt.Sprintf("%s from %s", s.BitNames[i], receivedFrom[i])
-					received = append(received, item)
+					received = append(received, FormatReceived(s.NotificationFormat, s.BitNames[i], receivedFrom[i]))
 				}
 			}
 			k <<= 1
 		}
 		if len(received) > 0 {
-			s.Notification = fmt.Sprintf("got %s", strings.Join(received, ", "))
+			s.Notification = joinReceived(s.NotificationFormat, received)
 			asm.Comment(s.Notification + ":")
 		}
 	}
@@ -199,6 +218,9 @@ type SyncableBitU16 struct {
 	GenerateAsm SyncableBitU16GenerateAsm
 	OnUpdated   SyncableBitU16OnUpdated
 
+	// NotificationFormat formats each received item with {name} and {player} placeholders; empty uses the default
+	NotificationFormat string
+
 	PendingUpdate bool
 	UpdatingTo    uint16
 	Notification  string
@@ -304,14 +326,13 @@ func (s *SyncableBitU16) GenerateUpdate(asm *asm.Emitter) bool {
 		for i := 0; i < len(s.BitNames); i++ {
 			if initial&k == 0 && updated&k == k {
 				if s.BitNames[i] != "" {
-					item := fmt.Sprintf("%s from %s", s.BitNames[i], receivedFrom[i])
-					received = append(received, item)
+					received = append(received, FormatReceived(s.NotificationFormat, s.BitNames[i], receivedFrom[i]))
 				}
 			}
 			k <<= 1
 		}
 		if len(received) > 0 {
-			s.Notification = fmt.Sprintf("got %s", strings.Join(received, ", "))
+			s.Notification = joinReceived(s.NotificationFormat, received)
 			asm.Comment(s.Notification + ":")
 		}
 	}
@@ -349,6 +370,9 @@ type SyncableMaxU8 struct {
 	GenerateAsm SyncableMaxU8GenerateAsm
 	OnUpdated   SyncableMaxU8OnUpdated
 
+	// NotificationFormat formats the received item with {name} and {player} placeholders; empty uses the default
+	NotificationFormat string
+
 	PendingUpdate bool
 	UpdatingTo    uint8
 	Notification  string
@@ -434,8 +458,8 @@ func (s *SyncableMaxU8) GenerateUpdate(asm *asm.Emitter) bool {
 		i := int(maxV) - 1
 		if i >= 0 && i < len(s.ValueNames) {
 			if s.ValueNames[i] != "" {
-				received := s.ValueNames[i]
-				s.Notification = fmt.Sprintf("got %s from %s", received, maxP.Name())
+				received := FormatReceived(s.NotificationFormat, s.ValueNames[i], maxP.Name())
+				s.Notification = joinReceived(s.NotificationFormat, []string{received})
 				asm.Comment(s.Notification + ":")
 			}
 		}
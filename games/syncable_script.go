@@ -0,0 +1,377 @@
+package games
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"o2/snes/asm"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// maximum number of bytes of asm a script may emit per update:
+const syncScriptMaxCode = 128
+
+// maximum number of Starlark computation steps a script may take per call, including its top-level statements:
+const syncScriptMaxSteps = 100000
+
+// ErrSyncScriptSteps is returned when a sync script exceeds syncScriptMaxSteps; such a script is not run again
+var ErrSyncScriptSteps = errors.New("syncscript: exceeded execution step limit")
+
+// SyncScript is a compiled Starlark sync script that defines generate_update(ctx) and optionally
+// is_update_complete(ctx, updating_to); see README.md for the API available to scripts. Starlark is sandboxed:
+// scripts have no access to files, the network or the clock, and every call is limited to syncScriptMaxSteps.
+type SyncScript struct {
+	name string
+
+	generateUpdate   starlark.Callable
+	isUpdateComplete starlark.Callable
+}
+
+// CompileSyncScript compiles a sync script; src may be a string, []byte or nil to read filename
+func CompileSyncScript(filename string, src interface{}) (*SyncScript, error) {
+	thread := newSyncScriptThread(filename)
+	globals, err := starlark.ExecFile(thread, filename, src, starlark.StringDict{
+		"SRAM": starlark.MakeInt(int(SRAM)),
+		"WRAM": starlark.MakeInt(int(WRAM)),
+		"SM":   starlark.MakeInt(int(SM)),
+	})
+	if err != nil {
+		if thread.ExecutionSteps() >= syncScriptMaxSteps {
+			return nil, fmt.Errorf("%w: %s", ErrSyncScriptSteps, filename)
+		}
+		return nil, fmt.Errorf("syncscript: %w", err)
+	}
+	globals.Freeze()
+
+	s := &SyncScript{name: filename}
+	var ok bool
+	if s.generateUpdate, ok = globals["generate_update"].(starlark.Callable); !ok {
+		return nil, fmt.Errorf("syncscript: %s: must define generate_update(ctx)", filename)
+	}
+	if fn, found := globals["is_update_complete"]; found {
+		if s.isUpdateComplete, ok = fn.(starlark.Callable); !ok {
+			return nil, fmt.Errorf("syncscript: %s: is_update_complete must be a function", filename)
+		}
+	}
+	return s, nil
+}
+
+// LoadSyncScript reads and compiles a sync script file
+func LoadSyncScript(path string) (*SyncScript, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return CompileSyncScript(path, src)
+}
+
+func (s *SyncScript) Name() string { return s.name }
+
+func newSyncScriptThread(name string) *starlark.Thread {
+	thread := &starlark.Thread{Name: name, Print: syncScriptPrint}
+	thread.SetMaxExecutionSteps(syncScriptMaxSteps)
+	return thread
+}
+
+func syncScriptPrint(thread *starlark.Thread, msg string) {
+	log.Printf("syncscript: %s: %s\n", thread.Name, msg)
+}
+
+// SyncableScript implements SyncStrategy with a SyncScript
+type SyncableScript struct {
+	SyncableGame
+
+	Offset uint32
+	MemoryKind
+	IsEnabledPtr *bool
+
+	Script *SyncScript
+
+	PendingUpdate bool
+	UpdatingTo    int64
+	Notification  string
+
+	// set once the script fails in a way that would repeat on every call, e.g. ErrSyncScriptSteps:
+	Failed error
+}
+
+func NewSyncableScript(g SyncableGame, offset uint32, enabled *bool, script *SyncScript) *SyncableScript {
+	return &SyncableScript{
+		SyncableGame: g,
+		Offset:       offset,
+		MemoryKind:   SRAM,
+		IsEnabledPtr: enabled,
+		Script:       script,
+	}
+}
+
+func (s *SyncableScript) Size() uint      { return 1 }
+func (s *SyncableScript) IsEnabled() bool { return *s.IsEnabledPtr && s.Failed == nil }
+
+func (s *SyncableScript) CanUpdate() bool {
+	if !s.PendingUpdate {
+		return true
+	}
+
+	// wait until we see the desired update:
+	complete := false
+	if s.Script.isUpdateComplete != nil {
+		ctx, _ := s.newContext(nil)
+		v, err := s.call(s.Script.isUpdateComplete, ctx, starlark.MakeInt64(s.UpdatingTo))
+		if err != nil {
+			// give up on the update rather than block this syncable forever:
+			complete = true
+		} else {
+			complete = bool(v.Truth())
+		}
+	} else {
+		complete = int64(s.SyncableGame.LocalSyncablePlayer().ReadableMemory(s.MemoryKind).ReadU8(s.Offset)) == s.UpdatingTo
+	}
+	if !complete {
+		return false
+	}
+
+	// send the notification:
+	if s.Notification != "" {
+		s.SyncableGame.PushNotification(s.Notification)
+		s.Notification = ""
+	}
+
+	s.PendingUpdate = false
+
+	return true
+}
+
+func (s *SyncableScript) GenerateUpdate(a *asm.Emitter) bool {
+	// let the script emit to a scratch emitter so that nothing invalid reaches the real one:
+	scratch := a.Clone()
+	if a.Text == nil {
+		scratch.Text = nil
+	}
+	if s.Failed != nil {
+		return false
+	}
+	ctx, sa := s.newContext(scratch)
+
+	s.Notification = ""
+	v, err := s.call(s.Script.generateUpdate, ctx)
+	if err != nil {
+		return false
+	}
+	if v == starlark.None || v == starlark.False {
+		return false
+	}
+
+	updatingTo, ok := v.(starlark.Int)
+	if !ok {
+		log.Printf("syncscript: %s: generate_update must return None or an int, got %s\n", s.Script.name, v.Type())
+		return false
+	}
+	if err = sa.validate(); err != nil {
+		log.Printf("syncscript: %s: %v\n", s.Script.name, err)
+		return false
+	}
+
+	s.PendingUpdate = true
+	s.UpdatingTo, _ = updatingTo.Int64()
+	a.Append(scratch)
+	return true
+}
+
+func (s *SyncableScript) call(fn starlark.Callable, args ...starlark.Value) (v starlark.Value, err error) {
+	if s.Failed != nil {
+		return nil, s.Failed
+	}
+
+	thread := newSyncScriptThread(s.Script.name)
+	v, err = starlark.Call(thread, fn, starlark.Tuple(args), nil)
+	if err != nil {
+		if thread.ExecutionSteps() >= syncScriptMaxSteps {
+			// the script would take just as long next frame so stop running it:
+			s.Failed = fmt.Errorf("%w: %s", ErrSyncScriptSteps, s.Script.name)
+			s.PendingUpdate = false
+			log.Printf("%v; disabled\n", s.Failed)
+			return nil, s.Failed
+		}
+		if evalErr, ok := err.(*starlark.EvalError); ok {
+			log.Printf("syncscript: %s\n", evalErr.Backtrace())
+		} else {
+			log.Printf("syncscript: %s: %v\n", s.Script.name, err)
+		}
+	}
+	return
+}
+
+func (s *SyncableScript) newContext(a *asm.Emitter) (*starlarkstruct.Struct, *syncScriptAsm) {
+	g := s.SyncableGame
+	remotes := g.RemoteSyncablePlayers()
+	remoteValues := make([]starlark.Value, 0, len(remotes))
+	for _, p := range remotes {
+		remoteValues = append(remoteValues, s.newPlayer(p))
+	}
+
+	var sa *syncScriptAsm
+	fields := starlark.StringDict{
+		"offset":  starlark.MakeUint(uint(s.Offset)),
+		"memory":  starlark.MakeInt(int(s.MemoryKind)),
+		"local":   s.newPlayer(g.LocalSyncablePlayer()),
+		"remotes": starlark.NewList(remoteValues),
+		"notify": starlark.NewBuiltin("notify", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var text string
+			if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &text); err != nil {
+				return nil, err
+			}
+			s.Notification = text
+			return starlark.None, nil
+		}),
+	}
+	if a != nil {
+		sa = &syncScriptAsm{a: a, start: a.Code.Len()}
+		fields["asm"] = sa.value()
+	}
+
+	return starlarkstruct.FromStringDict(starlark.String("ctx"), fields), sa
+}
+
+func (s *SyncableScript) newPlayer(p SyncablePlayer) starlark.Value {
+	// read wraps a memory access for a builtin; games may not bounds check every offset so recover from panics:
+	read := func(name string, access func(m ReadableMemory, offs uint32) uint) *starlark.Builtin {
+		return starlark.NewBuiltin(name, func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (v starlark.Value, err error) {
+			var offs, kind = 0, int(s.MemoryKind)
+			if err = starlark.UnpackArgs(fn.Name(), args, kwargs, "offset", &offs, "memory?", &kind); err != nil {
+				return
+			}
+			if kind != int(SRAM) && kind != int(WRAM) && kind != int(SM) {
+				return nil, fmt.Errorf("%s: invalid memory %d", fn.Name(), kind)
+			}
+			if offs < 0 || offs > 0x1FFFF {
+				return nil, fmt.Errorf("%s: offset $%x out of range", fn.Name(), offs)
+			}
+
+			defer func() {
+				if r := recover(); r != nil {
+					v, err = nil, fmt.Errorf("%s: cannot read offset $%x", fn.Name(), offs)
+				}
+			}()
+			return starlark.MakeUint(access(p.ReadableMemory(MemoryKind(kind)), uint32(offs))), nil
+		})
+	}
+
+	return starlarkstruct.FromStringDict(starlark.String("player"), starlark.StringDict{
+		"name":  starlark.String(p.Name()),
+		"index": starlark.MakeInt(p.Index()),
+		"team":  starlark.MakeInt(int(p.Team())),
+		"read_u8": read("read_u8", func(m ReadableMemory, offs uint32) uint {
+			return uint(m.ReadU8(offs))
+		}),
+		"read_u16": read("read_u16", func(m ReadableMemory, offs uint32) uint {
+			return uint(m.ReadU16(offs))
+		}),
+		"bus_address": read("bus_address", func(m ReadableMemory, offs uint32) uint {
+			return uint(m.BusAddress(offs))
+		}),
+	})
+}
+
+// syncScriptAsm is the safe subset of asm.Emitter exposed to scripts
+type syncScriptAsm struct {
+	a     *asm.Emitter
+	start int
+	// code offsets at which the script emitted an instruction:
+	instructions map[int]bool
+	// code offsets that branches jump to:
+	targets []int
+}
+
+// emit records the offset of the instruction it emits so branches can be checked to land on it
+func (sa *syncScriptAsm) emit(emit func()) {
+	if sa.instructions == nil {
+		sa.instructions = make(map[int]bool)
+	}
+	sa.instructions[sa.a.Code.Len()] = true
+	emit()
+}
+
+func (sa *syncScriptAsm) validate() error {
+	size := sa.a.Code.Len() - sa.start
+	if size > syncScriptMaxCode {
+		return fmt.Errorf("emitted %d bytes of asm; max is %d", size, syncScriptMaxCode)
+	}
+	for _, target := range sa.targets {
+		if target > sa.a.Code.Len() {
+			return fmt.Errorf("branch jumps past the end of the emitted asm")
+		}
+		if target != sa.a.Code.Len() && !sa.instructions[target] {
+			return fmt.Errorf("branch jumps into the middle of an instruction at +%d", target-sa.start)
+		}
+	}
+	return nil
+}
+
+func (sa *syncScriptAsm) value() starlark.Value {
+	imm := func(name string, emit func(v uint8)) *starlark.Builtin {
+		return starlark.NewBuiltin(name, func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var v int
+			if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &v); err != nil {
+				return nil, err
+			}
+			if v < 0 || v > 0xFF {
+				return nil, fmt.Errorf("%s: immediate $%x out of 8-bit range", fn.Name(), v)
+			}
+			sa.emit(func() { emit(uint8(v)) })
+			return starlark.None, nil
+		})
+	}
+	long := func(name string, emit func(addr uint32)) *starlark.Builtin {
+		return starlark.NewBuiltin(name, func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var addr int
+			if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &addr); err != nil {
+				return nil, err
+			}
+			if addr < 0x7E0000 || addr > 0x7FFFFF {
+				return nil, fmt.Errorf("%s: address $%06x is not in WRAM", fn.Name(), addr)
+			}
+			sa.emit(func() { emit(uint32(addr)) })
+			return starlark.None, nil
+		})
+	}
+	branch := func(name string, emit func(m int8)) *starlark.Builtin {
+		return starlark.NewBuiltin(name, func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var m int
+			if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &m); err != nil {
+				return nil, err
+			}
+			if m < 0 || m > 0x7F {
+				return nil, fmt.Errorf("%s: branch must skip forward 0..127 bytes", fn.Name())
+			}
+			sa.emit(func() { emit(int8(m)) })
+			sa.targets = append(sa.targets, sa.a.Code.Len()+m)
+			return starlark.None, nil
+		})
+	}
+
+	a := sa.a
+	return starlarkstruct.FromStringDict(starlark.String("asm"), starlark.StringDict{
+		"comment": starlark.NewBuiltin("comment", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var text string
+			if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &text); err != nil {
+				return nil, err
+			}
+			a.Comment(text)
+			return starlark.None, nil
+		}),
+		"lda_imm": imm("lda_imm", a.LDA_imm8_b),
+		"ora_imm": imm("ora_imm", a.ORA_imm8_b),
+		"and_imm": imm("and_imm", a.AND_imm8_b),
+		"cmp_imm": imm("cmp_imm", a.CMP_imm8_b),
+		"adc_imm": imm("adc_imm", a.ADC_imm8_b),
+		"lda":     long("lda", a.LDA_long),
+		"ora":     long("ora", a.ORA_long),
+		"sta":     long("sta", a.STA_long),
+		"bne":     branch("bne", a.BNE),
+		"beq":     branch("beq", a.BEQ),
+	})
+}
@@ -0,0 +1,227 @@
+package games
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// SyncTable declaratively describes the syncable memory locations of a game so that support for ROM variants
+// can be added without recompiling. It is loaded from JSON or YAML.
+type SyncTable struct {
+	Syncables []SyncTableEntry `json:"syncables" yaml:"syncables"`
+}
+
+// sync strategies a SyncTableEntry may use:
+const (
+	SyncStrategyBits   = "bits"   // OR together all players' bits of a u8
+	SyncStrategyBits16 = "bits16" // OR together all players' bits of a u16
+	SyncStrategyMax    = "max"    // take the maximum of all players' u8 values
+	SyncStrategyCustom = "custom" // a strategy implemented by the game, named by Custom
+	SyncStrategyScript = "script" // a SyncScript given by Script or Source
+)
+
+// SyncTableEntry describes a single syncable memory location
+type SyncTableEntry struct {
+	// Offset into the memory, e.g. 0x340 for SRAM $7EF340
+	Offset Hex `json:"offset" yaml:"offset"`
+	// Memory is "sram" (default), "wram" or "sm"
+	Memory string `json:"memory,omitempty" yaml:"memory,omitempty"`
+	// Strategy is one of "bits", "bits16", "max", "custom" or "script"
+	Strategy string `json:"strategy" yaml:"strategy"`
+	// Custom names the game's custom strategy when Strategy is "custom"
+	Custom string `json:"custom,omitempty" yaml:"custom,omitempty"`
+	// Script is the path of a Starlark sync script, relative to the table file, when Strategy is "script"
+	Script string `json:"script,omitempty" yaml:"script,omitempty"`
+	// Source is an inline Starlark sync script used instead of Script
+	Source string `json:"source,omitempty" yaml:"source,omitempty"`
+	// Mask limits which bits sync for "bits" and "bits16"; zero means all bits
+	Mask Hex `json:"mask,omitempty" yaml:"mask,omitempty"`
+	// AbsMax discards remote values above it for "max"; zero means no limit
+	AbsMax Hex `json:"absMax,omitempty" yaml:"absMax,omitempty"`
+	// Names are the bit names (LSB first) for "bits" and "bits16" or the names of values 1..N for "max"
+	Names []string `json:"names,omitempty" yaml:"names,omitempty"`
+	// Enable names the game's sync option that enables this entry, e.g. "items"
+	Enable string `json:"enable" yaml:"enable"`
+	// Notification is the text shown per received item with {name} and {player} placeholders;
+	// empty uses "got {name} from {player}"
+	Notification string `json:"notification,omitempty" yaml:"notification,omitempty"`
+	// OnUpdated names a game hook that emits extra asm after the update, e.g. to refresh graphics
+	OnUpdated string `json:"onUpdated,omitempty" yaml:"onUpdated,omitempty"`
+	// When restricts the entry to certain ROM variants; the game defines the variant names, e.g. "vt" or "!vt"
+	When string `json:"when,omitempty" yaml:"when,omitempty"`
+
+	// compiled script for the "script" strategy:
+	script *SyncScript
+}
+
+// SyncScript returns the compiled script for the "script" strategy
+func (e *SyncTableEntry) SyncScript() *SyncScript { return e.script }
+
+// MemoryKind returns the entry's memory kind
+func (e *SyncTableEntry) MemoryKind() (MemoryKind, error) {
+	switch strings.ToLower(e.Memory) {
+	case "", "sram":
+		return SRAM, nil
+	case "wram":
+		return WRAM, nil
+	case "sm":
+		return SM, nil
+	default:
+		return SRAM, fmt.Errorf("synctable: offset $%x: unknown memory '%s'", uint32(e.Offset), e.Memory)
+	}
+}
+
+// ParseSyncTable parses a sync table in the given format, "json" or "yaml"; script paths are relative to the
+// working directory
+func ParseSyncTable(data []byte, format string) (table *SyncTable, err error) {
+	return parseSyncTable(data, format, "")
+}
+
+func parseSyncTable(data []byte, format string, dir string) (table *SyncTable, err error) {
+	table = &SyncTable{}
+	switch strings.ToLower(format) {
+	case "json":
+		err = json.Unmarshal(data, table)
+	case "yaml", "yml":
+		err = yaml.UnmarshalStrict(data, table)
+	default:
+		err = fmt.Errorf("synctable: unknown format '%s'", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = table.Validate(); err != nil {
+		return nil, err
+	}
+	if err = table.compileScripts(dir); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (t *SyncTable) compileScripts(dir string) (err error) {
+	for i := range t.Syncables {
+		e := &t.Syncables[i]
+		if e.Strategy != SyncStrategyScript {
+			continue
+		}
+
+		if e.Source != "" {
+			e.script, err = CompileSyncScript(fmt.Sprintf("synctable[$%x]", uint32(e.Offset)), e.Source)
+		} else {
+			path := e.Script
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			e.script, err = LoadSyncScript(path)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// LoadSyncTable reads a sync table from a file whose format is determined by its extension
+func LoadSyncTable(path string) (table *SyncTable, err error) {
+	var data []byte
+	data, err = ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	format := strings.TrimPrefix(filepath.Ext(path), ".")
+	table, err = parseSyncTable(data, format, filepath.Dir(path))
+	if err != nil {
+		err = fmt.Errorf("synctable: '%s': %w", path, err)
+	}
+	return
+}
+
+// Validate checks the game-independent parts of each entry
+func (t *SyncTable) Validate() error {
+	for i := range t.Syncables {
+		e := &t.Syncables[i]
+		if _, err := e.MemoryKind(); err != nil {
+			return err
+		}
+
+		var width uint
+		switch e.Strategy {
+		case SyncStrategyBits:
+			width = 8
+		case SyncStrategyBits16:
+			width = 16
+		case SyncStrategyMax:
+			width = 8
+		case SyncStrategyCustom:
+			if e.Custom == "" {
+				return fmt.Errorf("synctable: offset $%x: custom strategy requires a name", uint32(e.Offset))
+			}
+			continue
+		case SyncStrategyScript:
+			if e.Script == "" && e.Source == "" {
+				return fmt.Errorf("synctable: offset $%x: script strategy requires a script or source", uint32(e.Offset))
+			}
+			continue
+		default:
+			return fmt.Errorf("synctable: offset $%x: unknown strategy '%s'", uint32(e.Offset), e.Strategy)
+		}
+
+		if uint64(e.Mask) >= 1<<width || uint64(e.AbsMax) >= 1<<width {
+			return fmt.Errorf("synctable: offset $%x: mask or absMax out of range for %d bits", uint32(e.Offset), width)
+		}
+		if e.Strategy != SyncStrategyMax && uint(len(e.Names)) > width {
+			return fmt.Errorf("synctable: offset $%x: %d names for %d bits", uint32(e.Offset), len(e.Names), width)
+		}
+	}
+	return nil
+}
+
+// Hex is an unsigned number that may be written in JSON or YAML as a number or as a string in decimal,
+// "0x" hexadecimal or "$" hexadecimal notation
+type Hex uint32
+
+func (h *Hex) parse(s string) error {
+	s = strings.TrimSpace(s)
+	base := 10
+	if strings.HasPrefix(s, "$") {
+		s, base = s[1:], 16
+	} else if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s, base = s[2:], 16
+	}
+	v, err := strconv.ParseUint(s, base, 32)
+	if err != nil {
+		return fmt.Errorf("synctable: invalid number '%s'", s)
+	}
+	*h = Hex(v)
+	return nil
+}
+
+func (h *Hex) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		return h.parse(s)
+	}
+	var v uint32
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*h = Hex(v)
+	return nil
+}
+
+func (h *Hex) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return h.parse(s)
+}
@@ -1,4 +1,4 @@
-package alttp
+package zelda3
 
 var (
 	underworldNames = map[uint16]string{
@@ -1,4 +1,4 @@
-package alttp
+package zelda3
 
 import (
 	"encoding/json"
@@ -18,8 +18,12 @@ const MaxPlayers = 256
 
 // Game implements game.Game
 type Game struct {
+	// layout describes the ROM's hooks; nil for spectators and tests
+	layout *Layout
 	// rom cannot be nil
 	rom *snes.ROM
+	// syncTable describes the syncable items created by initSync
+	syncTable *games.SyncTable
 
 	// queue can be nil at any time
 	queue snes.Queue
@@ -86,15 +90,26 @@ type Game struct {
 	overworld      [0xC0]games.SyncableBitU8
 	syncableBitU16 map[uint16]*games.SyncableBitU16
 
+	// Super Metroid's syncable items are kept apart since its offsets overlap ALTTP's:
+	smSyncableItems map[uint16]games.SyncStrategy
+
+	// delta-compressed SRAM broadcast state:
+	sramStreams    []*sramDeltaStream
+	sramDeltaRecv  map[sramDeltaRecvKey][]*sramSnapshot
+	sramDeltaPeers []uint32
+
 	romFunctions map[romFunction]uint32
 
-	lastGameFrame      uint8  // copy of wram[$001A] in-game frame counter of vanilla ALTTP game
+	lastGameFrame      uint8  // copy of wram[$001A] in-game frame counter of vanilla ALTTP game, or of wram[$05B6] in SM
 	localFrame         uint64 // total frame count since start of local game
 	serverFrame        uint64 // total frame count according to server (taken from first player to enter group)
 	monotonicFrameTime uint8  // always increments by 1 whenever game frame increases by any amount N
 
 	shouldUpdatePlayersList bool
 
+	// spectating games only follow other players; see Spectator:
+	spectating bool
+
 	colorPendingUpdate int
 	colorUpdatedTo     uint16
 	last15             uint8
@@ -114,14 +129,16 @@ type Game struct {
 	SyncChests       bool   `json:"syncChests"`
 	lastSyncChests   bool
 	SyncTunicColor   bool `json:"syncTunicColor"`
+	SyncSRAMDelta    bool `json:"syncSRAMDelta"`
 }
 
 func (f *Factory) NewGame(rom *snes.ROM) games.Game {
 	if rom == nil {
-		panic("alttp: rom cannot be nil")
+		panic("zelda3: rom cannot be nil")
 	}
 
 	g := &Game{
+		layout:             f.layout,
 		rom:                rom,
 		running:            false,
 		stopped:            make(chan struct{}),
@@ -133,7 +150,7 @@ func (f *Factory) NewGame(rom *snes.ROM) games.Game {
 		lastServerRecvTime: time.Now(),
 		// ViewModel:
 		IsCreated:        true,
-		GameName:         gameName,
+		GameName:         f.layout.Name,
 		SyncItems:        true,
 		SyncDungeonItems: true,
 		SyncProgress:     true,
@@ -147,14 +164,17 @@ func (f *Factory) NewGame(rom *snes.ROM) games.Game {
 
 	//go g.ntpQueryLoop()
 
+	g.syncTable = g.loadSyncTable()
+
 	g.initSerde()
+	g.initSRAMDelta()
 	g.fillRomFunctions()
 
 	return g
 }
 
 func (g *Game) Name() string {
-	return gameName
+	return g.GameName
 }
 
 func (g *Game) Title() string {
@@ -169,7 +189,7 @@ func (g *Game) LoadConfiguration(config json.RawMessage) {
 	// kind of dirty to just unmarshal the public `json` tagged fields, but it works:
 	err := json.Unmarshal(config, g)
 	if err != nil {
-		log.Printf("alttp: loadConfiguration: %v\n", err)
+		log.Printf("zelda3: loadConfiguration: %v\n", err)
 		return
 	}
 	g.IsCreated = true
@@ -191,6 +211,10 @@ func (g *Game) ProvideQueue(queue snes.Queue) {
 }
 func (g *Game) ProvideClient(client *client.Client) {
 	g.client = client
+	if client != nil {
+		client.Reliable().StampHeader = g.stampReliableHeader
+		g.provideSRAMDeltaClient(client.Reliable().Reliable)
+	}
 
 	// indicate we want a refresh of the NTP ClockOffset:
 	g.ntpC <- 0
@@ -204,14 +228,19 @@ func (g *Game) ProvideConfigurationSystem(configurationSystem interfaces.Configu
 
 // Notify is called by root ViewModel
 func (g *Game) Notify(key string, value interface{}) {
-	//log.Printf("alttp: notify('%s', '%+v')\n", key, value)
+	//log.Printf("zelda3: notify('%s', '%+v')\n", key, value)
 	switch key {
 	case "team":
-		g.local.Team = value.(uint8)
+		g.local.TeamF = value.(uint8)
 		g.updatePlayersList()
 		break
 	case "playerName":
+		lastName := g.local.NameF
 		g.local.NameF = value.(string)
+		if g.local.NameF != lastName && g.local.Index() >= 0 {
+			// let other players see the new name right away:
+			g.sendReliable(reliableChannelEvents, g.makePlayerNameMessage())
+		}
 		g.updatePlayersList()
 		break
 	}
@@ -244,6 +273,7 @@ func (g *Game) Reset() {
 	g.local = &Player{IndexF: -1, PlayerColor: 0x12ef}
 	local := g.local
 	local.WRAM = make(map[uint16]*SyncableWRAM)
+	local.smLayout = g.smLayout()
 
 	if g.viewModels != nil {
 		// preserve last-set info:
@@ -251,7 +281,7 @@ func (g *Game) Reset() {
 		if ok && serverViewModelIntf != nil {
 			serverViewModel := serverViewModelIntf.(*engine.ServerViewModel)
 			local.NameF = serverViewModel.PlayerName
-			local.Team = serverViewModel.Team
+			local.TeamF = serverViewModel.Team
 		}
 	}
 
@@ -329,6 +359,15 @@ func (g *Game) RemotePlayers() []*Player {
 	return remotePlayers
 }
 
+func (g *Game) remotePlayerIndexes() []uint32 {
+	remotePlayers := g.RemotePlayers()
+	indexes := make([]uint32, 0, len(remotePlayers))
+	for _, p := range remotePlayers {
+		indexes = append(indexes, uint32(p.Index()))
+	}
+	return indexes
+}
+
 func (g *Game) LocalSyncablePlayer() games.SyncablePlayer {
 	return g.local
 }
@@ -342,7 +381,8 @@ func (g *Game) RemoteSyncablePlayers() []games.SyncablePlayer {
 		}
 		remotePlayers = append(remotePlayers, p)
 	}
-	return remotePlayers
+	// only sync with teammates:
+	return games.PlayersOnTeam(remotePlayers, g.LocalPlayer().Team())
 }
 
 func (g *Game) ServerNow() time.Time {
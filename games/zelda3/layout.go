@@ -0,0 +1,86 @@
+package zelda3
+
+import (
+	"o2/games"
+	"o2/snes"
+	"o2/snes/exhirom"
+)
+
+// Layout describes how a ROM built on the ALTTP engine differs from the others. The sync engine itself
+// (WRAM/SRAM offsets, serialization, ASM generation) is shared; only the hooks it patches into the ROM and
+// the ROM recognition vary per game.
+type Layout struct {
+	// Name identifies the game for registration and configuration, e.g. "ALTTP"
+	Name string
+
+	// ResetHook is the bus address of `LDA #$81 : STA $4200` in the reset routine where NMI is enabled;
+	// it is replaced with a JSL to our init routine
+	ResetHook uint32
+	// InitHook is the bus address of free ROM space to write our init routine to
+	InitHook uint32
+	// FrameHook is the bus address of the `JSL GameModes` executed every frame
+	FrameHook uint32
+
+	// IsROMSupported recognizes the game from the ROM header
+	IsROMSupported func(rom *snes.ROM) bool
+	// IsVTRandomizer determines if the ROM uses VT randomizer SRAM semantics; nil checks for a "VT " title
+	IsVTRandomizer func(rom *snes.ROM) bool
+
+	// SyncTable describes the syncable items; nil uses the built-in ALTTP table
+	SyncTable *games.SyncTable
+
+	// SM describes the Super Metroid half of combo ROMs like SMZ3; nil for ROMs with only ALTTP
+	SM *SMLayout
+}
+
+// SMLayout describes where a combo ROM keeps Super Metroid's state. Only one of the two games runs at a time
+// and owns WRAM; the other game's state waits in SRAM until the player crosses over again.
+type SMLayout struct {
+	// FrameHook is the bus address of a `JSL` executed every frame of Super Metroid's main loop
+	FrameHook uint32
+
+	// CurrentGame is the SRAM offset of the byte that is zero while ALTTP runs and non-zero while Super Metroid runs
+	CurrentGame uint32
+	// SaveData is the SRAM offset of Super Metroid's save data while ALTTP runs; see games.SM for its layout
+	SaveData uint32
+}
+
+// sramBusAddress maps an offset into ExHiROM SRAM to its bus address in $A0-$BF:6000-7FFF
+func (l *SMLayout) sramBusAddress(offs uint32) uint32 {
+	return exhirom.PakAddressToBus(0xE00000 + offs)
+}
+
+type Factory struct {
+	layout *Layout
+}
+
+// NewFactory creates a games.Factory for ROMs described by layout
+func NewFactory(layout *Layout) *Factory {
+	return &Factory{layout: layout}
+}
+
+// Register creates a Factory for the layout and registers it under the layout's name
+func Register(layout *Layout) *Factory {
+	f := NewFactory(layout)
+	games.Register(layout.Name, f)
+	return f
+}
+
+func (f *Factory) Layout() *Layout { return f.layout }
+
+func (f *Factory) IsROMSupported(rom *snes.ROM) bool {
+	return f.layout.IsROMSupported(rom)
+}
+
+func (f *Factory) CanPlay(rom *snes.ROM) (ok bool, whyNot string) {
+	// TODO: read header of ROM to determine what variants are supported or not
+	return true, ""
+}
+
+func (f *Factory) Patcher(rom *snes.ROM) games.Patcher {
+	return NewPatcher(f.layout, rom)
+}
+
+func isVTTitle(rom *snes.ROM) bool {
+	return string(rom.Header.Title[0:3]) == "VT "
+}
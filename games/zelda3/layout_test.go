@@ -0,0 +1,12 @@
+package zelda3
+
+import "o2/snes"
+
+// factory for tests using the vanilla ALTTP hooks:
+var factory = NewFactory(&Layout{
+	Name:           "ALTTP",
+	ResetHook:      0x00802F,
+	InitHook:       0x1BB1D7,
+	FrameHook:      0x008056,
+	IsROMSupported: func(rom *snes.ROM) bool { return true },
+})
@@ -1,7 +1,8 @@
-package alttp
+package zelda3
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"google.golang.org/protobuf/proto"
 	"io"
@@ -10,12 +11,22 @@ import (
 	"o2/client/protocol01"
 	"o2/client/protocol02"
 	"o2/client/protocol03"
+	"o2/udpclient"
 	"time"
 )
 
 // server protocol to use:
 const protocol = 0x03
 
+// reliable delivery channels:
+const (
+	reliableChannelEvents uint8 = iota
+	// delta-compressed SRAM ranges:
+	reliableChannelSRAMItems
+	reliableChannelSRAMUnderworld
+	reliableChannelSRAMOverworld
+)
+
 type gameMessage interface {
 	SendToClient(c *client.Client)
 }
@@ -42,11 +53,19 @@ func (m *gameBroadcastMessage) SendToClient(c *client.Client) {
 		//case protocol02.BroadcastToSector:
 		//	p3msg.BroadcastSector = &protocol03.BroadcastSector{TargetSector: uint64(g.LocalPlayer().Location), Data: m.Bytes()}
 
+		c.Record(client.Outbound, p3msg)
+
+		// authenticate with the group password, if any:
+		if err := c.Seal(p3msg); err != nil {
+			log.Printf("zelda3: send: seal: %v\n", err)
+			return
+		}
+
 		// construct packet:
 		pkt := client.MakePacket(0x03)
 		b, err := proto.MarshalOptions{}.MarshalAppend(pkt.Bytes(), p3msg)
 		if err != nil {
-			log.Printf("alttp: send: proto.Marshal: %v\n", err)
+			log.Printf("zelda3: send: proto.Marshal: %v\n", err)
 			return
 		}
 
@@ -80,12 +99,13 @@ func (m *gameJoinMessage) SendToClient(c *client.Client) {
 			PlayerInSector: uint64(g.LocalPlayer().Location),
 		}
 		p3msg.JoinGroup = &protocol03.JoinGroup{}
+		c.Record(client.Outbound, p3msg)
 
 		// construct packet:
 		pkt := client.MakePacket(0x03)
 		b, err := proto.MarshalOptions{}.MarshalAppend(pkt.Bytes(), p3msg)
 		if err != nil {
-			log.Printf("alttp: send: proto.Marshal: %v\n", err)
+			log.Printf("zelda3: send: proto.Marshal: %v\n", err)
 			return
 		}
 
@@ -98,7 +118,7 @@ func (m *gameJoinMessage) SendToClient(c *client.Client) {
 		)
 		_, err := m.WriteTo(buf)
 		if err != nil {
-			log.Printf("alttp: send: writeTo: %v\n", err)
+			log.Printf("zelda3: send: writeTo: %v\n", err)
 			return
 		}
 		c.Write() <- buf.Bytes()
@@ -112,13 +132,27 @@ func (g *Game) makeBroadcastMessage() (m *gameBroadcastMessage) {
 	m.WriteByte(SerializationVersion)
 
 	// protocol starts with team number:
-	m.WriteByte(g.LocalPlayer().Team)
+	m.WriteByte(g.LocalPlayer().Team())
 	// frame number to correlate separate packets together:
 	m.WriteByte(g.lastGameFrame)
 
 	return
 }
 
+func (g *Game) makePlayerNameMessage() (m *gameBroadcastMessage) {
+	m = g.makeBroadcastMessage()
+
+	m.WriteByte(byte(MsgPlayerName))
+	var name [20]byte
+	n := copy(name[:], g.LocalPlayer().Name())
+	for ; n < 20; n++ {
+		name[n] = ' '
+	}
+	m.Write(name[:])
+
+	return
+}
+
 func (g *Game) makeJoinMessage() (m *gameJoinMessage) {
 	m = &gameJoinMessage{g: g}
 	return
@@ -143,11 +177,19 @@ func (m *gameEchoMessage) SendToClient(c *client.Client) {
 		}
 		p3msg.Echo = &protocol03.Echo{Data: m.Bytes()}
 
+		c.Record(client.Outbound, p3msg)
+
+		// authenticate with the group password, if any:
+		if err := c.Seal(p3msg); err != nil {
+			log.Printf("zelda3: send: seal: %v\n", err)
+			return
+		}
+
 		// construct packet:
 		pkt := client.MakePacket(0x03)
 		b, err := proto.MarshalOptions{}.MarshalAppend(pkt.Bytes(), p3msg)
 		if err != nil {
-			log.Printf("alttp: send: proto.Marshal: %v\n", err)
+			log.Printf("zelda3: send: proto.Marshal: %v\n", err)
 			return
 		}
 
@@ -155,6 +197,34 @@ func (m *gameEchoMessage) SendToClient(c *client.Client) {
 	}
 }
 
+// sendReliable delivers the broadcast message to all players exactly once and in order with other
+// messages on the same channel, or sends it unreliably if the server does not route reliable messages:
+func (g *Game) sendReliable(channel uint8, m *gameBroadcastMessage) {
+	c := g.client
+	if c == nil {
+		return
+	}
+	if !c.IsConnected() {
+		return
+	}
+
+	if !c.Reliable().Routed {
+		// the server would drop reliable frames so send it once as a plain broadcast:
+		m.SendToClient(c)
+		return
+	}
+
+	_, err := c.Reliable().Send(channel, m.Bytes())
+	if err != nil {
+		log.Printf("zelda3: sendReliable: %v\n", err)
+	}
+}
+
+func (g *Game) stampReliableHeader(gm *protocol03.GroupMessage) {
+	gm.PlayerIndex = uint32(g.LocalPlayer().IndexF)
+	gm.PlayerInSector = uint64(g.LocalPlayer().Location)
+}
+
 func (g *Game) send(m gameMessage) {
 	c := g.client
 	if c == nil {
@@ -200,7 +270,7 @@ func (g *Game) handleNetMessage(msg []byte) (err error) {
 
 		// pre-emptively avoid panics in accessing players array out of bounds:
 		if index >= MaxPlayers {
-			log.Printf("alttp: player index %v received in packet beyond max player count %v!\n", header.Index, MaxPlayers)
+			log.Printf("zelda3: player index %v received in packet beyond max player count %v!\n", header.Index, MaxPlayers)
 			return
 		}
 
@@ -238,7 +308,7 @@ func (g *Game) handleNetMessage(msg []byte) (err error) {
 		}
 
 		if err != nil {
-			log.Printf("alttp: net: deserialize: %v\n", err)
+			log.Printf("zelda3: net: deserialize: %v\n", err)
 			return
 		}
 
@@ -246,7 +316,7 @@ func (g *Game) handleNetMessage(msg []byte) (err error) {
 
 		// wait until we see a name packet to announce:
 		if p.showJoinMessage && p.Name() != "" {
-			log.Printf("alttp: player[%02x]: %s joined\n", uint8(p.Index()), p.Name())
+			log.Printf("zelda3: player[%02x]: %s joined\n", uint8(p.Index()), p.Name())
 			g.PushNotification(fmt.Sprintf("%s joined", p.Name()))
 			p.showJoinMessage = false
 			g.activePlayersClean = false
@@ -264,15 +334,21 @@ func (g *Game) handleNetMessage(msg []byte) (err error) {
 		var b []byte
 		b, err = io.ReadAll(r)
 		if err != nil {
-			log.Printf("alttp: net: p3: readall: %v\n", err)
+			log.Printf("zelda3: net: p3: readall: %v\n", err)
 			return
 		}
 		err = proto.Unmarshal(b, gm)
 		if err != nil {
-			log.Printf("alttp: net: p3: unmarshal: %v\n", err)
+			log.Printf("zelda3: net: p3: unmarshal: %v\n", err)
 			return
 		}
 
+		// silently drop anything not authenticated by our group password:
+		if g.client.Open(gm) != nil {
+			return
+		}
+		g.client.Record(client.Inbound, gm)
+
 		// record server time:
 		newServerTime := time.Unix((gm.GetServerTime())/1e9, int64(gm.GetServerTime()%1e9))
 		g.lastServerTime = newServerTime
@@ -283,7 +359,7 @@ func (g *Game) handleNetMessage(msg []byte) (err error) {
 
 		// pre-emptively avoid panics in accessing players array out of bounds:
 		if index >= MaxPlayers {
-			log.Printf("alttp: player index %v received in packet beyond max player count %v!\n", gm.PlayerIndex, MaxPlayers)
+			log.Printf("zelda3: player index %v received in packet beyond max player count %v!\n", gm.PlayerIndex, MaxPlayers)
 			return
 		}
 
@@ -292,7 +368,16 @@ func (g *Game) handleNetMessage(msg []byte) (err error) {
 		p.IndexF = index
 
 		// handle which kind of message it is:
-		if gm.GetJoinGroup() != nil {
+		if jg := gm.GetJoinGroup(); jg != nil {
+			g.client.Reliable().Routed = jg.Reliable
+			if g.spectating {
+				if !jg.Spectating {
+					// the server does not know spectators and gave us a player slot:
+					return ErrSpectatorUnsupported
+				}
+				// spectators have no player index:
+				return
+			}
 			// track local player index:
 			if (g.local.Index() < 0) || (g.local.Index() != index) {
 				if p != g.local {
@@ -313,10 +398,28 @@ func (g *Game) handleNetMessage(msg []byte) (err error) {
 			err = g.Deserialize(bytes.NewReader(bs.Data), p)
 		} else if ec := gm.GetEcho(); ec != nil {
 			// nothing to do
+		} else if gm.GetReliableData() != nil || gm.GetReliableAck() != nil {
+			var deliveries []udpclient.ReliableDelivery
+			deliveries, err = g.client.Reliable().HandleMessage(gm)
+			for _, d := range deliveries {
+				if err != nil {
+					break
+				}
+				err = g.Deserialize(bytes.NewReader(d.Data), p)
+				if errors.Is(err, errSRAMDeltaBaseMissing) {
+					// withhold our ack so the sender keeps its older base until we catch up with a keyframe:
+					err = nil
+					continue
+				}
+				if err == nil && g.client.Reliable().Mode(d.Channel) == udpclient.ReliableSequenced {
+					// acknowledge only once the snapshot has been applied:
+					err = g.client.Reliable().Ack(d.Peer, d.Channel, d.Seq)
+				}
+			}
 		}
 
 		if err != nil {
-			log.Printf("alttp: net: deserialize: %v\n", err)
+			log.Printf("zelda3: net: deserialize: %v\n", err)
 			return
 		}
 
@@ -324,7 +427,7 @@ func (g *Game) handleNetMessage(msg []byte) (err error) {
 
 		// wait until we see a name packet to announce:
 		if p.showJoinMessage && p.Name() != "" {
-			log.Printf("alttp: player[%02x]: %s joined\n", uint8(p.Index()), p.Name())
+			log.Printf("zelda3: player[%02x]: %s joined\n", uint8(p.Index()), p.Name())
 			g.PushNotification(fmt.Sprintf("%s joined", p.Name()))
 			p.showJoinMessage = false
 			g.activePlayersClean = false
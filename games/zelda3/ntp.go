@@ -1,4 +1,4 @@
-package alttp
+package zelda3
 
 import (
 	"github.com/beevik/ntp"
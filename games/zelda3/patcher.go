@@ -0,0 +1,53 @@
+package zelda3
+
+import (
+	"o2/games/base"
+	"o2/snes"
+)
+
+// free bytes in JP 1.0 rom:
+// $00:89C2 - 30 bytes
+// $00:E892 - 30 bytes
+// $00:F7E1 - 31 bytes
+// $00:FFB7 - 9 bytes
+
+// $1B:B1D7 - 1577 bytes free
+// the last valid SPC data is at $1B:B1D3: dw 0, $0800
+// if you do go looking for that 5.8k free space, you'll see this sequence of bytes most likely
+// $C0, $00, $00, $00, $00, $01, $FF, $00, $00
+// I can assure you it's garbage
+
+const (
+	preMainLen = base.PreMainLen
+	// SRAM address of preMain routine called nearly every frame before `JSL GameModes`
+	preMainAddr        = uint32(0x708000 - preMainLen)
+	preMainUpdateAAddr = uint32(0x707C00)
+	preMainUpdateBAddr = uint32(0x707E00)
+	// SRAM address of altPreMain routine called every frame of Super Metroid in combo ROMs
+	altPreMainAddr = uint32(preMainAddr - base.AltPreMainLen)
+)
+
+// Patcher patches the ROM for O2 support using the layout's hooks
+type Patcher = base.Patcher
+
+func NewPatcher(layout *Layout, rom *snes.ROM) *Patcher {
+	return base.NewPatcher(layout.hooks(), rom)
+}
+
+func (l *Layout) hooks() *base.Hooks {
+	h := &base.Hooks{
+		ResetHook: l.ResetHook,
+		InitHook:  l.InitHook,
+		FrameHook: l.FrameHook,
+		Routines: base.Routines{
+			PreMain:    preMainAddr,
+			AltPreMain: altPreMainAddr,
+			UpdateA:    preMainUpdateAAddr,
+			UpdateB:    preMainUpdateBAddr,
+		},
+	}
+	if l.SM != nil {
+		h.AltFrameHook = l.SM.FrameHook
+	}
+	return h
+}
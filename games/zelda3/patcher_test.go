@@ -0,0 +1,52 @@
+package zelda3
+
+import (
+	"bytes"
+	"io"
+	"o2/snes"
+	"testing"
+)
+
+func TestPatcher_Layout(t *testing.T) {
+	// a layout with hooks at non-vanilla addresses like those of Z3-based hacks:
+	layout := &Layout{
+		Name:      "test",
+		ResetHook: 0x008100,
+		InitHook:  0x1BB1D7,
+		FrameHook: 0x008200,
+	}
+
+	rom, err := snes.NewROM("test.sfc", make([]byte, 0x10_0000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(addr uint32, b []byte) {
+		if _, err := rom.BusWriter(addr).Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(addr uint32, n int) []byte {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(rom.BusReader(addr), b); err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	// LDA #$81 : STA $4200
+	write(layout.ResetHook, []byte{0xA9, 0x81, 0x8D, 0x00, 0x42})
+	// JSL GameModes
+	write(layout.FrameHook, []byte{0x22, 0xB5, 0x80, 0x00})
+
+	if err = NewPatcher(layout, rom).Patch(); err != nil {
+		t.Fatal(err)
+	}
+
+	// JSL $1BB1EA : NOP past the signature
+	if actual, expected := read(layout.ResetHook, 5), []byte{0x22, 0xEA, 0xB1, 0x1B, 0xEA}; !bytes.Equal(actual, expected) {
+		t.Errorf("reset hook = % x, expected % x", actual, expected)
+	}
+	// JSL $70:7FF8 (preMainAddr)
+	if actual, expected := read(layout.FrameHook, 4), []byte{0x22, 0xF8, 0x7F, 0x70}; !bytes.Equal(actual, expected) {
+		t.Errorf("frame hook = % x, expected % x", actual, expected)
+	}
+}
@@ -1,4 +1,4 @@
-package smz3
+package zelda3
 
 import (
 	"encoding/binary"
@@ -34,11 +34,14 @@ func (r SRAMShadow) BusAddress(offs uint32) uint32 {
 }
 
 func (r SRAMShadow) ReadU8(offs uint32) uint8 {
+	if offs >= uint32(len(r)) {
+		return 0xFF
+	}
 	return r[offs]
 }
 
 func (r SRAMShadow) ReadU16(offs uint32) uint16 {
-	if offs >= 0x500 {
+	if offs >= uint32(len(r))-1 {
 		return 0xFFFF
 	}
 	return binary.LittleEndian.Uint16(r[offs : offs+2])
@@ -50,11 +53,15 @@ func (r WRAMReadable) BusAddress(offs uint32) uint32 {
 	return 0x7E0000 + offs
 }
 
+// ReadU8 reads a watched WRAM value; offsets that are not watched read as $FF
 func (r WRAMReadable) ReadU8(offs uint32) uint8 {
-	return uint8(r[uint16(offs)].ValueUsed)
+	return uint8(r.ReadU16(offs))
 }
 
 func (r WRAMReadable) ReadU16(offs uint32) uint16 {
+	if offs > 0xFFFF || r[uint16(offs)] == nil {
+		return 0xFFFF
+	}
 	return r[uint16(offs)].ValueUsed
 }
 
@@ -62,7 +69,7 @@ type Player struct {
 	IndexF int
 	Ttl    int
 
-	Team  uint8
+	TeamF uint8
 	NameF string
 
 	Frame uint8
@@ -93,6 +100,12 @@ type Player struct {
 	SRAM SRAMShadow
 	WRAM WRAMReadable
 
+	// InSM is true while the player runs the Super Metroid half of a combo ROM
+	InSM bool
+	SM   SMShadow
+	// smLayout is where the local player's ROM keeps Super Metroid's state
+	smLayout *SMLayout
+
 	showJoinMessage bool
 }
 
@@ -104,6 +117,10 @@ func (p *Player) Name() string {
 	return p.NameF
 }
 
+func (p *Player) Team() uint8 {
+	return p.TeamF
+}
+
 func (p *Player) TTL() int {
 	return p.Ttl
 }
@@ -114,6 +131,8 @@ func (p *Player) ReadableMemory(kind games.MemoryKind) games.ReadableMemory {
 		return &p.SRAM
 	case games.WRAM:
 		return &p.WRAM
+	case games.SM:
+		return smMemory{SMShadow: &p.SM, inSM: p.InSM, layout: p.smLayout}
 	}
 	panic(fmt.Errorf("ReadableMemory kind %v not supported", kind))
 }
@@ -153,7 +172,7 @@ func (g *Game) PlayerLeft(p *Player) {
 	p.Ttl = 0
 	p.showJoinMessage = false
 
-	log.Printf("alttp: player[%02x]: %s left\n", uint8(p.IndexF), p.NameF)
+	log.Printf("zelda3: player[%02x]: %s left\n", uint8(p.IndexF), p.NameF)
 	g.PushNotification(fmt.Sprintf("%s left", p.NameF))
 
 	// refresh the ActivePlayers():
@@ -171,6 +190,10 @@ func (p *Player) IsInDungeon() bool {
 }
 
 func (p *Player) IsInGame() bool {
+	if p.InSM {
+		// Module is stale while in Super Metroid; its game state was already validated when read:
+		return true
+	}
 	return p.isModuleInGame(p.Module)
 }
 
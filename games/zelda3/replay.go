@@ -0,0 +1,32 @@
+package zelda3
+
+import (
+	"google.golang.org/protobuf/proto"
+	"o2/client"
+)
+
+// Replay feeds the inbound messages of a session recording through handleNetMessage as if they were just
+// received from the server; speed scales the recorded pacing and <= 0 replays as fast as possible.
+// Must not be called while the game is running.
+func (g *Game) Replay(rr *client.RecordingReader, speed float64) error {
+	if g.client == nil {
+		// replay needs a client for reliable delivery state but never connects it:
+		g.client = client.NewClient()
+		g.provideSRAMDeltaClient(g.client.Reliable().Reliable)
+	}
+
+	return rr.Replay(speed, func(rec *client.Recording) (err error) {
+		if rec.Direction != client.Inbound {
+			return
+		}
+
+		pkt := client.MakePacket(0x03)
+		var b []byte
+		b, err = proto.MarshalOptions{}.MarshalAppend(pkt.Bytes(), rec.Message)
+		if err != nil {
+			return
+		}
+
+		return g.handleNetMessage(b)
+	})
+}
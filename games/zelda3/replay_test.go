@@ -0,0 +1,60 @@
+package zelda3
+
+import (
+	"bytes"
+	"o2/client"
+	"o2/client/protocol03"
+	"o2/snes/emulator"
+	"testing"
+)
+
+func TestGame_Replay(t *testing.T) {
+	rom, err := emulator.MakeTestROM("VT test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a remote player serializes its SRAM:
+	remote := factory.NewGame(rom).(*Game)
+	remote.Reset()
+	remote.local.SRAM[0x340] = 0x02
+	m := remote.makeBroadcastMessage()
+	if err = remote.SerializeSRAM(remote.local, m, 0x340, 0x350); err != nil {
+		t.Fatal(err)
+	}
+
+	// record a session where we join as player 0 and then receive player 1's SRAM:
+	buf := &bytes.Buffer{}
+	rec, err := client.NewRecorder(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rec.Record(client.Outbound, &protocol03.GroupMessage{Group: "test", JoinGroup: &protocol03.JoinGroup{}})
+	_ = rec.Record(client.Inbound, &protocol03.GroupMessage{Group: "test", PlayerIndex: 0, JoinGroup: &protocol03.JoinGroup{}})
+	_ = rec.Record(client.Inbound, &protocol03.GroupMessage{
+		Group:        "test",
+		PlayerIndex:  1,
+		BroadcastAll: &protocol03.BroadcastAll{Data: m.Bytes()},
+	})
+	if err = rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// replay it into a fresh game:
+	g := factory.NewGame(rom).(*Game)
+	g.Reset()
+	rr, err := client.NewRecordingReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = g.Replay(rr, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual, expected := g.LocalPlayer().Index(), 0; actual != expected {
+		t.Errorf("local index = %d, expected %d", actual, expected)
+	}
+	if actual, expected := g.players[1].SRAM[0x340], uint8(0x02); actual != expected {
+		t.Errorf("player[1] SRAM[$340] = %02x, expected %02x", actual, expected)
+	}
+}
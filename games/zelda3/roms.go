@@ -1,4 +1,4 @@
-package alttp
+package zelda3
 
 import (
 	"log"
@@ -19,7 +19,10 @@ const (
 )
 
 func (g *Game) isVTRandomizer() bool {
-	return string(g.rom.Header.Title[0:3]) == "VT "
+	if g.layout != nil && g.layout.IsVTRandomizer != nil {
+		return g.layout.IsVTRandomizer(g.rom)
+	}
+	return isVTTitle(g.rom)
 }
 
 func (g *Game) fillRomFunctions() {
@@ -1,4 +1,4 @@
-package alttp
+package zelda3
 
 import (
 	"encoding/binary"
@@ -45,7 +45,7 @@ func (g *Game) readSubmit(readQueue []snes.Read) {
 			g.readResponseLock.Unlock()
 
 			if err != nil {
-				log.Printf("alttp: readSubmit: complete: %s\n", err)
+				log.Printf("zelda3: readSubmit: complete: %s\n", err)
 			}
 
 			// inform the main loop:
@@ -53,13 +53,13 @@ func (g *Game) readSubmit(readQueue []snes.Read) {
 		},
 	)
 
-	//log.Printf("alttp: readSubmit: enqueue start %d reads\n", len(readQueue))
+	//log.Printf("zelda3: readSubmit: enqueue start %d reads\n", len(readQueue))
 	err := sequence.EnqueueTo(q)
 	if err != nil {
-		log.Printf("alttp: readSubmit: enqueue: %s\n", err)
+		log.Printf("zelda3: readSubmit: enqueue: %s\n", err)
 		return
 	}
-	//log.Printf("alttp: readSubmit: enqueue complete\n")
+	//log.Printf("zelda3: readSubmit: enqueue complete\n")
 }
 
 const debugSprites = false
@@ -81,7 +81,7 @@ func (g *Game) run() {
 	defer func() {
 		fastbeat.Stop()
 		slowbeat.Stop()
-		log.Println("alttp: run loop exited")
+		log.Println("zelda3: run loop exited")
 	}()
 
 	for g.running {
@@ -103,6 +103,8 @@ func (g *Game) run() {
 		case msg := <-g.client.Read():
 			if msg == nil {
 				// disconnected?
+				g.client.Reliable().Reset()
+				g.resetSRAMDelta()
 				for i := range g.players {
 					p := &g.players[i]
 					// reset Ttl for all players to make them inactive:
@@ -134,7 +136,7 @@ func (g *Game) run() {
 				// make sure a read request is always in flight to keep our main loop running:
 				timeSinceRead := time.Now().Sub(g.lastReadCompleted)
 				if timeSinceRead >= time.Millisecond*512 {
-					log.Printf("alttp: fastbeat: enqueue main reads; %d msec since last read\n", timeSinceRead.Milliseconds())
+					log.Printf("zelda3: fastbeat: enqueue main reads; %d msec since last read\n", timeSinceRead.Milliseconds())
 					q := make([]snes.Read, 0, 8)
 					q = g.enqueueWRAMReads(q)
 					// must always read module number LAST to validate the prior reads:
@@ -157,6 +159,16 @@ func (g *Game) run() {
 				}
 			}
 
+			if g.LocalPlayer().Index() >= 0 && g.client != nil {
+				// resend any reliable messages not yet acknowledged by all remote players:
+				peers := g.remotePlayerIndexes()
+				g.sramDeltaSetPeers(peers)
+				g.client.Reliable().SetPeers(peers)
+				if err := g.client.Reliable().Retransmit(time.Now()); err != nil {
+					log.Printf("zelda3: fastbeat: retransmit: %v\n", err)
+				}
+			}
+
 			if g.LocalPlayer().Index() < 0 && g.client != nil {
 				// request our player index:
 				m := g.makeJoinMessage()
@@ -183,17 +195,10 @@ func (g *Game) run() {
 			g.send(&gameEchoMessage{g: g})
 
 			// broadcast player name:
-			m := g.makeBroadcastMessage()
+			m := g.makePlayerNameMessage()
 			if m == nil {
 				break
 			}
-			m.WriteByte(0x0C)
-			var name [20]byte
-			n := copy(name[:], g.LocalPlayer().Name())
-			for ; n < 20; n++ {
-				name[n] = ' '
-			}
-			m.Write(name[:])
 			g.send(m)
 
 			break
@@ -244,11 +249,17 @@ func (g *Game) enqueueWRAMReads(q []snes.Read) []snes.Read {
 	q = g.readEnqueue(q, 0xF5F340, 0xFF, 0) // [$F340..$F43E]
 	// Link's palette:
 	q = g.readEnqueue(q, 0xF5C6E0, 0x20, 0)
+	if g.smLayout() != nil {
+		q = g.enqueueSMReads(q)
+	}
 	return q
 }
 
 func (g *Game) enqueueMainRead(q []snes.Read, extra interface{}) []snes.Read {
 	// NOTE: order matters! must read the module number LAST to make sure all reads prior are valid.
+	if g.smLayout() != nil {
+		q = g.enqueueSMMainRead(q, extra)
+	}
 	q = g.readEnqueue(q, 0xF50010, 0xF0, extra) // [$0010..$00FF]
 	return q
 }
@@ -273,11 +284,11 @@ func (g *Game) readMainComplete(rsps []snes.Response) []snes.Read {
 		// handle update routine check:
 		g.updateLock.Lock()
 		if rsp.Address == g.lastUpdateTarget {
-			log.Printf("alttp: update: check: $%06x [$%02x] == $60\n", rsp.Address, rsp.Data[0])
+			log.Printf("zelda3: update: check: $%06x [$%02x] == $60\n", rsp.Address, rsp.Data[0])
 			// when executed, the routine replaces its first instruction with RTS ($60):
 			if rsp.Data[0] == 0x60 {
 				// allow next update:
-				log.Printf("alttp: update: complete: $%06x [$%02x] == $60\n", rsp.Address, rsp.Data[0])
+				log.Printf("zelda3: update: complete: $%06x [$%02x] == $60\n", rsp.Address, rsp.Data[0])
 				if g.updateStage == 2 {
 					g.updateStage = 0
 					g.nextUpdateA = !g.nextUpdateA
@@ -299,16 +310,22 @@ func (g *Game) readMainComplete(rsps []snes.Response) []snes.Read {
 	}
 
 	// validate new reads in staging area before copying to wram/sram:
-	if moduleStaging <= 0x06 || moduleStaging >= 0x1B {
+	valid := moduleStaging > 0x06 && moduleStaging < 0x1B
+	inSM := g.isReadInSM(rsps)
+	if inSM {
+		// ALTTP's module number is meaningless while Super Metroid runs; check its game state instead:
+		valid = smGameStateValid(g.wramStaging[0x0998])
+	}
+	if !valid {
 		if !g.invalid {
-			log.Println("alttp: game now in invalid state")
+			log.Println("zelda3: game now in invalid state")
 		}
 		g.invalid = true
 		return q
 	}
 
 	if g.invalid {
-		log.Println("alttp: game now in valid state")
+		log.Println("zelda3: game now in valid state")
 		g.invalid = false
 	}
 
@@ -324,17 +341,57 @@ func (g *Game) readMainComplete(rsps []snes.Response) []snes.Read {
 		}
 	}
 
-	//log.Printf("alttp: read %d responses\n", len(rsps))
+	//log.Printf("zelda3: read %d responses\n", len(rsps))
 
 	// assign local variables from WRAM:
 	local := g.LocalPlayer()
 
 	g.SetTTL(local, 255)
 
+	if g.smLayout() != nil {
+		g.readSMComplete(local, inSM)
+	}
+	if !local.InSM {
+		g.readZeldaComplete(local)
+	}
+
+	if g.shouldUpdatePlayersList {
+		g.updatePlayersList()
+	}
+
+	// did game frame change?
+	frame := g.wram[0x1A]
+	if local.InSM {
+		// Super Metroid's frame counter:
+		frame = g.wram[0x05B6]
+	}
+	if frame == g.lastGameFrame {
+		return q
+	}
+
+	// increment frame timer:
+	lastFrame := uint64(g.lastGameFrame)
+	nextFrame := uint64(frame)
+	if nextFrame < lastFrame {
+		nextFrame += 256
+	}
+	g.localFrame += nextFrame - lastFrame
+	g.lastGameFrame = frame
+
+	// should wrap around 255 to 0:
+	g.monotonicFrameTime++
+
+	g.frameAdvanced()
+
+	return q
+}
+
+// readZeldaComplete copies ALTTP's state from WRAM into the local player
+func (g *Game) readZeldaComplete(local *Player) {
 	newModule, newSubModule, newSubSubModule := Module(g.wram[0x10]), g.wram[0x11], g.wram[0xB0]
 	if local.Module != newModule || local.SubModule != newSubModule {
 		log.Printf(
-			"alttp: module [%02x,%02x] -> [%02x,%02x]\n",
+			"zelda3: module [%02x,%02x] -> [%02x,%02x]\n",
 			local.Module,
 			local.SubModule,
 			newModule,
@@ -351,7 +408,7 @@ func (g *Game) readMainComplete(rsps []snes.Response) []snes.Read {
 	dungeonRoom := g.wramU16(0xA0)
 	if local.OverworldArea != overworldArea || local.DungeonRoom != dungeonRoom {
 		log.Printf(
-			"alttp: supertile[overworld,underworld]: [%04x,%04x] -> [%04x,%04x]\n",
+			"zelda3: supertile[overworld,underworld]: [%04x,%04x] -> [%04x,%04x]\n",
 			local.OverworldArea,
 			local.DungeonRoom,
 			overworldArea,
@@ -369,7 +426,7 @@ func (g *Game) readMainComplete(rsps []snes.Response) []snes.Read {
 	dungeon := g.wramU16(0x040C)
 	if local.Dungeon != dungeon {
 		log.Printf(
-			"alttp: dungeon: %#04x -> %#04x\n",
+			"zelda3: dungeon: %#04x -> %#04x\n",
 			local.Dungeon,
 			dungeon,
 		)
@@ -424,31 +481,6 @@ func (g *Game) readMainComplete(rsps []snes.Response) []snes.Read {
 	// handle WRAM reads:
 	g.readWRAM()
 	g.notFirstWRAMRead = true
-
-	if g.shouldUpdatePlayersList {
-		g.updatePlayersList()
-	}
-
-	// did game frame change?
-	if g.wram[0x1A] == g.lastGameFrame {
-		return q
-	}
-
-	// increment frame timer:
-	lastFrame := uint64(g.lastGameFrame)
-	nextFrame := uint64(g.wram[0x1A])
-	if nextFrame < lastFrame {
-		nextFrame += 256
-	}
-	g.localFrame += nextFrame - lastFrame
-	g.lastGameFrame = g.wram[0x1A]
-
-	// should wrap around 255 to 0:
-	g.monotonicFrameTime++
-
-	g.frameAdvanced()
-
-	return q
 }
 
 func (g *Game) wramU8(addr uint32) uint8 {
@@ -1,4 +1,4 @@
-package alttp
+package zelda3
 
 import (
 	"hash/fnv"
@@ -47,6 +47,25 @@ func (g *Game) sendPackets() {
 		g.send(m)
 	}
 
+	if g.smLayout() != nil && g.monotonicFrameTime&15 == 8 {
+		// Super Metroid's items and progress:
+		g.sendSMSRAM()
+	}
+
+	if g.SyncSRAMDelta && !g.sramDeltaFallback() {
+		// send only what changed since the last snapshot all players acknowledged:
+		if g.monotonicFrameTime&15 == 0 {
+			g.sendSRAMDelta(g.sramStream(reliableChannelSRAMItems))
+		}
+		if g.SyncUnderworld && g.monotonicFrameTime&31 == 0 {
+			g.sendSRAMDelta(g.sramStream(reliableChannelSRAMUnderworld))
+		}
+		if g.SyncOverworld && g.monotonicFrameTime&31 == 16 {
+			g.sendSRAMDelta(g.sramStream(reliableChannelSRAMOverworld))
+		}
+		return
+	}
+
 	if g.monotonicFrameTime&15 == 0 {
 		// Broadcast items and progress SRAM:
 		m := g.makeBroadcastMessage()
@@ -1,4 +1,4 @@
-package alttp
+package zelda3
 
 import (
 	"encoding/binary"
@@ -28,6 +28,7 @@ const (
 	MsgTorches
 	MsgPvP
 	MsgPlayerName
+	MsgSRAMDelta
 
 	MsgMaxMessageType
 )
@@ -49,6 +50,7 @@ func (g *Game) initSerde() {
 		g.DeserializeTorches,
 		g.DeserializePvP,
 		g.DeserializePlayerName,
+		g.DeserializeSRAMDelta,
 	}
 }
 
@@ -91,11 +93,11 @@ func (g *Game) Deserialize(r io.Reader, p *Player) (err error) {
 		panic(fmt.Errorf("serializationVersion mismatch"))
 	}
 
-	lastTeam := p.Team
-	if err = binary.Read(r, binary.LittleEndian, &p.Team); err != nil {
+	lastTeam := p.TeamF
+	if err = binary.Read(r, binary.LittleEndian, &p.TeamF); err != nil {
 		panic(err)
 	}
-	if p.Team != lastTeam {
+	if p.TeamF != lastTeam {
 		g.shouldUpdatePlayersList = true
 	}
 
@@ -110,7 +112,7 @@ func (g *Game) Deserialize(r io.Reader, p *Player) (err error) {
 		lastFrame -= 256
 	}
 	if nextFrame < lastFrame {
-		log.Printf("alttp: discard stale frame data (%d < %d)\n", nextFrame, lastFrame)
+		log.Printf("zelda3: discard stale frame data (%d < %d)\n", nextFrame, lastFrame)
 		return
 	}
 	p.Frame = frame
@@ -125,7 +127,7 @@ func (g *Game) Deserialize(r io.Reader, p *Player) (err error) {
 
 		// check bounds for message type:
 		if msgType == 0 || msgType >= MsgMaxMessageType {
-			err = fmt.Errorf("alttp: msgType %#02x out of bounds", msgType)
+			err = fmt.Errorf("zelda3: msgType %#02x out of bounds", msgType)
 			// no good recourse to be able to skip over the message
 			break
 		}
@@ -216,6 +218,10 @@ func (g *Game) DeserializeLocation(p *Player, r io.Reader) (err error) {
 	if err = binary.Read(r, binary.LittleEndian, &inSM); err != nil {
 		panic(fmt.Errorf("error deserializing location: %w", err))
 	}
+	if (inSM != 0) != p.InSM {
+		p.InSM = inSM != 0
+		g.shouldUpdatePlayersList = true
+	}
 
 	//log.Printf("[%02x]: %04x, %04x\n", uint8(p.Index), p.X, p.Y)
 
@@ -328,9 +334,14 @@ func (g *Game) DeserializeWRAM(p *Player, r io.Reader) (err error) {
 }
 
 func (g *Game) DeserializeSRAM(p *Player, r io.Reader) (err error) {
-	// something about SM:
-	var dummy [2]byte
-	if _, err = r.Read(dummy[:]); err != nil {
+	var (
+		startIsZero uint8
+		inSM        uint8
+	)
+	if err = binary.Read(r, binary.LittleEndian, &startIsZero); err != nil {
+		panic(fmt.Errorf("error deserializing sram: %w", err))
+	}
+	if err = binary.Read(r, binary.LittleEndian, &inSM); err != nil {
 		panic(fmt.Errorf("error deserializing sram: %w", err))
 	}
 
@@ -345,12 +356,92 @@ func (g *Game) DeserializeSRAM(p *Player, r io.Reader) (err error) {
 		panic(fmt.Errorf("error deserializing sram: %w", err))
 	}
 
+	if inSM != 0 {
+		// Super Metroid's save data:
+		if int(start)+int(count) > len(p.SM) {
+			return fmt.Errorf("zelda3: sm sram: range $%03x+$%03x out of bounds", start, count)
+		}
+		if _, err = r.Read(p.SM[start : start+count]); err != nil {
+			panic(fmt.Errorf("error deserializing sm sram: %w", err))
+		}
+		return
+	}
+
 	if _, err = r.Read(p.SRAM[start : start+count]); err != nil {
 		panic(fmt.Errorf("error deserializing sram: %w", err))
 	}
 	return
 }
 
+// DeserializeSRAMDelta reconstructs a snapshot of SRAM from its base snapshot and the runs of bytes that changed
+func (g *Game) DeserializeSRAMDelta(p *Player, r io.Reader) (err error) {
+	var (
+		start  uint16
+		count  uint16
+		id     uint32
+		baseID uint32
+	)
+	if err = binary.Read(r, binary.LittleEndian, &start); err != nil {
+		panic(fmt.Errorf("error deserializing sram delta: %w", err))
+	}
+	if err = binary.Read(r, binary.LittleEndian, &count); err != nil {
+		panic(fmt.Errorf("error deserializing sram delta: %w", err))
+	}
+	if err = binary.Read(r, binary.LittleEndian, &id); err != nil {
+		panic(fmt.Errorf("error deserializing sram delta: %w", err))
+	}
+	if err = binary.Read(r, binary.LittleEndian, &baseID); err != nil {
+		panic(fmt.Errorf("error deserializing sram delta: %w", err))
+	}
+	if int(start)+int(count) > len(p.SRAM) {
+		return fmt.Errorf("zelda3: sram delta: range $%03x+$%03x out of bounds", start, count)
+	}
+
+	key := sramDeltaRecvKey{player: p.Index(), start: start}
+	data := make([]byte, count)
+	var base *sramSnapshot
+	if baseID != 0 {
+		base = g.sramDeltaFind(key, baseID)
+		if base != nil && len(base.data) == len(data) {
+			copy(data, base.data)
+		} else {
+			base = nil
+		}
+	}
+
+	var runCount uint16
+	if err = binary.Read(r, binary.LittleEndian, &runCount); err != nil {
+		panic(fmt.Errorf("error deserializing sram delta: %w", err))
+	}
+	for i := uint16(0); i < runCount; i++ {
+		var (
+			offs   uint16
+			length uint8
+		)
+		if err = binary.Read(r, binary.LittleEndian, &offs); err != nil {
+			panic(fmt.Errorf("error deserializing sram delta: %w", err))
+		}
+		if err = binary.Read(r, binary.LittleEndian, &length); err != nil {
+			panic(fmt.Errorf("error deserializing sram delta: %w", err))
+		}
+		if int(offs)+int(length) > len(data) {
+			return fmt.Errorf("zelda3: sram delta: run $%03x+$%02x out of bounds", offs, length)
+		}
+		if _, err = io.ReadFull(r, data[offs:int(offs)+int(length)]); err != nil {
+			panic(fmt.Errorf("error deserializing sram delta: %w", err))
+		}
+	}
+
+	// the runs must be consumed before we can give up on a missing base:
+	if baseID != 0 && base == nil {
+		return errSRAMDeltaBaseMissing
+	}
+
+	g.sramDeltaStore(key, &sramSnapshot{id: id, data: data}, baseID == 0)
+	copy(p.SRAM[start:], data)
+	return
+}
+
 func (g *Game) DeserializeTilemaps(p *Player, r io.Reader) (err error) {
 	var (
 		timestamp uint32
@@ -522,6 +613,9 @@ func (g *Game) SerializeLocation(p *Player, w io.Writer) (err error) {
 	}
 
 	var inSM uint8 = 0
+	if p.InSM {
+		inSM = 1
+	}
 	if err = binary.Write(w, binary.LittleEndian, &inSM); err != nil {
 		panic(fmt.Errorf("error serializing location: %w", err))
 	}
@@ -530,14 +624,20 @@ func (g *Game) SerializeLocation(p *Player, w io.Writer) (err error) {
 }
 
 func (g *Game) SerializeSRAM(p *Player, w io.Writer, start, endExclusive uint16) (err error) {
+	return g.serializeSRAM(w, 0, start, p.SRAM[start:endExclusive])
+}
+
+// SerializeSMSRAM writes Super Metroid's save data as an SRAM message flagged for SM
+func (g *Game) SerializeSMSRAM(p *Player, w io.Writer, start, endExclusive uint16) (err error) {
+	return g.serializeSRAM(w, 1, start, p.SM[start:endExclusive])
+}
+
+func (g *Game) serializeSRAM(w io.Writer, inSM uint8, start uint16, data []byte) (err error) {
 	if err = binary.Write(w, binary.LittleEndian, uint8(MsgSRAM)); err != nil {
 		panic(fmt.Errorf("error serializing sram: %w", err))
 	}
 
-	var (
-		startIsZero uint8 = 0
-		inSM        uint8 = 0
-	)
+	var startIsZero uint8 = 0
 	if start == 0 {
 		startIsZero = 1
 	}
@@ -552,17 +652,73 @@ func (g *Game) SerializeSRAM(p *Player, w io.Writer, start, endExclusive uint16)
 	if err = binary.Write(w, binary.LittleEndian, &start); err != nil {
 		panic(fmt.Errorf("error serializing sram: %w", err))
 	}
-	count := endExclusive - start
+	count := uint16(len(data))
 	if err = binary.Write(w, binary.LittleEndian, &count); err != nil {
 		panic(fmt.Errorf("error serializing sram: %w", err))
 	}
 
-	if _, err = w.Write(p.SRAM[start:endExclusive]); err != nil {
+	if _, err = w.Write(data); err != nil {
 		panic(fmt.Errorf("error serializing sram: %w", err))
 	}
 	return
 }
 
+// SerializeSRAMDelta writes the snapshot of SRAM starting at start as the runs of bytes that differ from base;
+// a nil base writes a keyframe containing the whole snapshot.
+func (g *Game) SerializeSRAMDelta(w io.Writer, start uint16, snap *sramSnapshot, base *sramSnapshot) (err error) {
+	if err = binary.Write(w, binary.LittleEndian, uint8(MsgSRAMDelta)); err != nil {
+		panic(fmt.Errorf("error serializing sram delta: %w", err))
+	}
+
+	count := uint16(len(snap.data))
+	var baseID uint32 = 0
+	var runs [][2]int
+	if base != nil {
+		baseID = base.id
+		runs = sramDeltaRuns(base.data, snap.data)
+	} else {
+		for i := 0; i < len(snap.data); i += 255 {
+			end := i + 255
+			if end > len(snap.data) {
+				end = len(snap.data)
+			}
+			runs = append(runs, [2]int{i, end})
+		}
+	}
+
+	if err = binary.Write(w, binary.LittleEndian, &start); err != nil {
+		panic(fmt.Errorf("error serializing sram delta: %w", err))
+	}
+	if err = binary.Write(w, binary.LittleEndian, &count); err != nil {
+		panic(fmt.Errorf("error serializing sram delta: %w", err))
+	}
+	if err = binary.Write(w, binary.LittleEndian, &snap.id); err != nil {
+		panic(fmt.Errorf("error serializing sram delta: %w", err))
+	}
+	if err = binary.Write(w, binary.LittleEndian, &baseID); err != nil {
+		panic(fmt.Errorf("error serializing sram delta: %w", err))
+	}
+
+	runCount := uint16(len(runs))
+	if err = binary.Write(w, binary.LittleEndian, &runCount); err != nil {
+		panic(fmt.Errorf("error serializing sram delta: %w", err))
+	}
+	for _, run := range runs {
+		offs := uint16(run[0])
+		length := uint8(run[1] - run[0])
+		if err = binary.Write(w, binary.LittleEndian, &offs); err != nil {
+			panic(fmt.Errorf("error serializing sram delta: %w", err))
+		}
+		if err = binary.Write(w, binary.LittleEndian, &length); err != nil {
+			panic(fmt.Errorf("error serializing sram delta: %w", err))
+		}
+		if _, err = w.Write(snap.data[run[0]:run[1]]); err != nil {
+			panic(fmt.Errorf("error serializing sram delta: %w", err))
+		}
+	}
+	return
+}
+
 func (g *Game) SerializeWRAM(p *Player, w io.Writer, start uint16, count uint8) (err error) {
 	if err = binary.Write(w, binary.LittleEndian, uint8(MsgWRAM)); err != nil {
 		panic(fmt.Errorf("error serializing wram: %w", err))
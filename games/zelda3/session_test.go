@@ -0,0 +1,157 @@
+package zelda3
+
+import (
+	"bytes"
+	"flag"
+	"net"
+	"o2/client"
+	"o2/server"
+	"o2/snes/asm"
+	"o2/snes/emulator"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var recordSession = flag.Bool("record-session", false, "re-record testdata/session.o2rec from a live session on a local server")
+
+// sessionFixture is a recording of bob's client while alice, who has the hookshot and master sword, joins the
+// same group on a local o2 server
+var sessionFixture = filepath.Join("testdata", "session.o2rec")
+
+// TestGame_RecordSession records sessionFixture; it only runs with -record-session
+func TestGame_RecordSession(t *testing.T) {
+	if !*recordSession {
+		t.Skip("run with -record-session to re-record " + sessionFixture)
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := server.New(conn)
+	s.MuteLog(true)
+	go func() { _ = s.Serve() }()
+	defer conn.Close()
+
+	rom, err := emulator.MakeTestROM("VT test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPlayer := func(name string) *Game {
+		g := factory.NewGame(rom).(*Game)
+		g.Reset()
+		g.local.NameF = name
+
+		c := client.NewClient()
+		c.SetGroup("fixture")
+		if err := c.Connect(conn.LocalAddr().(*net.UDPAddr)); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(c.Disconnect)
+		g.ProvideClient(c)
+		return g
+	}
+
+	alice := newPlayer("alice")
+	alice.local.SRAM[0x342] = 0x01 // hookshot
+	alice.local.SRAM[0x359] = 0x02 // master sword
+
+	bob := newPlayer("bob")
+	f, err := os.Create(sessionFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := client.NewRecorder(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob.client.SetRecorder(rec)
+
+	players := []*Game{alice, bob}
+	// handle all messages that arrive within a short while, as Game.run would:
+	receive := func() {
+		for _, g := range players {
+			for {
+				select {
+				case msg := <-g.client.Read():
+					_ = g.handleNetMessage(msg)
+					continue
+				case <-time.After(20 * time.Millisecond):
+				}
+				break
+			}
+		}
+	}
+
+	for _, g := range players {
+		g.send(g.makeJoinMessage())
+		receive()
+	}
+	// play a second's worth of frames:
+	for frame := uint8(0); frame < 60; frame++ {
+		for _, g := range players {
+			if frame%30 == 0 {
+				// Game.run's heartbeat:
+				g.send(&gameEchoMessage{g: g})
+				g.send(g.makePlayerNameMessage())
+			}
+			g.monotonicFrameTime = frame
+			g.sendPackets()
+		}
+		receive()
+	}
+
+	bob.client.SetRecorder(nil)
+	if err = rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if actual := bob.players[alice.local.Index()].SRAM[0x342]; actual != 0x01 {
+		t.Errorf("bob did not receive alice's hookshot: $%02x", actual)
+	}
+}
+
+func TestGame_ReplaySession(t *testing.T) {
+	f, err := os.Open(sessionFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rr, err := client.NewRecordingReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rom, err := emulator.MakeTestROM("VT test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := factory.NewGame(rom).(*Game)
+	g.Reset()
+	if err = g.Replay(rr, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	remotes := g.RemotePlayers()
+	if len(remotes) != 1 || remotes[0].Name() != "alice" {
+		t.Fatalf("%d remote players, want only alice", len(remotes))
+	}
+	if g.LocalPlayer().Index() < 0 {
+		t.Fatal("local player did not join")
+	}
+
+	// alice's items sync to the local player:
+	a := &asm.Emitter{Code: &bytes.Buffer{}, Text: &strings.Builder{}}
+	a.AssumeSEP(0x30)
+	if !g.generateUpdateAsm(a) {
+		t.Fatal("generateUpdateAsm() = false, want true")
+	}
+	t.Logf("%s", a.Text.String())
+	for _, want := range []string{"got Hookshot from alice", "got Master Sword from alice"} {
+		if !strings.Contains(a.Text.String(), want) {
+			t.Errorf("update does not sync the %s", want)
+		}
+	}
+}
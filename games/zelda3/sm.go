@@ -0,0 +1,333 @@
+package zelda3
+
+import (
+	"fmt"
+	"log"
+	"o2/games"
+	"o2/snes"
+	"o2/snes/asm"
+)
+
+// Super Metroid's save data starts with a copy of WRAM $7E:09A2-0A01 followed by WRAM $7E:D820 onward; offsets
+// into it are the games.SM memory kind. Below are the offsets we sync:
+const (
+	smEquippedItems  = 0x000 // $09A2
+	smCollectedItems = 0x002 // $09A4
+	smEquippedBeams  = 0x004 // $09A6
+	smCollectedBeams = 0x006 // $09A8
+
+	smBossFlags   = 0x068 // $D828..$D82F, one byte per area
+	smMapStations = 0x148 // $D908..$D90F, one byte per area
+
+	// smShadowSize covers the save data through the map station flags:
+	smShadowSize = 0x150
+	// smWRAMSplit is the offset of the first byte copied from $7E:D820:
+	smWRAMSplit = 0x060
+)
+
+// smGameStateMainGameplay is the value of Super Metroid's game state at $7E:0998 while Samus is in control
+const smGameStateMainGameplay = 0x08
+
+// SMShadow is a copy of Super Metroid's save data addressed by games.SM offsets
+type SMShadow [smShadowSize]byte
+
+func (r *SMShadow) ReadU8(offs uint32) uint8 {
+	if offs >= smShadowSize {
+		return 0xFF
+	}
+	return r[offs]
+}
+
+func (r *SMShadow) ReadU16(offs uint32) uint16 {
+	if offs >= smShadowSize-1 {
+		return 0xFFFF
+	}
+	return uint16(r[offs]) | uint16(r[offs+1])<<8
+}
+
+// smWRAMAddress maps a games.SM offset to its WRAM address while Super Metroid runs
+func smWRAMAddress(offs uint32) uint32 {
+	if offs < smWRAMSplit {
+		return 0x7E09A2 + offs
+	}
+	return 0x7ED7C0 + offs
+}
+
+// smMemory reads a player's SMShadow with bus addresses of wherever the player's game currently keeps it
+type smMemory struct {
+	*SMShadow
+
+	inSM   bool
+	layout *SMLayout
+}
+
+func (r smMemory) BusAddress(offs uint32) uint32 {
+	if r.inSM || r.layout == nil {
+		return smWRAMAddress(offs)
+	}
+	return r.layout.sramBusAddress(r.layout.SaveData + offs)
+}
+
+// smLayout returns the layout of the ROM's Super Metroid half or nil if it has none
+func (g *Game) smLayout() *SMLayout {
+	if g.layout == nil {
+		return nil
+	}
+	return g.layout.SM
+}
+
+// smGameStateValid determines if Super Metroid's game state at $7E:0998 is somewhere between loading a game
+// and the pause menu, excluding the title screen, demos, death and ending sequences
+func smGameStateValid(state uint8) bool {
+	return state >= 0x07 && state <= 0x12
+}
+
+func (g *Game) enqueueSMReads(q []snes.Read) []snes.Read {
+	sm := g.smLayout()
+	// Super Metroid's state in WRAM while it runs:
+	q = g.readEnqueue(q, 0xF509A2, smWRAMSplit, 0)              // [$09A2..$0A01]
+	q = g.readEnqueue(q, 0xF5D820, smShadowSize-smWRAMSplit, 0) // [$D820..$D90F]
+	// ... and its save data in SRAM while ALTTP runs:
+	q = g.readEnqueue(q, 0xE00000+sm.SaveData, smWRAMSplit, 0)
+	q = g.readEnqueue(q, 0xE00000+sm.SaveData+smWRAMSplit, smShadowSize-smWRAMSplit, 0)
+	return q
+}
+
+func (g *Game) enqueueSMMainRead(q []snes.Read, extra interface{}) []snes.Read {
+	sm := g.smLayout()
+	// which game is running:
+	q = g.readEnqueue(q, 0xE00000+sm.CurrentGame, 0x01, extra)
+	// Super Metroid's frame counter and game state:
+	q = g.readEnqueue(q, 0xF505B6, 0x02, extra)
+	q = g.readEnqueue(q, 0xF50998, 0x02, extra)
+	return q
+}
+
+// isReadInSM finds the current game flag among the responses and determines if Super Metroid is running
+func (g *Game) isReadInSM(rsps []snes.Response) bool {
+	sm := g.smLayout()
+	if sm == nil {
+		return false
+	}
+	for _, rsp := range rsps {
+		if rsp.Address == 0xE00000+sm.CurrentGame && len(rsp.Data) > 0 {
+			return rsp.Data[0] != 0
+		}
+	}
+	// keep the last known state:
+	return g.local.InSM
+}
+
+// readSMComplete copies Super Metroid's state into the local player from wherever it currently lives
+func (g *Game) readSMComplete(local *Player, inSM bool) {
+	if local.InSM != inSM {
+		if inSM {
+			log.Println("zelda3: crossed over to Super Metroid")
+		} else {
+			log.Println("zelda3: crossed over to ALTTP")
+		}
+		local.InSM = inSM
+		g.shouldUpdatePlayersList = true
+	}
+
+	if inSM {
+		copy(local.SM[:smWRAMSplit], g.wram[0x09A2:0x09A2+smWRAMSplit])
+		copy(local.SM[smWRAMSplit:], g.wram[0xD820:0xD7C0+smShadowSize])
+		return
+	}
+
+	saveData := g.smLayout().SaveData
+	copy(local.SM[:], g.sram[saveData:saveData+smShadowSize])
+}
+
+// generateSMGuard emits asm to skip the update unless the game it was generated for is still running
+func (g *Game) generateSMGuard(a *asm.Emitter) {
+	sm := g.smLayout()
+	currentGame := sm.sramBusAddress(sm.CurrentGame)
+	if g.local.InSM {
+		a.Comment("don't update unless Super Metroid is in main gameplay:")
+		a.LDA_long(currentGame)
+		a.BNE(0x01)
+		a.RTS()
+		a.LDA_long(0x7E0998)
+		a.CMP_imm8_b(smGameStateMainGameplay)
+		a.BEQ(0x01)
+		a.RTS()
+		return
+	}
+
+	a.Comment("don't update unless ALTTP is running:")
+	a.LDA_long(currentGame)
+	a.BEQ(0x01)
+	a.RTS()
+}
+
+func (g *Game) sendSMSRAM() {
+	m := g.makeBroadcastMessage()
+	if m == nil {
+		return
+	}
+
+	local := g.local
+	// equipment, energy and ammo:
+	if err := g.SerializeSMSRAM(local, m, smEquippedItems, smWRAMSplit); err != nil {
+		panic(err)
+	}
+	// boss flags:
+	if err := g.SerializeSMSRAM(local, m, smBossFlags, smBossFlags+8); err != nil {
+		panic(err)
+	}
+	// map stations:
+	if err := g.SerializeSMSRAM(local, m, smMapStations, smMapStations+8); err != nil {
+		panic(err)
+	}
+	g.send(m)
+}
+
+// syncSMEquipment equips newly collected items along with collecting them; the equipped bits are at offset-2
+func (g *Game) syncSMEquipment(s *games.SyncableBitU16, asm *asm.Emitter, initial, updated, newBits uint16) {
+	local := g.LocalSyncablePlayer().ReadableMemory(s.MemoryKind)
+	collected := local.BusAddress(s.Offset)
+	equipped := local.BusAddress(s.Offset - 2)
+
+	asm.LDA_imm16_w(newBits)
+	asm.ORA_long(collected)
+	asm.STA_long(collected)
+	asm.LDA_imm16_w(newBits)
+	asm.ORA_long(equipped)
+	asm.STA_long(equipped)
+}
+
+// Super Metroid beams at $09A6 and $09A8:
+const (
+	smBeamSpazer uint16 = 0x0004
+	smBeamPlasma uint16 = 0x0008
+)
+
+// syncSMBeams equips newly collected beams like syncSMEquipment except that Spazer and Plasma cannot be
+// equipped together; Plasma wins
+func (g *Game) syncSMBeams(s *games.SyncableBitU16, asm *asm.Emitter, initial, updated, newBits uint16) {
+	local := g.LocalSyncablePlayer().ReadableMemory(s.MemoryKind)
+	collected := local.BusAddress(s.Offset)
+	equipped := local.BusAddress(s.Offset - 2)
+
+	asm.LDA_imm16_w(newBits)
+	asm.ORA_long(collected)
+	asm.STA_long(collected)
+
+	equip := newBits
+	if updated&smBeamPlasma != 0 {
+		equip &^= smBeamSpazer
+	}
+	if equip == 0 {
+		return
+	}
+	asm.LDA_imm16_w(equip)
+	asm.ORA_long(equipped)
+	if equip&smBeamPlasma != 0 {
+		asm.AND_imm16_w(^smBeamSpazer)
+	}
+	asm.STA_long(equipped)
+}
+
+// syncableSMCapacity syncs the maximum of a capacity like energy or missiles as a u16 and, if fill is set, adds
+// what was gained to the current amount kept just before it
+type syncableSMCapacity struct {
+	g *Game
+
+	offset    uint32
+	isEnabled *bool
+	unit      uint16
+	fill      bool
+	names     []string
+
+	pendingUpdate bool
+	updatingTo    uint16
+	notification  string
+}
+
+func (g *Game) newSyncableSMCapacity(offset uint16, enabled *bool, names []string, unit uint16, fill bool) *syncableSMCapacity {
+	s := &syncableSMCapacity{
+		g:         g,
+		offset:    uint32(offset),
+		isEnabled: enabled,
+		unit:      unit,
+		fill:      fill,
+		names:     names,
+	}
+	g.syncableItems[offset] = s
+	return s
+}
+
+func (s *syncableSMCapacity) Size() uint      { return 2 }
+func (s *syncableSMCapacity) IsEnabled() bool { return *s.isEnabled }
+
+func (s *syncableSMCapacity) CanUpdate() bool {
+	if !s.pendingUpdate {
+		return true
+	}
+
+	// wait until we see the desired update:
+	g := s.g
+	if g.LocalSyncablePlayer().ReadableMemory(games.SM).ReadU16(s.offset) != s.updatingTo {
+		return false
+	}
+
+	// send the notification:
+	if s.notification != "" {
+		g.PushNotification(s.notification)
+		s.notification = ""
+	}
+
+	s.pendingUpdate = false
+
+	return true
+}
+
+func (s *syncableSMCapacity) GenerateUpdate(asm *asm.Emitter) bool {
+	g := s.g
+	local := g.LocalSyncablePlayer()
+	localMemory := local.ReadableMemory(games.SM)
+
+	initial := localMemory.ReadU16(s.offset)
+	maxP := local
+	maxV := initial
+	for _, p := range g.RemoteSyncablePlayers() {
+		v := p.ReadableMemory(games.SM).ReadU16(s.offset)
+		if v > maxV {
+			maxV, maxP = v, p
+		}
+	}
+
+	if maxV == initial {
+		// no change:
+		return false
+	}
+
+	gained := maxV - initial
+	s.pendingUpdate = true
+	s.updatingTo = maxV
+	s.notification = ""
+	if len(s.names) > 0 && s.names[0] != "" {
+		received := s.names[0]
+		if count := gained / s.unit; count > 1 {
+			received = fmt.Sprintf("%d %ss", count, received)
+		}
+		s.notification = fmt.Sprintf("got %s from %s", received, maxP.Name())
+		asm.Comment(s.notification + ":")
+	} else {
+		asm.Comment(fmt.Sprintf("sm[$%03x] = $%04x", s.offset, maxV))
+	}
+
+	if s.fill {
+		current := localMemory.BusAddress(s.offset - 2)
+		asm.LDA_long(current)
+		asm.CLC()
+		asm.ADC_imm16_w(gained)
+		asm.STA_long(current)
+	}
+	asm.LDA_imm16_w(maxV)
+	asm.STA_long(localMemory.BusAddress(s.offset))
+
+	return true
+}
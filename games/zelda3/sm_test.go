@@ -0,0 +1,218 @@
+package zelda3
+
+import (
+	"bytes"
+	"o2/games"
+	"o2/interfaces"
+	"o2/snes"
+	"o2/snes/asm"
+	"o2/snes/emulator"
+	"strings"
+	"testing"
+)
+
+// smFactory is for tests using a combo ROM layout with a Super Metroid half:
+var smFactory = NewFactory(&Layout{
+	Name:           "SMZ3",
+	ResetHook:      0x00802F,
+	InitHook:       0x1BB1D7,
+	FrameHook:      0x008056,
+	IsROMSupported: func(rom *snes.ROM) bool { return true },
+	SM: &SMLayout{
+		FrameHook:   0x82894B,
+		CurrentGame: 0x33FE,
+		SaveData:    0x2000,
+	},
+})
+
+func newSMTestGame(t *testing.T) *Game {
+	rom, err := emulator.MakeTestROM("ZELDANODENSETSU")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := smFactory.NewGame(rom).(*Game)
+	g.Reset()
+
+	g.players[1].IndexF = 1
+	g.players[1].Ttl = 255
+	g.players[1].NameF = "remote"
+	return g
+}
+
+func TestSMMemory_BusAddress(t *testing.T) {
+	layout := smFactory.Layout().SM
+	tests := []struct {
+		offs uint32
+		inSM bool
+		want uint32
+	}{
+		{offs: smCollectedItems, inSM: true, want: 0x7E09A4},
+		{offs: 0x026, inSM: true, want: 0x7E09C8},
+		{offs: smBossFlags + 1, inSM: true, want: 0x7ED829},
+		{offs: smMapStations, inSM: true, want: 0x7ED908},
+		{offs: smCollectedItems, inSM: false, want: 0xA16002},
+		{offs: smMapStations, inSM: false, want: 0xA16148},
+	}
+	for _, tt := range tests {
+		p := Player{InSM: tt.inSM, smLayout: layout}
+		if got := p.ReadableMemory(games.SM).BusAddress(tt.offs); got != tt.want {
+			t.Errorf("BusAddress($%03x) inSM=%v = $%06x, want $%06x", tt.offs, tt.inSM, got, tt.want)
+		}
+	}
+}
+
+func TestSyncTable_SM(t *testing.T) {
+	g := newSMTestGame(t)
+
+	if len(g.syncableItems) != 46 {
+		t.Errorf("len(syncableItems) = %d, want 46", len(g.syncableItems))
+	}
+	if len(g.smSyncableItems) != 18 {
+		t.Errorf("len(smSyncableItems) = %d, want 18", len(g.smSyncableItems))
+	}
+	items, ok := g.smSyncableItems[smCollectedItems].(*games.SyncableBitU16)
+	if !ok || items.MemoryKind != games.SM || items.GenerateAsm == nil {
+		t.Errorf("items syncable not configured from table")
+	}
+	if _, ok = g.smSyncableItems[0x022].(*syncableSMCapacity); !ok {
+		t.Errorf("energy syncable not configured from table")
+	}
+
+	// ALTTP-only games have no Super Metroid items:
+	rom, err := emulator.MakeTestROM("ZELDANODENSETSU")
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := factory.NewGame(rom).(*Game)
+	z.Reset()
+	if len(z.smSyncableItems) != 0 {
+		t.Errorf("len(smSyncableItems) = %d, want 0", len(z.smSyncableItems))
+	}
+}
+
+func TestGame_generateUpdateAsm_SM(t *testing.T) {
+	tests := []struct {
+		name     string
+		inSM     bool
+		want     [][]byte
+		wantNot  [][]byte
+		wantText []string
+	}{
+		{
+			name: "in ALTTP",
+			inSM: false,
+			want: [][]byte{
+				// sta.l $a16002 collected items in SRAM
+				{0x8f, 0x02, 0x60, 0xa1},
+				// sta.l $a16000 equipped items in SRAM
+				{0x8f, 0x00, 0x60, 0xa1},
+				// sta.l $a16026 max missiles in SRAM
+				{0x8f, 0x26, 0x60, 0xa1},
+				// sta.l $7ef359 sword
+				{0x8f, 0x59, 0xf3, 0x7e},
+			},
+			wantText: []string{"got Morph Ball from remote", "got 2 Missile Tanks from remote", "got Fighter Sword from remote"},
+		},
+		{
+			name: "in SM",
+			inSM: true,
+			want: [][]byte{
+				// sta.l $7e09a4 collected items
+				{0x8f, 0xa4, 0x09, 0x7e},
+				// sta.l $7e09a2 equipped items
+				{0x8f, 0xa2, 0x09, 0x7e},
+				// adc.w #$000a : sta.l $7e09c6 missiles
+				{0x69, 0x0a, 0x00, 0x8f, 0xc6, 0x09, 0x7e},
+				// lda.w #$000a : sta.l $7e09c8 max missiles
+				{0xa9, 0x0a, 0x00, 0x8f, 0xc8, 0x09, 0x7e},
+			},
+			wantNot: [][]byte{
+				// ALTTP waits until the player crosses back over:
+				{0x8f, 0x59, 0xf3, 0x7e},
+			},
+			wantText: []string{"got Morph Ball from remote", "got 2 Missile Tanks from remote"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newSMTestGame(t)
+			g.local.InSM = tt.inSM
+
+			remote := &g.players[1]
+			remote.InSM = !tt.inSM
+			// Morph Ball:
+			remote.SM[smCollectedItems] = 0x04
+			// 10 max missiles:
+			remote.SM[0x026] = 10
+			// Fighter Sword:
+			remote.SRAM[0x359] = 1
+
+			a := &asm.Emitter{
+				Code: &bytes.Buffer{},
+				Text: &strings.Builder{},
+			}
+			a.AssumeSEP(0x30)
+			if !g.generateUpdateAsm(a) {
+				t.Fatalf("generateUpdateAsm() = false, want true")
+			}
+			t.Logf("%s", a.Text.String())
+
+			code := a.Code.Bytes()
+			for _, want := range tt.want {
+				if !bytes.Contains(code, want) {
+					t.Errorf("code does not contain % x", want)
+				}
+			}
+			for _, wantNot := range tt.wantNot {
+				if bytes.Contains(code, wantNot) {
+					t.Errorf("code contains % x", wantNot)
+				}
+			}
+			for _, want := range tt.wantText {
+				if !strings.Contains(a.Text.String(), want) {
+					t.Errorf("asm does not mention '%s'", want)
+				}
+			}
+		})
+	}
+}
+
+func TestGame_generateUpdateAsm_SMBeams(t *testing.T) {
+	g := newSMTestGame(t)
+	g.local.InSM = true
+	// local has Spazer equipped:
+	g.local.SM[smEquippedBeams] = uint8(smBeamSpazer)
+	g.local.SM[smCollectedBeams] = uint8(smBeamSpazer)
+	// remote has Plasma:
+	g.players[1].SM[smCollectedBeams] = uint8(smBeamPlasma)
+
+	notifications := make([]string, 0, 1)
+	g.Notifications.Subscribe(interfaces.ObserverImpl(func(object interface{}) {
+		notifications = append(notifications, object.(string))
+	}))
+
+	a := &asm.Emitter{
+		Code: &bytes.Buffer{},
+		Text: &strings.Builder{},
+	}
+	a.AssumeSEP(0x30)
+	if !g.generateUpdateAsm(a) {
+		t.Fatalf("generateUpdateAsm() = false, want true")
+	}
+	t.Logf("%s", a.Text.String())
+
+	// lda.w #$0008 : ora.l $7e09a6 : and.w #$fffb : sta.l $7e09a6
+	want := []byte{0xa9, 0x08, 0x00, 0x0f, 0xa6, 0x09, 0x7e, 0x29, 0xfb, 0xff, 0x8f, 0xa6, 0x09, 0x7e}
+	if !bytes.Contains(a.Code.Bytes(), want) {
+		t.Errorf("code does not unequip Spazer: % x", a.Code.Bytes())
+	}
+
+	// the notification is sent once the update is seen:
+	g.local.SM[smCollectedBeams] |= uint8(smBeamPlasma)
+	a = &asm.Emitter{Code: &bytes.Buffer{}, Text: &strings.Builder{}}
+	a.AssumeSEP(0x30)
+	_ = g.generateUpdateAsm(a)
+	if len(notifications) != 1 || notifications[0] != "got Plasma Beam from remote" {
+		t.Errorf("notifications = %q, want [got Plasma Beam from remote]", notifications)
+	}
+}
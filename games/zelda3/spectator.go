@@ -0,0 +1,216 @@
+package zelda3
+
+import (
+	"errors"
+	"google.golang.org/protobuf/proto"
+	"log"
+	"o2/client"
+	"o2/client/protocol03"
+	"time"
+)
+
+// spectators rejoin the group this often to avoid being expired by the server:
+const spectatorRejoinInterval = 5 * time.Second
+
+// how often spectators tick down player TTLs and publish the feed:
+const spectatorUpdateInterval = 250 * time.Millisecond
+
+// ErrSpectatorUnsupported is returned by Spectator.Run when the server answers the spectator's join without
+// acknowledging it as a spectator, e.g. alttp.online, which would occupy a player slot instead
+var ErrSpectatorUnsupported = errors.New("zelda3: spectator: server does not support spectators")
+
+// SRAM offsets of items shown in the spectator feed:
+var spectatorItems = []struct {
+	offs uint16
+	name string
+}{
+	{0x340, "bow"},
+	{0x341, "boomerang"},
+	{0x342, "hookshot"},
+	{0x343, "bombs"},
+	{0x344, "mushroom"},
+	{0x345, "fireRod"},
+	{0x346, "iceRod"},
+	{0x347, "bombos"},
+	{0x348, "ether"},
+	{0x349, "quake"},
+	{0x34A, "lamp"},
+	{0x34B, "hammer"},
+	{0x34C, "flute"},
+	{0x34D, "bugNet"},
+	{0x34E, "book"},
+	{0x350, "caneOfSomaria"},
+	{0x351, "caneOfByrna"},
+	{0x352, "cape"},
+	{0x353, "mirror"},
+	{0x354, "gloves"},
+	{0x355, "boots"},
+	{0x356, "flippers"},
+	{0x357, "moonPearl"},
+	{0x359, "sword"},
+	{0x35A, "shield"},
+	{0x35B, "armor"},
+	{0x35C, "bottle1"},
+	{0x35D, "bottle2"},
+	{0x35E, "bottle3"},
+	{0x35F, "bottle4"},
+	{0x36C, "heartContainers"},
+	{0x37B, "magic"},
+}
+
+// SpectatorPlayerViewModel is a player's public state as seen by a spectator
+type SpectatorPlayerViewModel struct {
+	PlayerViewModel
+
+	Inventory map[string]uint8 `json:"inventory"`
+
+	// dungeon progress bit masks; see sync.go for bit assignments:
+	Pendants  uint8  `json:"pendants"`
+	Crystals  uint8  `json:"crystals"`
+	BigKeys   uint16 `json:"bigKeys"`
+	Compasses uint16 `json:"compasses"`
+	Maps      uint16 `json:"maps"`
+}
+
+// SpectatorFeed is a snapshot of all players in the group for stream overlays
+type SpectatorFeed struct {
+	Group   string                      `json:"group"`
+	Time    time.Time                   `json:"time"`
+	Players []*SpectatorPlayerViewModel `json:"players"`
+}
+
+// Spectator follows a group read-only: it deserializes every player's state but is never assigned a player
+// slot, never broadcasts and needs no SNES.
+type Spectator struct {
+	g *Game
+	c *client.Client
+
+	// OnUpdate is called with a fresh feed snapshot periodically from Run's goroutine
+	OnUpdate func(feed *SpectatorFeed)
+}
+
+func NewSpectator(c *client.Client) *Spectator {
+	g := &Game{
+		client:  c,
+		ntpC:    make(chan int, 16),
+		stopped: make(chan struct{}),
+	}
+	g.spectating = true
+	g.initSerde()
+	g.initSRAMDelta()
+
+	for i := range g.players {
+		g.players[i] = Player{IndexF: -1, PlayerColor: 0x12ef}
+	}
+	// spectators never have a local player in the players array:
+	g.local = &Player{IndexF: -1, PlayerColor: 0x12ef}
+
+	// never acknowledge anything since no player waits on us:
+	c.Reliable().ReadOnly = true
+	g.provideSRAMDeltaClient(c.Reliable().Reliable)
+
+	return &Spectator{g: g, c: c}
+}
+
+// Run processes network messages until the client is disconnected or stop is closed. It returns
+// ErrSpectatorUnsupported and stops rejoining if the server does not support spectators.
+func (s *Spectator) Run(stop <-chan struct{}) error {
+	g := s.g
+
+	rejoin := time.NewTicker(spectatorRejoinInterval)
+	defer rejoin.Stop()
+	update := time.NewTicker(spectatorUpdateInterval)
+	defer update.Stop()
+
+	s.join()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+
+		case msg := <-s.c.Read():
+			if msg == nil {
+				// disconnected:
+				return nil
+			}
+
+			err := g.handleNetMessage(msg)
+			if errors.Is(err, ErrSpectatorUnsupported) {
+				return err
+			}
+			if err != nil {
+				log.Printf("zelda3: spectator: %v\n", err)
+			}
+
+		case <-rejoin.C:
+			s.join()
+
+		case <-update.C:
+			// tick down TTLs in place of the game frames we don't have:
+			frames := int(spectatorUpdateInterval * 60 / time.Second)
+			for _, p := range g.ActivePlayers() {
+				g.DecTTL(p, frames)
+			}
+
+			if s.OnUpdate != nil {
+				s.OnUpdate(s.Feed())
+			}
+		}
+	}
+}
+
+func (s *Spectator) join() {
+	if !s.c.IsConnected() {
+		return
+	}
+
+	gm := &protocol03.GroupMessage{
+		Group:      string(s.c.Group()),
+		PlayerTime: time.Now().UnixNano(),
+		JoinGroup:  &protocol03.JoinGroup{Spectator: true},
+	}
+	s.c.Record(client.Outbound, gm)
+
+	pkt := client.MakePacket(0x03)
+	b, err := proto.MarshalOptions{}.MarshalAppend(pkt.Bytes(), gm)
+	if err != nil {
+		log.Printf("zelda3: spectator: proto.Marshal: %v\n", err)
+		return
+	}
+
+	s.c.Write() <- b
+}
+
+// Feed returns a snapshot of all active players' state
+func (s *Spectator) Feed() *SpectatorFeed {
+	g := s.g
+
+	playerViewModels := g.playerViewModels()
+	feed := &SpectatorFeed{
+		Group:   string(s.c.Group()),
+		Time:    time.Now(),
+		Players: make([]*SpectatorPlayerViewModel, 0, len(playerViewModels)),
+	}
+	for _, pvm := range playerViewModels {
+		p := &g.players[pvm.Index]
+		sram := &p.SRAM
+
+		spvm := &SpectatorPlayerViewModel{
+			PlayerViewModel: *pvm,
+			Inventory:       make(map[string]uint8, len(spectatorItems)),
+			Pendants:        sram[0x374],
+			Crystals:        sram[0x37A],
+			Compasses:       uint16(sram[0x364])<<8 | uint16(sram[0x365]),
+			BigKeys:         uint16(sram[0x366])<<8 | uint16(sram[0x367]),
+			Maps:            uint16(sram[0x368])<<8 | uint16(sram[0x369]),
+		}
+		for _, item := range spectatorItems {
+			spvm.Inventory[item.name] = sram[item.offs]
+		}
+
+		feed.Players = append(feed.Players, spvm)
+	}
+
+	return feed
+}
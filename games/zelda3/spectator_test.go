@@ -0,0 +1,76 @@
+package zelda3
+
+import (
+	"google.golang.org/protobuf/proto"
+	"o2/client"
+	"o2/client/protocol03"
+	"o2/snes/emulator"
+	"testing"
+)
+
+func TestSpectator_Feed(t *testing.T) {
+	rom, err := emulator.MakeTestROM("VT test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a remote player serializes its SRAM:
+	remote := factory.NewGame(rom).(*Game)
+	remote.Reset()
+	remote.local.SRAM[0x340] = 0x02
+	remote.local.SRAM[0x37A] = 0x05
+	m := remote.makeBroadcastMessage()
+	if err = remote.SerializeSRAM(remote.local, m, 0x340, 0x380); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSpectator(client.NewClient())
+
+	deliver := func(gm *protocol03.GroupMessage) {
+		t.Helper()
+		b, err := proto.MarshalOptions{}.MarshalAppend(client.MakePacket(0x03).Bytes(), gm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = s.g.handleNetMessage(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// servers that do not acknowledge spectators would give us a player slot:
+	b, _ := proto.MarshalOptions{}.MarshalAppend(client.MakePacket(0x03).Bytes(), &protocol03.GroupMessage{
+		Group:       "test",
+		PlayerIndex: 0,
+		JoinGroup:   &protocol03.JoinGroup{Spectator: true},
+	})
+	if err = s.g.handleNetMessage(b); err != ErrSpectatorUnsupported {
+		t.Errorf("unacknowledged join: err = %v, expected %v", err, ErrSpectatorUnsupported)
+	}
+
+	// the server's reply to our join must not give us a player slot:
+	deliver(&protocol03.GroupMessage{Group: "test", PlayerIndex: 0, JoinGroup: &protocol03.JoinGroup{Spectator: true, Spectating: true}})
+	if actual, expected := s.g.LocalPlayer().Index(), -1; actual != expected {
+		t.Errorf("local index = %d, expected %d", actual, expected)
+	}
+
+	deliver(&protocol03.GroupMessage{
+		Group:        "test",
+		PlayerIndex:  1,
+		BroadcastAll: &protocol03.BroadcastAll{Data: m.Bytes()},
+	})
+
+	feed := s.Feed()
+	if len(feed.Players) != 1 {
+		t.Fatalf("len(feed.Players) = %d, expected 1", len(feed.Players))
+	}
+	p := feed.Players[0]
+	if actual, expected := p.Index, 1; actual != expected {
+		t.Errorf("player index = %d, expected %d", actual, expected)
+	}
+	if actual, expected := p.Inventory["bow"], uint8(0x02); actual != expected {
+		t.Errorf("bow = %02x, expected %02x", actual, expected)
+	}
+	if actual, expected := p.Crystals, uint8(0x05); actual != expected {
+		t.Errorf("crystals = %02x, expected %02x", actual, expected)
+	}
+}
@@ -0,0 +1,264 @@
+package zelda3
+
+import (
+	"errors"
+	"log"
+	"o2/udpclient"
+)
+
+// errSRAMDeltaBaseMissing is returned when a delta refers to a snapshot we never received; the
+// sender will eventually recover us with a keyframe:
+var errSRAMDeltaBaseMissing = errors.New("zelda3: sram delta: base snapshot missing")
+
+const (
+	// send a full keyframe at least once every this many send opportunities even if nothing changed:
+	sramDeltaKeyframeInterval = 16
+	// number of received snapshots to keep per player per stream for use as delta bases:
+	sramDeltaHistory = 32
+	// differing runs separated by fewer equal bytes than this are merged to save on run headers:
+	sramDeltaMergeGap = 4
+	// fall back to full SRAM frames once this many snapshots in a row went unacknowledged:
+	sramDeltaMaxGivenUp = 3
+)
+
+type sramSnapshot struct {
+	id   uint32
+	data []byte
+}
+
+// sramDeltaStream tracks the sending side of a delta-compressed SRAM range:
+type sramDeltaStream struct {
+	channel uint8
+	start   uint16
+	end     uint16
+
+	nextID uint32
+	// last snapshot acknowledged by all remote players:
+	base *sramSnapshot
+	// snapshots sent but not yet acknowledged by all, keyed by reliable sequence number:
+	sent map[uint32]*sramSnapshot
+	// snapshots in a row the reliable layer gave up on:
+	givenUp int
+
+	sinceKeyframe int
+}
+
+type sramDeltaRecvKey struct {
+	player int
+	start  uint16
+}
+
+func (g *Game) initSRAMDelta() {
+	itemsEnd := uint16(0x3CA)
+	if g.rom == nil || g.isVTRandomizer() {
+		itemsEnd = 0x43A
+	}
+
+	g.sramStreams = []*sramDeltaStream{
+		{channel: reliableChannelSRAMItems, start: 0x340, end: itemsEnd},
+		{channel: reliableChannelSRAMUnderworld, start: 0x000, end: 0x250},
+		{channel: reliableChannelSRAMOverworld, start: 0x280, end: 0x340},
+	}
+	g.resetSRAMDelta()
+}
+
+// resetSRAMDelta forgets all delta bases, e.g. after reconnecting or when the set of players changes:
+func (g *Game) resetSRAMDelta() {
+	for _, s := range g.sramStreams {
+		s.base = nil
+		s.sent = make(map[uint32]*sramSnapshot)
+		s.givenUp = 0
+	}
+	g.sramDeltaRecv = make(map[sramDeltaRecvKey][]*sramSnapshot)
+}
+
+// sramDeltaSetPeers forces keyframes when the set of remote players changes since newcomers have no bases:
+func (g *Game) sramDeltaSetPeers(peers []uint32) {
+	changed := len(peers) != len(g.sramDeltaPeers)
+	for i := 0; !changed && i < len(peers); i++ {
+		changed = peers[i] != g.sramDeltaPeers[i]
+	}
+	if !changed {
+		return
+	}
+
+	g.sramDeltaPeers = peers
+	for _, s := range g.sramStreams {
+		s.base = nil
+		// the new players may well acknowledge so give delta mode another chance:
+		s.givenUp = 0
+	}
+}
+
+// sramDeltaFallback reports whether to send full SRAM frames instead of deltas since the server does not route
+// reliable messages or snapshots keep going unacknowledged:
+func (g *Game) sramDeltaFallback() bool {
+	if c := g.client; c != nil && !c.Reliable().Routed {
+		return true
+	}
+	for _, s := range g.sramStreams {
+		if s.givenUp >= sramDeltaMaxGivenUp {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) sramStream(channel uint8) *sramDeltaStream {
+	for _, s := range g.sramStreams {
+		if s.channel == channel {
+			return s
+		}
+	}
+	return nil
+}
+
+// provideSRAMDeltaClient configures the reliable channels used for delta-compressed SRAM:
+func (g *Game) provideSRAMDeltaClient(r *udpclient.Reliable) {
+	for _, s := range g.sramStreams {
+		r.SetMode(s.channel, udpclient.ReliableSequenced)
+	}
+	r.OnDelivered = g.sramDeltaDelivered
+	r.OnGivenUp = g.sramDeltaGivenUp
+}
+
+// sramDeltaDelivered is called when all remote players have acknowledged a snapshot:
+func (g *Game) sramDeltaDelivered(channel uint8, seq uint32) {
+	s := g.sramStream(channel)
+	if s == nil {
+		return
+	}
+
+	snap, ok := s.sent[seq]
+	if !ok {
+		return
+	}
+	if s.base == nil || int32(snap.id-s.base.id) > 0 {
+		s.base = snap
+	}
+	s.givenUp = 0
+
+	// older snapshots are superseded:
+	for sq := range s.sent {
+		if int32(sq-seq) <= 0 {
+			delete(s.sent, sq)
+		}
+	}
+}
+
+// sramDeltaGivenUp is called when the reliable layer drops a snapshot that not all remote players acknowledged:
+func (g *Game) sramDeltaGivenUp(channel uint8, seq uint32) {
+	s := g.sramStream(channel)
+	if s == nil {
+		return
+	}
+	if _, ok := s.sent[seq]; !ok {
+		return
+	}
+
+	delete(s.sent, seq)
+	s.givenUp++
+	if s.givenUp == sramDeltaMaxGivenUp {
+		log.Printf("zelda3: sram delta: channel %d: %d snapshots went unacknowledged; falling back to full SRAM frames\n", channel, s.givenUp)
+	}
+}
+
+// sendSRAMDelta sends the bytes of the stream's range that changed since the last acknowledged snapshot:
+func (g *Game) sendSRAMDelta(s *sramDeltaStream) {
+	c := g.client
+	if c == nil {
+		return
+	}
+	if !c.IsConnected() {
+		return
+	}
+
+	cur := make([]byte, s.end-s.start)
+	copy(cur, g.local.SRAM[s.start:s.end])
+
+	s.sinceKeyframe++
+	var base *sramSnapshot
+	if s.base != nil && s.sinceKeyframe < sramDeltaKeyframeInterval {
+		base = s.base
+		if len(sramDeltaRuns(base.data, cur)) == 0 {
+			// nothing changed since the last acknowledged snapshot:
+			return
+		}
+	} else {
+		s.sinceKeyframe = 0
+	}
+
+	s.nextID++
+	if s.nextID == 0 {
+		// id 0 is reserved to indicate a keyframe:
+		s.nextID++
+	}
+	snap := &sramSnapshot{id: s.nextID, data: cur}
+
+	m := g.makeBroadcastMessage()
+	if err := g.SerializeSRAMDelta(m, s.start, snap, base); err != nil {
+		panic(err)
+	}
+
+	// record the snapshot before sending since delivery may be immediate if we're alone:
+	s.sent[c.Reliable().NextSequence(s.channel)] = snap
+	if _, err := c.Reliable().Send(s.channel, m.Bytes()); err != nil {
+		log.Printf("zelda3: sendSRAMDelta: %v\n", err)
+	}
+}
+
+// sramDeltaRuns finds the runs of bytes in cur that differ from base:
+func sramDeltaRuns(base, cur []byte) (runs [][2]int) {
+	n := len(cur)
+	for i := 0; i < n; {
+		if base[i] == cur[i] {
+			i++
+			continue
+		}
+
+		start := i
+		end := i + 1
+		for end < n && end-start < 255 {
+			if base[end] != cur[end] {
+				end++
+				continue
+			}
+			// look ahead for another difference close enough to merge with:
+			j := end
+			for j < n && j-end < sramDeltaMergeGap && base[j] == cur[j] {
+				j++
+			}
+			if j >= n || j-end >= sramDeltaMergeGap || j-start >= 255 {
+				break
+			}
+			end = j
+		}
+
+		runs = append(runs, [2]int{start, end})
+		i = end
+	}
+	return
+}
+
+// sramDeltaStore records a received snapshot for use as a future delta base:
+func (g *Game) sramDeltaStore(key sramDeltaRecvKey, snap *sramSnapshot, keyframe bool) {
+	history := g.sramDeltaRecv[key]
+	if keyframe {
+		// a keyframe starts a new history, e.g. when a new player reuses an index:
+		history = nil
+	}
+	history = append(history, snap)
+	if len(history) > sramDeltaHistory {
+		history = history[len(history)-sramDeltaHistory:]
+	}
+	g.sramDeltaRecv[key] = history
+}
+
+func (g *Game) sramDeltaFind(key sramDeltaRecvKey, id uint32) *sramSnapshot {
+	for _, snap := range g.sramDeltaRecv[key] {
+		if snap.id == id {
+			return snap
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package zelda3
+
+import (
+	"bytes"
+	"errors"
+	"o2/udpclient"
+	"testing"
+	"time"
+)
+
+func TestGame_SerializeSRAMDelta(t *testing.T) {
+	g := &Game{}
+	g.initSerde()
+	g.resetSRAMDelta()
+
+	p := &Player{IndexF: 1}
+
+	deserialize := func(b []byte) error {
+		r := bytes.NewReader(b)
+		var msgType [1]byte
+		if _, err := r.Read(msgType[:]); err != nil {
+			t.Fatal(err)
+		}
+		if MessageType(msgType[0]) != MsgSRAMDelta {
+			t.Fatalf("msgType = %02x, expected %02x", msgType[0], MsgSRAMDelta)
+		}
+		err := g.DeserializeSRAMDelta(p, r)
+		if r.Len() != 0 {
+			t.Fatalf("%d bytes left unconsumed", r.Len())
+		}
+		return err
+	}
+
+	data := make([]byte, 0x250)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	// keyframe:
+	key := &sramSnapshot{id: 1, data: append([]byte(nil), data...)}
+	m := &bytes.Buffer{}
+	if err := g.SerializeSRAMDelta(m, 0, key, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := deserialize(m.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p.SRAM[:0x250], data) {
+		t.Fatal("keyframe not applied")
+	}
+
+	// delta against the keyframe only carries the changed runs:
+	data[0x010] = 0xFF
+	data[0x012] = 0xFF
+	data[0x200] = 0xFF
+	delta := &sramSnapshot{id: 2, data: append([]byte(nil), data...)}
+	m.Reset()
+	if err := g.SerializeSRAMDelta(m, 0, delta, key); err != nil {
+		t.Fatal(err)
+	}
+	if actual, max := m.Len(), 32; actual > max {
+		t.Errorf("delta is %d bytes, expected at most %d", actual, max)
+	}
+	if err := deserialize(m.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p.SRAM[:0x250], data) {
+		t.Fatal("delta not applied")
+	}
+
+	// delta against a base we never received is consumed but not applied:
+	data[0x020] = 0xEE
+	missing := &sramSnapshot{id: 4, data: append([]byte(nil), data...)}
+	m.Reset()
+	if err := g.SerializeSRAMDelta(m, 0, missing, &sramSnapshot{id: 3, data: delta.data}); err != nil {
+		t.Fatal(err)
+	}
+	if err := deserialize(m.Bytes()); !errors.Is(err, errSRAMDeltaBaseMissing) {
+		t.Fatalf("err = %v, expected %v", err, errSRAMDeltaBaseMissing)
+	}
+	if p.SRAM[0x020] == 0xEE {
+		t.Fatal("delta applied without its base")
+	}
+}
+
+type nullTransport struct{}
+
+func (nullTransport) SendData(channel uint8, seq uint32, base uint32, data []byte) error { return nil }
+func (nullTransport) SendAck(peer uint32, channel uint8, seq uint32) error               { return nil }
+
+func TestGame_SRAMDeltaGivenUp(t *testing.T) {
+	g := &Game{}
+	g.initSRAMDelta()
+
+	r := udpclient.NewReliable(nullTransport{})
+	g.provideSRAMDeltaClient(r)
+	r.SetPeers([]uint32{1})
+
+	s := g.sramStream(reliableChannelSRAMItems)
+	send := func(id uint32) uint32 {
+		seq, _ := r.Send(s.channel, nil)
+		s.sent[seq] = &sramSnapshot{id: id}
+		return seq
+	}
+
+	// a player that never acks makes the reliable layer give up on each snapshot:
+	now := time.Now()
+	for i := uint32(1); i <= sramDeltaMaxGivenUp; i++ {
+		if g.sramDeltaFallback() {
+			t.Fatalf("fell back after %d unacknowledged snapshots", i-1)
+		}
+		send(i)
+		now = now.Add(r.RetransmitInterval * time.Duration(r.MaxRetransmits+1))
+		_ = r.Retransmit(now)
+	}
+
+	if actual, expected := len(s.sent), 0; actual != expected {
+		t.Errorf("sent = %d snapshots, expected %d once given up on", actual, expected)
+	}
+	if !g.sramDeltaFallback() {
+		t.Error("expected fallback to full SRAM frames")
+	}
+
+	// a new set of players gets another chance at delta mode:
+	g.sramDeltaSetPeers([]uint32{1, 2})
+	if g.sramDeltaFallback() {
+		t.Error("expected delta mode after the players changed")
+	}
+}
@@ -0,0 +1,374 @@
+package zelda3
+
+import (
+	"fmt"
+	"log"
+	"o2/games"
+	"o2/snes/asm"
+)
+
+var dungeonNames = []string{
+	"Sewer Passage",     // $37C
+	"Hyrule Castle",     // $37D
+	"Eastern Palace",    // $37E
+	"Desert Palace",     // $37F
+	"Hyrule Castle 2",   // $380
+	"Swamp Palace",      // $381
+	"Dark Palace",       // $382
+	"Misery Mire",       // $383
+	"Skull Woods",       // $384
+	"Ice Palace",        // $385
+	"Tower of Hera",     // $386
+	"Gargoyle's Domain", // $387
+	"Turtle Rock",       // $388
+	"Ganon's Tower",     // $389
+	"Extra Dungeon 1",   // $38A unused
+	"Extra Dungeon 2",   // $38B unused
+}
+
+// for VT randomizers:
+// InventorySwap1 $38C
+const (
+	IS1FluteActive uint8 = 1 << iota
+	IS1FluteInactive
+	IS1Shovel
+	_
+	IS1MagicPowder
+	IS1Mushroom
+	IS1RedBoomerang
+	IS1BlueBoomerang
+)
+
+// InventorySwap2 $38E
+const (
+	_ uint8 = 1 << iota
+	_
+	_
+	_
+	_
+	_
+	IS2SilverBow // 0x40
+	IS2WoodBow   // 0x80
+)
+
+func (g *Game) initSync() {
+	// reset map:
+	g.syncableItems = make(map[uint16]games.SyncStrategy)
+	g.syncableBitU16 = make(map[uint16]*games.SyncableBitU16)
+	g.smSyncableItems = make(map[uint16]games.SyncStrategy)
+
+	// don't set WRAM timestamps on first read from SNES:
+	g.notFirstWRAMRead = false
+
+	// WRAM offsets for small keys, crystal switches, etc:
+	g.initSmallKeysSync()
+	g.local.WRAM[0x0400] = &SyncableWRAM{
+		Name:      "current dungeon supertile state",
+		Size:      2,
+		Timestamp: 0,
+		Value:     0xFFFF,
+	}
+
+	// define syncable items from the sync table:
+	if err := g.applySyncTable(g.syncTableOrDefault()); err != nil {
+		log.Printf("zelda3: initSync: %v\n", err)
+	}
+	if g.smLayout() != nil {
+		if err := g.applySyncTable(smSyncTable); err != nil {
+			log.Printf("zelda3: initSync: %v\n", err)
+		}
+	}
+
+	openDoor := func(asm *asm.Emitter, initial, updated uint16) bool {
+		// must only be in dungeon module:
+		if !g.LocalPlayer().IsDungeon() {
+			return false
+		}
+		if g.LocalPlayer().SubModule != 0 {
+			return false
+		}
+
+		// only pay attention to the door bits changing:
+		initial &= 0xF000
+		updated &= 0xF000
+		if initial == updated {
+			return false
+		}
+
+		// determine which door opened (or the first door that opened if multiple?):
+		b := uint16(0x8000)
+		k := uint32(0)
+		for ; k < 4; k++ {
+			if updated&b != 0 {
+				break
+			}
+			b >>= 1
+		}
+
+		// emit some asm to open this door locally:
+		k2 := k << 1
+		doorTilemapAddr := g.wramU16(0x19A0+k2) >> 1
+		doorType := g.wramU16(0x19C0 + k2)
+		kind := ""
+		switch doorType & 3 {
+		case 0: // up
+			doorTilemapAddr += 0x81
+			kind = "UP"
+			break
+		case 1: // down
+			doorTilemapAddr += 0x42
+			kind = "DOWN"
+			break
+		case 2: // left
+			doorTilemapAddr += 0x42
+			kind = "LEFT"
+			break
+		case 3: // right
+			doorTilemapAddr += 0x42
+			kind = "RIGHT"
+			break
+		}
+
+		asm.Comment(fmt.Sprintf("open door[%d] %s", k, kind))
+		asm.REP(0x30)
+		asm.LDA_imm16_w(doorTilemapAddr)
+		asm.STA_abs(0x068E)
+		asm.LDA_imm16_w(0x0008) // TODO: confirm this value?
+		asm.STA_abs(0x0690)
+		asm.SEP(0x30)
+		// set door open submodule:
+		asm.LDA_imm8_b(0x04)
+		asm.STA_dp(0x11)
+		asm.REP(0x30)
+		return true
+	}
+
+	// sync wram[$0400] for current dungeon supertile door state:
+	g.syncableBitU16[0x0400] = games.NewSyncableBitU16(
+		g,
+		0x0400,
+		&g.SyncUnderworld,
+		nil,
+		// open the local door(s):
+		func(s *games.SyncableBitU16, asm *asm.Emitter, initial, updated uint16) {
+			asm.Comment("open door based on wram[$0400] bits")
+			openDoor(asm, initial, updated)
+		},
+	)
+	g.syncableBitU16[0x0400].MemoryKind = games.WRAM
+	// filter out players not in local player's current dungeon supertile:
+	g.syncableBitU16[0x0400].PlayerPredicate = func(sp games.SyncablePlayer) bool {
+		p := sp.(*Player)
+		// player must be in dungeon module:
+		if !p.IsDungeon() {
+			return false
+		}
+		if p.SubModule != 0 {
+			return false
+		}
+		// player must have same dungeon supertile as local:
+		if p.DungeonRoom != g.local.DungeonRoom {
+			return false
+		}
+		return true
+	}
+
+	// underworld rooms:
+	for room := uint16(0x000); room < 0x128; room++ {
+		g.underworld[room] = games.SyncableBitU16{
+			SyncableGame:    g,
+			Offset:          uint32(room << 1),
+			MemoryKind:      games.SRAM,
+			IsEnabledPtr:    &g.SyncUnderworld,
+			BitNames:        nil,
+			SyncMask:        0xFFFF,
+			PlayerPredicate: games.PlayerPredicateIdentity,
+			OnUpdated: func(s *games.SyncableBitU16, asm *asm.Emitter, initial, updated uint16) {
+				// local player must only be in dungeon module:
+				if !g.LocalPlayer().IsDungeon() {
+					return
+				}
+				// only pay attention to supertile state changes when the local player is in that supertile:
+				if uint16(s.Offset>>1) != g.LocalPlayer().DungeonRoom {
+					return
+				}
+
+				openDoor(asm, initial, updated)
+			},
+		}
+	}
+
+	// notify about bosses defeated:
+	// u16[$7ef190] |= 0b0000100000000000 Armos
+	g.underworld[0xC8].BitNames = make([]string, 16)
+	g.underworld[0xC8].BitNames[0xb] = "Armos defeated"
+
+	// u16[$7ef066] |= 0b0000100000000000 Lanmola
+	g.underworld[0x33].BitNames = make([]string, 16)
+	g.underworld[0x33].BitNames[0xb] = "Lanmola defeated"
+
+	// u16[$7ef00e] |= 0b0000100000000000 Moldorm
+	g.underworld[0x07].BitNames = make([]string, 16)
+	g.underworld[0x07].BitNames[0xb] = "Moldorm defeated"
+
+	// u16[$7ef040] |= 0b0000100000000000 Agahnim
+	g.underworld[0x20].BitNames = make([]string, 16)
+	g.underworld[0x20].BitNames[0xb] = "Agahnim defeated"
+	g.underworld[0x20].OnUpdated = func(s *games.SyncableBitU16, a *asm.Emitter, initial, updated uint16) {
+		// asm runs in 16-bit mode (REP #$30) by default for underworld sync.
+		if initial&0b0000100000000000 != 0 || updated&0b0000100000000000 == 0 {
+			return
+		}
+		a.Comment("check if in HC overworld:")
+		a.SEP(0x30)
+
+		// check if in dungeon:
+		a.LDA_dp(0x1B)
+		a.BNE(0x6F - 0x06) // exit
+		// check if in HC overworld:
+		a.LDA_dp(0x8A)
+		a.CMP_imm8_b(0x1B)
+		a.BNE(0x6F - 0x0C) // exit
+
+		a.Comment("find free sprite slot:")
+		a.LDX_imm8_b(0x0f)  //   LDX   #$0F
+		_ = 0               // loop:
+		a.LDA_abs_x(0x0DD0) //   LDA.w $0DD0,X
+		a.BEQ(0x05)         //   BEQ   found
+		a.DEX()             //   DEX
+		a.BPL(-8)           //   BPL   loop
+		a.BRA(0x6F - 0x18)  //   BRA   exit
+		_ = 0               // found:
+
+		a.Comment("open portal at HC:")
+		// Y:
+		a.LDA_imm8_b(0x50)
+		a.STA_abs_x(0x0D00)
+		a.LDA_imm8_b(0x08)
+		a.STA_abs_x(0x0D20)
+		// X:
+		a.LDA_imm8_b(0xe0)
+		a.STA_abs_x(0x0D10)
+		a.LDA_imm8_b(0x07)
+		a.STA_abs_x(0x0D30)
+		// zeros:
+		a.STZ_abs_x(0x0D40)
+		a.STZ_abs_x(0x0D50)
+		a.STZ_abs_x(0x0D60)
+		a.STZ_abs_x(0x0D70)
+		a.STZ_abs_x(0x0D80)
+		// gfx?
+		a.LDA_imm8_b(0x01)
+		a.STA_abs_x(0x0D90)
+		// hitbox/persist:
+		a.STA_abs_x(0x0F60)
+		// zeros:
+		a.STZ_abs_x(0x0DA0)
+		a.STZ_abs_x(0x0DB0)
+		a.STZ_abs_x(0x0DC0)
+		// active
+		a.LDA_imm8_b(0x09)
+		a.STA_abs_x(0x0DD0)
+		// zeros:
+		a.STZ_abs_x(0x0DE0)
+		a.STZ_abs_x(0x0DF0)
+		a.STZ_abs_x(0x0E00)
+		a.STZ_abs_x(0x0E10)
+		// whirlpool
+		a.LDA_imm8_b(0xBA)
+		a.STA_abs_x(0x0E20)
+		// zeros:
+		a.STZ_abs_x(0x0E30)
+		// harmless
+		a.LDA_imm8_b(0x80)
+		a.STA_abs_x(0x0E40)
+		// OAM:
+		a.LDA_imm8_b(0x04)
+		a.STA_abs_x(0x0F50)
+		// exit:
+		a.REP(0x30)
+
+		// let player know the portal is opened:
+		g.PushNotification("HC portal opened")
+	}
+
+	// u16[$7ef0b4] |= 0b0000100000000000 Helmasaur
+	g.underworld[0x5A].BitNames = make([]string, 16)
+	g.underworld[0x5A].BitNames[0xb] = "Helmasaur defeated"
+
+	// u16[$7ef158] |= 0b0000100000000000 Blind
+	g.underworld[0xAC].BitNames = make([]string, 16)
+	g.underworld[0xAC].BitNames[0xb] = "Blind defeated"
+
+	// u16[$7ef052] |= 0b0000100000000000 Mothula
+	g.underworld[0x29].BitNames = make([]string, 16)
+	g.underworld[0x29].BitNames[0xb] = "Mothula defeated"
+
+	// u16[$7ef1bc] |= 0b0000100000000000 Kholdstare
+	g.underworld[0xDE].BitNames = make([]string, 16)
+	g.underworld[0xDE].BitNames[0xb] = "Kholdstare defeated"
+
+	// u16[$7ef00c] |= 0b0000100000000000 Arrghus
+	g.underworld[0x06].BitNames = make([]string, 16)
+	g.underworld[0x06].BitNames[0xb] = "Arrghus defeated"
+
+	// u16[$7ef120] |= 0b0000100000000000 Vitreous
+	g.underworld[0x90].BitNames = make([]string, 16)
+	g.underworld[0x90].BitNames[0xb] = "Vitreous defeated"
+
+	// u16[$7ef148] |= 0b0000100000000000 Trinexx
+	g.underworld[0xA4].BitNames = make([]string, 16)
+	g.underworld[0xA4].BitNames[0xb] = "Trinexx defeated"
+
+	// u16[$7ef01a] |= 0b0000100000000000 Agahnim 2
+	g.underworld[0x0D].BitNames = make([]string, 16)
+	g.underworld[0x0D].BitNames[0xb] = "Agahnim 2 defeated"
+
+	g.setUnderworldSyncMasks()
+
+	// overworld areas:
+	for offs := uint16(0x280); offs < 0x340; offs++ {
+		g.overworld[offs-0x280] = games.SyncableBitU8{
+			SyncableGame: g,
+			Offset:       uint32(offs),
+			IsEnabledPtr: &g.SyncUnderworld,
+			BitNames:     nil,
+			OnUpdated:    nil,
+			SyncMask:     0xFF,
+		}
+	}
+
+	// Pyramid bat crash: ([$7EF2DB] | 0x20)
+	g.overworld[0x5B].OnUpdated = func(s *games.SyncableBitU8, a *asm.Emitter, initial, updated uint8) {
+		if initial&0x20 == 0 && updated&0x20 == 0x20 {
+			if g.local.OverworldArea == 0x5B {
+				notification := "create pyramid hole:"
+				a.Comment(notification)
+				g.PushNotification(notification)
+				a.JSL(g.romFunctions[fnOverworldCreatePyramidHole])
+			}
+		}
+	}
+}
+
+func (g *Game) setUnderworldSyncMasks() {
+	if g.SyncChests == g.lastSyncChests {
+		return
+	}
+
+	g.lastSyncChests = g.SyncChests
+
+	mask := uint16(0xFFFF)
+	if !g.SyncChests {
+		// chops off the 6 bits that sync chests/keys
+		mask = ^uint16(0x03F0)
+	}
+
+	// set the masks for all the underworld rooms:
+	for room := uint16(0x000); room < 0x128; room++ {
+		g.underworld[room].SyncMask = mask
+	}
+
+	// desync swamp inner watergate at $7EF06A (supertile $35):
+	g.underworld[0x035].SyncMask &= ^uint16(0x0080)
+}
@@ -1,4 +1,4 @@
-package smz3
+package zelda3
 
 import (
 	"testing"
@@ -0,0 +1,323 @@
+package zelda3
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"o2/games"
+	"o2/snes/asm"
+	"strings"
+	"testing"
+)
+
+type testSyncableGame struct {
+	players [2]Player
+}
+
+func (t *testSyncableGame) LocalSyncablePlayer() games.SyncablePlayer {
+	return &t.players[0]
+}
+
+func (t *testSyncableGame) RemoteSyncablePlayers() []games.SyncablePlayer {
+	activePlayers := make([]games.SyncablePlayer, 0, len(t.players))
+	for i := range t.players {
+		activePlayers = append(activePlayers, &t.players[i])
+	}
+	return activePlayers
+}
+
+func (t *testSyncableGame) PushNotification(notification string) {
+	log.Printf("notification: '%s'\n", notification)
+}
+
+func Test_syncableBitU8_GenerateUpdate(t *testing.T) {
+	type fields struct {
+		offset    uint16
+		isEnabled *bool
+		names     []string
+		mask      uint8
+		p0sram    uint8
+		p1sram    uint8
+	}
+	tests := []struct {
+		name             string
+		fields           fields
+		want             bool
+		wantAsm          []byte
+		wantNotification string
+	}{
+		{
+			name: "SyncableBitU8 syncs from zero to most bits",
+			fields: fields{
+				offset:    0x379,
+				isEnabled: new(bool),
+				names:     []string{"0", "1", "2", "3", "4", "5", "6", "7"},
+				mask:      0xFF,
+				p0sram:    0x00,
+				p1sram:    0x66,
+			},
+			want:             true,
+			wantAsm:          []byte{0xa9, 0x66, 0xf, 0x79, 0xf3, 0x7e, 0x8f, 0x79, 0xf3, 0x7e},
+			wantNotification: "got 1 from p1, 2 from p1, 5 from p1, 6 from p1",
+		},
+		{
+			name: "SyncableBitU8 syncs from non-zero to most bits",
+			fields: fields{
+				offset:    0x379,
+				isEnabled: new(bool),
+				names:     []string{"0", "1", "2", "3", "4", "5", "6", "7"},
+				mask:      0xFF,
+				p0sram:    0x24,
+				p1sram:    0x66,
+			},
+			want:             true,
+			wantAsm:          []byte{0xa9, 0x42, 0xf, 0x79, 0xf3, 0x7e, 0x8f, 0x79, 0xf3, 0x7e},
+			wantNotification: "got 1 from p1, 6 from p1",
+		},
+		{
+			name: "SyncableBitU8 ignores empty names",
+			fields: fields{
+				offset:    0x379,
+				isEnabled: new(bool),
+				names:     []string{"", "1", "2", "", "", "5", "6", ""},
+				mask:      0xFF,
+				p0sram:    0x42,
+				p1sram:    0x77,
+			},
+			want:             true,
+			wantAsm:          []byte{0xa9, 0x35, 0xf, 0x79, 0xf3, 0x7e, 0x8f, 0x79, 0xf3, 0x7e},
+			wantNotification: "got 2 from p1, 5 from p1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange:
+			g := &testSyncableGame{players: [2]Player{}}
+			g.players[0] = Player{
+				IndexF:       0,
+				Ttl:          255,
+				TeamF:        0,
+				NameF:        "p0",
+				Module:       7,
+				PriorModule:  9,
+				SubModule:    0,
+				SubSubModule: 0,
+				SRAM:         [1280]byte{},
+				WRAM:         make(map[uint16]*SyncableWRAM),
+			}
+			g.players[1] = Player{
+				IndexF:       1,
+				Ttl:          255,
+				TeamF:        0,
+				NameF:        "p1",
+				Module:       7,
+				PriorModule:  9,
+				SubModule:    0,
+				SubSubModule: 0,
+				SRAM:         [1280]byte{},
+				WRAM:         make(map[uint16]*SyncableWRAM),
+			}
+			g.players[0].SRAM[tt.fields.offset] = tt.fields.p0sram
+			g.players[1].SRAM[tt.fields.offset] = tt.fields.p1sram
+			s := &games.SyncableBitU8{
+				SyncableGame: g,
+				IsEnabledPtr: new(bool),
+				Offset:       uint32(tt.fields.offset),
+				BitNames:     tt.fields.names,
+				SyncMask:     tt.fields.mask,
+			}
+			*s.IsEnabledPtr = true
+
+			a := &asm.Emitter{
+				Code: &bytes.Buffer{},
+				Text: &strings.Builder{},
+			}
+			a.AssumeSEP(0x30)
+
+			// act:
+			got := s.GenerateUpdate(a)
+
+			// assert:
+			if got != tt.want {
+				t.Errorf("GenerateUpdate() = %v, want %v", got, tt.want)
+			}
+			if actual, expected := a.Code.Bytes(), tt.wantAsm; !bytes.Equal(expected, actual) {
+				t.Errorf("asm.Code.Bytes() = %#v, want %#v\n%s\n", actual, expected, a.Text.String())
+			}
+			if actual, expected := s.Notification, tt.wantNotification; actual != expected {
+				t.Errorf("notification = '%s', want '%s'", actual, expected)
+			}
+		})
+	}
+}
+
+func TestGame_RemoteSyncablePlayers(t *testing.T) {
+	g := &Game{}
+	for i := range g.players {
+		g.players[i] = Player{IndexF: -1}
+	}
+	g.players[0] = Player{IndexF: 0, Ttl: 255, TeamF: 1, NameF: "local"}
+	g.players[1] = Player{IndexF: 1, Ttl: 255, TeamF: 1, NameF: "teammate"}
+	g.players[2] = Player{IndexF: 2, Ttl: 255, TeamF: 2, NameF: "rival"}
+	g.local = &g.players[0]
+
+	remotes := g.RemoteSyncablePlayers()
+	if len(remotes) != 1 || remotes[0].Name() != "teammate" {
+		names := make([]string, 0, len(remotes))
+		for _, p := range remotes {
+			names = append(names, p.Name())
+		}
+		t.Errorf("RemoteSyncablePlayers() = %v, want [teammate]", names)
+	}
+}
+
+func Test_syncableScript_GenerateUpdate(t *testing.T) {
+	script, err := games.CompileSyncScript("test.star", `
+def generate_update(ctx):
+    initial = ctx.local.read_u8(ctx.offset)
+    updated = initial
+    for p in ctx.remotes:
+        v = p.read_u8(ctx.offset) & 0x0F
+        if v & ~updated:
+            ctx.notify("got flags from " + p.name)
+        updated |= v
+    if updated == initial:
+        return None
+    addr = ctx.local.bus_address(ctx.offset)
+    ctx.asm.comment("flags |= %d" % (updated & ~initial))
+    ctx.asm.lda_imm(updated & ~initial)
+    ctx.asm.ora(addr)
+    ctx.asm.sta(addr)
+    return updated
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := &testSyncableGame{}
+	g.players[0] = Player{IndexF: 0, Ttl: 255, NameF: "p0", WRAM: make(map[uint16]*SyncableWRAM)}
+	g.players[1] = Player{IndexF: 1, Ttl: 255, NameF: "p1", WRAM: make(map[uint16]*SyncableWRAM)}
+	g.players[0].SRAM[0x3A0] = 0x01
+	g.players[1].SRAM[0x3A0] = 0xF6
+
+	s := games.NewSyncableScript(g, 0x3A0, new(bool), script)
+	a := &asm.Emitter{Code: &bytes.Buffer{}, Text: &strings.Builder{}}
+	a.AssumeSEP(0x30)
+
+	if !s.GenerateUpdate(a) {
+		t.Fatal("GenerateUpdate() = false, want true")
+	}
+	wantAsm := []byte{0xa9, 0x06, 0x0f, 0xa0, 0xf3, 0x7e, 0x8f, 0xa0, 0xf3, 0x7e}
+	if actual := a.Code.Bytes(); !bytes.Equal(wantAsm, actual) {
+		t.Errorf("asm.Code.Bytes() = %#v, want %#v\n%s\n", actual, wantAsm, a.Text.String())
+	}
+	if s.Notification != "got flags from p1" {
+		t.Errorf("notification = '%s', want 'got flags from p1'", s.Notification)
+	}
+
+	// update is pending until the local player's memory reflects it:
+	if s.CanUpdate() {
+		t.Error("CanUpdate() = true before update applied")
+	}
+	g.players[0].SRAM[0x3A0] = 0x07
+	if !s.CanUpdate() {
+		t.Error("CanUpdate() = false after update applied")
+	}
+}
+
+func Test_syncableScript_Unsafe(t *testing.T) {
+	scripts := []string{
+		// writes outside of WRAM:
+		"def generate_update(ctx):\n    ctx.asm.sta(0x008000)\n    return 1\n",
+		// branches past its own code:
+		"def generate_update(ctx):\n    ctx.asm.bne(4)\n    return 1\n",
+		// branches into the operand of an instruction:
+		"def generate_update(ctx):\n    ctx.asm.bne(1)\n    ctx.asm.lda_imm(0xEA)\n    return 1\n",
+		// reads out of bounds:
+		"def generate_update(ctx):\n    return ctx.local.read_u8(0x20000)\n",
+		// recursion is not allowed:
+		"def f(x):\n    return f(x)\ndef generate_update(ctx):\n    return f(1)\n",
+	}
+
+	g := &testSyncableGame{}
+	g.players[0] = Player{IndexF: 0, Ttl: 255, NameF: "p0", WRAM: make(map[uint16]*SyncableWRAM)}
+	for _, src := range scripts {
+		script, err := games.CompileSyncScript("unsafe.star", src)
+		if err != nil {
+			// rejected at compile time:
+			continue
+		}
+
+		s := games.NewSyncableScript(g, 0x3A0, new(bool), script)
+		a := &asm.Emitter{Code: &bytes.Buffer{}, Text: &strings.Builder{}}
+		a.AssumeSEP(0x30)
+		if s.GenerateUpdate(a) {
+			t.Errorf("GenerateUpdate() = true for unsafe script:\n%s", src)
+		}
+		if a.Code.Len() != 0 {
+			t.Errorf("unsafe script emitted %d bytes:\n%s", a.Code.Len(), src)
+		}
+	}
+}
+
+func Test_syncableScript_Branch(t *testing.T) {
+	// skips over a whole instruction and to the end of the code:
+	script, err := games.CompileSyncScript("branch.star", `
+def generate_update(ctx):
+    ctx.asm.lda(0x7EF3A0)
+    ctx.asm.bne(2)
+    ctx.asm.lda_imm(1)
+    ctx.asm.beq(4)
+    ctx.asm.sta(0x7EF3A0)
+    return 1
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := &testSyncableGame{}
+	g.players[0] = Player{IndexF: 0, Ttl: 255, NameF: "p0", WRAM: make(map[uint16]*SyncableWRAM)}
+	s := games.NewSyncableScript(g, 0x3A0, new(bool), script)
+	a := &asm.Emitter{Code: &bytes.Buffer{}, Text: &strings.Builder{}}
+	a.AssumeSEP(0x30)
+	if !s.GenerateUpdate(a) {
+		t.Fatal("GenerateUpdate() = false, want true")
+	}
+	if a.Code.Len() != 14 {
+		t.Errorf("emitted %d bytes, want 14", a.Code.Len())
+	}
+}
+
+func Test_syncableScript_StepLimit(t *testing.T) {
+	// top-level statements are limited too:
+	_, err := games.CompileSyncScript("slow.star", "x = [i for i in range(1000000000)]\ndef generate_update(ctx):\n    return None\n")
+	if !errors.Is(err, games.ErrSyncScriptSteps) {
+		t.Errorf("CompileSyncScript() err = %v, want ErrSyncScriptSteps", err)
+	}
+
+	script, err := games.CompileSyncScript("slow.star", `
+def generate_update(ctx):
+    n = 0
+    for i in range(1000000000):
+        n += i
+    return n
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := &testSyncableGame{}
+	g.players[0] = Player{IndexF: 0, Ttl: 255, NameF: "p0", WRAM: make(map[uint16]*SyncableWRAM)}
+	enabled := true
+	s := games.NewSyncableScript(g, 0x3A0, &enabled, script)
+	a := &asm.Emitter{Code: &bytes.Buffer{}, Text: &strings.Builder{}}
+	if s.GenerateUpdate(a) {
+		t.Error("GenerateUpdate() = true, want false")
+	}
+	if !errors.Is(s.Failed, games.ErrSyncScriptSteps) {
+		t.Errorf("Failed = %v, want ErrSyncScriptSteps", s.Failed)
+	}
+	if s.IsEnabled() {
+		t.Error("IsEnabled() = true after exceeding the step limit")
+	}
+}
@@ -0,0 +1,486 @@
+package zelda3
+
+import (
+	"fmt"
+	"o2/games"
+	"o2/snes/asm"
+	"strings"
+)
+
+// Go implementations of the custom strategies and update hooks that sync tables refer to by name:
+
+// syncCustoms creates custom sync strategies named by a sync table's "custom" field:
+var syncCustoms = map[string]func(g *Game, offset uint16, enabled *bool, names []string) games.SyncStrategy{
+	"bow": func(g *Game, offset uint16, enabled *bool, names []string) games.SyncStrategy {
+		s := g.NewSyncableCustomU8(offset, enabled, g.syncBowGenerateUpdate)
+		s.IsUpdateStillPending = g.syncBowIsUpdateStillPending
+		return s
+	},
+	"bottle": func(g *Game, offset uint16, enabled *bool, names []string) games.SyncStrategy {
+		return g.newSyncableBottle(offset, enabled, names)
+	},
+	"hearts": func(g *Game, offset uint16, enabled *bool, names []string) games.SyncStrategy {
+		s := g.NewSyncableCustomU8(offset, enabled, g.syncHeartsGenerateUpdate)
+		s.IsUpdateStillPending = g.syncHeartsIsUpdateStillPending
+		return s
+	},
+	"progress1": func(g *Game, offset uint16, enabled *bool, names []string) games.SyncStrategy {
+		return g.NewSyncableCustomU8(offset, enabled, g.syncProgress1GenerateUpdate)
+	},
+	"progress2": func(g *Game, offset uint16, enabled *bool, names []string) games.SyncStrategy {
+		return g.NewSyncableCustomU8(offset, enabled, g.syncProgress2GenerateUpdate)
+	},
+	// Super Metroid capacities; the current amount is kept just before the maximum:
+	"smEnergy": func(g *Game, offset uint16, enabled *bool, names []string) games.SyncStrategy {
+		return g.newSyncableSMCapacity(offset, enabled, names, 100, true)
+	},
+	"smReserve": func(g *Game, offset uint16, enabled *bool, names []string) games.SyncStrategy {
+		// reserve tanks are collected empty:
+		return g.newSyncableSMCapacity(offset, enabled, names, 100, false)
+	},
+	"smAmmo": func(g *Game, offset uint16, enabled *bool, names []string) games.SyncStrategy {
+		return g.newSyncableSMCapacity(offset, enabled, names, 5, true)
+	},
+}
+
+// syncHooks attach extra behavior to a sync strategy, named by a sync table's "onUpdated" field:
+var syncHooks = map[string]func(g *Game, s games.SyncStrategy) error{
+	"updateArmorGlovesPalette": onMaxU8Updated((*Game).syncUpdateArmorGlovesPalette),
+	"updateSword":              onMaxU8Updated((*Game).syncUpdateSword),
+	"updateShield":             onMaxU8Updated((*Game).syncUpdateShield),
+	"worldState":               onMaxU8Updated((*Game).syncWorldStateUpdated),
+	"inventorySwap1": func(g *Game, s games.SyncStrategy) error {
+		b, ok := s.(*games.SyncableBitU8)
+		if !ok {
+			return fmt.Errorf("zelda3: hook 'inventorySwap1' requires the bits strategy")
+		}
+		b.OnUpdated = g.syncInventorySwap1Updated
+		b.GenerateAsm = g.syncInventorySwap1GenerateAsm
+		return nil
+	},
+	"inventorySwap2": func(g *Game, s games.SyncStrategy) error {
+		b, ok := s.(*games.SyncableBitU8)
+		if !ok {
+			return fmt.Errorf("zelda3: hook 'inventorySwap2' requires the bits strategy")
+		}
+		b.OnUpdated = g.syncInventorySwap2Updated
+		return nil
+	},
+	"smEquipment": onBitU16GenerateAsm((*Game).syncSMEquipment),
+	"smBeams":     onBitU16GenerateAsm((*Game).syncSMBeams),
+}
+
+func onMaxU8Updated(hook func(g *Game, s *games.SyncableMaxU8, asm *asm.Emitter, initial, updated uint8)) func(g *Game, s games.SyncStrategy) error {
+	return func(g *Game, s games.SyncStrategy) error {
+		m, ok := s.(*games.SyncableMaxU8)
+		if !ok {
+			return fmt.Errorf("zelda3: hook requires the max strategy")
+		}
+		m.OnUpdated = func(s *games.SyncableMaxU8, asm *asm.Emitter, initial, updated uint8) {
+			hook(g, s, asm, initial, updated)
+		}
+		return nil
+	}
+}
+
+func onBitU16GenerateAsm(generate func(g *Game, s *games.SyncableBitU16, asm *asm.Emitter, initial, updated, newBits uint16)) func(g *Game, s games.SyncStrategy) error {
+	return func(g *Game, s games.SyncStrategy) error {
+		b, ok := s.(*games.SyncableBitU16)
+		if !ok {
+			return fmt.Errorf("zelda3: hook requires the bits16 strategy")
+		}
+		b.GenerateAsm = func(s *games.SyncableBitU16, asm *asm.Emitter, initial, updated, newBits uint16) {
+			generate(g, s, asm, initial, updated, newBits)
+		}
+		return nil
+	}
+}
+
+func (g *Game) syncBowGenerateUpdate(s *games.SyncableCustomU8, asm *asm.Emitter) bool {
+	local := g.LocalSyncablePlayer()
+	offset := s.Offset
+
+	initial := local.ReadableMemory(games.SRAM).ReadU8(offset)
+	// treat w/ and w/o arrows as the same:
+	if initial == 2 {
+		initial = 1
+	} else if initial >= 4 {
+		initial = 3
+	}
+
+	maxP := local
+	maxV := initial
+	for _, p := range g.RemoteSyncablePlayers() {
+		v := p.ReadableMemory(games.SRAM).ReadU8(offset)
+		// treat w/ and w/o arrows as the same:
+		if v == 2 {
+			v = 1
+		} else if v >= 4 {
+			v = 3
+		}
+		if v > maxV {
+			maxV, maxP = v, p
+		}
+	}
+
+	if maxV == initial {
+		// no change:
+		return false
+	}
+
+	// notify local player of new item received:
+	received := ""
+	if maxV == 1 {
+		received = "Bow"
+	} else if maxV == 3 {
+		received = "Silver Bow"
+		maxV = 3
+	}
+	s.PendingUpdate = true
+	s.UpdatingTo = maxV
+	s.Notification = fmt.Sprintf("got %s from %s", received, maxP.Name())
+	asm.Comment(s.Notification + ":")
+
+	asm.LDA_long(0x7EF377) // arrows
+	asm.CMP_imm8_b(0x01)   // are arrows present?
+	asm.LDA_imm8_b(maxV)   // bow level; 1 = wood, 3 = silver
+	asm.ADC_imm8_b(0x00)   // add +1 to bow if arrows are present
+	asm.STA_long(local.ReadableMemory(games.SRAM).BusAddress(offset))
+
+	return true
+}
+
+func (g *Game) syncBowIsUpdateStillPending(s *games.SyncableCustomU8) bool {
+	return g.LocalPlayer().ReadableMemory(games.SRAM).ReadU8(s.Offset) != s.UpdatingTo &&
+		g.LocalPlayer().ReadableMemory(games.SRAM).ReadU8(s.Offset) != s.UpdatingTo+1
+}
+
+func (g *Game) syncUpdateArmorGlovesPalette(s *games.SyncableMaxU8, asm *asm.Emitter, initial, updated uint8) {
+	asm.Comment("update armor/gloves palette:")
+	asm.JSL(g.romFunctions[fnUpdatePaletteArmorGloves])
+}
+
+func (g *Game) syncUpdateSword(s *games.SyncableMaxU8, asm *asm.Emitter, initial, updated uint8) {
+	asm.Comment("decompress sword gfx:")
+	asm.JSL(g.romFunctions[fnDecompGfxSword])
+	asm.Comment("update sword palette:")
+	asm.JSL(g.romFunctions[fnUpdatePaletteSword])
+}
+
+func (g *Game) syncUpdateShield(s *games.SyncableMaxU8, asm *asm.Emitter, initial, updated uint8) {
+	asm.Comment("decompress shield gfx:")
+	asm.JSL(g.romFunctions[fnDecompGfxShield])
+	asm.Comment("update shield palette:")
+	asm.JSL(g.romFunctions[fnUpdatePaletteShield])
+}
+
+func (g *Game) syncHeartsGenerateUpdate(s *games.SyncableCustomU8, asm *asm.Emitter) bool {
+	local := g.LocalSyncablePlayer()
+
+	localSRAM := local.ReadableMemory(games.SRAM)
+	initial := (localSRAM.ReadU8(0x36C) & ^uint8(7)) | (localSRAM.ReadU8(0x36B) & 3)
+
+	maxP := local
+	updated := initial
+	for _, p := range g.RemoteSyncablePlayers() {
+		pSRAM := p.ReadableMemory(games.SRAM)
+		v := (pSRAM.ReadU8(0x36C) & ^uint8(7)) | (pSRAM.ReadU8(0x36B) & 3)
+		if v > updated {
+			updated, maxP = v, p
+		}
+	}
+
+	if updated == initial {
+		// no change:
+		return false
+	}
+
+	// notify local player of new item received:
+	s.PendingUpdate = true
+	s.UpdatingTo = updated
+
+	oldHearts := initial & ^uint8(7)
+	oldPieces := initial & uint8(3)
+	newHearts := updated & ^uint8(7)
+	newPieces := updated & uint8(3)
+
+	diffHearts := (newHearts + (newPieces << 1)) - (oldHearts + (oldPieces << 1))
+	fullHearts := diffHearts >> 3
+	pieces := (diffHearts & 7) >> 1
+
+	hc := &strings.Builder{}
+	if fullHearts == 1 {
+		hc.WriteString("1 new heart")
+	} else if fullHearts > 1 {
+		hc.WriteString(fmt.Sprintf("%d new hearts", fullHearts))
+	}
+	if fullHearts >= 1 && pieces >= 1 {
+		hc.WriteString(", ")
+	}
+
+	if pieces == 1 {
+		hc.WriteString("1 new heart piece")
+	} else if pieces > 0 {
+		hc.WriteString(fmt.Sprintf("%d new heart pieces", pieces))
+	}
+
+	received := hc.String()
+	s.Notification = fmt.Sprintf("got %s from %s", received, maxP.Name())
+	asm.Comment(s.Notification + ":")
+
+	asm.LDA_imm8_b(updated & ^uint8(7))
+	asm.STA_long(localSRAM.BusAddress(0x36C))
+	asm.LDA_imm8_b(updated & uint8(3))
+	asm.STA_long(localSRAM.BusAddress(0x36B))
+
+	return true
+}
+
+func (g *Game) syncHeartsIsUpdateStillPending(s *games.SyncableCustomU8) bool {
+	if !s.PendingUpdate {
+		return false
+	}
+
+	localSRAM := s.SyncableGame.LocalSyncablePlayer().ReadableMemory(games.SRAM)
+	current := (localSRAM.ReadU8(0x36C) & ^uint8(7)) | (localSRAM.ReadU8(0x36B) & 3)
+	if current != s.UpdatingTo {
+		return true
+	}
+
+	return false
+}
+
+func (g *Game) syncInventorySwap1Updated(s *games.SyncableBitU8, a *asm.Emitter, initial, updated uint8) {
+	// mushroom/powder:
+	if initial&IS1MagicPowder == 0 && updated&IS1MagicPowder != 0 {
+		// set powder in inventory:
+		a.Comment("set Magic Powder in inventory:")
+		a.LDA_long(0x7EF344)
+		a.BNE(6)
+		a.LDA_imm8_b(2)
+		a.STA_long(0x7EF344)
+	} else if initial&IS1Mushroom == 0 && updated&IS1Mushroom != 0 {
+		// set mushroom in inventory:
+		a.Comment("set Mushroom in inventory:")
+		a.LDA_long(0x7EF344)
+		a.BNE(6)
+		a.LDA_imm8_b(1)
+		a.STA_long(0x7EF344)
+	}
+
+	// shovel/flute:
+	if initial&IS1FluteActive == 0 && updated&IS1FluteActive != 0 {
+		// flute (activated):
+		a.Comment("set Flute (active) in inventory:")
+		a.LDA_long(0x7EF34C)
+		a.BNE(6)
+		a.LDA_imm8_b(3)
+		a.STA_long(0x7EF34C)
+	} else if initial&IS1FluteInactive == 0 && updated&IS1FluteInactive != 0 {
+		// flute (activated):
+		a.Comment("set Flute (inactive) in inventory:")
+		a.LDA_long(0x7EF34C)
+		a.BNE(6)
+		a.LDA_imm8_b(2)
+		a.STA_long(0x7EF34C)
+	} else if initial&IS1Shovel == 0 && updated&IS1Shovel != 0 {
+		// flute (activated):
+		a.Comment("set Shovel in inventory:")
+		a.LDA_long(0x7EF34C)
+		a.BNE(6)
+		a.LDA_imm8_b(1)
+		a.STA_long(0x7EF34C)
+	}
+
+	// red/blue boomerang:
+	if initial&IS1RedBoomerang == 0 && updated&IS1RedBoomerang != 0 {
+		// set powder in inventory:
+		a.Comment("set Red Boomerang in inventory:")
+		a.LDA_long(0x7EF341)
+		a.BNE(6)
+		a.LDA_imm8_b(2)
+		a.STA_long(0x7EF341)
+	} else if initial&IS1BlueBoomerang == 0 && updated&IS1BlueBoomerang != 0 {
+		// set mushroom in inventory:
+		a.Comment("set Blue Boomerang in inventory:")
+		a.LDA_long(0x7EF341)
+		a.BNE(6)
+		a.LDA_imm8_b(1)
+		a.STA_long(0x7EF341)
+	}
+}
+
+func (g *Game) syncInventorySwap1GenerateAsm(s *games.SyncableBitU8, asm *asm.Emitter, initial, updated, newBits uint8) {
+	const longAddr = 0x7EF38C
+	// make flute (inactive) and flute (activated) mutually exclusive:
+	asm.LDA_long(longAddr)
+	if newBits&0b00000011 != 0 {
+		asm.AND_imm8_b(0b11111100)
+		s.UpdatingTo = initial&0b11111100 | newBits
+	} else {
+		s.UpdatingTo = initial | newBits
+	}
+	asm.ORA_imm8_b(newBits)
+	asm.STA_long(longAddr)
+}
+
+func (g *Game) syncInventorySwap2Updated(s *games.SyncableBitU8, a *asm.Emitter, initial, updated uint8) {
+	// bow/silver:
+	if initial&IS2SilverBow == 0 && updated&IS2SilverBow != 0 {
+		// set silver bow in inventory:
+		a.Comment("set Silver Bow in inventory:")
+		a.LDA_long(0x7EF340)
+		a.BNE(0xe)
+
+		a.LDA_long(0x7EF377) // load arrows
+		a.CMP_imm8_b(0x01)   // are arrows present?
+		a.LDA_imm8_b(3)      // bow level; 1 = wood, 3 = silver
+		a.ADC_imm8_b(0x00)   // add +1 to bow if arrows are present
+
+		a.STA_long(0x7EF340)
+	} else if initial&IS2WoodBow == 0 && updated&IS2WoodBow != 0 {
+		// set bow in inventory:
+		a.Comment("set Bow in inventory:")
+		a.LDA_long(0x7EF340)
+		a.BNE(0xe)
+
+		a.LDA_long(0x7EF377) // load arrows
+		a.CMP_imm8_b(0x01)   // are arrows present?
+		a.LDA_imm8_b(1)      // bow level; 1 = wood, 3 = silver
+		a.ADC_imm8_b(0x00)   // add +1 to bow if arrows are present
+
+		a.STA_long(0x7EF340)
+	}
+}
+
+func (g *Game) syncWorldStateUpdated(s *games.SyncableMaxU8, asm *asm.Emitter, initial, updated uint8) {
+	if initial < 2 && updated >= 2 {
+		asm.Comment("load sprite gfx:")
+		asm.JSL(g.romFunctions[fnLoadSpriteGfx])
+
+		// overworld only:
+		if g.local.Module == 0x09 && g.local.SubModule == 0 {
+			asm.Comment("reset overworld:")
+			asm.LDA_imm8_b(0x00)
+			asm.STA_dp(0x1D)
+			asm.STA_dp(0x8C)
+			asm.JSL(g.romFunctions[fnOverworldFinishMirrorWarp])
+			// clear sfx:
+			asm.LDA_imm8_b(0x05)
+			asm.STA_abs(0x012D)
+		}
+	}
+}
+
+// progress flags 1/2:
+func (g *Game) syncProgress1GenerateUpdate(s *games.SyncableCustomU8, asm *asm.Emitter) bool {
+	offset := s.Offset
+	local := s.SyncableGame.LocalSyncablePlayer()
+	localSRAM := local.ReadableMemory(games.SRAM)
+	initial := localSRAM.ReadU8(offset)
+
+	// check to make sure zelda telepathic follower removed if have uncle's gear:
+	if initial&0x01 == 0x01 && localSRAM.ReadU8(0x3CC) == 0x05 {
+		asm.Comment("already have uncle's gear; remove telepathic zelda follower:")
+		asm.LDA_long(0x7EF3CC)
+		asm.CMP_imm8_b(0x05)
+		asm.BNE(0x06)
+		asm.LDA_imm8_b(0x00)   // 2 bytes
+		asm.STA_long(0x7EF3CC) // 4 bytes
+		return true
+	}
+
+	newBits := initial
+	for _, p := range g.RemoteSyncablePlayers() {
+		v := p.ReadableMemory(games.SRAM).ReadU8(offset)
+		// if local player has not achieved uncle leaving house, leave it cleared otherwise link never wakes up:
+		if initial&0x10 == 0 {
+			v &= ^uint8(0x10)
+		}
+		newBits |= v
+	}
+
+	if newBits == initial {
+		// no change:
+		return false
+	}
+
+	// notify local player of new item received:
+	s.PendingUpdate = true
+	s.UpdatingTo = newBits
+
+	orBits := newBits & ^initial
+	asm.Comment(fmt.Sprintf("progress1 |= %#08b", orBits))
+
+	addr := localSRAM.BusAddress(offset)
+	asm.LDA_imm8_b(orBits)
+	asm.ORA_long(addr)
+	asm.STA_long(addr)
+
+	// if receiving uncle's gear, remove zelda telepathic follower:
+	if newBits&0x01 == 0x01 && initial&0x01 == 0 {
+		asm.Comment("received uncle's gear; remove telepathic zelda follower:")
+		// this may run when link is still in bed so uncle adds the follower before link can get up:
+		asm.LDA_long(0x7EF3CC)
+		asm.CMP_imm8_b(0x05)
+		asm.BNE(0x06)
+		asm.LDA_imm8_b(0x00)   // 2 bytes
+		asm.STA_long(0x7EF3CC) // 4 bytes
+	}
+
+	return true
+}
+
+// progress flags 2/2:
+func (g *Game) syncProgress2GenerateUpdate(s *games.SyncableCustomU8, asm *asm.Emitter) bool {
+	offset := s.Offset
+	initial := s.SyncableGame.LocalSyncablePlayer().ReadableMemory(games.SRAM).ReadU8(offset)
+
+	newBits := initial
+	for _, p := range g.RemoteSyncablePlayers() {
+		v := p.ReadableMemory(games.SRAM).ReadU8(offset)
+		newBits |= v
+	}
+
+	if newBits == initial {
+		// no change:
+		return false
+	}
+
+	// notify local player of new item received:
+	s.PendingUpdate = true
+	s.UpdatingTo = newBits
+
+	orBits := newBits & ^initial
+	asm.Comment(fmt.Sprintf("progress2 |= %#08b", orBits))
+
+	addr := 0x7EF000 + uint32(offset)
+	asm.LDA_imm8_b(orBits)
+	asm.ORA_long(addr)
+	asm.STA_long(addr)
+
+	// remove purple chest follower if purple chest opened:
+	if newBits&0x10 == 0x10 {
+		asm.Comment("lose purple chest follower:")
+		asm.LDA_long(0x7EF3CC)
+		asm.CMP_imm8_b(0x0C)
+		asm.BNE(0x06)
+		asm.LDA_imm8_b(0x00)   // 2 bytes
+		asm.STA_long(0x7EF3CC) // 4 bytes
+	}
+	// lose smithy follower if already rescued:
+	if newBits&0x20 == 0x20 {
+		asm.Comment("lose smithy follower:")
+		asm.LDA_long(0x7EF3CC)
+		asm.CMP_imm8_b(0x07)
+		asm.BNE(0x06)
+		asm.LDA_imm8_b(0x00)   // 2 bytes
+		asm.STA_long(0x7EF3CC) // 4 bytes
+		asm.CMP_imm8_b(0x08)
+		asm.BNE(0x06)
+		asm.LDA_imm8_b(0x00)   // 2 bytes
+		asm.STA_long(0x7EF3CC) // 4 bytes
+	}
+
+	return true
+}
@@ -1,4 +1,4 @@
-package alttp
+package zelda3
 
 import (
 	"fmt"
@@ -62,7 +62,7 @@ func (g *Game) readWRAM() {
 			}
 			w.Value = v
 			w.ValueUsed = v
-			log.Printf("alttp: wram[$%04x] -> %08x (%v), %04x   ; %s\n", offs, w.Timestamp, now.Format(time.RFC3339Nano), w.Value, w.Name)
+			log.Printf("zelda3: wram[$%04x] -> %08x (%v), %04x   ; %s\n", offs, w.Timestamp, now.Format(time.RFC3339Nano), w.Value, w.Name)
 		}
 	}
 
@@ -79,7 +79,7 @@ func (g *Game) readWRAM() {
 					w.Timestamp = nowTs
 				}
 				w.ValueUsed = currentKeyCount
-				log.Printf("alttp: wram[$%04x] -> %08x (%v), %04x   ; current key counter\n", dungeonOffs, w.Timestamp, now.Format(time.RFC3339Nano), w.ValueUsed)
+				log.Printf("zelda3: wram[$%04x] -> %08x (%v), %04x   ; current key counter\n", dungeonOffs, w.Timestamp, now.Format(time.RFC3339Nano), w.ValueUsed)
 			}
 		}
 	}
@@ -100,6 +100,11 @@ func (g *Game) doSyncSmallKeys(a *asm.Emitter) (updated bool) {
 		// find latest timestamp among players:
 		winner := local
 		for _, p := range g.RemotePlayers() {
+			// only sync with teammates:
+			if p.Team() != local.Team() {
+				continue
+			}
+
 			rw, ok := p.WRAM[offs]
 			if !ok {
 				continue
@@ -123,7 +128,7 @@ func (g *Game) doSyncSmallKeys(a *asm.Emitter) (updated bool) {
 		lw.IsWriting = true
 		lw.Timestamp = ww.Timestamp
 		lw.ValueExpected = ww.Value
-		log.Printf("alttp: keys[$%04x] <- %08x, %02x <- player '%s'\n", offs, ww.Timestamp, ww.Value, winner.Name())
+		log.Printf("zelda3: keys[$%04x] <- %08x, %02x <- player '%s'\n", offs, ww.Timestamp, ww.Value, winner.Name())
 
 		dungeonNumber := offs - smallKeyFirst
 		notification := fmt.Sprintf("update %s to %d from %s", lw.Name, ww.Value, winner.Name())
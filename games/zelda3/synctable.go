@@ -0,0 +1,251 @@
+package zelda3
+
+import (
+	_ "embed"
+	"fmt"
+	"log"
+	"o2/games"
+	"o2/util/env"
+)
+
+//go:embed synctable.yaml
+var defaultSyncTableYAML []byte
+
+// defaultSyncTable describes the ALTTP and VT randomizer syncable items:
+var defaultSyncTable = mustParseSyncTable(defaultSyncTableYAML)
+
+//go:embed synctable_sm.yaml
+var smSyncTableYAML []byte
+
+// smSyncTable describes the syncable items of Super Metroid in combo ROMs:
+var smSyncTable = mustParseSyncTable(smSyncTableYAML)
+
+func mustParseSyncTable(data []byte) *games.SyncTable {
+	table, err := games.ParseSyncTable(data, "yaml")
+	if err != nil {
+		panic(err)
+	}
+	if err = checkSyncTable(table); err != nil {
+		panic(err)
+	}
+	return table
+}
+
+// loadSyncTable picks the sync table for a new game: the file named by $O2_SYNC_TABLE, if any, then the
+// layout's table, then the built-in ALTTP table
+func (g *Game) loadSyncTable() *games.SyncTable {
+	if path := env.GetOrDefault("O2_SYNC_TABLE", ""); path != "" {
+		table, err := games.LoadSyncTable(path)
+		if err == nil {
+			err = checkSyncTable(table)
+		}
+		if err == nil {
+			log.Printf("zelda3: loaded sync table '%s'\n", path)
+			return table
+		}
+		log.Printf("zelda3: %v; using built-in sync table\n", err)
+	}
+
+	if g.layout != nil && g.layout.SyncTable != nil {
+		return g.layout.SyncTable
+	}
+	return defaultSyncTable
+}
+
+func (g *Game) syncTableOrDefault() *games.SyncTable {
+	if g.syncTable == nil {
+		// spectators and tests do not come through NewGame:
+		g.syncTable = g.loadSyncTable()
+	}
+	return g.syncTable
+}
+
+// checkSyncTable verifies that all names a table refers to are known to this game and that all offsets are in
+// memory it reads from the SNES
+func checkSyncTable(table *games.SyncTable) error {
+	var g Game
+	for i := range table.Syncables {
+		e := &table.Syncables[i]
+		if g.syncEnabledPtr(e.Enable) == nil {
+			return fmt.Errorf("zelda3: sync table: offset $%x: unknown enable flag '%s'", uint32(e.Offset), e.Enable)
+		}
+		if err := checkSyncTableOffset(e); err != nil {
+			return err
+		}
+		if _, err := g.syncTableWhen(e); err != nil {
+			return err
+		}
+		if e.Strategy == games.SyncStrategyCustom && syncCustoms[e.Custom] == nil {
+			return fmt.Errorf("zelda3: sync table: offset $%x: unknown custom strategy '%s'", uint32(e.Offset), e.Custom)
+		}
+		if e.OnUpdated != "" && syncHooks[e.OnUpdated] == nil {
+			return fmt.Errorf("zelda3: sync table: offset $%x: unknown hook '%s'", uint32(e.Offset), e.OnUpdated)
+		}
+	}
+	return nil
+}
+
+// checkSyncTableOffset verifies that an entry's offset is within its kind of memory as the game reads it; WRAM is
+// only read at the offsets in Player.WRAM
+func checkSyncTableOffset(e *games.SyncTableEntry) error {
+	kind, err := e.MemoryKind()
+	if err != nil {
+		return err
+	}
+
+	offs, size := uint64(e.Offset), uint64(1)
+	if e.Strategy == games.SyncStrategyBits16 {
+		size = 2
+	}
+	switch kind {
+	case games.SRAM:
+		if offs+size > uint64(len(SRAMShadow{})) {
+			return fmt.Errorf("zelda3: sync table: offset $%x: beyond the $%x bytes of SRAM that sync", offs, len(SRAMShadow{}))
+		}
+	case games.WRAM:
+		if !isWatchedWRAM(offs, size) {
+			return fmt.Errorf("zelda3: sync table: offset $%x: WRAM is not read from the SNES there", offs)
+		}
+	case games.SM:
+		if offs+size > smShadowSize {
+			return fmt.Errorf("zelda3: sync table: offset $%x: beyond the $%x bytes of Super Metroid save data that sync", offs, smShadowSize)
+		}
+	}
+	return nil
+}
+
+// isWatchedWRAM determines if size bytes at WRAM offset offs are one of the values initSync adds to Player.WRAM
+func isWatchedWRAM(offs, size uint64) bool {
+	if offs == 0x0400 {
+		// current dungeon supertile state:
+		return size <= 2
+	}
+	return size == 1 && offs >= uint64(smallKeyFirst) && offs <= uint64(smallKeyLast)
+}
+
+// syncEnabledPtr maps a sync table's "enable" names to the game's sync options
+func (g *Game) syncEnabledPtr(name string) *bool {
+	switch name {
+	case "items":
+		return &g.SyncItems
+	case "dungeonItems":
+		return &g.SyncDungeonItems
+	case "progress":
+		return &g.SyncProgress
+	case "hearts":
+		return &g.SyncHearts
+	case "smallKeys":
+		return &g.SyncSmallKeys
+	case "underworld":
+		return &g.SyncUnderworld
+	case "overworld":
+		return &g.SyncOverworld
+	}
+	return nil
+}
+
+// syncTableWhen determines if an entry applies to this ROM; "vt" and "!vt" select by VT randomizer
+func (g *Game) syncTableWhen(e *games.SyncTableEntry) (bool, error) {
+	switch e.When {
+	case "":
+		return true, nil
+	case "vt":
+		return g.rom == nil || g.isVTRandomizer(), nil
+	case "!vt":
+		return g.rom == nil || !g.isVTRandomizer(), nil
+	}
+	return false, fmt.Errorf("zelda3: sync table: offset $%x: unknown condition '%s'", uint32(e.Offset), e.When)
+}
+
+// applySyncTable creates the syncable items described by the table
+func (g *Game) applySyncTable(table *games.SyncTable) error {
+	for i := range table.Syncables {
+		e := &table.Syncables[i]
+		if ok, err := g.syncTableWhen(e); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+
+		enabled := g.syncEnabledPtr(e.Enable)
+		if enabled == nil {
+			return fmt.Errorf("zelda3: sync table: offset $%x: unknown enable flag '%s'", uint32(e.Offset), e.Enable)
+		}
+		kind, err := e.MemoryKind()
+		if err != nil {
+			return err
+		}
+		// tables that did not come through checkSyncTable must not be truncated to a different offset:
+		if err = checkSyncTableOffset(e); err != nil {
+			return err
+		}
+
+		offset := uint16(e.Offset)
+		items := g.syncableItems
+		if kind == games.SM {
+			// Super Metroid's offsets overlap ALTTP's so its syncables are kept apart:
+			g.syncableItems = g.smSyncableItems
+		}
+		s, err := g.newSyncTableStrategy(e, offset, kind, enabled)
+		g.syncableItems = items
+		if err != nil {
+			return err
+		}
+
+		if e.OnUpdated != "" {
+			hook := syncHooks[e.OnUpdated]
+			if hook == nil {
+				return fmt.Errorf("zelda3: sync table: offset $%x: unknown hook '%s'", uint32(e.Offset), e.OnUpdated)
+			}
+			if err = hook(g, s); err != nil {
+				return fmt.Errorf("zelda3: sync table: offset $%x: %w", uint32(e.Offset), err)
+			}
+		}
+	}
+	return nil
+}
+
+// newSyncTableStrategy creates the sync strategy for a sync table entry and registers it in syncableItems
+func (g *Game) newSyncTableStrategy(e *games.SyncTableEntry, offset uint16, kind games.MemoryKind, enabled *bool) (games.SyncStrategy, error) {
+	var s games.SyncStrategy
+	switch e.Strategy {
+	case games.SyncStrategyBits:
+		b := g.NewSyncableBitU8(offset, enabled, e.Names, nil)
+		b.MemoryKind = kind
+		if e.Mask != 0 {
+			b.SyncMask = uint8(e.Mask)
+		}
+		b.NotificationFormat = e.Notification
+		s = b
+	case games.SyncStrategyBits16:
+		b := g.NewSyncableBitU16(offset, enabled, e.Names, nil)
+		b.MemoryKind = kind
+		if e.Mask != 0 {
+			b.SyncMask = uint16(e.Mask)
+		}
+		b.NotificationFormat = e.Notification
+		s = b
+	case games.SyncStrategyMax:
+		m := g.NewSyncableMaxU8(offset, enabled, e.Names, nil)
+		m.MemoryKind = kind
+		if e.AbsMax != 0 {
+			m.AbsMax = uint8(e.AbsMax)
+		}
+		m.NotificationFormat = e.Notification
+		s = m
+	case games.SyncStrategyCustom:
+		create := syncCustoms[e.Custom]
+		if create == nil {
+			return nil, fmt.Errorf("zelda3: sync table: offset $%x: unknown custom strategy '%s'", uint32(e.Offset), e.Custom)
+		}
+		s = create(g, offset, enabled, e.Names)
+	case games.SyncStrategyScript:
+		sc := games.NewSyncableScript(g, uint32(offset), enabled, e.SyncScript())
+		sc.MemoryKind = kind
+		g.syncableItems[offset] = sc
+		s = sc
+	default:
+		return nil, fmt.Errorf("zelda3: sync table: offset $%x: unknown strategy '%s'", uint32(e.Offset), e.Strategy)
+	}
+	return s, nil
+}
@@ -0,0 +1,170 @@
+package zelda3
+
+import (
+	"o2/games"
+	"o2/snes/emulator"
+	"testing"
+)
+
+func TestSyncTable_Default(t *testing.T) {
+	tests := []struct {
+		romTitle  string
+		wantCount int
+		want      []uint16
+		wantNot   []uint16
+	}{
+		{romTitle: "ZELDANODENSETSU", wantCount: 46, want: []uint16{0x340, 0x341, 0x344, 0x34C, 0x359}, wantNot: []uint16{0x38C, 0x38E, 0x410}},
+		{romTitle: "VT test", wantCount: 50, want: []uint16{0x38C, 0x38E, 0x410, 0x42A, 0x359}, wantNot: []uint16{0x340, 0x341, 0x344, 0x34C}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.romTitle, func(t *testing.T) {
+			rom, err := emulator.MakeTestROM(tt.romTitle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			g := factory.NewGame(rom).(*Game)
+			g.Reset()
+
+			if len(g.syncableItems) != tt.wantCount {
+				t.Errorf("len(syncableItems) = %d, want %d", len(g.syncableItems), tt.wantCount)
+			}
+			for _, offs := range tt.want {
+				if g.syncableItems[offs] == nil {
+					t.Errorf("syncableItems[$%03x] missing", offs)
+				}
+			}
+			for _, offs := range tt.wantNot {
+				if g.syncableItems[offs] != nil {
+					t.Errorf("syncableItems[$%03x] should not be synced", offs)
+				}
+			}
+
+			sword := g.syncableItems[0x359].(*games.SyncableMaxU8)
+			if sword.AbsMax != 4 || sword.OnUpdated == nil || sword.IsEnabledPtr != &g.SyncItems {
+				t.Errorf("sword syncable not configured from table")
+			}
+		})
+	}
+}
+
+func TestSyncTable_Parse(t *testing.T) {
+	json := `{"syncables": [
+		{"offset": "$3A0", "strategy": "bits", "mask": "0x0F", "names": ["Ocarina"], "enable": "items", "notification": "{player} found the {name}"},
+		{"offset": 62332, "memory": "wram", "strategy": "max", "absMax": 3, "enable": "progress", "onUpdated": "worldState"}
+	]}`
+	yaml := `
+syncables:
+  - {offset: 0x3A0, strategy: bits, mask: 0x0F, names: [Ocarina], enable: items, notification: "{player} found the {name}"}
+  - {offset: 62332, memory: wram, strategy: max, absMax: 3, enable: progress, onUpdated: worldState}
+`
+	for _, format := range []string{"json", "yaml"} {
+		data := json
+		if format == "yaml" {
+			data = yaml
+		}
+		table, err := games.ParseSyncTable([]byte(data), format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if err = checkSyncTable(table); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		g := &Game{syncableItems: make(map[uint16]games.SyncStrategy)}
+		if err = g.applySyncTable(table); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		bits := g.syncableItems[0x3A0].(*games.SyncableBitU8)
+		if bits.SyncMask != 0x0F || bits.NotificationFormat != "{player} found the {name}" {
+			t.Errorf("%s: bits = %+v", format, bits)
+		}
+		max := g.syncableItems[0xF37C].(*games.SyncableMaxU8)
+		if max.MemoryKind != games.WRAM || max.AbsMax != 3 || max.OnUpdated == nil || max.IsEnabledPtr != &g.SyncProgress {
+			t.Errorf("%s: max = %+v", format, max)
+		}
+	}
+
+	scripted := `
+syncables:
+  - offset: 0x3A2
+    strategy: script
+    enable: items
+    source: |
+      def generate_update(ctx):
+          return None
+`
+	table, err := games.ParseSyncTable([]byte(scripted), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Game{syncableItems: make(map[uint16]games.SyncStrategy)}
+	if err = g.applySyncTable(table); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.syncableItems[0x3A2].(*games.SyncableScript); !ok {
+		t.Errorf("syncableItems[$3A2] = %T, want *games.SyncableScript", g.syncableItems[0x3A2])
+	}
+
+	if got := games.FormatReceived("{player} found the {name}", "Ocarina", "Link"); got != "Link found the Ocarina" {
+		t.Errorf("FormatReceived() = %q", got)
+	}
+}
+
+func TestSyncTable_Invalid(t *testing.T) {
+	tables := []string{
+		`syncables: [{offset: 0x340, strategy: xor, enable: items}]`,
+		`syncables: [{offset: 0x340, strategy: bits, mask: 0x100, enable: items}]`,
+		`syncables: [{offset: 0x340, strategy: custom, enable: items}]`,
+		`syncables: [{offset: 0x340, strategy: max, enable: items, typo: 1}]`,
+	}
+	for _, data := range tables {
+		if _, err := games.ParseSyncTable([]byte(data), "yaml"); err == nil {
+			t.Errorf("ParseSyncTable(%q) should fail", data)
+		}
+	}
+
+	unknown := []string{
+		`syncables: [{offset: 0x340, strategy: max, enable: everything}]`,
+		`syncables: [{offset: 0x340, strategy: custom, custom: boomerang, enable: items}]`,
+		`syncables: [{offset: 0x340, strategy: max, enable: items, onUpdated: explode}]`,
+		`syncables: [{offset: 0x340, strategy: max, enable: items, when: sometimes}]`,
+		`syncables: [{offset: 0x600, strategy: max, enable: items}]`,
+		`syncables: [{offset: 0x4FF, strategy: bits16, enable: items}]`,
+		`syncables: [{offset: 0x3A1, memory: wram, strategy: max, enable: items}]`,
+		`syncables: [{offset: 0x1F37C, memory: wram, strategy: max, enable: items}]`,
+		`syncables: [{offset: 0x150, memory: sm, strategy: bits, enable: items}]`,
+	}
+	for _, data := range unknown {
+		table, err := games.ParseSyncTable([]byte(data), "yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = checkSyncTable(table); err == nil {
+			t.Errorf("checkSyncTable(%q) should fail", data)
+		}
+	}
+}
+
+func TestReadableMemory_OutOfRange(t *testing.T) {
+	var p Player
+	p.WRAM = make(map[uint16]*SyncableWRAM)
+	p.WRAM[0x0400] = &SyncableWRAM{ValueUsed: 0x1234}
+
+	sram, wram := p.ReadableMemory(games.SRAM), p.ReadableMemory(games.WRAM)
+	if v := sram.ReadU8(0x600); v != 0xFF {
+		t.Errorf("SRAM ReadU8($600) = $%02x, want $FF", v)
+	}
+	if v := sram.ReadU16(0x4FF); v != 0xFFFF {
+		t.Errorf("SRAM ReadU16($4FF) = $%04x, want $FFFF", v)
+	}
+	if v := wram.ReadU16(0x0400); v != 0x1234 {
+		t.Errorf("WRAM ReadU16($400) = $%04x, want $1234", v)
+	}
+	if v := wram.ReadU8(0x03A1); v != 0xFF {
+		t.Errorf("WRAM ReadU8($3A1) = $%02x, want $FF", v)
+	}
+	if v := wram.ReadU8(0x10400); v != 0xFF {
+		t.Errorf("WRAM ReadU8($10400) = $%02x, want $FF", v)
+	}
+}
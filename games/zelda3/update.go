@@ -1,4 +1,4 @@
-package alttp
+package zelda3
 
 import (
 	"bytes"
@@ -45,10 +45,16 @@ func (g *Game) updateWRAM() {
 	// assume 8-bit mode for accumulator and index registers:
 	a.AssumeSEP(0x30)
 
-	a.Comment("don't update if link is currently frozen:")
-	a.LDA_abs(0x02E4)
-	a.BEQ(0x01)
-	a.RTS()
+	if g.smLayout() != nil {
+		// the routine runs from whichever game's frame hook comes first:
+		g.generateSMGuard(&a)
+	}
+	if !g.local.InSM {
+		a.Comment("don't update if link is currently frozen:")
+		a.LDA_abs(0x02E4)
+		a.BEQ(0x01)
+		a.RTS()
+	}
 
 	// custom asm overrides update asm generation:
 	if !g.generateCustomAsm(&a) {
@@ -71,43 +77,53 @@ func (g *Game) updateWRAM() {
 	log.Print(a.Text.String())
 
 	if a.Code.Len() > 255 {
-		panic(fmt.Errorf("alttp: generated update ASM larger than 255 bytes: %d", a.Code.Len()))
+		panic(fmt.Errorf("zelda3: generated update ASM larger than 255 bytes: %d", a.Code.Len()))
 	}
 
 	// prevent more updates until the upcoming write completes:
 	g.updateStage = 1
-	log.Println("alttp: update: write started")
+	log.Println("zelda3: update: write started")
 
 	// calculate target address in FX Pak Pro address space:
 	// SRAM starts at $E00000
 	target := lorom.BusAddressToPak(targetSNES)
 	g.lastUpdateTarget = target
 
+	writes := []snes.Write{
+		{
+			Address: target,
+			Size:    uint8(a.Code.Len()),
+			Data:    a.Code.Bytes(),
+		},
+	}
+	if g.smLayout() != nil {
+		// update the JSR instruction in Super Metroid's altPreMain too:
+		writes = append(writes, snes.Write{
+			Address: lorom.BusAddressToPak(altPreMainAddr + 5),
+			Size:    1,
+			Data:    []byte{uint8(targetSNES >> 8)},
+		})
+	}
+	// finally, update the JSR instruction to point to the updated routine:
+	writes = append(writes, snes.Write{
+		// JSR $7C00 | JSR $7E00
+		// update the $7C or $7E byte in the JSR instruction:
+		Address: lorom.BusAddressToPak(preMainAddr + 2),
+		Size:    1,
+		Data:    []byte{uint8(targetSNES >> 8)},
+	})
+
 	// write generated asm routine to SRAM:
 	err := q.MakeWriteCommands(
-		[]snes.Write{
-			{
-				Address: target,
-				Size:    uint8(a.Code.Len()),
-				Data:    a.Code.Bytes(),
-			},
-			// finally, update the JSR instruction to point to the updated routine:
-			{
-				// JSR $7C00 | JSR $7E00
-				// update the $7C or $7E byte in the JSR instruction:
-				Address: lorom.BusAddressToPak(preMainAddr + 2),
-				Size:    1,
-				Data:    []byte{uint8(targetSNES >> 8)},
-			},
-		},
+		writes,
 		func(cmd snes.Command, err error) {
-			log.Println("alttp: update: write completed")
+			log.Println("zelda3: update: write completed")
 
 			defer g.updateLock.Unlock()
 			g.updateLock.Lock()
 
 			if g.updateStage != 1 {
-				log.Printf("alttp: update: write complete but updateStage = %d (should be 1)\n", g.updateStage)
+				log.Printf("zelda3: update: write complete but updateStage = %d (should be 1)\n", g.updateStage)
 			}
 
 			g.updateStage = 2
@@ -120,13 +136,13 @@ func (g *Game) updateWRAM() {
 		},
 	).EnqueueTo(q)
 	if err != nil {
-		log.Println(fmt.Errorf("alttp: update: error enqueuing snes write for update routine: %w", err))
+		log.Println(fmt.Errorf("zelda3: update: error enqueuing snes write for update routine: %w", err))
 		return
 	}
 }
 
 func (g *Game) enqueueUpdateCheckRead(q []snes.Read) []snes.Read {
-	log.Println("alttp: update: enqueueUpdateCheckRead")
+	log.Println("zelda3: update: enqueueUpdateCheckRead")
 	// read the first instruction of the last update routine to check if it completed (if it's a RTS):
 	addr := g.lastUpdateTarget
 	if addr != 0xFFFFFF {
@@ -153,10 +169,40 @@ func (g *Game) generateCustomAsm(a *asm.Emitter) bool {
 func (g *Game) generateUpdateAsm(a *asm.Emitter) bool {
 	updated := false
 
-	// generate update ASM code for any 8-bit values:
-	for offs, item := range g.syncableItems {
+	// ALTTP's state is out of reach while the player is in Super Metroid; it syncs once they cross back over:
+	inSM := g.local.InSM
+
+	if !inSM {
+		// generate update ASM code for any 8-bit values:
+		for offs, item := range g.syncableItems {
+			if item.Size() != 1 {
+				a.Comment(fmt.Sprintf("TODO: ignoring non-1 size syncableItem[%#04x]", offs))
+				continue
+			}
+			if !item.IsEnabled() {
+				continue
+			}
+			if !item.CanUpdate() {
+				continue
+			}
+
+			// clone the assembler to a temporary:
+			ta := a.Clone()
+			// generate the update asm routine in the temporary assembler:
+			u := item.GenerateUpdate(ta)
+			if u {
+				// don't emit the routine if it pushes us over the code size limit:
+				if ta.Code.Len()+a.Code.Len()+10 <= 255 {
+					a.Append(ta)
+					updated = true
+				}
+			}
+		}
+	}
+
+	// generate update ASM code for Super Metroid's 8-bit values:
+	for _, item := range g.smSyncableItems {
 		if item.Size() != 1 {
-			a.Comment(fmt.Sprintf("TODO: ignoring non-1 size syncableItem[%#04x]", offs))
 			continue
 		}
 		if !item.IsEnabled() {
@@ -179,7 +225,7 @@ func (g *Game) generateUpdateAsm(a *asm.Emitter) bool {
 		}
 	}
 
-	if g.SyncSmallKeys {
+	if g.SyncSmallKeys && !inSM {
 		// clone the assembler to a temporary:
 		ta := a.Clone()
 		// generate the update asm routine in the temporary assembler:
@@ -193,7 +239,7 @@ func (g *Game) generateUpdateAsm(a *asm.Emitter) bool {
 		}
 	}
 
-	if g.SyncOverworld {
+	if g.SyncOverworld && !inSM {
 		for i := range g.overworld {
 			s := &g.overworld[i]
 			if !s.IsEnabled() {
@@ -225,7 +271,7 @@ func (g *Game) generateUpdateAsm(a *asm.Emitter) bool {
 		a16.Comment("switch to 16-bit mode:")
 		a16.REP(0x30)
 
-		if g.SyncTunicColor {
+		if g.SyncTunicColor && !inSM {
 			// update Link's palette:
 			local := g.LocalPlayer()
 			lightColor := local.PlayerColor
@@ -298,7 +344,7 @@ func (g *Game) generateUpdateAsm(a *asm.Emitter) bool {
 		}
 
 		// sync all the underworld supertile state:
-		if g.SyncUnderworld {
+		if g.SyncUnderworld && !inSM {
 			for i := range g.underworld {
 				s := &g.underworld[i]
 				if !s.IsEnabled() {
@@ -323,7 +369,34 @@ func (g *Game) generateUpdateAsm(a *asm.Emitter) bool {
 		}
 
 		// sync any other u16 data:
-		for _, s := range g.syncableBitU16 {
+		if !inSM {
+			for _, s := range g.syncableBitU16 {
+				if !s.IsEnabled() {
+					continue
+				}
+				if !s.CanUpdate() {
+					continue
+				}
+
+				// clone the assembler to a temporary:
+				ta := a16.Clone()
+				// generate the update asm routine in the temporary assembler:
+				u := s.GenerateUpdate(ta)
+				if u {
+					// don't emit the routine if it pushes us over the code size limit:
+					if ta.Code.Len()+a16.Code.Len()+a.Code.Len()+10 <= 255 {
+						a16.Append(ta)
+						updated16 = true
+					}
+				}
+			}
+		}
+
+		// sync Super Metroid's u16 data:
+		for _, s := range g.smSyncableItems {
+			if s.Size() != 2 {
+				continue
+			}
 			if !s.IsEnabled() {
 				continue
 			}
@@ -1,4 +1,4 @@
-package smz3
+package zelda3
 
 import (
 	"fmt"
@@ -63,6 +63,15 @@ type PlayerViewModel struct {
 func (g *Game) updatePlayersList() {
 	g.shouldUpdatePlayersList = false
 
+	playerViewModels := g.playerViewModels()
+
+	// send the players list:
+	if viewModels := g.viewModels; viewModels != nil {
+		viewModels.NotifyView("game/players", playerViewModels)
+	}
+}
+
+func (g *Game) playerViewModels() []*PlayerViewModel {
 	activePlayers := g.ActivePlayers()
 
 	playerViewModels := make([]*PlayerViewModel, 0, len(activePlayers))
@@ -93,7 +102,7 @@ func (g *Game) updatePlayersList() {
 
 		playerViewModels = append(playerViewModels, &PlayerViewModel{
 			Index: p.Index(),
-			Team:  int(p.Team),
+			Team:  int(p.Team()),
 			Name:  name,
 			Color: p.PlayerColor,
 
@@ -104,8 +113,7 @@ func (g *Game) updatePlayersList() {
 		})
 	}
 
-	// send the players list:
-	g.viewModels.NotifyView("game/players", playerViewModels)
+	return playerViewModels
 }
 
 func (g *Game) CommandFor(command string) (interfaces.Command, error) {
@@ -132,6 +140,7 @@ type setFieldArgs struct {
 	SyncOverworld    *bool `json:"syncOverworld"`
 	SyncChests       *bool `json:"syncChests"`
 	SyncTunicColor   *bool `json:"syncTunicColor"`
+	SyncSRAMDelta    *bool `json:"syncSRAMDelta"`
 }
 
 func (c *setFieldCmd) CreateArgs() interfaces.CommandArgs { return &setFieldArgs{} }
@@ -180,6 +189,10 @@ func (c *setFieldCmd) Execute(args interfaces.CommandArgs) error {
 		g.SyncTunicColor = *f.SyncTunicColor
 		g.clean = false
 	}
+	if f.SyncSRAMDelta != nil {
+		g.SyncSRAMDelta = *f.SyncSRAMDelta
+		g.clean = false
+	}
 	if f.PlayerColor != nil {
 		g.local.PlayerColor = *f.PlayerColor
 		g.shouldUpdatePlayersList = true
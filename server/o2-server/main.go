@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"o2/server"
+	"o2/util/env"
+)
+
+func main() {
+	var err error
+
+	listenAddr := flag.String(
+		"listen",
+		net.JoinHostPort(
+			env.GetOrDefault("O2_SERVER_LISTEN_HOST", "0.0.0.0"),
+			env.GetOrDefault("O2_SERVER_LISTEN_PORT", "4590"),
+		),
+		"UDP host:port to listen on",
+	)
+	idleTimeout := flag.Duration("idle", server.DefaultIdleTimeout, "expire players after this much time without a packet")
+	flag.Parse()
+
+	log.SetFlags(log.LstdFlags | log.Lmicroseconds | log.LUTC)
+
+	var conn net.PacketConn
+	conn, err = net.ListenPacket("udp", *listenAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	s := server.New(conn)
+	s.IdleTimeout = *idleTimeout
+
+	err = s.Serve()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,331 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"log"
+	"net"
+	"o2/client"
+	"o2/client/protocol03"
+	"sync"
+	"time"
+)
+
+// MaxPlayers is the maximum number of players the server will assign indexes to in a single group
+const MaxPlayers = 256
+
+// DefaultIdleTimeout is how long a player may go without sending a packet before being expired from its group
+const DefaultIdleTimeout = 15 * time.Second
+
+type player struct {
+	index    int
+	addr     net.Addr
+	sector   uint64
+	lastSeen time.Time
+}
+
+type spectator struct {
+	addr     net.Addr
+	lastSeen time.Time
+}
+
+type group struct {
+	name    string
+	players [MaxPlayers]*player
+	byAddr  map[string]*player
+
+	spectators map[string]*spectator
+}
+
+func (g *group) isEmpty() bool {
+	return len(g.byAddr) == 0 && len(g.spectators) == 0
+}
+
+// Server is a reference implementation of the protocol 0x03 group server
+type Server struct {
+	conn net.PacketConn
+
+	IdleTimeout time.Duration
+	// Now returns the server's clock; replaceable for tests
+	Now func() time.Time
+
+	groupsLock sync.Mutex
+	groups     map[string]*group
+
+	muteLog bool
+}
+
+func New(conn net.PacketConn) *Server {
+	return &Server{
+		conn:        conn,
+		IdleTimeout: DefaultIdleTimeout,
+		Now:         time.Now,
+		groups:      make(map[string]*group),
+	}
+}
+
+func (s *Server) MuteLog(muted bool) {
+	s.muteLog = muted
+}
+
+func (s *Server) log(fmt string, args ...interface{}) {
+	if s.muteLog {
+		return
+	}
+	log.Printf(fmt, args...)
+}
+
+// Serve reads packets from conn until it is closed and expires idle players periodically
+func (s *Server) Serve() (err error) {
+	conn := s.conn
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.ExpireIdle()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	s.log("server: listening on '%s'\n", conn.LocalAddr())
+
+	// we only need a single receive buffer:
+	b := make([]byte, 1500)
+	for {
+		var n int
+		var addr net.Addr
+		n, addr, err = conn.ReadFrom(b)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				err = nil
+			}
+			return
+		}
+
+		err = s.HandlePacket(addr, b[:n])
+		if err != nil {
+			s.log("server: %s: %v\n", addr, err)
+			err = nil
+		}
+	}
+}
+
+// HandlePacket processes a single datagram received from addr
+func (s *Server) HandlePacket(addr net.Addr, msg []byte) (err error) {
+	var protocol uint8
+	var r io.Reader
+
+	r, err = client.ParseHeader(msg, &protocol)
+	if err != nil {
+		return
+	}
+	if protocol != 0x03 {
+		return fmt.Errorf("unsupported protocol %02x", protocol)
+	}
+
+	var b []byte
+	b, err = io.ReadAll(r)
+	if err != nil {
+		return
+	}
+
+	gm := &protocol03.GroupMessage{}
+	err = proto.Unmarshal(b, gm)
+	if err != nil {
+		return fmt.Errorf("p3: unmarshal: %w", err)
+	}
+
+	defer s.groupsLock.Unlock()
+	s.groupsLock.Lock()
+
+	now := s.Now()
+
+	g, ok := s.groups[gm.Group]
+	if !ok {
+		if gm.GetJoinGroup() == nil {
+			// ignore messages for groups nobody has joined:
+			return
+		}
+		g = &group{
+			name:       gm.Group,
+			byAddr:     make(map[string]*player),
+			spectators: make(map[string]*spectator),
+		}
+		s.groups[gm.Group] = g
+		s.log("server: group '%s' created\n", gm.Group)
+	}
+
+	if jg := gm.GetJoinGroup(); jg != nil && jg.Spectator {
+		// spectators rejoin periodically to stay in the group:
+		sp, ok := g.spectators[addr.String()]
+		if !ok {
+			sp = &spectator{addr: addr}
+			g.spectators[addr.String()] = sp
+			s.log("server: group '%s': spectator joined from '%s'\n", g.name, addr)
+		}
+		sp.lastSeen = now
+
+		gm.PlayerIndex = 0
+		gm.ServerTime = now.UnixNano()
+		// confirm the spectator was not given a player slot:
+		jg.Spectating = true
+		jg.Reliable = true
+		return s.send(addr, gm)
+	}
+	if _, ok := g.spectators[addr.String()]; ok {
+		// spectators are read-only:
+		return
+	}
+
+	p, ok := g.byAddr[addr.String()]
+	if !ok {
+		if gm.GetJoinGroup() == nil {
+			// players must join before sending anything else:
+			return
+		}
+
+		p = g.join(addr)
+		if p == nil {
+			return fmt.Errorf("group '%s' is full", gm.Group)
+		}
+		s.log("server: group '%s': player[%02x] joined from '%s'\n", g.name, uint8(p.index), addr)
+	}
+
+	p.lastSeen = now
+	p.sector = gm.PlayerInSector
+
+	// the server is the authority on player index and time:
+	gm.PlayerIndex = uint32(p.index)
+	gm.ServerTime = now.UnixNano()
+
+	if jg := gm.GetJoinGroup(); jg != nil {
+		// reply to the joining player with its assigned index and let it know reliable messages are routed:
+		jg.Reliable = true
+		return s.send(p.addr, gm)
+	} else if gm.GetBroadcastAll() != nil || gm.GetReliableData() != nil {
+		return s.sendToOthers(g, p, gm)
+	} else if bs := gm.GetBroadcastSector(); bs != nil {
+		for _, o := range g.byAddr {
+			if o == p {
+				continue
+			}
+			if o.sector != bs.TargetSector {
+				continue
+			}
+			s.sendOrLog(o.addr, gm)
+		}
+		// spectators see every sector:
+		return s.sendToSpectators(g, gm)
+	} else if gm.GetEcho() != nil {
+		return s.send(p.addr, gm)
+	} else if ra := gm.GetReliableAck(); ra != nil {
+		if ra.TargetPlayerIndex >= MaxPlayers {
+			return
+		}
+		o := g.players[ra.TargetPlayerIndex]
+		if o == nil {
+			return
+		}
+		return s.send(o.addr, gm)
+	}
+
+	return
+}
+
+func (g *group) join(addr net.Addr) *player {
+	for i := range g.players {
+		if g.players[i] != nil {
+			continue
+		}
+
+		p := &player{index: i, addr: addr}
+		g.players[i] = p
+		g.byAddr[addr.String()] = p
+		return p
+	}
+
+	return nil
+}
+
+func (g *group) leave(p *player) {
+	g.players[p.index] = nil
+	delete(g.byAddr, p.addr.String())
+}
+
+// ExpireIdle removes players that have not sent a packet within IdleTimeout and removes empty groups
+func (s *Server) ExpireIdle() {
+	defer s.groupsLock.Unlock()
+	s.groupsLock.Lock()
+
+	now := s.Now()
+	for name, g := range s.groups {
+		for _, p := range g.byAddr {
+			if now.Sub(p.lastSeen) < s.IdleTimeout {
+				continue
+			}
+
+			g.leave(p)
+			s.log("server: group '%s': player[%02x] expired\n", name, uint8(p.index))
+		}
+		for key, sp := range g.spectators {
+			if now.Sub(sp.lastSeen) < s.IdleTimeout {
+				continue
+			}
+
+			delete(g.spectators, key)
+			s.log("server: group '%s': spectator '%s' expired\n", name, sp.addr)
+		}
+
+		if g.isEmpty() {
+			delete(s.groups, name)
+			s.log("server: group '%s' removed\n", name)
+		}
+	}
+}
+
+func (s *Server) sendToOthers(g *group, p *player, gm *protocol03.GroupMessage) (err error) {
+	for _, o := range g.byAddr {
+		if o == p {
+			continue
+		}
+		s.sendOrLog(o.addr, gm)
+	}
+	return s.sendToSpectators(g, gm)
+}
+
+func (s *Server) sendToSpectators(g *group, gm *protocol03.GroupMessage) (err error) {
+	for _, sp := range g.spectators {
+		s.sendOrLog(sp.addr, gm)
+	}
+	return
+}
+
+// sendOrLog sends to one peer of a fan-out; a failure is logged so it does not stop delivery to the others
+func (s *Server) sendOrLog(addr net.Addr, gm *protocol03.GroupMessage) {
+	if err := s.send(addr, gm); err != nil {
+		s.log("server: group '%s': send to %s: %v\n", gm.Group, addr, err)
+	}
+}
+
+func (s *Server) send(addr net.Addr, gm *protocol03.GroupMessage) (err error) {
+	pkt := client.MakePacket(0x03)
+	var b []byte
+	b, err = proto.MarshalOptions{}.MarshalAppend(pkt.Bytes(), gm)
+	if err != nil {
+		return fmt.Errorf("p3: marshal: %w", err)
+	}
+
+	_, err = s.conn.WriteTo(b, addr)
+	return
+}
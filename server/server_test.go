@@ -0,0 +1,329 @@
+package server
+
+import (
+	"bytes"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net"
+	"o2/client"
+	"o2/client/protocol03"
+	"testing"
+	"time"
+)
+
+type testPlayer struct {
+	t    *testing.T
+	conn net.PacketConn
+}
+
+func newTestPlayer(t *testing.T) *testPlayer {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testPlayer{t: t, conn: conn}
+}
+
+func (tp *testPlayer) send(s *Server, gm *protocol03.GroupMessage) {
+	pkt := client.MakePacket(0x03)
+	b, err := proto.MarshalOptions{}.MarshalAppend(pkt.Bytes(), gm)
+	if err != nil {
+		tp.t.Fatal(err)
+	}
+	if err = s.HandlePacket(tp.conn.LocalAddr(), b); err != nil {
+		tp.t.Fatal(err)
+	}
+}
+
+func (tp *testPlayer) recv() *protocol03.GroupMessage {
+	_ = tp.conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	b := make([]byte, 1500)
+	n, _, err := tp.conn.ReadFrom(b)
+	if err != nil {
+		return nil
+	}
+
+	var protocol uint8
+	r, err := client.ParseHeader(b[:n], &protocol)
+	if err != nil {
+		tp.t.Fatal(err)
+	}
+	if protocol != 0x03 {
+		tp.t.Fatalf("protocol = %02x, expected 03", protocol)
+	}
+	m, _ := io.ReadAll(r)
+	gm := &protocol03.GroupMessage{}
+	if err = proto.Unmarshal(m, gm); err != nil {
+		tp.t.Fatal(err)
+	}
+	return gm
+}
+
+func (tp *testPlayer) join(s *Server, group string) uint32 {
+	tp.send(s, &protocol03.GroupMessage{Group: group, JoinGroup: &protocol03.JoinGroup{}})
+	gm := tp.recv()
+	if gm == nil || gm.GetJoinGroup() == nil {
+		tp.t.Fatal("expected JoinGroup reply")
+	}
+	if !gm.GetJoinGroup().GetReliable() {
+		tp.t.Fatal("expected JoinGroup reply to announce reliable routing")
+	}
+	return gm.PlayerIndex
+}
+
+func newTestServer(t *testing.T) *Server {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	s := New(conn)
+	s.MuteLog(true)
+	return s
+}
+
+func TestServer_JoinGroup(t *testing.T) {
+	s := newTestServer(t)
+	p0, p1, p2 := newTestPlayer(t), newTestPlayer(t), newTestPlayer(t)
+
+	if actual, expected := p0.join(s, "group"), uint32(0); actual != expected {
+		t.Errorf("p0 index = %d, expected %d", actual, expected)
+	}
+	if actual, expected := p1.join(s, "group"), uint32(1); actual != expected {
+		t.Errorf("p1 index = %d, expected %d", actual, expected)
+	}
+	// rejoining keeps the same index:
+	if actual, expected := p0.join(s, "group"), uint32(0); actual != expected {
+		t.Errorf("p0 rejoin index = %d, expected %d", actual, expected)
+	}
+	// separate groups assign indexes independently:
+	if actual, expected := p2.join(s, "other"), uint32(0); actual != expected {
+		t.Errorf("p2 index = %d, expected %d", actual, expected)
+	}
+}
+
+func TestServer_BroadcastAll(t *testing.T) {
+	s := newTestServer(t)
+	p0, p1, p2 := newTestPlayer(t), newTestPlayer(t), newTestPlayer(t)
+	p0.join(s, "group")
+	p1.join(s, "group")
+	p2.join(s, "other")
+
+	now := time.Unix(1000, 0)
+	s.Now = func() time.Time { return now }
+
+	p0.send(s, &protocol03.GroupMessage{
+		Group:        "group",
+		PlayerIndex:  7, // spoofed index must be replaced
+		BroadcastAll: &protocol03.BroadcastAll{Data: []byte{1, 2, 3}},
+	})
+
+	gm := p1.recv()
+	if gm == nil {
+		t.Fatal("p1 expected broadcast")
+	}
+	if actual, expected := gm.PlayerIndex, uint32(0); actual != expected {
+		t.Errorf("PlayerIndex = %d, expected %d", actual, expected)
+	}
+	if actual, expected := gm.ServerTime, now.UnixNano(); actual != expected {
+		t.Errorf("ServerTime = %d, expected %d", actual, expected)
+	}
+	if !bytes.Equal(gm.GetBroadcastAll().GetData(), []byte{1, 2, 3}) {
+		t.Errorf("Data = %v", gm.GetBroadcastAll().GetData())
+	}
+
+	if gm = p0.recv(); gm != nil {
+		t.Error("sender should not receive its own broadcast")
+	}
+	if gm = p2.recv(); gm != nil {
+		t.Error("other group should not receive broadcast")
+	}
+}
+
+// badAddr is an address the server's UDP connection cannot send to
+type badAddr string
+
+func (a badAddr) Network() string { return "bad" }
+func (a badAddr) String() string  { return string(a) }
+
+func TestServer_BroadcastAll_BadPeer(t *testing.T) {
+	s := newTestServer(t)
+	p0, p1, p2 := newTestPlayer(t), newTestPlayer(t), newTestPlayer(t)
+	p0.join(s, "group")
+
+	// the join reply to the bad peer fails but it still occupies a slot:
+	pkt := client.MakePacket(0x03)
+	b, _ := proto.MarshalOptions{}.MarshalAppend(pkt.Bytes(), &protocol03.GroupMessage{Group: "group", JoinGroup: &protocol03.JoinGroup{}})
+	if err := s.HandlePacket(badAddr("bad"), b); err == nil {
+		t.Fatal("expected send to bad peer to fail")
+	}
+	p1.join(s, "group")
+	p2.join(s, "group")
+
+	p0.send(s, &protocol03.GroupMessage{Group: "group", BroadcastAll: &protocol03.BroadcastAll{Data: []byte{1}}})
+	for i, p := range []*testPlayer{p1, p2} {
+		if gm := p.recv(); gm == nil || gm.GetBroadcastAll() == nil {
+			t.Errorf("p%d expected broadcast despite a failing peer", i+1)
+		}
+	}
+}
+
+func TestServer_BroadcastSector(t *testing.T) {
+	s := newTestServer(t)
+	p0, p1, p2 := newTestPlayer(t), newTestPlayer(t), newTestPlayer(t)
+	p0.join(s, "group")
+	p1.join(s, "group")
+	p2.join(s, "group")
+
+	p1.send(s, &protocol03.GroupMessage{Group: "group", PlayerInSector: 0x10012, Echo: &protocol03.Echo{}})
+	p1.recv()
+	p2.send(s, &protocol03.GroupMessage{Group: "group", PlayerInSector: 0x00018, Echo: &protocol03.Echo{}})
+	p2.recv()
+
+	p0.send(s, &protocol03.GroupMessage{
+		Group:           "group",
+		PlayerInSector:  0x10012,
+		BroadcastSector: &protocol03.BroadcastSector{TargetSector: 0x10012, Data: []byte{4}},
+	})
+
+	if gm := p1.recv(); gm == nil || gm.GetBroadcastSector() == nil {
+		t.Error("p1 in target sector expected broadcast")
+	}
+	if gm := p2.recv(); gm != nil {
+		t.Error("p2 outside target sector should not receive broadcast")
+	}
+}
+
+func TestServer_Echo(t *testing.T) {
+	s := newTestServer(t)
+	p0, p1 := newTestPlayer(t), newTestPlayer(t)
+	p0.join(s, "group")
+	p1.join(s, "group")
+
+	p1.send(s, &protocol03.GroupMessage{Group: "group", PlayerTime: 1234, Echo: &protocol03.Echo{Data: []byte{9}}})
+	gm := p1.recv()
+	if gm == nil || gm.GetEcho() == nil {
+		t.Fatal("expected echo reply")
+	}
+	if actual, expected := gm.PlayerTime, int64(1234); actual != expected {
+		t.Errorf("PlayerTime = %d, expected %d", actual, expected)
+	}
+	if gm.ServerTime == 0 {
+		t.Error("ServerTime not stamped")
+	}
+	if gm = p0.recv(); gm != nil {
+		t.Error("echo should only go to sender")
+	}
+}
+
+func TestServer_ExpireIdle(t *testing.T) {
+	s := newTestServer(t)
+	p0, p1, p2 := newTestPlayer(t), newTestPlayer(t), newTestPlayer(t)
+
+	now := time.Unix(1000, 0)
+	s.Now = func() time.Time { return now }
+	p0.join(s, "group")
+	p1.join(s, "group")
+
+	now = now.Add(DefaultIdleTimeout - time.Second)
+	p1.send(s, &protocol03.GroupMessage{Group: "group", Echo: &protocol03.Echo{}})
+	p1.recv()
+
+	now = now.Add(2 * time.Second)
+	s.ExpireIdle()
+
+	// p0 expired so its slot is free again:
+	if actual, expected := p2.join(s, "group"), uint32(0); actual != expected {
+		t.Errorf("p2 index = %d, expected %d", actual, expected)
+	}
+	// p0 must rejoin before broadcasting:
+	p0.send(s, &protocol03.GroupMessage{Group: "group", BroadcastAll: &protocol03.BroadcastAll{}})
+	if gm := p1.recv(); gm != nil {
+		t.Error("expired player broadcast should be dropped")
+	}
+
+	now = now.Add(DefaultIdleTimeout)
+	s.ExpireIdle()
+	if len(s.groups) != 0 {
+		t.Errorf("expected empty groups to be removed; %d remain", len(s.groups))
+	}
+}
+
+func TestServer_Reliable(t *testing.T) {
+	s := newTestServer(t)
+	p0, p1, p2 := newTestPlayer(t), newTestPlayer(t), newTestPlayer(t)
+	p0.join(s, "group")
+	p1.join(s, "group")
+	p2.join(s, "group")
+
+	p0.send(s, &protocol03.GroupMessage{
+		Group:        "group",
+		ReliableData: &protocol03.ReliableData{Channel: 1, Sequence: 5, Data: []byte{1}},
+	})
+	for _, p := range []*testPlayer{p1, p2} {
+		gm := p.recv()
+		if gm == nil || gm.GetReliableData() == nil {
+			t.Fatal("expected reliable data fan-out")
+		}
+	}
+
+	// acks are routed only to the target player:
+	p1.send(s, &protocol03.GroupMessage{
+		Group:       "group",
+		ReliableAck: &protocol03.ReliableAck{TargetPlayerIndex: 0, Channel: 1, Sequence: 5},
+	})
+	gm := p0.recv()
+	if gm == nil || gm.GetReliableAck() == nil {
+		t.Fatal("expected reliable ack")
+	}
+	if actual, expected := gm.PlayerIndex, uint32(1); actual != expected {
+		t.Errorf("PlayerIndex = %d, expected %d", actual, expected)
+	}
+	if gm = p2.recv(); gm != nil {
+		t.Error("ack should only go to its target")
+	}
+}
+
+func TestServer_Spectator(t *testing.T) {
+	s := newTestServer(t)
+	p0, sp := newTestPlayer(t), newTestPlayer(t)
+	p0.join(s, "group")
+
+	sp.send(s, &protocol03.GroupMessage{Group: "group", JoinGroup: &protocol03.JoinGroup{Spectator: true}})
+	if gm := sp.recv(); gm == nil || !gm.GetJoinGroup().GetSpectating() {
+		t.Fatal("expected JoinGroup reply acknowledging the spectator")
+	}
+
+	// spectators are never assigned a player slot:
+	p1 := newTestPlayer(t)
+	if actual, expected := p1.join(s, "group"), uint32(1); actual != expected {
+		t.Errorf("p1 index = %d, expected %d", actual, expected)
+	}
+
+	// spectators see broadcasts to any sector:
+	p0.send(s, &protocol03.GroupMessage{
+		Group:           "group",
+		BroadcastSector: &protocol03.BroadcastSector{TargetSector: 99, Data: []byte{1}},
+	})
+	if gm := sp.recv(); gm == nil || gm.GetBroadcastSector() == nil {
+		t.Fatal("expected spectator to receive sector broadcast")
+	}
+	p0.send(s, &protocol03.GroupMessage{
+		Group:        "group",
+		BroadcastAll: &protocol03.BroadcastAll{Data: []byte{2}},
+	})
+	if gm := sp.recv(); gm == nil || gm.GetBroadcastAll() == nil {
+		t.Fatal("expected spectator to receive broadcast")
+	}
+	_ = p1.recv()
+
+	// spectators are read-only:
+	sp.send(s, &protocol03.GroupMessage{
+		Group:        "group",
+		BroadcastAll: &protocol03.BroadcastAll{Data: []byte{3}},
+	})
+	if gm := p0.recv(); gm != nil {
+		t.Error("spectator broadcast should be dropped")
+	}
+}
@@ -0,0 +1,767 @@
+package asm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Assemble assembles 65C816 source text through the emitter, starting at its current address and M/X flags, and
+// returns the addresses of the labels the source defines. Each line is of the form
+//
+//	label: mnemonic[.b|.w|.l] operand ; comment
+//
+// where every part is optional. Operands use the usual syntax, e.g. `#$12`, `$12,X`, `($12),Y`, `[$12]`, `$12,S`
+// and `$7E,$7F` for block moves, with expressions of `$hex`, `%binary`, decimal, 'c' characters, `*` for the
+// current address and labels, added or subtracted and optionally prefixed with `<` (low byte), `>` (high byte) or
+// `^` (bank). Without a size suffix a hex literal's size follows its digits, `$12` being direct page, `$1234`
+// absolute and `$7E1234` long, and labels are absolute; when the instruction does not have that addressing mode
+// the nearest available size is used. A `jmp` to a label defined earlier in another bank becomes a `jml`; any
+// other `jmp` or `jsr` to a label in another bank is an error since the absolute modes stay in the program bank.
+//
+// Immediate operands are 8- or 16-bit as tracked from the M and X flags by `rep` and `sep`; a size suffix on an
+// immediate must agree with the flags. Directives are:
+//
+//	org $708000       ; continue at an address; the code still follows on in the emitter's Code
+//	db  $01, "text"   ; bytes
+//	dw  $1234, label  ; 16-bit words
+//	dl  label         ; 24-bit long addresses
+//	a8, a16, i8, i16  ; assume the M or X flag's size, e.g. after a `plp`
+//
+// Labels are emitted as comments to the emitter's Text so they appear in the listing.
+func (a *Emitter) Assemble(r io.Reader) (labels map[string]uint32, err error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	if err = sc.Err(); err != nil {
+		return
+	}
+
+	s := &assembler{labels: make(map[string]uint32), labelLines: make(map[string]int)}
+
+	// the first pass on a scratch emitter finds the addresses of all labels so forward references resolve:
+	scratch := &Emitter{flagsTracker: a.flagsTracker, address: a.address, baseSet: a.baseSet}
+	if err = s.pass(scratch, lines); err != nil {
+		return
+	}
+
+	s.final = true
+	if err = s.pass(a, lines); err != nil {
+		return
+	}
+
+	return s.labels, nil
+}
+
+type assembler struct {
+	labels map[string]uint32
+	// labelLines are the lines that define the labels, to tell forward references apart:
+	labelLines map[string]int
+	// lineNo is the line being assembled:
+	lineNo int
+	// final is set on the second pass where all labels must be defined:
+	final bool
+}
+
+// noMode marks an addressing mode that does not exist for an operand form and size
+const noMode addressMode = 0xFF
+
+// operandForm is the syntax of an operand, from which its addressing mode follows together with its size
+type operandForm int
+
+const (
+	formNone operandForm = iota
+	formAccumulator
+	formImmediate
+	formPlain
+	formX
+	formY
+	formS
+	formIndirect
+	formIndirectX
+	formIndirectY
+	formStackIndirectY
+	formLongIndirect
+	formLongIndirectY
+	formPair
+)
+
+// formModes gives the addressing mode of an operand form by size: direct page, absolute, long
+var formModes = map[operandForm][3]addressMode{
+	formPlain:          {modeDirect, modeAbsolute, modeLong},
+	formX:              {modeDirectX, modeAbsoluteX, modeLongX},
+	formY:              {modeDirectY, modeAbsoluteY, noMode},
+	formS:              {modeStackRelative, noMode, noMode},
+	formIndirect:       {modeDirectIndirect, modeAbsoluteIndirect, noMode},
+	formIndirectX:      {modeDirectIndirectX, modeAbsoluteIndirectX, noMode},
+	formIndirectY:      {modeDirectIndirectY, noMode, noMode},
+	formStackIndirectY: {modeStackRelativeIndirectY, noMode, noMode},
+	formLongIndirect:   {modeDirectIndirectLong, modeAbsoluteIndirectLong, noMode},
+	formLongIndirectY:  {modeDirectIndirectLongY, noMode, noMode},
+}
+
+// modeFormats formats the operand of each addressing mode given its hex digits
+var modeFormats = map[addressMode]string{
+	modeDirect:                 "%s",
+	modeDirectX:                "%s,X",
+	modeDirectY:                "%s,Y",
+	modeDirectIndirect:         "(%s)",
+	modeDirectIndirectX:        "(%s,X)",
+	modeDirectIndirectY:        "(%s),Y",
+	modeDirectIndirectLong:     "[%s]",
+	modeDirectIndirectLongY:    "[%s],Y",
+	modeAbsolute:               "%s",
+	modeAbsoluteX:              "%s,X",
+	modeAbsoluteY:              "%s,Y",
+	modeAbsoluteIndirect:       "(%s)",
+	modeAbsoluteIndirectX:      "(%s,X)",
+	modeAbsoluteIndirectLong:   "[%s]",
+	modeLong:                   "%s",
+	modeLongX:                  "%s,X",
+	modeStackRelative:          "%s,S",
+	modeStackRelativeIndirectY: "(%s,S),Y",
+	modeRelative:               "%s",
+	modeRelativeLong:           "%s",
+	modeImmediateM:             "#%s",
+	modeImmediateX:             "#%s",
+	modeImmediate8:             "#%s",
+}
+
+// hexFormats formats 1 to 3 operand bytes as hex for emit2, emit3 and emit4
+var hexFormats = [...]string{"", "$%02[1]x", "$%02[2]x%02[1]x", "$%02[3]x%02[2]x%02[1]x"}
+
+// longAliases lets `jmp` and `jsr` assemble to `jml` and `jsl` for long operands
+var longAliases = map[string]string{
+	"jmp": "jml",
+	"jsr": "jsl",
+}
+
+// unsized lists mnemonics whose operand size is implied by the mnemonic so the listing shows no size suffix
+var unsized = map[string]bool{
+	"jmp": true, "jml": true, "jsr": true, "jsl": true,
+	"rep": true, "sep": true, "brk": true, "cop": true, "wdm": true,
+	"pea": true, "pei": true, "per": true, "mvn": true, "mvp": true,
+}
+
+var (
+	labelRe      = regexp.MustCompile(`^\s*([A-Za-z_.][A-Za-z0-9_.]*):`)
+	identifierRe = regexp.MustCompile(`^[A-Za-z_.][A-Za-z0-9_.]*$`)
+)
+
+func (s *assembler) pass(e *Emitter, lines []string) error {
+	for i, line := range lines {
+		s.lineNo = i
+		if err := s.line(e, line); err != nil {
+			return fmt.Errorf("asm: line %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (s *assembler) line(e *Emitter, line string) error {
+	line = stripComment(line)
+
+	if m := labelRe.FindStringSubmatchIndex(line); m != nil {
+		name := line[m[2]:m[3]]
+		if s.final {
+			if s.labels[name] != e.GetBase() {
+				return fmt.Errorf("label '%s' moved from $%06x to $%06x between passes", name, s.labels[name], e.GetBase())
+			}
+			e.Comment(name + ":")
+		} else {
+			if _, ok := s.labels[name]; ok {
+				return fmt.Errorf("label '%s' already defined", name)
+			}
+			s.labels[name] = e.GetBase()
+			s.labelLines[name] = s.lineNo
+		}
+		line = line[m[1]:]
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+
+	mnemonic, operand := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		mnemonic, operand = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	mnemonic = strings.ToLower(mnemonic)
+	size := ""
+	if i := strings.IndexByte(mnemonic, '.'); i >= 0 {
+		mnemonic, size = mnemonic[:i], mnemonic[i+1:]
+	}
+
+	switch mnemonic {
+	case "org":
+		x, err := s.eval(e, operand)
+		if err != nil {
+			return err
+		}
+		if !x.known {
+			return fmt.Errorf("org must not refer to labels defined after it")
+		}
+		e.SetBase(uint32(x.v) & 0xFFFFFF)
+		return nil
+	case "db":
+		return s.data(e, operand, 1)
+	case "dw":
+		return s.data(e, operand, 2)
+	case "dl":
+		return s.data(e, operand, 3)
+	case "a8":
+		e.AssumeSEP(Accumulator8bit)
+		return nil
+	case "a16":
+		e.AssumeREP(Accumulator8bit)
+		return nil
+	case "i8":
+		e.AssumeSEP(IndexRegister8bit)
+		return nil
+	case "i16":
+		e.AssumeREP(IndexRegister8bit)
+		return nil
+	}
+
+	if _, ok := opcodeFor[mnemonic]; !ok {
+		return fmt.Errorf("unknown mnemonic '%s'", mnemonic)
+	}
+	width := -1
+	switch size {
+	case "":
+	case "b":
+		width = 0
+	case "w":
+		width = 1
+	case "l":
+		width = 2
+	default:
+		return fmt.Errorf("unknown size suffix '.%s'", size)
+	}
+
+	return s.instruction(e, mnemonic, width, operand)
+}
+
+// scanUnquoted calls fn with each byte of text outside of "strings" and 'c' characters until fn returns false
+func scanUnquoted(text string, fn func(i int, c byte) bool) {
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '"':
+			j := strings.IndexByte(text[i+1:], '"')
+			if j < 0 {
+				// an unterminated string runs to the end:
+				return
+			}
+			i += 1 + j
+		case c == '\'' && i+2 < len(text) && text[i+2] == '\'':
+			i += 2
+		default:
+			if !fn(i, c) {
+				return
+			}
+		}
+	}
+}
+
+// stripComment removes a `;` comment outside of quotes
+func stripComment(line string) string {
+	end := len(line)
+	scanUnquoted(line, func(i int, c byte) bool {
+		if c == ';' {
+			end = i
+			return false
+		}
+		return true
+	})
+	return line[:end]
+}
+
+// removeSpaces removes whitespace outside of quotes
+func removeSpaces(operand string) string {
+	b := []byte(operand)
+	scanUnquoted(operand, func(i int, c byte) bool {
+		if c == ' ' || c == '\t' {
+			b[i] = 0
+		}
+		return true
+	})
+	return strings.ReplaceAll(string(b), "\x00", "")
+}
+
+func parseOperand(operand string) (form operandForm, exprs []string) {
+	o := removeSpaces(operand)
+	u := strings.ToUpper(o)
+	switch {
+	case o == "":
+		return formNone, nil
+	case u == "A":
+		return formAccumulator, nil
+	case strings.HasPrefix(o, "#"):
+		return formImmediate, []string{o[1:]}
+	case strings.HasPrefix(o, "(") && strings.HasSuffix(u, ",S),Y"):
+		return formStackIndirectY, []string{o[1 : len(o)-5]}
+	case strings.HasPrefix(o, "(") && strings.HasSuffix(u, ",X)"):
+		return formIndirectX, []string{o[1 : len(o)-3]}
+	case strings.HasPrefix(o, "(") && strings.HasSuffix(u, "),Y"):
+		return formIndirectY, []string{o[1 : len(o)-3]}
+	case strings.HasPrefix(o, "(") && strings.HasSuffix(u, ")"):
+		return formIndirect, []string{o[1 : len(o)-1]}
+	case strings.HasPrefix(o, "[") && strings.HasSuffix(u, "],Y"):
+		return formLongIndirectY, []string{o[1 : len(o)-3]}
+	case strings.HasPrefix(o, "[") && strings.HasSuffix(u, "]"):
+		return formLongIndirect, []string{o[1 : len(o)-1]}
+	case strings.HasSuffix(u, ",X"):
+		return formX, []string{o[:len(o)-2]}
+	case strings.HasSuffix(u, ",Y"):
+		return formY, []string{o[:len(o)-2]}
+	case strings.HasSuffix(u, ",S"):
+		return formS, []string{o[:len(o)-2]}
+	case len(splitOperands(o)) > 1:
+		return formPair, splitOperands(o)
+	default:
+		return formPlain, []string{o}
+	}
+}
+
+// lookup finds the opcode for the mnemonic and mode, trying the long alias of `jmp` and `jsr`
+func lookup(mnemonic string, mode addressMode) (string, byte, bool) {
+	if op, ok := opcodeFor[mnemonic][mode]; ok {
+		return mnemonic, op, true
+	}
+	if alias, ok := longAliases[mnemonic]; ok {
+		if op, ok := opcodeFor[alias][mode]; ok {
+			return alias, op, true
+		}
+	}
+	return mnemonic, 0, false
+}
+
+func (s *assembler) instruction(e *Emitter, mnemonic string, width int, operand string) error {
+	modes := opcodeFor[mnemonic]
+	form, exprs := parseOperand(operand)
+
+	switch form {
+	case formNone:
+		if op, ok := modes[modeImplied]; ok {
+			e.emit1(mnemonic, [1]byte{op})
+			return nil
+		}
+		if op, ok := modes[modeAccumulator]; ok {
+			e.emit1(mnemonic, [1]byte{op})
+			return nil
+		}
+		if op, ok := modes[modeImmediate8]; ok && (mnemonic == "brk" || mnemonic == "cop" || mnemonic == "wdm") {
+			// the signature byte defaults to 0:
+			e.emit2(mnemonic, "#$%02x", [2]byte{op, 0})
+			return nil
+		}
+		return fmt.Errorf("%s needs an operand", mnemonic)
+
+	case formAccumulator:
+		op, ok := modes[modeAccumulator]
+		if !ok {
+			return fmt.Errorf("%s has no accumulator addressing mode", mnemonic)
+		}
+		e.emit1(mnemonic, [1]byte{op})
+		return nil
+
+	case formImmediate:
+		return s.immediate(e, mnemonic, width, exprs[0])
+
+	case formPair:
+		op, ok := modes[modeBlockMove]
+		if !ok || len(exprs) != 2 {
+			return fmt.Errorf("bad operand '%s' for %s", operand, mnemonic)
+		}
+		var banks [2]byte
+		for i, text := range exprs {
+			x, err := s.eval(e, text)
+			if err != nil {
+				return err
+			}
+			if err = s.fits(x.v, 1); err != nil {
+				return err
+			}
+			banks[i] = byte(x.v)
+		}
+		// the destination bank is encoded first:
+		e.emit3(mnemonic, "$%02[2]x,$%02[1]x", [3]byte{op, banks[1], banks[0]})
+		return nil
+	}
+
+	x, err := s.eval(e, exprs[0])
+	if err != nil {
+		return err
+	}
+
+	// the absolute modes of `jmp` and `jsr` stay in the program bank so a label in another bank needs a long jump:
+	if _, ok := longAliases[mnemonic]; ok && x.label && x.known && x.v>>16 != int64(e.GetBase()>>16) {
+		switch {
+		case mnemonic != "jmp":
+			return fmt.Errorf("%s to $%06x cannot leave bank $%02x; call it with jsl and return with rtl", mnemonic, x.v, e.GetBase()>>16)
+		case form != formPlain || width == 0 || width == 1:
+			return fmt.Errorf("%s to $%06x cannot leave bank $%02x; use jml", mnemonic, x.v, e.GetBase()>>16)
+		case x.forward:
+			// the first pass already assumed the shorter jump:
+			return fmt.Errorf("%s to $%06x in bank $%02x is a forward reference; use jml", mnemonic, x.v, x.v>>16)
+		}
+		width = 2
+	}
+
+	if form == formPlain {
+		if op, ok := modes[modeRelative]; ok {
+			return s.branch(e, mnemonic, op, 1, x)
+		}
+		if op, ok := modes[modeRelativeLong]; ok {
+			return s.branch(e, mnemonic, op, 2, x)
+		}
+	}
+
+	// try the requested or inferred size first, then larger sizes, then smaller ones:
+	sizes, ok := formModes[form]
+	if !ok {
+		return fmt.Errorf("bad operand '%s' for %s", operand, mnemonic)
+	}
+	var widths []int
+	if width >= 0 {
+		widths = []int{width}
+	} else {
+		for w := x.width; w < 3; w++ {
+			widths = append(widths, w)
+		}
+		for w := x.width - 1; w >= 0; w-- {
+			widths = append(widths, w)
+		}
+	}
+	for _, w := range widths {
+		mode := sizes[w]
+		if mode == noMode {
+			continue
+		}
+		name, op, ok := lookup(mnemonic, mode)
+		if !ok {
+			continue
+		}
+
+		// a literal too large for a smaller mode is an error but labels are truncated to their bank's offset:
+		if w < x.width && !x.label {
+			if err = s.fits(x.v, w+1); err != nil {
+				return err
+			}
+		}
+		s.emit(e, name, mode, op, w+1, x.v)
+		return nil
+	}
+
+	return fmt.Errorf("bad operand '%s' for %s", operand, mnemonic)
+}
+
+func (s *assembler) immediate(e *Emitter, mnemonic string, width int, text string) error {
+	modes := opcodeFor[mnemonic]
+
+	x, err := s.eval(e, text)
+	if err != nil {
+		return err
+	}
+
+	// `pea #$1234` is accepted for `pea $1234`:
+	if op, ok := modes[modeAbsolute]; ok && mnemonic == "pea" {
+		s.emit(e, mnemonic, modeAbsolute, op, 2, x.v)
+		return nil
+	}
+
+	for _, mode := range []addressMode{modeImmediateM, modeImmediateX, modeImmediate8} {
+		op, ok := modes[mode]
+		if !ok {
+			continue
+		}
+
+		n := 1
+		switch mode {
+		case modeImmediateM:
+			if e.IsM16bit() {
+				n = 2
+			}
+		case modeImmediateX:
+			if e.IsX16bit() {
+				n = 2
+			}
+		}
+		if width >= 0 && width+1 != n {
+			flag, size := "x", "8"
+			if mode == modeImmediateM {
+				flag = "m"
+			}
+			if n == 2 {
+				size = "16"
+			}
+			return fmt.Errorf("%s.%s but '%s' flag is %s-bit; use rep/sep or a8/a16/i8/i16 first", mnemonic, "bwl"[width:width+1], flag, size)
+		}
+		if err = s.fits(x.v, n); err != nil {
+			return err
+		}
+
+		s.emit(e, mnemonic, mode, op, n, x.v)
+
+		switch mnemonic {
+		case "rep":
+			e.AssumeREP(Flags(x.v))
+		case "sep":
+			e.AssumeSEP(Flags(x.v))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%s has no immediate addressing mode", mnemonic)
+}
+
+func (s *assembler) branch(e *Emitter, mnemonic string, op byte, n int, x value) error {
+	pc := e.GetBase()
+	target := int64(x.v)
+	// short literals are in the current bank:
+	if !x.label && x.width < 2 {
+		target |= int64(pc & 0xFF0000)
+	}
+
+	offset := target - int64(pc+uint32(1+n))
+	if s.final {
+		if n == 1 && (offset < -128 || offset > 127) {
+			return fmt.Errorf("branch to $%06x out of range by %d bytes", target, offset)
+		}
+		if target>>16 != int64(pc>>16) {
+			return fmt.Errorf("branch to $%06x crosses from bank $%02x", target, pc>>16)
+		}
+	}
+
+	if n == 1 {
+		e.emit2(mnemonic, "$%02x", [2]byte{op, byte(offset)})
+	} else {
+		e.emit3(mnemonic, hexFormats[2], [3]byte{op, byte(offset), byte(offset >> 8)})
+	}
+	return nil
+}
+
+func (s *assembler) emit(e *Emitter, mnemonic string, mode addressMode, op byte, n int, v int64) {
+	ins := mnemonic
+	if !unsized[mnemonic] {
+		ins += "." + "bwl"[n-1:n]
+	}
+	format := fmt.Sprintf(modeFormats[mode], hexFormats[n])
+
+	switch n {
+	case 1:
+		e.emit2(ins, format, [2]byte{op, byte(v)})
+	case 2:
+		e.emit3(ins, format, [3]byte{op, byte(v), byte(v >> 8)})
+	case 3:
+		e.emit4(ins, format, [4]byte{op, byte(v), byte(v >> 8), byte(v >> 16)})
+	}
+}
+
+// fits checks a value fits in n bytes as either signed or unsigned
+func (s *assembler) fits(v int64, n int) error {
+	if !s.final {
+		return nil
+	}
+	bits := uint(8 * n)
+	if v < -(1<<(bits-1)) || v >= 1<<bits {
+		return fmt.Errorf("value $%x does not fit in %d byte(s)", v, n)
+	}
+	return nil
+}
+
+func (s *assembler) data(e *Emitter, operands string, n int) error {
+	var b []byte
+	for _, text := range splitOperands(operands) {
+		if strings.HasPrefix(text, "\"") {
+			if n != 1 || len(text) < 2 || !strings.HasSuffix(text, "\"") {
+				return fmt.Errorf("bad string %s", text)
+			}
+			b = append(b, text[1:len(text)-1]...)
+			continue
+		}
+
+		x, err := s.eval(e, text)
+		if err != nil {
+			return err
+		}
+		if !x.label || n == 1 {
+			if err = s.fits(x.v, n); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < n; i++ {
+			b = append(b, byte(x.v>>(8*i)))
+		}
+	}
+	if len(b) == 0 {
+		return fmt.Errorf("no data")
+	}
+
+	e.EmitBytes(b)
+	return nil
+}
+
+// splitOperands splits on commas outside of quotes
+func splitOperands(operands string) (list []string) {
+	start := 0
+	scanUnquoted(operands, func(i int, c byte) bool {
+		if c == ',' {
+			list = append(list, strings.TrimSpace(operands[start:i]))
+			start = i + 1
+		}
+		return true
+	})
+	if t := strings.TrimSpace(operands[start:]); t != "" || len(list) > 0 {
+		list = append(list, t)
+	}
+	return
+}
+
+// value is an evaluated expression
+type value struct {
+	v int64
+	// width is the size the expression implies: 0 direct page, 1 absolute, 2 long
+	width int
+	// label is set when the expression refers to a label
+	label bool
+	// known is cleared when a label is not yet defined in the first pass
+	known bool
+	// forward is set when the expression refers to a label defined on a later line
+	forward bool
+}
+
+func (s *assembler) eval(e *Emitter, text string) (x value, err error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return x, fmt.Errorf("missing operand")
+	}
+
+	// byte selection operators apply to the whole expression:
+	if c := text[0]; c == '<' || c == '>' || c == '^' {
+		if x, err = s.eval(e, text[1:]); err != nil {
+			return
+		}
+		x.v = (x.v >> map[byte]uint{'<': 0, '>': 8, '^': 16}[c]) & 0xFF
+		x.width = 0
+		x.label = false
+		return
+	}
+
+	x.known = true
+	sign := int64(1)
+	for i := 0; ; {
+		switch text[i] {
+		case '+':
+			i++
+		case '-':
+			sign = -sign
+			i++
+		}
+		if i >= len(text) {
+			return x, fmt.Errorf("bad expression '%s'", text)
+		}
+
+		var t value
+		var n int
+		if t, n, err = s.term(e, text[i:]); err != nil {
+			return
+		}
+		x.v += sign * t.v
+		x.label = x.label || t.label
+		x.known = x.known && t.known
+		x.forward = x.forward || t.forward
+		if t.width > x.width {
+			x.width = t.width
+		}
+		i += n
+
+		if i >= len(text) {
+			break
+		}
+		if text[i] != '+' && text[i] != '-' {
+			return x, fmt.Errorf("bad expression '%s'", text)
+		}
+		sign = 1
+	}
+	if x.label {
+		x.width = 1
+	}
+	return
+}
+
+// term evaluates a single number, character, `*` or label and returns the number of characters it spans
+func (s *assembler) term(e *Emitter, text string) (t value, n int, err error) {
+	t.known = true
+	end := strings.IndexAny(text, "+-")
+	if end < 0 {
+		end = len(text)
+	}
+	// a quoted character may itself be '+' or '-':
+	if strings.HasPrefix(text, "'") && len(text) >= 3 && text[2] == '\'' {
+		return value{v: int64(text[1]), known: true}, 3, nil
+	}
+
+	literal := text[:end]
+	var u uint64
+	switch {
+	case literal == "*":
+		t.v = int64(e.GetBase())
+		t.width = 1
+		t.label = true
+	case strings.HasPrefix(literal, "$"):
+		digits := literal[1:]
+		if u, err = strconv.ParseUint(digits, 16, 32); err != nil {
+			return t, 0, fmt.Errorf("bad hex number '%s'", literal)
+		}
+		t.v = int64(u)
+		t.width = (len(digits) - 1) / 2
+		if t.width > 2 {
+			t.width = 2
+		}
+	case strings.HasPrefix(literal, "%"):
+		if u, err = strconv.ParseUint(literal[1:], 2, 32); err != nil {
+			return t, 0, fmt.Errorf("bad binary number '%s'", literal)
+		}
+		t.v = int64(u)
+		t.width = widthOf(t.v)
+	case literal != "" && literal[0] >= '0' && literal[0] <= '9':
+		if u, err = strconv.ParseUint(literal, 10, 32); err != nil {
+			return t, 0, fmt.Errorf("bad number '%s'", literal)
+		}
+		t.v = int64(u)
+		t.width = widthOf(t.v)
+	case identifierRe.MatchString(literal):
+		t.label = true
+		t.width = 1
+		addr, ok := s.labels[literal]
+		if !ok {
+			if s.final {
+				return t, 0, fmt.Errorf("undefined label '%s'", literal)
+			}
+			t.known = false
+		}
+		t.v = int64(addr)
+		t.forward = s.labelLines[literal] > s.lineNo
+	default:
+		return t, 0, fmt.Errorf("bad expression '%s'", literal)
+	}
+	return t, end, nil
+}
+
+func widthOf(v int64) int {
+	switch {
+	case v < 0x100:
+		return 0
+	case v < 0x10000:
+		return 1
+	default:
+		return 2
+	}
+}
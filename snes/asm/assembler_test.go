@@ -0,0 +1,246 @@
+package asm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func assemble(t *testing.T, base uint32, source string) (*Emitter, map[string]uint32) {
+	t.Helper()
+
+	a := &Emitter{Code: &bytes.Buffer{}, Text: &strings.Builder{}}
+	a.SetBase(base)
+	a.AssumeSEP(0x30)
+	labels, err := a.Assemble(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, labels
+}
+
+func TestAssemble_AllOpcodes(t *testing.T) {
+	operands := map[addressMode]struct {
+		text string
+		size int
+	}{
+		modeImplied:                {"", 1},
+		modeAccumulator:            {"A", 1},
+		modeImmediateM:             {"#$12", 2},
+		modeImmediateX:             {"#$12", 2},
+		modeImmediate8:             {"#$12", 2},
+		modeDirect:                 {"$12", 2},
+		modeDirectX:                {"$12,X", 2},
+		modeDirectY:                {"$12,Y", 2},
+		modeDirectIndirect:         {"($12)", 2},
+		modeDirectIndirectX:        {"($12,X)", 2},
+		modeDirectIndirectY:        {"($12),Y", 2},
+		modeDirectIndirectLong:     {"[$12]", 2},
+		modeDirectIndirectLongY:    {"[$12],Y", 2},
+		modeAbsolute:               {"$1234", 3},
+		modeAbsoluteX:              {"$1234,X", 3},
+		modeAbsoluteY:              {"$1234,Y", 3},
+		modeAbsoluteIndirect:       {"($1234)", 3},
+		modeAbsoluteIndirectX:      {"($1234,X)", 3},
+		modeAbsoluteIndirectLong:   {"[$1234]", 3},
+		modeLong:                   {"$123456", 4},
+		modeLongX:                  {"$123456,X", 4},
+		modeStackRelative:          {"$12,S", 2},
+		modeStackRelativeIndirectY: {"($12,S),Y", 2},
+		modeRelative:               {"*", 2},
+		modeRelativeLong:           {"*", 3},
+		modeBlockMove:              {"$7E,$7F", 3},
+	}
+
+	n := 0
+	for _, modes := range opcodeFor {
+		n += len(modes)
+	}
+	if n != 256 {
+		t.Fatalf("%d distinct mnemonic and mode pairs; expected 256", n)
+	}
+
+	for i, o := range opcodes {
+		operand, ok := operands[o.mode]
+		if !ok {
+			t.Fatalf("no test operand for mode %d", o.mode)
+		}
+		source := o.mnemonic + " " + operand.text
+		a, _ := assemble(t, 0x008000, source)
+		code := a.Code.Bytes()
+		if len(code) != operand.size || code[0] != byte(i) {
+			t.Errorf("%s: assembled % x; expected opcode $%02x of %d bytes", source, code, i, operand.size)
+		}
+	}
+}
+
+func TestAssemble_Operands(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected []byte
+	}{
+		{"lda $12", []byte{0xA5, 0x12}},
+		{"lda $0012", []byte{0xAD, 0x12, 0x00}},
+		{"lda $7E0012", []byte{0xAF, 0x12, 0x00, 0x7E}},
+		{"lda.l $12", []byte{0xAF, 0x12, 0x00, 0x00}},
+		{"LDA.W $12,x", []byte{0xBD, 0x12, 0x00}},
+		// no direct page,Y for lda so absolute:
+		{"lda $12,Y", []byte{0xB9, 0x12, 0x00}},
+		// no long,Y so absolute:
+		{"lda 18 , y", []byte{0xB9, 0x12, 0x00}},
+		{"jmp $123456", []byte{0x5C, 0x56, 0x34, 0x12}},
+		{"jsr.l $123456", []byte{0x22, 0x56, 0x34, 0x12}},
+		{"jmp [$0010]", []byte{0xDC, 0x10, 0x00}},
+		{"mvn $7E, $7F", []byte{0x54, 0x7F, 0x7E}},
+		{"brk", []byte{0x00, 0x00}},
+		{"asl", []byte{0x0A}},
+		{"pea #$1234", []byte{0xF4, 0x34, 0x12}},
+		{"lda #'A'", []byte{0xA9, 0x41}},
+		{"cmp #' '", []byte{0xC9, 0x20}},
+		{"cmp #';' ; comment", []byte{0xC9, 0x3B}},
+		{"cmp #','", []byte{0xC9, 0x2C}},
+		{"db ','", []byte{','}},
+		{"db ';', '\"', \"'\" ; comment", []byte{';', '"', '\''}},
+		{"lda #%1010", []byte{0xA9, 0x0A}},
+		{"lda #<$123456", []byte{0xA9, 0x56}},
+		{"lda #>$123456", []byte{0xA9, 0x34}},
+		{"lda #^$123456", []byte{0xA9, 0x12}},
+		{"lda #$10+2-1", []byte{0xA9, 0x11}},
+		{"db $01, 2, \"a;b\" ; comment", []byte{0x01, 0x02, 'a', ';', 'b'}},
+		{"dw $1234, -1", []byte{0x34, 0x12, 0xFF, 0xFF}},
+		{"dl $123456", []byte{0x56, 0x34, 0x12}},
+	}
+	for _, tt := range tests {
+		a, _ := assemble(t, 0x008000, tt.source)
+		if actual := a.Code.Bytes(); !bytes.Equal(actual, tt.expected) {
+			t.Errorf("%s: assembled % x; expected % x", tt.source, actual, tt.expected)
+		}
+	}
+}
+
+func TestAssemble_Flags(t *testing.T) {
+	// the custom ASM from DOORS.md:
+	a, _ := assemble(t, 0x7F8000, `
+		REP   #$30
+		LDA.w #$098F
+		STA.w  $068E
+		LDA.w #$0008
+		STA.w  $0690
+		SEP   #$30
+		LDA.b #$04
+		STA.b  $11
+
+		rep #$10
+		i8
+		ldx #$01
+		a16
+		lda #$0203
+`)
+	expected := []byte{
+		0xC2, 0x30,
+		0xA9, 0x8F, 0x09,
+		0x8D, 0x8E, 0x06,
+		0xA9, 0x08, 0x00,
+		0x8D, 0x90, 0x06,
+		0xE2, 0x30,
+		0xA9, 0x04,
+		0x85, 0x11,
+		0xC2, 0x10,
+		0xA2, 0x01,
+		0xA9, 0x03, 0x02,
+	}
+	if actual := a.Code.Bytes(); !bytes.Equal(actual, expected) {
+		t.Errorf("assembled % x; expected % x", actual, expected)
+	}
+	if a.IsX16bit() || !a.IsM16bit() {
+		t.Errorf("flags = %08b; expected m=0 x=1", a.Flags())
+	}
+}
+
+func TestAssemble_Labels(t *testing.T) {
+	a, labels := assemble(t, 0x708000, `
+start:	ldx #$04
+loop:	dex
+		bne loop       ; backward
+		bra done       ; forward
+		jsr sub
+		brl done
+sub:	rts
+done:	rtl
+table:	dw sub, done
+		dl table
+		org $709000
+far:	jml start
+		org $718000
+		jmp far        ; another bank
+`)
+
+	expected := map[string]uint32{
+		"start": 0x708000,
+		"loop":  0x708002,
+		"sub":   0x70800D,
+		"done":  0x70800E,
+		"table": 0x70800F,
+		"far":   0x709000,
+	}
+	for name, addr := range expected {
+		if labels[name] != addr {
+			t.Errorf("label %s = $%06x; expected $%06x", name, labels[name], addr)
+		}
+	}
+
+	code := []byte{
+		0xA2, 0x04,
+		0xCA,
+		0xD0, 0xFD,
+		0x80, 0x07,
+		0x20, 0x0D, 0x80,
+		0x82, 0x01, 0x00,
+		0x60,
+		0x6B,
+		0x0D, 0x80, 0x0E, 0x80,
+		0x0F, 0x80, 0x70,
+		0x5C, 0x00, 0x80, 0x70,
+		0x5C, 0x00, 0x90, 0x70,
+	}
+	if actual := a.Code.Bytes(); !bytes.Equal(actual, code) {
+		t.Errorf("assembled % x; expected % x", actual, code)
+	}
+
+	text := a.Text.String()
+	for _, s := range []string{"    ; loop:\n", "    bne   $fd      ; $708003  d0 fd\n", "base $709000\n", "    jml   $708000  ; $709000  5c 00 80 70\n"} {
+		if !strings.Contains(text, s) {
+			t.Errorf("listing does not contain %q:\n%s", s, text)
+		}
+	}
+}
+
+func TestAssemble_Errors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{"nop\nfoo", "asm: line 2: unknown mnemonic 'foo'"},
+		{"lda.w #$1234", "asm: line 1: lda.w but 'm' flag is 8-bit"},
+		{"ldx #$1234", "asm: line 1: value $1234 does not fit in 1 byte(s)"},
+		{"jmp nowhere", "asm: line 1: undefined label 'nowhere'"},
+		{"x: nop\nx: nop", "asm: line 2: label 'x' already defined"},
+		{"bra far\norg $8100\nfar: nop", "asm: line 1: branch to $008100 out of range"},
+		{"lda ($1234),Y", "asm: line 1: value $1234 does not fit in 1 byte(s)"},
+		{"lda.q $12", "asm: line 1: unknown size suffix '.q'"},
+		{"mvn $7E", "asm: line 1: bad operand"},
+		{"x: nop\norg $018000\njsr x", "asm: line 3: jsr to $008000 cannot leave bank $01"},
+		{"x: nop\norg $018000\njmp.w x", "asm: line 3: jmp to $008000 cannot leave bank $01"},
+		{"x: nop\norg $018000\njmp (x,X)", "asm: line 3: jmp to $008000 cannot leave bank $01"},
+		{"jmp x\norg $018000\nx: nop", "asm: line 1: jmp to $018000 in bank $01 is a forward reference"},
+	}
+	for _, tt := range tests {
+		a := &Emitter{Code: &bytes.Buffer{}}
+		a.SetBase(0x008000)
+		a.AssumeSEP(0x30)
+		_, err := a.Assemble(strings.NewReader(tt.source))
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("%q: error = %v; expected %s", tt.source, err, tt.err)
+		}
+	}
+}
@@ -33,7 +33,9 @@ func (a *Emitter) Append(e *Emitter) {
 	a.flagsTracker = e.flagsTracker
 
 	_, _ = e.Code.WriteTo(a.Code)
-	_, _ = a.Text.WriteString(e.Text.String())
+	if a.Text != nil && e.Text != nil {
+		_, _ = a.Text.WriteString(e.Text.String())
+	}
 }
 
 func (a *Emitter) SetBase(addr uint32) {
@@ -154,6 +156,14 @@ func (a *Emitter) SEP(c Flags) {
 	a.emit2("sep", "#$%02x", [2]byte{0xE2, byte(c)})
 }
 
+func (a *Emitter) PHP() {
+	a.emit1("php", [1]byte{0x08})
+}
+
+func (a *Emitter) PLP() {
+	a.emit1("plp", [1]byte{0x28})
+}
+
 func (a *Emitter) NOP() {
 	a.emit1("nop", [1]byte{0xEA})
 }
@@ -290,6 +300,16 @@ func (a *Emitter) ORA_imm8_b(m uint8) {
 	a.emit2("ora.b", "#$%02x", d)
 }
 
+func (a *Emitter) ORA_imm16_w(m uint16) {
+	if !a.IsM16bit() {
+		panic(fmt.Errorf("asm: ORA_imm16_w called but 'm' flag is 8-bit; call REP(0x20) or AssumeREP(0x20) first"))
+	}
+	var d [3]byte
+	d[0] = 0x09
+	d[1], d[2] = imm16(m)
+	a.emit3("ora.w", "#$%02[2]x%02[1]x", d)
+}
+
 func (a *Emitter) CMP_imm8_b(m uint8) {
 	if a.IsM16bit() {
 		panic(fmt.Errorf("asm: CMP_imm8_b called but 'm' flag is 16-bit; call SEP(0x20) or AssumeSEP(0x20) first"))
@@ -338,6 +358,20 @@ func (a *Emitter) ADC_imm8_b(m uint8) {
 	a.emit2("adc.b", "#$%02x", d)
 }
 
+func (a *Emitter) ADC_imm16_w(m uint16) {
+	if !a.IsM16bit() {
+		panic(fmt.Errorf("asm: ADC_imm16_w called but 'm' flag is 8-bit; call REP(0x20) or AssumeREP(0x20) first"))
+	}
+	var d [3]byte
+	d[0] = 0x69
+	d[1], d[2] = imm16(m)
+	a.emit3("adc.w", "#$%02[2]x%02[1]x", d)
+}
+
+func (a *Emitter) CLC() {
+	a.emit1("clc", [1]byte{0x18})
+}
+
 func (a *Emitter) CPY_imm8_b(m uint8) {
 	if a.IsX16bit() {
 		panic(fmt.Errorf("asm: CPY_imm8_b called but 'x' flag is 16-bit; call SEP(0x10) or AssumeSEP(0x10) first"))
@@ -410,3 +444,13 @@ func (a *Emitter) AND_imm8_b(m uint8) {
 	d[1] = m
 	a.emit2("and.b", "#$%02x", d)
 }
+
+func (a *Emitter) AND_imm16_w(m uint16) {
+	if !a.IsM16bit() {
+		panic(fmt.Errorf("asm: AND_imm16_w called but 'm' flag is 8-bit; call REP(0x20) or AssumeREP(0x20) first"))
+	}
+	var d [3]byte
+	d[0] = 0x29
+	d[1], d[2] = imm16(m)
+	a.emit3("and.w", "#$%02[2]x%02[1]x", d)
+}
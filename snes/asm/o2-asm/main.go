@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"o2/snes/asm"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// assembles a 65C816 source file and prints its listing, e.g. to check custom ASM or a patch before use
+func main() {
+	var err error
+
+	base := flag.String("base", "008000", "hex address to assemble at until the source's first org")
+	m16 := flag.Bool("m16", false, "start with a 16-bit accumulator instead of 8-bit")
+	x16 := flag.Bool("x16", false, "start with 16-bit index registers instead of 8-bit")
+	outPath := flag.String("o", "", "file to write the assembled code to")
+	flag.Parse()
+
+	log.SetFlags(0)
+
+	if flag.NArg() != 1 {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: o2-asm [flags] source.asm\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	var addr uint64
+	if addr, err = strconv.ParseUint(strings.TrimPrefix(*base, "$"), 16, 24); err != nil {
+		log.Fatalf("bad -base: %v", err)
+	}
+
+	var f *os.File
+	if f, err = os.Open(flag.Arg(0)); err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	a := &asm.Emitter{Code: &bytes.Buffer{}, Text: &strings.Builder{}}
+	a.SetBase(uint32(addr))
+	a.AssumeSEP(asm.Accumulator8bit | asm.IndexRegister8bit)
+	if *m16 {
+		a.AssumeREP(asm.Accumulator8bit)
+	}
+	if *x16 {
+		a.AssumeREP(asm.IndexRegister8bit)
+	}
+
+	var labels map[string]uint32
+	if labels, err = a.Assemble(f); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Print(a.Text.String())
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return labels[names[i]] < labels[names[j]] })
+	for _, name := range names {
+		fmt.Printf("; $%06x %s\n", labels[name], name)
+	}
+
+	if *outPath != "" {
+		if err = ioutil.WriteFile(*outPath, a.Code.Bytes(), 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package asm
+
+// addressMode is an operand addressing mode of the 65C816
+type addressMode uint8
+
+const (
+	modeImplied                addressMode = iota
+	modeAccumulator                        // asl A
+	modeImmediateM                         // lda #$12; 8- or 16-bit by the M flag
+	modeImmediateX                         // ldx #$12; 8- or 16-bit by the X flag
+	modeImmediate8                         // rep #$30
+	modeDirect                             // lda $12
+	modeDirectX                            // lda $12,X
+	modeDirectY                            // ldx $12,Y
+	modeDirectIndirect                     // lda ($12)
+	modeDirectIndirectX                    // lda ($12,X)
+	modeDirectIndirectY                    // lda ($12),Y
+	modeDirectIndirectLong                 // lda [$12]
+	modeDirectIndirectLongY                // lda [$12],Y
+	modeAbsolute                           // lda $1234
+	modeAbsoluteX                          // lda $1234,X
+	modeAbsoluteY                          // lda $1234,Y
+	modeAbsoluteIndirect                   // jmp ($1234)
+	modeAbsoluteIndirectX                  // jmp ($1234,X)
+	modeAbsoluteIndirectLong               // jml [$1234]
+	modeLong                               // lda $123456
+	modeLongX                              // lda $123456,X
+	modeStackRelative                      // lda $12,S
+	modeStackRelativeIndirectY             // lda ($12,S),Y
+	modeRelative                           // bra label
+	modeRelativeLong                       // brl label
+	modeBlockMove                          // mvn $7E,$7F
+)
+
+type opcodeInfo struct {
+	mnemonic string
+	mode     addressMode
+}
+
+// opcodes describes all 256 opcodes of the 65C816
+var opcodes = [256]opcodeInfo{
+	// $00-$0F:
+	{"brk", modeImmediate8}, {"ora", modeDirectIndirectX}, {"cop", modeImmediate8}, {"ora", modeStackRelative},
+	{"tsb", modeDirect}, {"ora", modeDirect}, {"asl", modeDirect}, {"ora", modeDirectIndirectLong},
+	{"php", modeImplied}, {"ora", modeImmediateM}, {"asl", modeAccumulator}, {"phd", modeImplied},
+	{"tsb", modeAbsolute}, {"ora", modeAbsolute}, {"asl", modeAbsolute}, {"ora", modeLong},
+	// $10-$1F:
+	{"bpl", modeRelative}, {"ora", modeDirectIndirectY}, {"ora", modeDirectIndirect}, {"ora", modeStackRelativeIndirectY},
+	{"trb", modeDirect}, {"ora", modeDirectX}, {"asl", modeDirectX}, {"ora", modeDirectIndirectLongY},
+	{"clc", modeImplied}, {"ora", modeAbsoluteY}, {"inc", modeAccumulator}, {"tcs", modeImplied},
+	{"trb", modeAbsolute}, {"ora", modeAbsoluteX}, {"asl", modeAbsoluteX}, {"ora", modeLongX},
+	// $20-$2F:
+	{"jsr", modeAbsolute}, {"and", modeDirectIndirectX}, {"jsl", modeLong}, {"and", modeStackRelative},
+	{"bit", modeDirect}, {"and", modeDirect}, {"rol", modeDirect}, {"and", modeDirectIndirectLong},
+	{"plp", modeImplied}, {"and", modeImmediateM}, {"rol", modeAccumulator}, {"pld", modeImplied},
+	{"bit", modeAbsolute}, {"and", modeAbsolute}, {"rol", modeAbsolute}, {"and", modeLong},
+	// $30-$3F:
+	{"bmi", modeRelative}, {"and", modeDirectIndirectY}, {"and", modeDirectIndirect}, {"and", modeStackRelativeIndirectY},
+	{"bit", modeDirectX}, {"and", modeDirectX}, {"rol", modeDirectX}, {"and", modeDirectIndirectLongY},
+	{"sec", modeImplied}, {"and", modeAbsoluteY}, {"dec", modeAccumulator}, {"tsc", modeImplied},
+	{"bit", modeAbsoluteX}, {"and", modeAbsoluteX}, {"rol", modeAbsoluteX}, {"and", modeLongX},
+	// $40-$4F:
+	{"rti", modeImplied}, {"eor", modeDirectIndirectX}, {"wdm", modeImmediate8}, {"eor", modeStackRelative},
+	{"mvp", modeBlockMove}, {"eor", modeDirect}, {"lsr", modeDirect}, {"eor", modeDirectIndirectLong},
+	{"pha", modeImplied}, {"eor", modeImmediateM}, {"lsr", modeAccumulator}, {"phk", modeImplied},
+	{"jmp", modeAbsolute}, {"eor", modeAbsolute}, {"lsr", modeAbsolute}, {"eor", modeLong},
+	// $50-$5F:
+	{"bvc", modeRelative}, {"eor", modeDirectIndirectY}, {"eor", modeDirectIndirect}, {"eor", modeStackRelativeIndirectY},
+	{"mvn", modeBlockMove}, {"eor", modeDirectX}, {"lsr", modeDirectX}, {"eor", modeDirectIndirectLongY},
+	{"cli", modeImplied}, {"eor", modeAbsoluteY}, {"phy", modeImplied}, {"tcd", modeImplied},
+	{"jml", modeLong}, {"eor", modeAbsoluteX}, {"lsr", modeAbsoluteX}, {"eor", modeLongX},
+	// $60-$6F:
+	{"rts", modeImplied}, {"adc", modeDirectIndirectX}, {"per", modeRelativeLong}, {"adc", modeStackRelative},
+	{"stz", modeDirect}, {"adc", modeDirect}, {"ror", modeDirect}, {"adc", modeDirectIndirectLong},
+	{"pla", modeImplied}, {"adc", modeImmediateM}, {"ror", modeAccumulator}, {"rtl", modeImplied},
+	{"jmp", modeAbsoluteIndirect}, {"adc", modeAbsolute}, {"ror", modeAbsolute}, {"adc", modeLong},
+	// $70-$7F:
+	{"bvs", modeRelative}, {"adc", modeDirectIndirectY}, {"adc", modeDirectIndirect}, {"adc", modeStackRelativeIndirectY},
+	{"stz", modeDirectX}, {"adc", modeDirectX}, {"ror", modeDirectX}, {"adc", modeDirectIndirectLongY},
+	{"sei", modeImplied}, {"adc", modeAbsoluteY}, {"ply", modeImplied}, {"tdc", modeImplied},
+	{"jmp", modeAbsoluteIndirectX}, {"adc", modeAbsoluteX}, {"ror", modeAbsoluteX}, {"adc", modeLongX},
+	// $80-$8F:
+	{"bra", modeRelative}, {"sta", modeDirectIndirectX}, {"brl", modeRelativeLong}, {"sta", modeStackRelative},
+	{"sty", modeDirect}, {"sta", modeDirect}, {"stx", modeDirect}, {"sta", modeDirectIndirectLong},
+	{"dey", modeImplied}, {"bit", modeImmediateM}, {"txa", modeImplied}, {"phb", modeImplied},
+	{"sty", modeAbsolute}, {"sta", modeAbsolute}, {"stx", modeAbsolute}, {"sta", modeLong},
+	// $90-$9F:
+	{"bcc", modeRelative}, {"sta", modeDirectIndirectY}, {"sta", modeDirectIndirect}, {"sta", modeStackRelativeIndirectY},
+	{"sty", modeDirectX}, {"sta", modeDirectX}, {"stx", modeDirectY}, {"sta", modeDirectIndirectLongY},
+	{"tya", modeImplied}, {"sta", modeAbsoluteY}, {"txs", modeImplied}, {"txy", modeImplied},
+	{"stz", modeAbsolute}, {"sta", modeAbsoluteX}, {"stz", modeAbsoluteX}, {"sta", modeLongX},
+	// $A0-$AF:
+	{"ldy", modeImmediateX}, {"lda", modeDirectIndirectX}, {"ldx", modeImmediateX}, {"lda", modeStackRelative},
+	{"ldy", modeDirect}, {"lda", modeDirect}, {"ldx", modeDirect}, {"lda", modeDirectIndirectLong},
+	{"tay", modeImplied}, {"lda", modeImmediateM}, {"tax", modeImplied}, {"plb", modeImplied},
+	{"ldy", modeAbsolute}, {"lda", modeAbsolute}, {"ldx", modeAbsolute}, {"lda", modeLong},
+	// $B0-$BF:
+	{"bcs", modeRelative}, {"lda", modeDirectIndirectY}, {"lda", modeDirectIndirect}, {"lda", modeStackRelativeIndirectY},
+	{"ldy", modeDirectX}, {"lda", modeDirectX}, {"ldx", modeDirectY}, {"lda", modeDirectIndirectLongY},
+	{"clv", modeImplied}, {"lda", modeAbsoluteY}, {"tsx", modeImplied}, {"tyx", modeImplied},
+	{"ldy", modeAbsoluteX}, {"lda", modeAbsoluteX}, {"ldx", modeAbsoluteY}, {"lda", modeLongX},
+	// $C0-$CF:
+	{"cpy", modeImmediateX}, {"cmp", modeDirectIndirectX}, {"rep", modeImmediate8}, {"cmp", modeStackRelative},
+	{"cpy", modeDirect}, {"cmp", modeDirect}, {"dec", modeDirect}, {"cmp", modeDirectIndirectLong},
+	{"iny", modeImplied}, {"cmp", modeImmediateM}, {"dex", modeImplied}, {"wai", modeImplied},
+	{"cpy", modeAbsolute}, {"cmp", modeAbsolute}, {"dec", modeAbsolute}, {"cmp", modeLong},
+	// $D0-$DF:
+	{"bne", modeRelative}, {"cmp", modeDirectIndirectY}, {"cmp", modeDirectIndirect}, {"cmp", modeStackRelativeIndirectY},
+	{"pei", modeDirectIndirect}, {"cmp", modeDirectX}, {"dec", modeDirectX}, {"cmp", modeDirectIndirectLongY},
+	{"cld", modeImplied}, {"cmp", modeAbsoluteY}, {"phx", modeImplied}, {"stp", modeImplied},
+	{"jml", modeAbsoluteIndirectLong}, {"cmp", modeAbsoluteX}, {"dec", modeAbsoluteX}, {"cmp", modeLongX},
+	// $E0-$EF:
+	{"cpx", modeImmediateX}, {"sbc", modeDirectIndirectX}, {"sep", modeImmediate8}, {"sbc", modeStackRelative},
+	{"cpx", modeDirect}, {"sbc", modeDirect}, {"inc", modeDirect}, {"sbc", modeDirectIndirectLong},
+	{"inx", modeImplied}, {"sbc", modeImmediateM}, {"nop", modeImplied}, {"xba", modeImplied},
+	{"cpx", modeAbsolute}, {"sbc", modeAbsolute}, {"inc", modeAbsolute}, {"sbc", modeLong},
+	// $F0-$FF:
+	{"beq", modeRelative}, {"sbc", modeDirectIndirectY}, {"sbc", modeDirectIndirect}, {"sbc", modeStackRelativeIndirectY},
+	{"pea", modeAbsolute}, {"sbc", modeDirectX}, {"inc", modeDirectX}, {"sbc", modeDirectIndirectLongY},
+	{"sed", modeImplied}, {"sbc", modeAbsoluteY}, {"plx", modeImplied}, {"xce", modeImplied},
+	{"jsr", modeAbsoluteIndirectX}, {"sbc", modeAbsoluteX}, {"inc", modeAbsoluteX}, {"sbc", modeLongX},
+}
+
+// opcodeFor maps mnemonic and addressing mode to opcode
+var opcodeFor = func() map[string]map[addressMode]byte {
+	m := make(map[string]map[addressMode]byte)
+	for i, o := range opcodes {
+		if m[o.mnemonic] == nil {
+			m[o.mnemonic] = make(map[addressMode]byte)
+		}
+		m[o.mnemonic][o.mode] = byte(i)
+	}
+	return m
+}()
@@ -44,9 +44,10 @@ type busEntry struct {
 // simplest, fastest and with greater memory usage
 //
 type Bus struct {
-	EA      uint32                 // last memory access - r/w
-	Write   bool                   // is write op?
-	segment [1048576]memory.Memory // 2^10 because segments are 4bits length
+	EA      uint32                                 // last memory access - r/w
+	Write   bool                                   // is write op?
+	Watch   func(a uint32, write bool, value byte) // called for every access if set, e.g. by a debugger
+	segment [1048576]memory.Memory                 // 2^10 because segments are 4bits length
 	entries []busEntry
 }
 
@@ -120,6 +121,9 @@ func (b *Bus) EaRead(a uint32) byte {
 	b.EA = a // for debug interface
 	b.Write = false
 	value := mem.Read(a)
+	if b.Watch != nil {
+		b.Watch(a, false, value)
+	}
 	return value
 }
 
@@ -132,6 +136,9 @@ func (b *Bus) EaWrite(a uint32, value byte) {
 	b.EA = a // for debug interface
 	b.Write = true
 	mem.Write(a, value)
+	if b.Watch != nil {
+		b.Watch(a, true, value)
+	}
 }
 
 // Dumps 16 bytes of memory, aligned to 16 bytes, used by
@@ -28,14 +28,6 @@ var (
 
 const CPUFrequency = 14000000 // 14MHz. XXX - fix it and move to platform?
 
-// interrupt types
-const (
-	_ = iota
-	interruptNone
-	interruptNMI
-	interruptIRQ
-)
-
 // addressing modes
 // reference:
 // 1 - "Programming the 65816" / WDC 2007
@@ -382,9 +374,10 @@ type CPU struct {
 	B byte // Break flag
 	E byte // Emulation mode flag
 
-	interrupt byte // interrupt type to perform
-	stall     int  // number of cycles to stall
-	table     [256]func(*stepInfo)
+	nmiPending bool // NMI to perform; takes priority over irqPending
+	irqPending bool // IRQ to perform
+	stall      int  // number of cycles to stall
+	table      [256]func(*stepInfo)
 }
 
 func New(bus *bus.Bus) (*CPU, error) {
@@ -790,15 +783,15 @@ func (cpu *CPU) setZN16(value uint16) {
 	cpu.setN16(value)
 }
 
-// triggerNMI causes a non-maskable interrupt to occur on the next cycle
-func (cpu *CPU) triggerNMI() {
-	cpu.interrupt = interruptNMI
+// TriggerNMI causes a non-maskable interrupt to occur on the next cycle
+func (cpu *CPU) TriggerNMI() {
+	cpu.nmiPending = true
 }
 
-// triggerIRQ causes an IRQ interrupt to occur on the next cycle
+// TriggerIRQ causes an IRQ interrupt to occur on the next cycle; a pending NMI is performed first
 func (cpu *CPU) TriggerIRQ() {
 	if cpu.I == 0 {
-		cpu.interrupt = interruptIRQ
+		cpu.irqPending = true
 	}
 }
 
@@ -827,13 +820,14 @@ func (cpu *CPU) Step() (int, bool) {
 
 	//cycles := cpu.Cycles
 
-	switch cpu.interrupt {
-	case interruptNMI:
+	// IRQ is level-triggered so an IRQ that loses to an NMI is triggered again while its line is held:
+	switch {
+	case cpu.nmiPending:
 		cpu.nmi()
-	case interruptIRQ:
+	case cpu.irqPending:
 		cpu.irq()
 	}
-	cpu.interrupt = interruptNone
+	cpu.nmiPending, cpu.irqPending = false, false
 
 	cpu.PPC = cpu.PC
 	cpu.PRK = cpu.RK
@@ -1087,13 +1081,21 @@ func (cpu *CPU) Step() (int, bool) {
 }
 
 // NMI - Non-Maskable Interrupt
-// XXXX - valid for 6502
 func (cpu *CPU) nmi() {
-	cpu.push16(cpu.PC)
-	cpu.op_php(nil)
-	//cpu.PC = cpu.Read16(0xFFFA)
-	cpu.PC = cpu.nRead16_cross(0x00, 0xFFEA)
+	if cpu.E == 1 {
+		cpu.push16(cpu.PC)
+		cpu.op_php(nil)
+		cpu.PC = cpu.nRead16_cross(0x00, 0xFFFA)
+	} else {
+		// native mode also pushes the program bank so RTI can return to any bank:
+		cpu.push(cpu.RK)
+		cpu.push16(cpu.PC)
+		cpu.op_php(nil)
+		cpu.PC = cpu.nRead16_cross(0x00, 0xFFEA)
+	}
 	cpu.I = 1
+	cpu.D = 0
+	cpu.RK = 0
 	cpu.Cycles += 7
 }
 
@@ -0,0 +1,251 @@
+package debugger
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DefaultContinueCycles limits `continue` so a routine stuck in a loop returns control to the console:
+const DefaultContinueCycles = 10_000_000
+
+const consoleHelp = `commands:
+  s, step [n]              execute n instructions (default 1)
+  c, continue [cycles]     run until stopped or cycles have run
+  b, break [addr]          add a breakpoint; list breakpoints without addr
+  bd addr                  remove a breakpoint
+  w, watch [addr] [r|w|rw] add a watchpoint (default w); list watchpoints without addr
+  wd addr                  remove a watchpoint
+  r, regs                  show registers
+  m, mem addr [len]        dump memory (default 16 bytes)
+  poke addr byte...        write bytes to memory
+  pc addr                  set the program counter
+  where                    show the listing line at the program counter
+  find text                find the first listing line whose comment contains text
+  q, quit                  exit
+addresses are hex as $7E0010, 0x7E0010, 7E:0010 or 7e0010`
+
+// ParseAddress parses a 24-bit bus address written as $7E0010, 0x7E0010, 7E:0010 or 7e0010
+func ParseAddress(s string) (uint32, error) {
+	t := strings.TrimPrefix(s, "$")
+	t = strings.TrimPrefix(strings.TrimPrefix(t, "0x"), "0X")
+	t = strings.Replace(t, ":", "", 1)
+	a, err := strconv.ParseUint(t, 16, 24)
+	if err != nil {
+		return 0, fmt.Errorf("debugger: bad address '%s'", s)
+	}
+	return uint32(a), nil
+}
+
+func parseHexByte(s string) (byte, error) {
+	t := strings.TrimPrefix(s, "$")
+	t = strings.TrimPrefix(strings.TrimPrefix(t, "0x"), "0X")
+	b, err := strconv.ParseUint(t, 16, 8)
+	if err != nil {
+		return 0, fmt.Errorf("debugger: bad byte '%s'", s)
+	}
+	return byte(b), nil
+}
+
+// Exec runs a single console command and writes its output to w
+func (d *Debugger) Exec(line string, w io.Writer) (quit bool, err error) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return
+	}
+
+	cmd, args := strings.ToLower(args[0]), args[1:]
+	switch cmd {
+	case "h", "help", "?":
+		_, _ = fmt.Fprintln(w, consoleHelp)
+
+	case "s", "step":
+		n := 1
+		if len(args) > 0 {
+			if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+				return false, fmt.Errorf("debugger: bad step count '%s'", args[0])
+			}
+		}
+		stop := Stop{}
+		for i := 0; i < n; i++ {
+			stop = d.Step()
+			if stop.Reason != StopStep {
+				break
+			}
+			if i < n-1 {
+				// show the instructions executed along the way:
+				d.printLocation(w)
+			}
+		}
+		d.printStop(w, stop)
+
+	case "c", "continue":
+		cycles := uint64(DefaultContinueCycles)
+		if len(args) > 0 {
+			if cycles, err = strconv.ParseUint(args[0], 0, 64); err != nil {
+				return false, fmt.Errorf("debugger: bad cycle count '%s'", args[0])
+			}
+		}
+		d.printStop(w, d.Continue(cycles))
+
+	case "b", "break":
+		if len(args) == 0 {
+			for _, pc := range d.Breakpoints() {
+				_, _ = fmt.Fprintf(w, "$%06x\n", pc)
+			}
+			return
+		}
+		var pc uint32
+		if pc, err = ParseAddress(args[0]); err != nil {
+			return
+		}
+		d.AddBreakpoint(pc)
+
+	case "bd":
+		if len(args) == 0 {
+			return false, fmt.Errorf("debugger: bd needs an address")
+		}
+		var pc uint32
+		if pc, err = ParseAddress(args[0]); err != nil {
+			return
+		}
+		if !d.RemoveBreakpoint(pc) {
+			return false, fmt.Errorf("debugger: no breakpoint at $%06x", pc)
+		}
+
+	case "w", "watch":
+		if len(args) == 0 {
+			watchpoints := d.Watchpoints()
+			keys := make(map[uint32]struct{}, len(watchpoints))
+			for a := range watchpoints {
+				keys[a] = struct{}{}
+			}
+			for _, a := range sortedKeys(keys) {
+				_, _ = fmt.Fprintf(w, "$%06x %s\n", a, watchpoints[a])
+			}
+			return
+		}
+		var addr uint32
+		if addr, err = ParseAddress(args[0]); err != nil {
+			return
+		}
+		access := AccessWrite
+		if len(args) > 1 {
+			if access, err = ParseAccess(args[1]); err != nil {
+				return
+			}
+		}
+		d.AddWatchpoint(addr, access)
+
+	case "wd":
+		if len(args) == 0 {
+			return false, fmt.Errorf("debugger: wd needs an address")
+		}
+		var addr uint32
+		if addr, err = ParseAddress(args[0]); err != nil {
+			return
+		}
+		if !d.RemoveWatchpoint(addr) {
+			return false, fmt.Errorf("debugger: no watchpoint at $%06x", addr)
+		}
+
+	case "r", "regs":
+		_, _ = fmt.Fprintf(w, "%v cycles=%d\n", d.Registers(), d.System.CPU.AllCycles)
+
+	case "m", "mem":
+		if len(args) == 0 {
+			return false, fmt.Errorf("debugger: mem needs an address")
+		}
+		var addr uint32
+		if addr, err = ParseAddress(args[0]); err != nil {
+			return
+		}
+		n := 16
+		if len(args) > 1 {
+			var v uint64
+			if v, err = strconv.ParseUint(args[1], 0, 16); err != nil {
+				return false, fmt.Errorf("debugger: bad length '%s'", args[1])
+			}
+			n = int(v)
+		}
+		var data []byte
+		if data, err = d.ReadMemory(addr, n); err != nil {
+			return
+		}
+		for i := 0; i < len(data); i += 16 {
+			j := i + 16
+			if j > len(data) {
+				j = len(data)
+			}
+			_, _ = fmt.Fprintf(w, "$%06x  % x\n", (addr+uint32(i))&0xFFFFFF, data[i:j])
+		}
+
+	case "poke":
+		if len(args) < 2 {
+			return false, fmt.Errorf("debugger: poke needs an address and bytes")
+		}
+		var addr uint32
+		if addr, err = ParseAddress(args[0]); err != nil {
+			return
+		}
+		data := make([]byte, 0, len(args)-1)
+		for _, a := range args[1:] {
+			var b byte
+			if b, err = parseHexByte(a); err != nil {
+				return
+			}
+			data = append(data, b)
+		}
+		err = d.WriteMemory(addr, data)
+
+	case "pc":
+		if len(args) == 0 {
+			return false, fmt.Errorf("debugger: pc needs an address")
+		}
+		var pc uint32
+		if pc, err = ParseAddress(args[0]); err != nil {
+			return
+		}
+		d.SetPC(pc)
+		d.printLocation(w)
+
+	case "where":
+		d.printLocation(w)
+
+	case "find":
+		if d.Source == nil {
+			return false, fmt.Errorf("debugger: no listing loaded")
+		}
+		l, ok := d.Source.FindComment(strings.Join(args, " "))
+		if !ok {
+			return false, fmt.Errorf("debugger: no listing comment contains '%s'", strings.Join(args, " "))
+		}
+		_, _ = fmt.Fprintln(w, l)
+
+	case "q", "quit", "exit":
+		return true, nil
+
+	default:
+		return false, fmt.Errorf("debugger: unknown command '%s'; try 'help'", cmd)
+	}
+
+	return
+}
+
+func (d *Debugger) printStop(w io.Writer, stop Stop) {
+	if stop.Reason != StopStep {
+		_, _ = fmt.Fprintln(w, stop)
+	}
+	if stop.Reason == StopCrash {
+		return
+	}
+	d.printLocation(w)
+}
+
+func (d *Debugger) printLocation(w io.Writer) {
+	_, _ = fmt.Fprintln(w, d.Disassemble())
+	if l, ok := d.Where(); ok {
+		_, _ = fmt.Fprintf(w, "  listing line %d: %s\n", l.Line, l)
+	}
+}
@@ -0,0 +1,325 @@
+package debugger
+
+import (
+	"fmt"
+	"o2/snes/emulator"
+	"sort"
+	"strings"
+)
+
+// Access is the kind of bus access a watchpoint stops on
+type Access uint8
+
+const (
+	AccessRead Access = 1 << iota
+	AccessWrite
+
+	AccessReadWrite = AccessRead | AccessWrite
+)
+
+func (a Access) String() string {
+	switch a {
+	case AccessRead:
+		return "r"
+	case AccessWrite:
+		return "w"
+	case AccessReadWrite:
+		return "rw"
+	default:
+		return fmt.Sprintf("Access(%d)", uint8(a))
+	}
+}
+
+// ParseAccess parses "r", "w" or "rw"
+func ParseAccess(s string) (Access, error) {
+	switch strings.ToLower(s) {
+	case "r":
+		return AccessRead, nil
+	case "w":
+		return AccessWrite, nil
+	case "rw", "wr":
+		return AccessReadWrite, nil
+	default:
+		return 0, fmt.Errorf("debugger: access must be r, w or rw; got '%s'", s)
+	}
+}
+
+type StopReason int
+
+const (
+	// StopStep means a single instruction was executed
+	StopStep StopReason = iota
+	StopBreakpoint
+	StopWatchpoint
+	// StopReturn means the routine started with Call returned
+	StopReturn
+	// StopCycles means Continue ran out of cycles
+	StopCycles
+	// StopCrash means the CPU accessed unmapped memory
+	StopCrash
+)
+
+var stopReasonNames = map[StopReason]string{
+	StopStep:       "step",
+	StopBreakpoint: "breakpoint",
+	StopWatchpoint: "watchpoint",
+	StopReturn:     "return",
+	StopCycles:     "cycles",
+	StopCrash:      "crash",
+}
+
+func (r StopReason) String() string { return stopReasonNames[r] }
+
+// Stop describes why execution stopped
+type Stop struct {
+	Reason StopReason
+	PC     uint32
+
+	// the access that hit a watchpoint:
+	Address uint32
+	Access  Access
+	Value   byte
+
+	// Err is the reason for a crash
+	Err error
+}
+
+func (s Stop) String() string {
+	switch s.Reason {
+	case StopWatchpoint:
+		return fmt.Sprintf("watchpoint: %s $%06x = $%02x at $%06x", s.Access, s.Address, s.Value, s.PC)
+	case StopCrash:
+		return fmt.Sprintf("crash at $%06x: %v", s.PC, s.Err)
+	default:
+		return fmt.Sprintf("%s at $%06x", s.Reason, s.PC)
+	}
+}
+
+// returnTrap is the offset in the called routine's bank that Call returns to
+const returnTrap = 0xFFFF
+
+// Debugger controls the execution of an emulated System with breakpoints on the PC and watchpoints on bus
+// addresses. Watchpoints in the WRAM mirrors at $0000-$1FFF of the system banks are tracked as $7E:0000-1FFF.
+type Debugger struct {
+	System *emulator.System
+
+	// Source maps addresses back to the listing of the code being debugged; optional
+	Source *SourceMap
+
+	breakpoints map[uint32]struct{}
+	watchpoints map[uint32]Access
+
+	returnPC uint32
+	hit      *Stop
+}
+
+func New(s *emulator.System) *Debugger {
+	return &Debugger{
+		System:      s,
+		breakpoints: make(map[uint32]struct{}),
+		watchpoints: make(map[uint32]Access),
+		returnPC:    noReturn,
+	}
+}
+
+// noReturn is a PC the CPU can never reach:
+const noReturn = 0x1000000
+
+// canonical maps the WRAM mirrors to $7E so a watchpoint matches however WRAM is addressed
+func canonical(addr uint32) uint32 {
+	addr &= 0xFFFFFF
+	bank := addr >> 16
+	if (bank < 0x40 || (bank >= 0x80 && bank < 0xC0)) && addr&0xFFFF < 0x2000 {
+		return 0x7E0000 | addr&0x1FFF
+	}
+	return addr
+}
+
+func sortedKeys(m map[uint32]struct{}) []uint32 {
+	keys := make([]uint32, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func (d *Debugger) AddBreakpoint(pc uint32) {
+	d.breakpoints[pc&0xFFFFFF] = struct{}{}
+}
+
+func (d *Debugger) RemoveBreakpoint(pc uint32) bool {
+	_, ok := d.breakpoints[pc&0xFFFFFF]
+	delete(d.breakpoints, pc&0xFFFFFF)
+	return ok
+}
+
+// Breakpoints lists the breakpoints in order
+func (d *Debugger) Breakpoints() []uint32 {
+	return sortedKeys(d.breakpoints)
+}
+
+func (d *Debugger) AddWatchpoint(addr uint32, access Access) {
+	d.watchpoints[canonical(addr)] = access
+}
+
+func (d *Debugger) RemoveWatchpoint(addr uint32) bool {
+	_, ok := d.watchpoints[canonical(addr)]
+	delete(d.watchpoints, canonical(addr))
+	return ok
+}
+
+// Watchpoints lists the watched addresses and the accesses they stop on
+func (d *Debugger) Watchpoints() map[uint32]Access {
+	w := make(map[uint32]Access, len(d.watchpoints))
+	for k, v := range d.watchpoints {
+		w[k] = v
+	}
+	return w
+}
+
+func (d *Debugger) watch(addr uint32, write bool, value byte) {
+	if d.hit != nil {
+		return
+	}
+	access, ok := d.watchpoints[canonical(addr)]
+	if !ok {
+		return
+	}
+	kind := AccessRead
+	if write {
+		kind = AccessWrite
+	}
+	if access&kind == 0 {
+		return
+	}
+	d.hit = &Stop{Reason: StopWatchpoint, Address: addr, Access: kind, Value: value}
+}
+
+func (d *Debugger) Registers() emulator.Registers {
+	return d.System.Registers()
+}
+
+func (d *Debugger) SetPC(pc uint32) {
+	d.System.SetPC(pc)
+}
+
+// Call sets up the CPU to run the routine at pc as if called with `JSR`; execution stops with StopReturn when it
+// returns
+func (d *Debugger) Call(pc uint32) error {
+	ret := pc&0xFF0000 | returnTrap
+	// JSR pushes the address of its last byte:
+	r := uint16(ret - 1)
+	sp := d.System.CPU.SP
+	if err := d.WriteMemory(uint32(sp-1), []byte{byte(r), byte(r >> 8)}); err != nil {
+		return err
+	}
+	d.System.CPU.SP = sp - 2
+	d.returnPC = ret
+	d.SetPC(pc)
+	return nil
+}
+
+// Step executes a single instruction
+func (d *Debugger) Step() (stop Stop) {
+	d.hit = nil
+	d.System.Bus.Watch = d.watch
+	defer func() {
+		d.System.Bus.Watch = nil
+		// the bus panics when the CPU accesses unmapped memory:
+		if r := recover(); r != nil {
+			stop = Stop{Reason: StopCrash, PC: d.System.GetPC(), Err: fmt.Errorf("%v", r)}
+		}
+	}()
+
+	d.System.Step()
+
+	pc := d.System.GetPC()
+	if d.hit != nil {
+		stop = *d.hit
+		stop.PC = pc
+		return
+	}
+	if pc == d.returnPC {
+		d.returnPC = noReturn
+		return Stop{Reason: StopReturn, PC: pc}
+	}
+	if _, ok := d.breakpoints[pc]; ok {
+		return Stop{Reason: StopBreakpoint, PC: pc}
+	}
+	return Stop{Reason: StopStep, PC: pc}
+}
+
+// Continue runs until a breakpoint, watchpoint, return or crash stops execution or maxCycles have run
+func (d *Debugger) Continue(maxCycles uint64) Stop {
+	start := d.System.CPU.AllCycles
+	for {
+		stop := d.Step()
+		if stop.Reason != StopStep {
+			return stop
+		}
+		if d.System.CPU.AllCycles-start >= maxCycles {
+			stop.Reason = StopCycles
+			return stop
+		}
+	}
+}
+
+// ReadMemory reads through the bus; note that reading hardware registers has the same side effects as when the
+// CPU reads them
+func (d *Debugger) ReadMemory(addr uint32, n int) (data []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("debugger: %v", r)
+		}
+	}()
+
+	data = make([]byte, n)
+	for i := range data {
+		data[i] = d.System.Bus.EaRead((addr + uint32(i)) & 0xFFFFFF)
+	}
+	return
+}
+
+func (d *Debugger) WriteMemory(addr uint32, data []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("debugger: %v", r)
+		}
+	}()
+
+	for i, b := range data {
+		d.System.Bus.EaWrite((addr+uint32(i))&0xFFFFFF, b)
+	}
+	return
+}
+
+// Disassemble disassembles the instruction at the PC
+func (d *Debugger) Disassemble() (text string) {
+	defer func() {
+		if r := recover(); r != nil {
+			text = fmt.Sprintf("$%06x  ???", d.System.GetPC())
+		}
+	}()
+
+	return strings.TrimSpace(d.System.CPU.DisassembleCurrentPC())
+}
+
+// Where finds the listing line of the instruction at the PC
+func (d *Debugger) Where() (SourceLine, bool) {
+	if d.Source == nil {
+		return SourceLine{}, false
+	}
+	return d.Source.Lookup(d.System.GetPC())
+}
+
+// LoadListing writes the code of a listing into memory and uses it as the Source
+func (d *Debugger) LoadListing(m *SourceMap) error {
+	for _, l := range m.Lines {
+		if err := d.WriteMemory(l.Address, l.Bytes); err != nil {
+			return err
+		}
+	}
+	d.Source = m
+	return nil
+}
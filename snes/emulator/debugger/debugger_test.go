@@ -0,0 +1,142 @@
+package debugger
+
+import (
+	"bytes"
+	"o2/snes/asm"
+	"o2/snes/emulator"
+	"strings"
+	"testing"
+)
+
+// makeDebugger loads a small update routine at $70:7C00 into a fresh system and calls it
+func makeDebugger(t *testing.T) *Debugger {
+	t.Helper()
+
+	s := &emulator.System{}
+	if err := s.CreateEmulator(); err != nil {
+		t.Fatal(err)
+	}
+
+	a := asm.Emitter{Code: &bytes.Buffer{}, Text: &strings.Builder{}}
+	a.SetBase(0x70_7C00)
+	a.AssumeSEP(0x30)
+	a.Comment("update $7E0010:")
+	a.LDA_imm8_b(0x07)
+	a.STA_long(0x7E0010)
+	a.Comment("update $7EF340:")
+	a.LDA_long(0x7E0011)
+	a.STA_long(0x7EF340)
+	a.RTS()
+
+	m, err := ParseListing(strings.NewReader(a.Text.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := New(s)
+	if err = d.LoadListing(m); err != nil {
+		t.Fatal(err)
+	}
+	s.CPU.SP = 0x01FF
+	s.CPU.SetFlags(0x34)
+	if err = d.Call(0x70_7C00); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestDebugger_Step(t *testing.T) {
+	d := makeDebugger(t)
+
+	if stop := d.Step(); stop.Reason != StopStep || stop.PC != 0x70_7C02 {
+		t.Fatalf("stop = %v", stop)
+	}
+	if r := d.Registers(); r.A&0xFF != 0x07 || r.P&0x30 != 0x30 {
+		t.Errorf("registers = %v", r)
+	}
+	if stop := d.Step(); stop.Reason != StopStep || stop.PC != 0x70_7C06 {
+		t.Fatalf("stop = %v", stop)
+	}
+	if actual := d.System.WRAM[0x10]; actual != 0x07 {
+		t.Errorf("WRAM[$10] = $%02x; expected $07", actual)
+	}
+}
+
+func TestDebugger_Breakpoint(t *testing.T) {
+	d := makeDebugger(t)
+	d.AddBreakpoint(0x70_7C0A)
+
+	stop := d.Continue(1000)
+	if stop.Reason != StopBreakpoint || stop.PC != 0x70_7C0A {
+		t.Fatalf("stop = %v", stop)
+	}
+	l, ok := d.Where()
+	if !ok {
+		t.Fatal("no listing line at the breakpoint")
+	}
+	if l.Comment != "update $7EF340:" || !strings.HasPrefix(l.Text, "sta") {
+		t.Errorf("line = %v", l)
+	}
+
+	if !d.RemoveBreakpoint(0x70_7C0A) {
+		t.Error("breakpoint not removed")
+	}
+	if stop = d.Continue(1000); stop.Reason != StopReturn || stop.PC != 0x70_FFFF {
+		t.Fatalf("stop = %v", stop)
+	}
+}
+
+func TestDebugger_Watchpoint(t *testing.T) {
+	d := makeDebugger(t)
+	d.System.WRAM[0x11] = 0x5A
+	// watch through the bank $00 mirror of WRAM:
+	d.AddWatchpoint(0x00_0011, AccessRead)
+	d.AddWatchpoint(0x7E_F340, AccessWrite)
+
+	stop := d.Continue(1000)
+	if stop.Reason != StopWatchpoint || stop.Address != 0x7E_0011 || stop.Access != AccessRead || stop.Value != 0x5A {
+		t.Fatalf("stop = %v", stop)
+	}
+	stop = d.Continue(1000)
+	if stop.Reason != StopWatchpoint || stop.Address != 0x7E_F340 || stop.Access != AccessWrite || stop.Value != 0x5A {
+		t.Fatalf("stop = %v", stop)
+	}
+	if stop.PC != 0x70_7C0E {
+		t.Errorf("stopped at $%06x; expected after the store at $707c0e", stop.PC)
+	}
+}
+
+func TestDebugger_Crash(t *testing.T) {
+	d := makeDebugger(t)
+	// only banks $00-3F map ROM so nothing is at $40:8000:
+	d.SetPC(0x70_0000)
+	copy(d.System.SRAM[0:], []byte{0xAF, 0x00, 0x80, 0x40}) // lda.l $408000
+
+	if stop := d.Step(); stop.Reason != StopCrash || stop.Err == nil {
+		t.Fatalf("stop = %v", stop)
+	}
+}
+
+func TestDebugger_Exec(t *testing.T) {
+	d := makeDebugger(t)
+
+	w := &strings.Builder{}
+	for _, line := range []string{"b 70:7c06", "c", "m $7e0010 2", "poke 7e0011 $12 34", "mem 0x7e0011 2"} {
+		if _, err := d.Exec(line, w); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+	}
+	out := w.String()
+	for _, expected := range []string{"breakpoint at $707c06", "update $7EF340:", "$7e0010  07 00", "$7e0011  12 34"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("output does not contain %q:\n%s", expected, out)
+		}
+	}
+
+	if quit, _ := d.Exec("q", w); !quit {
+		t.Error("q did not quit")
+	}
+	if _, err := d.Exec("bogus", w); err == nil {
+		t.Error("expected an error for an unknown command")
+	}
+}
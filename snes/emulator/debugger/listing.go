@@ -0,0 +1,163 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SourceLine is an instruction or data from an asm.Emitter listing
+type SourceLine struct {
+	// Line is the line number in the listing starting at 1
+	Line    int
+	Address uint32
+	Bytes   []byte
+	// Text is the instruction as listed, e.g. "lda.b #$01"
+	Text string
+	// Comment is the nearest asm.Emitter comment above the instruction; it describes the block of code the
+	// instruction belongs to
+	Comment string
+}
+
+func (l SourceLine) String() string {
+	if l.Comment == "" {
+		return fmt.Sprintf("$%06x  %s", l.Address, l.Text)
+	}
+	return fmt.Sprintf("$%06x  %-20s ; %s", l.Address, l.Text, l.Comment)
+}
+
+// SourceMap maps addresses back to the lines of an asm.Emitter listing
+type SourceMap struct {
+	// Lines are the listed instructions and data in order
+	Lines []SourceLine
+
+	byAddress map[uint32]int
+}
+
+var (
+	// log.Print prefixes the first line of a listing with the date and time:
+	logPrefixRe   = regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(\.\d+)? `)
+	baseRe        = regexp.MustCompile(`^base \$([0-9a-fA-F]{6})\s*$`)
+	commentRe     = regexp.MustCompile(`^\s+; (.*)$`)
+	instructionRe = regexp.MustCompile(`^\s+(\S+)\s*(.*?)\s*; \$([0-9a-fA-F]{6})\s+((?:[0-9a-fA-F]{2} ?)+)$`)
+	dataRe        = regexp.MustCompile(`^\s+db\s+(.*)$`)
+)
+
+// ParseListing parses the Text of an asm.Emitter, e.g. as logged with a generated update routine
+func ParseListing(r io.Reader) (*SourceMap, error) {
+	m := &SourceMap{byAddress: make(map[uint32]int)}
+
+	var address uint32
+	comment := ""
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		text := logPrefixRe.ReplaceAllString(strings.TrimRight(s.Text(), "\r"), "")
+
+		if g := baseRe.FindStringSubmatch(text); g != nil {
+			a, _ := strconv.ParseUint(g[1], 16, 32)
+			address = uint32(a)
+			comment = ""
+			continue
+		}
+		if g := commentRe.FindStringSubmatch(text); g != nil {
+			comment = g[1]
+			continue
+		}
+		if g := instructionRe.FindStringSubmatch(text); g != nil {
+			a, _ := strconv.ParseUint(g[3], 16, 32)
+			b, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(g[4]), " ", ""))
+			if err != nil {
+				return nil, fmt.Errorf("debugger: listing line %d: %w", n, err)
+			}
+			address = uint32(a)
+			m.add(SourceLine{
+				Line:    n,
+				Address: address,
+				Bytes:   b,
+				Text:    strings.TrimSpace(g[1] + " " + g[2]),
+				Comment: comment,
+			})
+			address += uint32(len(b))
+			continue
+		}
+		if g := dataRe.FindStringSubmatch(text); g != nil {
+			var b []byte
+			for _, v := range strings.Split(g[1], ",") {
+				v = strings.TrimPrefix(strings.TrimSpace(v), "$")
+				c, err := strconv.ParseUint(v, 16, 8)
+				if err != nil {
+					return nil, fmt.Errorf("debugger: listing line %d: %w", n, err)
+				}
+				b = append(b, byte(c))
+			}
+			m.add(SourceLine{
+				Line:    n,
+				Address: address,
+				Bytes:   b,
+				Text:    strings.TrimSpace(text),
+				Comment: comment,
+			})
+			address += uint32(len(b))
+			continue
+		}
+		// anything else, e.g. other log output, ends the block the last comment described:
+		comment = ""
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	// keep the lines in address order, the latest listed winning:
+	sort.SliceStable(m.Lines, func(i, j int) bool { return m.Lines[i].Address < m.Lines[j].Address })
+	m.index()
+	return m, nil
+}
+
+func (m *SourceMap) add(l SourceLine) {
+	m.Lines = append(m.Lines, l)
+}
+
+func (m *SourceMap) index() {
+	m.byAddress = make(map[uint32]int)
+	for i, l := range m.Lines {
+		for j := range l.Bytes {
+			k := l.Address + uint32(j)
+			if o, ok := m.byAddress[k]; ok && m.Lines[o].Line > l.Line {
+				continue
+			}
+			m.byAddress[k] = i
+		}
+	}
+}
+
+// Lookup finds the line whose instruction or data covers the address
+func (m *SourceMap) Lookup(addr uint32) (SourceLine, bool) {
+	i, ok := m.byAddress[addr]
+	if !ok {
+		return SourceLine{}, false
+	}
+	return m.Lines[i], true
+}
+
+// FindComment finds the first line described by a comment containing the text, ignoring case
+func (m *SourceMap) FindComment(text string) (SourceLine, bool) {
+	text = strings.ToLower(text)
+	best := -1
+	for i, l := range m.Lines {
+		if !strings.Contains(strings.ToLower(l.Comment), text) {
+			continue
+		}
+		if best < 0 || l.Line < m.Lines[best].Line {
+			best = i
+		}
+	}
+	if best < 0 {
+		return SourceLine{}, false
+	}
+	return m.Lines[best], true
+}
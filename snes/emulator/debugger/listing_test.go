@@ -0,0 +1,54 @@
+package debugger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseListing(t *testing.T) {
+	// as logged by a game's update routine generator:
+	listing := `2021/03/01 02:03:04.567890 base $707c00
+    ; update $7EF340:
+    lda.b #$01     ; $707c00  a9 01
+    sta.l $7ef340  ; $707c02  8f 40 f3 7e
+    ; reserved:
+    db    $ea, $ea
+    rts            ; $707c08  60
+`
+	m, err := ParseListing(strings.NewReader(listing))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Lines) != 4 {
+		t.Fatalf("len(Lines) = %d; expected 4", len(m.Lines))
+	}
+
+	tests := []struct {
+		addr    uint32
+		line    int
+		text    string
+		comment string
+	}{
+		{0x707c00, 3, "lda.b #$01", "update $7EF340:"},
+		{0x707c04, 4, "sta.l $7ef340", "update $7EF340:"},
+		{0x707c07, 6, "db    $ea, $ea", "reserved:"},
+		{0x707c08, 7, "rts", "reserved:"},
+	}
+	for _, tt := range tests {
+		l, ok := m.Lookup(tt.addr)
+		if !ok {
+			t.Errorf("Lookup($%06x) not found", tt.addr)
+			continue
+		}
+		if l.Line != tt.line || l.Text != tt.text || l.Comment != tt.comment {
+			t.Errorf("Lookup($%06x) = %d %q %q; expected %d %q %q", tt.addr, l.Line, l.Text, l.Comment, tt.line, tt.text, tt.comment)
+		}
+	}
+	if _, ok := m.Lookup(0x707c09); ok {
+		t.Error("Lookup past the end found a line")
+	}
+
+	if l, ok := m.FindComment("7ef340"); !ok || l.Address != 0x707c00 {
+		t.Errorf("FindComment = %v, %v", l, ok)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"o2/snes"
+	"o2/snes/emulator"
+	"o2/snes/emulator/debugger"
+	"o2/snes/emulator/savestate"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	var err error
+
+	romPath := flag.String("rom", "", "LoROM ROM file to load")
+	wramPath := flag.String("wram", "", "WRAM snapshot to load ($7E0000-$7FFFFF)")
+	sramPath := flag.String("sram", "", "SRAM snapshot to load ($700000-)")
+	statePath := flag.String("state", "", "save state to load WRAM, SRAM and CPU registers from (snes9x, bsnes or o2)")
+	listingPath := flag.String("listing", "", "asm.Emitter listing (e.g. a logged update routine) to load into memory")
+	pcFlag := flag.String("pc", "", "address of the routine to call; defaults to the start of the listing")
+	flag.Parse()
+
+	log.SetFlags(0)
+
+	s := &emulator.System{}
+	if err = s.CreateEmulator(); err != nil {
+		log.Fatal(err)
+	}
+
+	var rom *snes.ROM
+	if *romPath != "" {
+		var b []byte
+		if b, err = ioutil.ReadFile(*romPath); err != nil {
+			log.Fatal(err)
+		}
+		if rom, err = snes.NewROM(filepath.Base(*romPath), b); err != nil {
+			log.Fatal(err)
+		}
+		if err = s.LoadROM(rom); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// states and snapshots are loaded after the ROM since loading a ROM clears WRAM and SRAM:
+	if *statePath != "" {
+		if err = loadState(s, *statePath, rom); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *wramPath != "" {
+		if err = loadSnapshot(s.WRAM[:], *wramPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *sramPath != "" {
+		if err = loadSnapshot(s.SRAM[:], *sramPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	d := debugger.New(s)
+	if *listingPath != "" {
+		var f *os.File
+		if f, err = os.Open(*listingPath); err != nil {
+			log.Fatal(err)
+		}
+		var m *debugger.SourceMap
+		m, err = debugger.ParseListing(f)
+		_ = f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err = d.LoadListing(m); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var pc uint32
+	call := true
+	switch {
+	case *pcFlag != "":
+		if pc, err = debugger.ParseAddress(*pcFlag); err != nil {
+			log.Fatal(err)
+		}
+	case d.Source != nil && len(d.Source.Lines) > 0:
+		pc = d.Source.Lines[0].Address
+	case *statePath != "":
+		// resume where the state was saved:
+		call = false
+	default:
+		log.Fatal("need -pc, -listing or -state to know where to start")
+	}
+
+	if call {
+		// start in native mode with 8-bit registers the way the frame hook calls the update routines:
+		s.CPU.E = 0
+		s.CPU.SetFlags(0x34)
+		if *statePath == "" {
+			s.CPU.SP = 0x01FF
+		}
+		if err = d.Call(pc); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("calling $%06x; type 'help' for commands\n", pc)
+	} else {
+		fmt.Printf("resuming at $%06x; type 'help' for commands\n", s.GetPC())
+	}
+	_, _ = d.Exec("where", os.Stdout)
+
+	in := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("> ")
+		if !in.Scan() {
+			break
+		}
+		quit, err := d.Exec(in.Text(), os.Stdout)
+		if err != nil {
+			fmt.Println(err)
+		}
+		if quit {
+			break
+		}
+	}
+}
+
+func loadSnapshot(mem []byte, path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(b) > len(mem) {
+		return fmt.Errorf("snapshot '%s' is %#x bytes; larger than the %#x of memory", path, len(b), len(mem))
+	}
+	copy(mem, b)
+	return nil
+}
+
+func loadState(s *emulator.System, path string, rom *snes.ROM) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// bsnes states need the SRAM size from the ROM header:
+	sramSize := 0
+	if rom != nil && rom.Header.RAMSize > 0 {
+		sramSize = 1024 << rom.Header.RAMSize
+	}
+	st, _, err := savestate.Load(f, sramSize)
+	if err != nil {
+		return err
+	}
+	return st.Apply(s)
+}
@@ -0,0 +1,20 @@
+package hwio
+
+// apu stands in for the SPC700 which is not emulated. Its ports read back $AA, $BB after reset like the IPL ROM's
+// boot signature and then echo whatever the CPU last wrote to them, which satisfies the handshakes games use to
+// upload their sound programs.
+type apu struct {
+	ports [4]byte
+}
+
+func (a *apu) reset() {
+	a.ports = [4]byte{0xAA, 0xBB, 0x00, 0x00}
+}
+
+func (a *apu) read(offs uint32) byte {
+	return a.ports[offs&3]
+}
+
+func (a *apu) write(offs uint32, value byte) {
+	a.ports[offs&3] = value
+}
@@ -0,0 +1,101 @@
+package hwio
+
+func (h *HW) readCPU(offs uint32) (value byte) {
+	t := &h.timing
+	switch offs {
+	case 0x4210:
+		// RDNMI: reading acknowledges NMI; CPU version 2
+		value = 0x02
+		if t.nmiFlag {
+			value |= 0x80
+		}
+		t.nmiFlag = false
+	case 0x4211:
+		// TIMEUP: reading acknowledges IRQ
+		if t.irqFlag {
+			value = 0x80
+		}
+		t.irqFlag = false
+	case 0x4212:
+		// HVBJOY:
+		if t.inVBlank() {
+			value |= 0x80
+		}
+		if t.inHBlank() {
+			value |= 0x40
+		}
+		if h.nmitimen&0x01 != 0 && t.joypadBusy() {
+			value |= 0x01
+		}
+	case 0x4213:
+		// RDIO:
+		value = h.wrio
+	case 0x4214:
+		value = byte(h.rddiv)
+	case 0x4215:
+		value = byte(h.rddiv >> 8)
+	case 0x4216:
+		value = byte(h.rdmpy)
+	case 0x4217:
+		value = byte(h.rdmpy >> 8)
+	case 0x4218, 0x4219, 0x421A, 0x421B, 0x421C, 0x421D, 0x421E, 0x421F:
+		// JOY1L-JOY4H:
+		j := h.joy[(offs-0x4218)>>1]
+		value = byte(j >> ((offs & 1) * 8))
+	default:
+		value = h.unhandled[offs-0x2000]
+	}
+	return
+}
+
+func (h *HW) writeCPU(offs uint32, value byte) {
+	switch offs {
+	case 0x4200:
+		// NMITIMEN: enabling NMI during vblank before it is acknowledged raises it immediately
+		if h.nmitimen&0x80 == 0 && value&0x80 != 0 && h.timing.nmiFlag {
+			h.cpu.TriggerNMI()
+		}
+		h.nmitimen = value
+		if value&0x30 == 0 {
+			h.timing.irqFlag = false
+		}
+	case 0x4201:
+		h.wrio = value
+	case 0x4202:
+		h.wrmpya = value
+	case 0x4203:
+		// WRMPYB starts the multiplication:
+		h.rdmpy = uint16(h.wrmpya) * uint16(value)
+	case 0x4204:
+		h.wrdiv = h.wrdiv&0xFF00 | uint16(value)
+	case 0x4205:
+		h.wrdiv = h.wrdiv&0x00FF | uint16(value)<<8
+	case 0x4206:
+		// WRDIVB starts the division:
+		if value == 0 {
+			h.rddiv = 0xFFFF
+			h.rdmpy = h.wrdiv
+		} else {
+			h.rddiv = h.wrdiv / uint16(value)
+			h.rdmpy = h.wrdiv % uint16(value)
+		}
+	case 0x4207:
+		h.htime = h.htime&0x100 | uint16(value)
+	case 0x4208:
+		h.htime = h.htime&0x0FF | uint16(value&1)<<8
+	case 0x4209:
+		h.vtime = h.vtime&0x100 | uint16(value)
+	case 0x420A:
+		h.vtime = h.vtime&0x0FF | uint16(value&1)<<8
+	case 0x420B:
+		// MDMAEN:
+		h.startDMA(value)
+	case 0x420C:
+		// HDMAEN: HDMA is not emulated
+		h.hdmaen = value
+	case 0x420D:
+		h.memsel = value
+	default:
+		h.unhandled[offs-0x2000] = value
+	}
+}
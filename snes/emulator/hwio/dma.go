@@ -0,0 +1,62 @@
+package hwio
+
+// dmaPatterns are the B-bus address offsets each transfer unit writes to, by the DMAPx transfer pattern
+var dmaPatterns = [8][]byte{
+	{0},
+	{0, 1},
+	{0, 0},
+	{0, 0, 1, 1},
+	{0, 1, 2, 3},
+	{0, 1, 0, 1},
+	{0, 0},
+	{0, 0, 1, 1},
+}
+
+// startDMA performs general-purpose DMA on each channel enabled in MDMAEN in priority order. The CPU is halted
+// for the duration so the whole transfer happens at once.
+func (h *HW) startDMA(channels byte) {
+	for c := 0; c < 8; c++ {
+		if channels&(1<<c) == 0 {
+			continue
+		}
+
+		r := &h.dma[c]
+		params := r[0]
+		bAddr := r[1]
+		aAddr := uint16(r[2]) | uint16(r[3])<<8
+		aBank := uint32(r[4]) << 16
+		count := int(uint16(r[5]) | uint16(r[6])<<8)
+		if count == 0 {
+			count = 0x10000
+		}
+
+		var step uint16
+		switch {
+		case params&0x08 != 0:
+			step = 0
+		case params&0x10 != 0:
+			step = 0xFFFF
+		default:
+			step = 1
+		}
+
+		pattern := dmaPatterns[params&7]
+		for i := 0; i < count; i++ {
+			b := 0x2100 | uint32(bAddr+pattern[i%len(pattern)])
+			a := aBank | uint32(aAddr)
+			if params&0x80 == 0 {
+				// A-bus to B-bus:
+				h.Write(b, h.bus.EaRead(a))
+			} else {
+				h.bus.EaWrite(a, h.Read(b))
+			}
+			aAddr += step
+		}
+
+		r[2], r[3] = byte(aAddr), byte(aAddr>>8)
+		r[5], r[6] = 0, 0
+
+		// 8 master clocks per byte plus 8 per channel:
+		h.clock(8*count + 8)
+	}
+}
@@ -0,0 +1,145 @@
+package hwio
+
+import (
+	"o2/snes/emulator/bus"
+	"o2/snes/emulator/cpu65c816"
+)
+
+// HW emulates the memory-mapped hardware registers at $2000-$7FFF of banks $00-$3F and $80-$BF: the PPU and APU
+// ports at $2100-$21FF and the CPU's registers at $4200-$43FF including DMA. Nothing is rendered; the PPU's
+// memories are kept so their contents can be verified.
+type HW struct {
+	bus *bus.Bus
+	cpu *cpu65c816.CPU
+
+	VRAM  [0x10000]byte
+	CGRAM [0x200]byte
+	OAM   [0x220]byte
+
+	// Joypads are the buttons held on each controller as read by auto-joypad read
+	Joypads [4]uint16
+
+	// unhandled keeps the last value written to addresses that are not emulated:
+	unhandled [0x6000]byte
+
+	ppu ppu
+	apu apu
+
+	// WRAM port:
+	wmAddr uint32
+
+	// CPU registers:
+	nmitimen byte
+	wrio     byte
+	wrmpya   byte
+	wrdiv    uint16
+	rddiv    uint16
+	rdmpy    uint16
+	htime    uint16
+	vtime    uint16
+	hdmaen   byte
+	memsel   byte
+	joy      [4]uint16
+
+	dma [8][0x10]byte
+
+	timing timing
+}
+
+func New(b *bus.Bus, cpu *cpu65c816.CPU) *HW {
+	h := &HW{
+		bus: b,
+		cpu: cpu,
+	}
+	h.Reset()
+	return h
+}
+
+// Reset puts the registers in their power-on state
+func (h *HW) Reset() {
+	joypads := h.Joypads
+	*h = HW{bus: h.bus, cpu: h.cpu, Joypads: joypads}
+	h.htime = 0x1FF
+	h.vtime = 0x1FF
+	h.apu.reset()
+}
+
+func (h *HW) Read(address uint32) (value byte) {
+	offs := address & 0xFFFF
+	switch {
+	case offs >= 0x2100 && offs < 0x2140:
+		value = h.readPPU(offs)
+	case offs >= 0x2140 && offs < 0x2180:
+		value = h.apu.read(offs)
+	case offs >= 0x2180 && offs < 0x2184:
+		value = h.readWRAMPort(offs)
+	case offs >= 0x4200 && offs < 0x4300:
+		value = h.readCPU(offs)
+	case offs >= 0x4300 && offs < 0x4380:
+		value = h.dma[(offs>>4)&7][offs&0xF]
+	default:
+		value = h.unhandled[offs-0x2000]
+	}
+
+	//log.Printf("hwio[$%06x] -> $%02x\n", address, value)
+	return
+}
+
+func (h *HW) Write(address uint32, value byte) {
+	offs := address & 0xFFFF
+
+	//log.Printf("hwio[$%06x] <- $%02x\n", address, value)
+	switch {
+	case offs >= 0x2100 && offs < 0x2140:
+		h.writePPU(offs, value)
+	case offs >= 0x2140 && offs < 0x2180:
+		h.apu.write(offs, value)
+	case offs >= 0x2180 && offs < 0x2184:
+		h.writeWRAMPort(offs, value)
+	case offs >= 0x4200 && offs < 0x4300:
+		h.writeCPU(offs, value)
+	case offs >= 0x4300 && offs < 0x4380:
+		h.dma[(offs>>4)&7][offs&0xF] = value
+	default:
+		h.unhandled[offs-0x2000] = value
+	}
+}
+
+func (h *HW) Shutdown() {
+}
+
+func (h *HW) Size() uint32 {
+	return 0x6000
+}
+
+func (h *HW) Clear() {
+	h.Reset()
+}
+
+func (h *HW) Dump(address uint32) []byte {
+	return nil
+}
+
+func (h *HW) readWRAMPort(offs uint32) byte {
+	if offs != 0x2180 {
+		// WMADD is write-only:
+		return 0
+	}
+	value := h.bus.EaRead(0x7E0000 + h.wmAddr)
+	h.wmAddr = (h.wmAddr + 1) & 0x1FFFF
+	return value
+}
+
+func (h *HW) writeWRAMPort(offs uint32, value byte) {
+	switch offs {
+	case 0x2180:
+		h.bus.EaWrite(0x7E0000+h.wmAddr, value)
+		h.wmAddr = (h.wmAddr + 1) & 0x1FFFF
+	case 0x2181:
+		h.wmAddr = h.wmAddr&0x1FF00 | uint32(value)
+	case 0x2182:
+		h.wmAddr = h.wmAddr&0x100FF | uint32(value)<<8
+	case 0x2183:
+		h.wmAddr = h.wmAddr&0x0FFFF | uint32(value&1)<<16
+	}
+}
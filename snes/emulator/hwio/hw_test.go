@@ -0,0 +1,271 @@
+package hwio
+
+import (
+	"bytes"
+	"o2/snes/emulator/bus"
+	"o2/snes/emulator/cpu65c816"
+	"o2/snes/emulator/memory"
+	"testing"
+)
+
+func newTestHW(t *testing.T) (*HW, []byte) {
+	b, _ := bus.New()
+	cpu, _ := cpu65c816.New(b)
+	wram := make([]byte, 0x20000)
+	if err := b.Attach(memory.NewRAM(wram, 0x7E0000), "wram", 0x7E0000, 0x7FFFFF); err != nil {
+		t.Fatal(err)
+	}
+	h := New(b, cpu)
+	if err := b.Attach(h, "hwio", 0x2000, 0x7FFF); err != nil {
+		t.Fatal(err)
+	}
+	return h, wram
+}
+
+func TestHW_VRAM(t *testing.T) {
+	h, _ := newTestHW(t)
+
+	// increment after the high byte, word address $1000:
+	h.Write(0x2115, 0x80)
+	h.Write(0x2116, 0x00)
+	h.Write(0x2117, 0x10)
+	for _, v := range []byte{0x11, 0x22, 0x33, 0x44} {
+		h.Write(0x2118, v)
+		h.Write(0x2119, ^v)
+	}
+	if actual, expected := h.VRAM[0x2000:0x2008], []byte{0x11, 0xEE, 0x22, 0xDD, 0x33, 0xCC, 0x44, 0xBB}; !bytes.Equal(actual, expected) {
+		t.Errorf("VRAM = % x, expected % x", actual, expected)
+	}
+
+	// reads are prefetched when the address is set:
+	h.Write(0x2116, 0x01)
+	h.Write(0x2117, 0x10)
+	if actual := []byte{h.Read(0x2139), h.Read(0x213A), h.Read(0x2139), h.Read(0x213A)}; !bytes.Equal(actual, []byte{0x22, 0xDD, 0x33, 0xCC}) {
+		t.Errorf("VMDATAREAD = % x, expected 22 dd 33 cc", actual)
+	}
+
+	// increment by 32 after the low byte:
+	h.Write(0x2115, 0x01)
+	h.Write(0x2116, 0x00)
+	h.Write(0x2117, 0x00)
+	h.Write(0x2118, 0x55)
+	h.Write(0x2118, 0x66)
+	if h.VRAM[0x0000] != 0x55 || h.VRAM[0x0040] != 0x66 {
+		t.Errorf("VRAM[$0000] = %02x, VRAM[$0040] = %02x, expected 55, 66", h.VRAM[0x0000], h.VRAM[0x0040])
+	}
+}
+
+func TestHW_CGRAM(t *testing.T) {
+	h, _ := newTestHW(t)
+
+	h.Write(0x2121, 0x01)
+	h.Write(0x2122, 0xEF)
+	// the first byte is latched until the second is written:
+	if h.CGRAM[2] != 0 {
+		t.Errorf("CGRAM[2] = %02x before the high byte is written", h.CGRAM[2])
+	}
+	h.Write(0x2122, 0xFF)
+	if actual, expected := h.CGRAM[2:4], []byte{0xEF, 0x7F}; !bytes.Equal(actual, expected) {
+		t.Errorf("CGRAM = % x, expected % x", actual, expected)
+	}
+
+	h.Write(0x2121, 0x01)
+	if actual := []byte{h.Read(0x213B), h.Read(0x213B)}; !bytes.Equal(actual, []byte{0xEF, 0x7F}) {
+		t.Errorf("CGDATAREAD = % x, expected ef 7f", actual)
+	}
+}
+
+func TestHW_OAM(t *testing.T) {
+	h, _ := newTestHW(t)
+
+	h.Write(0x2102, 0x02)
+	h.Write(0x2103, 0x00)
+	h.Write(0x2104, 0x12)
+	h.Write(0x2104, 0x34)
+	if actual, expected := h.OAM[4:6], []byte{0x12, 0x34}; !bytes.Equal(actual, expected) {
+		t.Errorf("OAM = % x, expected % x", actual, expected)
+	}
+
+	// the high table is written a byte at a time:
+	h.Write(0x2102, 0x00)
+	h.Write(0x2103, 0x01)
+	h.Write(0x2104, 0x56)
+	if actual, expected := h.OAM[0x200], byte(0x56); actual != expected {
+		t.Errorf("OAM[$200] = %02x, expected %02x", actual, expected)
+	}
+}
+
+func TestHW_Math(t *testing.T) {
+	h, _ := newTestHW(t)
+
+	h.Write(0x4202, 200)
+	h.Write(0x4203, 100)
+	if actual, expected := uint16(h.Read(0x4216))|uint16(h.Read(0x4217))<<8, uint16(20000); actual != expected {
+		t.Errorf("RDMPY = %d, expected %d", actual, expected)
+	}
+
+	h.Write(0x4204, 0x39)
+	h.Write(0x4205, 0x30)
+	h.Write(0x4206, 100)
+	if actual, expected := uint16(h.Read(0x4214))|uint16(h.Read(0x4215))<<8, uint16(12345/100); actual != expected {
+		t.Errorf("RDDIV = %d, expected %d", actual, expected)
+	}
+	if actual, expected := uint16(h.Read(0x4216))|uint16(h.Read(0x4217))<<8, uint16(12345%100); actual != expected {
+		t.Errorf("RDMPY = %d, expected %d", actual, expected)
+	}
+
+	// mode 7 signed multiplication: -2 * 3
+	h.Write(0x211B, 0xFE)
+	h.Write(0x211B, 0xFF)
+	h.Write(0x211C, 0x03)
+	if actual, expected := []byte{h.Read(0x2134), h.Read(0x2135), h.Read(0x2136)}, []byte{0xFA, 0xFF, 0xFF}; !bytes.Equal(actual, expected) {
+		t.Errorf("MPY = % x, expected % x", actual, expected)
+	}
+}
+
+func TestHW_DMA(t *testing.T) {
+	h, wram := newTestHW(t)
+	for i := 0; i < 0x20; i++ {
+		wram[0x1000+i] = byte(i)
+	}
+
+	dma := func(channel uint32, params, bAddr byte, count uint16) {
+		base := 0x4300 | channel<<4
+		h.Write(base+0, params)
+		h.Write(base+1, bAddr)
+		h.Write(base+2, 0x00)
+		h.Write(base+3, 0x10)
+		h.Write(base+4, 0x7E)
+		h.Write(base+5, byte(count))
+		h.Write(base+6, byte(count>>8))
+	}
+
+	// two registers, write once to VRAM:
+	h.Write(0x2115, 0x80)
+	h.Write(0x2116, 0x00)
+	h.Write(0x2117, 0x00)
+	dma(0, 0x01, 0x18, 0x10)
+	// one register, write twice to CGRAM:
+	h.Write(0x2121, 0x00)
+	dma(1, 0x02, 0x22, 0x20)
+	// one register to OAM:
+	h.Write(0x2102, 0x00)
+	h.Write(0x2103, 0x00)
+	dma(2, 0x00, 0x04, 0x08)
+
+	h.Write(0x420B, 0x07)
+
+	if actual, expected := h.VRAM[0:0x10], wram[0x1000:0x1010]; !bytes.Equal(actual, expected) {
+		t.Errorf("VRAM = % x, expected % x", actual, expected)
+	}
+	for i := 0; i < 0x20; i += 2 {
+		if h.CGRAM[i] != byte(i) || h.CGRAM[i+1] != byte(i+1) {
+			t.Errorf("CGRAM[%#02x] = % x, expected % x", i, h.CGRAM[i:i+2], []byte{byte(i), byte(i + 1)})
+		}
+	}
+	if actual, expected := h.OAM[0:8], wram[0x1000:0x1008]; !bytes.Equal(actual, expected) {
+		t.Errorf("OAM = % x, expected % x", actual, expected)
+	}
+
+	// the channel's address is advanced and its count is exhausted:
+	if actual, expected := []byte{h.Read(0x4302), h.Read(0x4303), h.Read(0x4305), h.Read(0x4306)}, []byte{0x10, 0x10, 0, 0}; !bytes.Equal(actual, expected) {
+		t.Errorf("channel 0 = % x, expected % x", actual, expected)
+	}
+
+	// B-bus to A-bus through the WRAM port:
+	h.Write(0x2181, 0x00)
+	h.Write(0x2182, 0x20)
+	h.Write(0x2183, 0x00)
+	h.Write(0x2180, 0xAB)
+	if actual, expected := wram[0x2000], byte(0xAB); actual != expected {
+		t.Errorf("WRAM[$2000] = %02x, expected %02x", actual, expected)
+	}
+}
+
+// tickScanlines runs the CPU for just over n scanlines
+func tickScanlines(h *HW, n int) {
+	h.Tick(n*clocksPerScanline/MasterClocksPerCycle + 1)
+}
+
+func TestHW_Timing(t *testing.T) {
+	h, _ := newTestHW(t)
+	h.Write(0x4200, 0x81)
+	h.Joypads[0] = 0x8000
+
+	if h.Read(0x4210)&0x80 != 0 {
+		t.Errorf("RDNMI set before vblank")
+	}
+	tickScanlines(h, vblankStartScanline)
+	if actual, expected := h.VCounter(), vblankStartScanline; actual != expected {
+		t.Fatalf("VCounter() = %d, expected %d", actual, expected)
+	}
+	if h.Read(0x4212)&0x81 != 0x81 {
+		t.Errorf("HVBJOY = %02x, expected vblank and auto-joypad read busy", h.Read(0x4212))
+	}
+	if h.Read(0x4210)&0x80 == 0 {
+		t.Errorf("RDNMI not set in vblank")
+	}
+	if h.Read(0x4210)&0x80 != 0 {
+		t.Errorf("RDNMI not cleared by reading")
+	}
+	if actual, expected := h.Read(0x4219), byte(0x80); actual != expected {
+		t.Errorf("JOY1H = %02x, expected %02x", actual, expected)
+	}
+
+	// the rest of the frame wraps around to scanline 0:
+	tickScanlines(h, scanlinesPerFrame-vblankStartScanline)
+	if actual, expected := h.VCounter(), 0; actual != expected {
+		t.Errorf("VCounter() = %d, expected %d", actual, expected)
+	}
+	if h.Read(0x4212)&0x80 != 0 {
+		t.Errorf("HVBJOY vblank set after the frame")
+	}
+
+	// V-IRQ at scanline 10:
+	h.Write(0x4209, 10)
+	h.Write(0x420A, 0)
+	h.Write(0x4200, 0x20)
+	tickScanlines(h, 9)
+	if h.Read(0x4211)&0x80 != 0 {
+		t.Errorf("TIMEUP set before scanline 10")
+	}
+	tickScanlines(h, 1)
+	if h.Read(0x4211)&0x80 == 0 {
+		t.Errorf("TIMEUP not set at scanline 10")
+	}
+	if h.Read(0x4211)&0x80 != 0 {
+		t.Errorf("TIMEUP not cleared by reading")
+	}
+}
+
+func TestHW_Timing_NMIPriority(t *testing.T) {
+	h, _ := newTestHW(t)
+
+	// map the stack and NOPs at $00:8000-FFFF with the native NMI vector at $9000 and IRQ vector at $A000:
+	stack, rom := make([]byte, 0x2000), make([]byte, 0x8000)
+	if err := h.bus.Attach(memory.NewRAM(stack, 0x000000), "stack", 0x000000, 0x001FFF); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.bus.Attach(memory.NewRAM(rom, 0x008000), "rom", 0x008000, 0x00FFFF); err != nil {
+		t.Fatal(err)
+	}
+	for i := range rom {
+		rom[i] = 0xEA
+	}
+	rom[0x7FEA], rom[0x7FEB] = 0x00, 0x90
+	rom[0x7FEE], rom[0x7FEF] = 0x00, 0xA0
+
+	cpu := h.cpu
+	cpu.E, cpu.I, cpu.RK, cpu.PC, cpu.SP = 0, 0, 0, 0x8000, 0x01FF
+
+	// H-IRQ every scanline is held while vblank raises NMI:
+	h.Write(0x4207, 0)
+	h.Write(0x4208, 0)
+	h.Write(0x4200, 0x90)
+	tickScanlines(h, vblankStartScanline)
+
+	cpu.Step()
+	if cpu.PC&0xF000 != 0x9000 {
+		t.Errorf("PC = $%04x after vblank, expected the NMI handler at $9000", cpu.PC)
+	}
+}
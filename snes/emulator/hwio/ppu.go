@@ -0,0 +1,194 @@
+package hwio
+
+// ppu holds the state of the PPU's ports at $2100-$213F
+type ppu struct {
+	// regs keeps the last value written to each write-only register:
+	regs [0x40]byte
+
+	// VRAM port:
+	vmain        byte
+	vramAddr     uint16 // word address
+	vramPrefetch uint16 // word prefetched for reads
+
+	// CGRAM port:
+	cgAddr  uint16 // byte address
+	cgLatch byte
+
+	// OAM port:
+	oamReload uint16 // word address
+	oamAddr   uint16 // byte address
+	oamLatch  byte
+
+	// mode 7 multiplication:
+	m7a     int16
+	m7b     int8
+	m7Latch byte
+
+	// H/V counter latches:
+	ophct, opvct     uint16
+	ophctHi, opvctHi bool
+	latched          bool
+}
+
+// vramIncrement is the word increment after each VRAM access selected by VMAIN:
+func (p *ppu) vramIncrement() uint16 {
+	switch p.vmain & 3 {
+	case 0:
+		return 1
+	case 1:
+		return 32
+	default:
+		return 128
+	}
+}
+
+func (h *HW) prefetchVRAM() {
+	p := &h.ppu
+	a := uint32(p.vramAddr) << 1 & 0xFFFF
+	p.vramPrefetch = uint16(h.VRAM[a]) | uint16(h.VRAM[a|1])<<8
+}
+
+// oamIndex maps the OAM byte address to OAM; the 32-byte high table is mirrored above $200:
+func (p *ppu) oamIndex() uint16 {
+	if p.oamAddr >= 0x200 {
+		return 0x200 | p.oamAddr&0x1F
+	}
+	return p.oamAddr
+}
+
+func (h *HW) readPPU(offs uint32) (value byte) {
+	p := &h.ppu
+	switch offs {
+	case 0x2134, 0x2135, 0x2136:
+		// MPYL, MPYM, MPYH:
+		product := int32(p.m7a) * int32(p.m7b)
+		value = byte(product >> ((offs - 0x2134) * 8))
+	case 0x2137:
+		// SLHV latches the counters:
+		h.latchCounters()
+	case 0x2138:
+		// OAMDATAREAD:
+		value = h.OAM[p.oamIndex()]
+		p.oamAddr = (p.oamAddr + 1) & 0x3FF
+	case 0x2139:
+		// VMDATALREAD:
+		value = byte(p.vramPrefetch)
+		if p.vmain&0x80 == 0 {
+			p.vramAddr += p.vramIncrement()
+			h.prefetchVRAM()
+		}
+	case 0x213A:
+		// VMDATAHREAD:
+		value = byte(p.vramPrefetch >> 8)
+		if p.vmain&0x80 != 0 {
+			p.vramAddr += p.vramIncrement()
+			h.prefetchVRAM()
+		}
+	case 0x213B:
+		// CGDATAREAD:
+		value = h.CGRAM[p.cgAddr]
+		if p.cgAddr&1 != 0 {
+			value &= 0x7F
+		}
+		p.cgAddr = (p.cgAddr + 1) & 0x1FF
+	case 0x213C:
+		// OPHCT:
+		value = byte(p.ophct)
+		if p.ophctHi {
+			value = byte(p.ophct>>8) & 1
+		}
+		p.ophctHi = !p.ophctHi
+	case 0x213D:
+		// OPVCT:
+		value = byte(p.opvct)
+		if p.opvctHi {
+			value = byte(p.opvct>>8) & 1
+		}
+		p.opvctHi = !p.opvctHi
+	case 0x213E:
+		// STAT77: PPU1 version 1
+		value = 0x01
+	case 0x213F:
+		// STAT78: PPU2 version 3, NTSC
+		value = 0x03
+		if p.latched {
+			value |= 0x40
+		}
+		p.latched = false
+		p.ophctHi, p.opvctHi = false, false
+	default:
+		// write-only registers:
+		value = p.regs[offs&0x3F]
+	}
+	return
+}
+
+func (h *HW) writePPU(offs uint32, value byte) {
+	p := &h.ppu
+	p.regs[offs&0x3F] = value
+
+	switch offs {
+	case 0x2102:
+		// OAMADDL:
+		p.oamReload = p.oamReload&0x100 | uint16(value)
+		p.oamAddr = p.oamReload << 1
+	case 0x2103:
+		// OAMADDH:
+		p.oamReload = p.oamReload&0x0FF | uint16(value&1)<<8
+		p.oamAddr = p.oamReload << 1
+	case 0x2104:
+		// OAMDATA: the low table is written a word at a time:
+		if p.oamAddr >= 0x200 {
+			h.OAM[p.oamIndex()] = value
+		} else if p.oamAddr&1 == 0 {
+			p.oamLatch = value
+		} else {
+			h.OAM[p.oamAddr-1] = p.oamLatch
+			h.OAM[p.oamAddr] = value
+		}
+		p.oamAddr = (p.oamAddr + 1) & 0x3FF
+	case 0x2115:
+		// VMAIN:
+		p.vmain = value
+	case 0x2116:
+		// VMADDL:
+		p.vramAddr = p.vramAddr&0xFF00 | uint16(value)
+		h.prefetchVRAM()
+	case 0x2117:
+		// VMADDH:
+		p.vramAddr = p.vramAddr&0x00FF | uint16(value)<<8
+		h.prefetchVRAM()
+	case 0x2118:
+		// VMDATAL:
+		h.VRAM[uint32(p.vramAddr)<<1&0xFFFF] = value
+		if p.vmain&0x80 == 0 {
+			p.vramAddr += p.vramIncrement()
+		}
+	case 0x2119:
+		// VMDATAH:
+		h.VRAM[uint32(p.vramAddr)<<1&0xFFFF|1] = value
+		if p.vmain&0x80 != 0 {
+			p.vramAddr += p.vramIncrement()
+		}
+	case 0x211B:
+		// M7A is written low byte then high byte:
+		p.m7a = int16(uint16(value)<<8 | uint16(p.m7Latch))
+		p.m7Latch = value
+	case 0x211C:
+		// M7B:
+		p.m7b = int8(value)
+		p.m7Latch = value
+	case 0x2121:
+		// CGADD:
+		p.cgAddr = uint16(value) << 1
+	case 0x2122:
+		// CGDATA: colors are written a word at a time:
+		if p.cgAddr&1 == 0 {
+			p.cgLatch = value
+		} else {
+			h.CGRAM[p.cgAddr-1] = p.cgLatch
+			h.CGRAM[p.cgAddr] = value & 0x7F
+		}
+		p.cgAddr = (p.cgAddr + 1) & 0x1FF
+	}
+}
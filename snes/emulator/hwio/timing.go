@@ -0,0 +1,134 @@
+package hwio
+
+// NTSC timing in master clocks:
+const (
+	masterClocksPerDot  = 4
+	dotsPerScanline     = 341
+	clocksPerScanline   = masterClocksPerDot * dotsPerScanline
+	scanlinesPerFrame   = 262
+	vblankStartScanline = 225
+
+	// hblankStartDot is where the PPU stops drawing the scanline:
+	hblankStartDot = 274
+	// joypadScanlines is how long auto-joypad read takes from the start of vblank:
+	joypadScanlines = 3
+
+	// MasterClocksPerCycle approximates the length of a CPU cycle; the CPU does not report which memory it
+	// accessed so every cycle counts as the fastest one
+	MasterClocksPerCycle = 6
+	// CyclesPerFrame is the number of CPU cycles in a frame at MasterClocksPerCycle
+	CyclesPerFrame = clocksPerScanline * scanlinesPerFrame / MasterClocksPerCycle
+)
+
+// timing tracks the position of the beam to raise NMI at vblank and the H/V timer IRQs
+type timing struct {
+	// hclock is the master clock within the scanline:
+	hclock int
+	vcount int
+
+	nmiFlag bool
+	irqFlag bool
+}
+
+func (t *timing) inVBlank() bool { return t.vcount >= vblankStartScanline }
+
+func (t *timing) inHBlank() bool {
+	dot := t.hclock / masterClocksPerDot
+	return dot >= hblankStartDot || dot < 1
+}
+
+func (t *timing) joypadBusy() bool {
+	return t.vcount >= vblankStartScanline && t.vcount < vblankStartScanline+joypadScanlines
+}
+
+// HCounter returns the current dot within the scanline
+func (h *HW) HCounter() int { return h.timing.hclock / masterClocksPerDot }
+
+// VCounter returns the current scanline
+func (h *HW) VCounter() int { return h.timing.vcount }
+
+func (h *HW) latchCounters() {
+	p := &h.ppu
+	p.ophct = uint16(h.HCounter())
+	p.opvct = uint16(h.VCounter())
+	p.latched = true
+}
+
+// Tick advances the beam by the CPU cycles just executed, raising NMI and IRQ as they come due
+func (h *HW) Tick(cycles int) {
+	h.clock(cycles * MasterClocksPerCycle)
+}
+
+func (h *HW) clock(clocks int) {
+	t := &h.timing
+	for clocks > 0 {
+		n := clocksPerScanline - t.hclock
+		if n > clocks {
+			n = clocks
+		}
+
+		// the H/V timer fires as the beam passes its dot:
+		before := t.hclock / masterClocksPerDot
+		after := (t.hclock + n) / masterClocksPerDot
+		h.checkTimer(before, after)
+
+		t.hclock += n
+		clocks -= n
+		if t.hclock >= clocksPerScanline {
+			t.hclock = 0
+			h.nextScanline()
+		}
+	}
+
+	// IRQ is level-triggered and held until TIMEUP is read:
+	if t.irqFlag && h.nmitimen&0x30 != 0 {
+		h.cpu.TriggerIRQ()
+	}
+}
+
+// checkTimer raises IRQ if the H/V timer's position is within the dots [before, after) of the current scanline
+func (h *HW) checkTimer(before, after int) {
+	t := &h.timing
+	dot := int(h.htime)
+	switch h.nmitimen & 0x30 {
+	case 0x00:
+		return
+	case 0x10:
+		// H-IRQ every scanline
+	case 0x20:
+		// V-IRQ at the start of the scanline:
+		if t.vcount != int(h.vtime) {
+			return
+		}
+		dot = 0
+	case 0x30:
+		// HV-IRQ:
+		if t.vcount != int(h.vtime) {
+			return
+		}
+	}
+
+	if dot >= before && dot < after {
+		t.irqFlag = true
+	}
+}
+
+func (h *HW) nextScanline() {
+	t := &h.timing
+	t.vcount++
+
+	switch t.vcount {
+	case vblankStartScanline:
+		t.nmiFlag = true
+		if h.nmitimen&0x80 != 0 {
+			h.cpu.TriggerNMI()
+		}
+		if h.nmitimen&0x01 != 0 {
+			// auto-joypad read:
+			h.joy = h.Joypads
+		}
+	case scanlinesPerFrame:
+		t.vcount = 0
+		t.nmiFlag = false
+	}
+}
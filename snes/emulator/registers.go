@@ -0,0 +1,91 @@
+package emulator
+
+import "fmt"
+
+// Registers is a copy of the CPU's registers; A, X and Y are full 16-bit values regardless of the M and X flags
+type Registers struct {
+	PC  uint32
+	A   uint16
+	X   uint16
+	Y   uint16
+	SP  uint16
+	D   uint16
+	DBR uint8
+	P   uint8
+	E   bool
+}
+
+func (r Registers) String() string {
+	flags := []byte("nvmxdizc")
+	for i := range flags {
+		if r.P&(0x80>>i) != 0 {
+			flags[i] -= 'a' - 'A'
+		}
+	}
+	e := 0
+	if r.E {
+		e = 1
+	}
+	return fmt.Sprintf(
+		"PC=$%06x A=$%04x X=$%04x Y=$%04x SP=$%04x D=$%04x DB=$%02x P=%s E=%d",
+		r.PC, r.A, r.X, r.Y, r.SP, r.D, r.DBR, flags, e,
+	)
+}
+
+func (s *System) Registers() Registers {
+	cpu := s.CPU
+	r := Registers{
+		PC:  s.GetPC(),
+		A:   cpu.RA,
+		X:   cpu.RX,
+		Y:   cpu.RY,
+		SP:  cpu.SP,
+		D:   cpu.RD,
+		DBR: cpu.RDBR,
+		P:   cpu.Flags(),
+		E:   cpu.E == 1,
+	}
+	// 8-bit registers are kept separately:
+	if cpu.M == 1 {
+		r.A = uint16(cpu.RAh)<<8 | uint16(cpu.RAl)
+	}
+	if cpu.X == 1 {
+		r.X = uint16(cpu.RXl)
+		r.Y = uint16(cpu.RYl)
+	}
+	return r
+}
+
+// SetRegisters loads the CPU's registers, e.g. from a save state. As on hardware, emulation mode forces the M and X
+// flags and the stack to page 1, and 8-bit index registers drop their high byte.
+func (s *System) SetRegisters(r Registers) {
+	cpu := s.CPU
+
+	cpu.E = 0
+	if r.E {
+		cpu.E = 1
+		r.P |= 0x30
+		r.SP = 0x0100 | r.SP&0xFF
+	}
+	// set M and X first so SetFlags does not resize the registers set below:
+	cpu.M = (r.P >> 5) & 1
+	cpu.X = (r.P >> 4) & 1
+	cpu.SetFlags(r.P)
+
+	cpu.RA = r.A
+	cpu.RAl = byte(r.A)
+	cpu.RAh = byte(r.A >> 8)
+	if cpu.X == 1 {
+		r.X &= 0xFF
+		r.Y &= 0xFF
+	}
+	cpu.RX = r.X
+	cpu.RXl = byte(r.X)
+	cpu.RY = r.Y
+	cpu.RYl = byte(r.Y)
+
+	cpu.SP = r.SP
+	cpu.RD = r.D
+	cpu.RDBR = r.DBR
+	s.SetPC(r.PC)
+}
@@ -0,0 +1,145 @@
+package savestate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"o2/snes/emulator"
+)
+
+// bsnes (v107 and later) serializes the whole system as the fields of each component in a fixed order with
+// no framing, so the layout below must follow the emulator's serialize() functions:
+//
+//	System:    signature, size, version[16], description[512], synchronize, fastPPU
+//	Random:    entropy, state, increment
+//	Cartridge: ram[sramSize]
+//	CPU:       WDC65816 registers, Thread, PPUcounter, wram[0x20000], ...
+//
+// Integers are little-endian at their C++ size and booleans are one byte. The .bst files bsnes writes wrap the
+// serialized system in an RLE compressed block behind a small header of their own.
+const (
+	bsnesStateSignature      = 0x5A220000
+	bsnesSerializerSignature = 0x31545342 // "BST1"
+
+	bsnesSystemHeaderSize = 4 + 4 + 16 + 512 + 1 + 1
+	bsnesRandomSize       = 4 + 8 + 8
+	// pc.d, a.w, x.w, y.w, z.w, s.w, d.w, b, p.c/z/i/d/x/m/v/n, e, irq, wai, stp, vector, mar, mdr, u.d, v.d, w.d:
+	bsnesWDC65816Size = 4 + 2*6 + 1 + 8 + 4 + 2 + 4 + 1 + 4*3
+	// frequency, clock:
+	bsnesThreadSize = 4 + 8
+	// interlace, field, vperiod, hperiod, vcounter, hcounter, last vperiod, last hperiod:
+	bsnesPPUCounterSize = 1 + 1 + 4*6
+
+	bsnesWRAMSize = 0x20000
+
+	// the RLE encoding repeats a value at least this many times:
+	bsnesRLEMinRun = 4
+)
+
+// ReadBSNES reads the WRAM, SRAM and CPU registers from a bsnes .bst file or a raw serialized state, e.g. from the
+// bsnes libretro core. The states do not record the size of the cartridge's SRAM so it must be given.
+func ReadBSNES(r io.Reader, sramSize int) (st *State, err error) {
+	if sramSize < 0 {
+		return nil, fmt.Errorf("savestate: invalid SRAM size %d", sramSize)
+	}
+
+	var b []byte
+	if b, err = ioutil.ReadAll(r); err != nil {
+		return
+	}
+	if len(b) < 4 {
+		return nil, fmt.Errorf("savestate: bsnes state too short")
+	}
+
+	if binary.LittleEndian.Uint32(b) == bsnesStateSignature {
+		if len(b) < 12 {
+			return nil, fmt.Errorf("savestate: bsnes state too short")
+		}
+		n := binary.LittleEndian.Uint32(b[4:])
+		if uint64(n) > uint64(len(b)-12) {
+			return nil, fmt.Errorf("savestate: bsnes state size %#x larger than the file", n)
+		}
+		if b, err = decodeBSNESRLE(b[12 : 12+n]); err != nil {
+			return
+		}
+		if len(b) < 4 {
+			return nil, fmt.Errorf("savestate: bsnes state too short")
+		}
+	}
+
+	if binary.LittleEndian.Uint32(b) != bsnesSerializerSignature {
+		return nil, fmt.Errorf("savestate: not a bsnes state")
+	}
+
+	cpu := bsnesSystemHeaderSize + bsnesRandomSize + sramSize
+	wram := cpu + bsnesWDC65816Size + bsnesThreadSize + bsnesPPUCounterSize
+	if len(b) < wram+bsnesWRAMSize {
+		return nil, fmt.Errorf("savestate: bsnes state too short for %#x bytes of SRAM", sramSize)
+	}
+
+	// the flags are serialized as booleans; anything else means the layout does not match:
+	flags := b[cpu+17 : cpu+29]
+	for _, f := range flags {
+		if f > 1 {
+			return nil, fmt.Errorf("savestate: unsupported bsnes state layout or wrong SRAM size %#x", sramSize)
+		}
+	}
+	var p uint8
+	for i, f := range flags[:8] {
+		p |= f << i
+	}
+
+	st = &State{
+		Registers: emulator.Registers{
+			PC:  binary.LittleEndian.Uint32(b[cpu:]) & 0xFFFFFF,
+			A:   binary.LittleEndian.Uint16(b[cpu+4:]),
+			X:   binary.LittleEndian.Uint16(b[cpu+6:]),
+			Y:   binary.LittleEndian.Uint16(b[cpu+8:]),
+			SP:  binary.LittleEndian.Uint16(b[cpu+12:]),
+			D:   binary.LittleEndian.Uint16(b[cpu+14:]),
+			DBR: b[cpu+16],
+			P:   p,
+			E:   flags[8] != 0,
+		},
+		SRAM: make([]byte, sramSize),
+		WRAM: make([]byte, bsnesWRAMSize),
+	}
+	copy(st.SRAM, b[cpu-sramSize:cpu])
+	copy(st.WRAM, b[wram:])
+	return
+}
+
+// decodeBSNESRLE decodes bsnes' byte-wise RLE: a 64-bit decoded size followed by runs where a control byte below
+// 128 copies the next control+1 bytes and one of 128 or above repeats the next byte (control&127)+4 times
+func decodeBSNESRLE(in []byte) ([]byte, error) {
+	if len(in) < 8 {
+		return nil, fmt.Errorf("savestate: bsnes RLE block too short")
+	}
+	size := binary.LittleEndian.Uint64(in)
+	if size > uint64(len(in))*(127+bsnesRLEMinRun) {
+		return nil, fmt.Errorf("savestate: bsnes RLE size %#x too large", size)
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, size))
+	in = in[8:]
+	for uint64(out.Len()) < size {
+		if len(in) < 2 {
+			return nil, fmt.Errorf("savestate: bsnes RLE block truncated")
+		}
+		control := int(in[0])
+		if control < 128 {
+			n := control + 1
+			if len(in) < 1+n {
+				return nil, fmt.Errorf("savestate: bsnes RLE block truncated")
+			}
+			out.Write(in[1 : 1+n])
+			in = in[1+n:]
+		} else {
+			out.Write(bytes.Repeat(in[1:2], control&127+bsnesRLEMinRun))
+			in = in[2:]
+		}
+	}
+	return out.Bytes()[:size], nil
+}
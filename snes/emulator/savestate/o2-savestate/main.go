@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"o2/snes"
+	"o2/snes/emulator/savestate"
+	"os"
+	"path/filepath"
+)
+
+// converts a snes9x or bsnes save state into an o2 snapshot, e.g. for a test fixture
+func main() {
+	var err error
+
+	inPath := flag.String("in", "", "save state to convert (snes9x .frz/.000, bsnes .bst or o2 snapshot)")
+	outPath := flag.String("out", "", "o2 snapshot to write")
+	romPath := flag.String("rom", "", "ROM the state was saved with; gives the SRAM size bsnes states need")
+	sramSize := flag.Int("sram-size", 0, "SRAM size in bytes if -rom is not given")
+	flag.Parse()
+
+	log.SetFlags(0)
+
+	if *inPath == "" || *outPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *romPath != "" {
+		var b []byte
+		if b, err = ioutil.ReadFile(*romPath); err != nil {
+			log.Fatal(err)
+		}
+		var rom *snes.ROM
+		if rom, err = snes.NewROM(filepath.Base(*romPath), b); err != nil {
+			log.Fatal(err)
+		}
+		*sramSize = 0
+		if rom.Header.RAMSize > 0 {
+			*sramSize = 1024 << rom.Header.RAMSize
+		}
+	}
+
+	var in *os.File
+	if in, err = os.Open(*inPath); err != nil {
+		log.Fatal(err)
+	}
+	st, f, err := savestate.Load(in, *sramSize)
+	_ = in.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("read %s state; %v\n", f, st.Registers)
+
+	var out *os.File
+	if out, err = os.Create(*outPath); err != nil {
+		log.Fatal(err)
+	}
+	if err = savestate.WriteO2(out, st); err != nil {
+		_ = out.Close()
+		log.Fatal(err)
+	}
+	if err = out.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,124 @@
+package savestate
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"o2/snes/emulator"
+)
+
+// o2 snapshot format, all little-endian:
+//
+//	"O2SNAP"       magic
+//	uint8          version
+//	o2Registers    CPU registers
+//	uint32, bytes  WRAM
+//	uint32, bytes  SRAM
+const (
+	o2Magic   = "O2SNAP"
+	o2Version = 1
+
+	// maximum memory size accepted when reading, to reject corrupt lengths:
+	o2MaxMemory = 0x100000
+)
+
+type o2Registers struct {
+	PC  uint32
+	A   uint16
+	X   uint16
+	Y   uint16
+	SP  uint16
+	D   uint16
+	DBR uint8
+	P   uint8
+	E   uint8
+}
+
+// WriteO2 writes the state in o2's own snapshot format
+func WriteO2(w io.Writer, st *State) (err error) {
+	if _, err = io.WriteString(w, o2Magic); err != nil {
+		return
+	}
+	if err = binary.Write(w, binary.LittleEndian, uint8(o2Version)); err != nil {
+		return
+	}
+
+	r := st.Registers
+	regs := o2Registers{
+		PC:  r.PC,
+		A:   r.A,
+		X:   r.X,
+		Y:   r.Y,
+		SP:  r.SP,
+		D:   r.D,
+		DBR: r.DBR,
+		P:   r.P,
+	}
+	if r.E {
+		regs.E = 1
+	}
+	if err = binary.Write(w, binary.LittleEndian, &regs); err != nil {
+		return
+	}
+
+	for _, mem := range [][]byte{st.WRAM, st.SRAM} {
+		if err = binary.Write(w, binary.LittleEndian, uint32(len(mem))); err != nil {
+			return
+		}
+		if _, err = w.Write(mem); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// ReadO2 reads a state in o2's own snapshot format
+func ReadO2(r io.Reader) (st *State, err error) {
+	magic := make([]byte, len(o2Magic))
+	if _, err = io.ReadFull(r, magic); err != nil {
+		return
+	}
+	if string(magic) != o2Magic {
+		return nil, fmt.Errorf("savestate: not an o2 snapshot")
+	}
+	var version uint8
+	if err = binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return
+	}
+	if version != o2Version {
+		return nil, fmt.Errorf("savestate: unsupported o2 snapshot version %d", version)
+	}
+
+	var regs o2Registers
+	if err = binary.Read(r, binary.LittleEndian, &regs); err != nil {
+		return
+	}
+	st = &State{
+		Registers: emulator.Registers{
+			PC:  regs.PC & 0xFFFFFF,
+			A:   regs.A,
+			X:   regs.X,
+			Y:   regs.Y,
+			SP:  regs.SP,
+			D:   regs.D,
+			DBR: regs.DBR,
+			P:   regs.P,
+			E:   regs.E != 0,
+		},
+	}
+
+	for _, mem := range []*[]byte{&st.WRAM, &st.SRAM} {
+		var n uint32
+		if err = binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return nil, err
+		}
+		if n > o2MaxMemory {
+			return nil, fmt.Errorf("savestate: o2 snapshot memory size %#x too large", n)
+		}
+		*mem = make([]byte, n)
+		if _, err = io.ReadFull(r, *mem); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
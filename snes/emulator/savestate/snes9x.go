@@ -0,0 +1,120 @@
+package savestate
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"o2/snes/emulator"
+	"strconv"
+)
+
+// snes9x freeze files (.frz, .000-.009) start with "#!s9xsnp:NNNN\n" followed by blocks of "NAM:NNNNNN:" and
+// NNNNNN bytes of data. Structs are frozen field by field in big-endian while memory is copied as is. The whole
+// file is usually gzip compressed.
+const (
+	snes9xMagic      = "#!s9xsnp:"
+	snes9xHeaderSize = len(snes9xMagic) + 5
+	snes9xBlockSize  = 11
+
+	// REG: PB, DB, P, A, D, S, X, Y, PC
+	snes9xRegSize = 1 + 1 + 2*7
+	// P's bit 8 is the emulation flag:
+	snes9xEmulation = 0x100
+	// snes9x saves at most 512KiB of SRAM; no block we keep is larger:
+	snes9xMaxBlockSize = 0x80000
+)
+
+var gzipMagic = []byte{0x1F, 0x8B}
+
+// ReadSnes9x reads the WRAM, SRAM and CPU registers from a snes9x freeze file
+func ReadSnes9x(r io.Reader) (st *State, err error) {
+	br := bufio.NewReader(r)
+	var magic []byte
+	if magic, err = br.Peek(len(gzipMagic)); err != nil {
+		return
+	}
+	if bytes.Equal(magic, gzipMagic) {
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(br); err != nil {
+			return
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	header := make([]byte, snes9xHeaderSize)
+	if _, err = io.ReadFull(br, header); err != nil {
+		return
+	}
+	if !bytes.HasPrefix(header, []byte(snes9xMagic)) || header[snes9xHeaderSize-1] != '\n' {
+		return nil, fmt.Errorf("savestate: not a snes9x freeze file")
+	}
+	if _, err = strconv.Atoi(string(header[len(snes9xMagic) : snes9xHeaderSize-1])); err != nil {
+		return nil, fmt.Errorf("savestate: bad snes9x freeze file version")
+	}
+
+	st = &State{}
+	var regs []byte
+	block := make([]byte, snes9xBlockSize)
+	// REG, RAM and SRA come early in the file so stop once they are read:
+	for regs == nil || st.WRAM == nil || st.SRAM == nil {
+		if _, err = io.ReadFull(br, block); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("savestate: snes9x freeze file is missing REG, RAM or SRA: %w", err)
+		}
+		if block[3] != ':' || block[10] != ':' {
+			return nil, fmt.Errorf("savestate: bad snes9x block header %q", block)
+		}
+		// the size is 6 decimal digits; ParseUint rejects signs:
+		var n uint64
+		if n, err = strconv.ParseUint(string(block[4:10]), 10, 32); err != nil {
+			return nil, fmt.Errorf("savestate: bad snes9x block size %q", block)
+		}
+
+		name := string(block[0:3])
+		if name != "REG" && name != "RAM" && name != "SRA" {
+			if _, err = io.CopyN(io.Discard, br, int64(n)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if n > snes9xMaxBlockSize {
+			return nil, fmt.Errorf("savestate: snes9x %s block is %d bytes; expected at most %d", name, n, snes9xMaxBlockSize)
+		}
+		data := make([]byte, n)
+		if _, err = io.ReadFull(br, data); err != nil {
+			return nil, err
+		}
+
+		switch name {
+		case "REG":
+			regs = data
+		case "RAM":
+			st.WRAM = data
+		case "SRA":
+			st.SRAM = data
+		}
+	}
+
+	if len(regs) < snes9xRegSize {
+		return nil, fmt.Errorf("savestate: snes9x REG block is %d bytes; expected %d", len(regs), snes9xRegSize)
+	}
+	p := binary.BigEndian.Uint16(regs[2:])
+	st.Registers = emulator.Registers{
+		PC:  uint32(regs[0])<<16 | uint32(binary.BigEndian.Uint16(regs[14:])),
+		DBR: regs[1],
+		P:   uint8(p),
+		A:   binary.BigEndian.Uint16(regs[4:]),
+		D:   binary.BigEndian.Uint16(regs[6:]),
+		SP:  binary.BigEndian.Uint16(regs[8:]),
+		X:   binary.BigEndian.Uint16(regs[10:]),
+		Y:   binary.BigEndian.Uint16(regs[12:]),
+		E:   p&snes9xEmulation != 0,
+	}
+	return st, nil
+}
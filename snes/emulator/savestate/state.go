@@ -0,0 +1,103 @@
+package savestate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"o2/snes/emulator"
+)
+
+// State is the part of a save state the emulator models
+type State struct {
+	Registers emulator.Registers
+
+	WRAM []byte
+	SRAM []byte
+}
+
+// Capture copies the system's WRAM, SRAM and CPU registers
+func Capture(s *emulator.System) *State {
+	st := &State{
+		Registers: s.Registers(),
+		WRAM:      make([]byte, len(s.WRAM)),
+		SRAM:      make([]byte, len(s.SRAM)),
+	}
+	copy(st.WRAM, s.WRAM[:])
+	copy(st.SRAM, s.SRAM[:])
+	return st
+}
+
+// Apply loads the state into the system. SRAM larger than the emulator's is truncated since games do not address
+// more than the emulator maps; anything not in the state, e.g. the PPU, is left as is.
+func (st *State) Apply(s *emulator.System) error {
+	if len(st.WRAM) != len(s.WRAM) {
+		return fmt.Errorf("savestate: WRAM is %#x bytes; expected %#x", len(st.WRAM), len(s.WRAM))
+	}
+
+	copy(s.WRAM[:], st.WRAM)
+	n := copy(s.SRAM[:], st.SRAM)
+	for i := range s.SRAM[n:] {
+		s.SRAM[n+i] = 0
+	}
+	s.SetRegisters(st.Registers)
+	return nil
+}
+
+type Format int
+
+const (
+	FormatUnknown Format = iota
+	FormatO2
+	FormatSnes9x
+	FormatBSNES
+)
+
+var formatNames = map[Format]string{
+	FormatUnknown: "unknown",
+	FormatO2:      "o2",
+	FormatSnes9x:  "snes9x",
+	FormatBSNES:   "bsnes",
+}
+
+func (f Format) String() string { return formatNames[f] }
+
+// DetectFormat identifies the format of a save state from its first bytes
+func DetectFormat(b []byte) Format {
+	switch {
+	case bytes.HasPrefix(b, []byte(o2Magic)):
+		return FormatO2
+	case bytes.HasPrefix(b, []byte(snes9xMagic)), bytes.HasPrefix(b, gzipMagic):
+		// snes9x compresses its states with gzip by default:
+		return FormatSnes9x
+	case len(b) >= 4 && binary.LittleEndian.Uint32(b) == bsnesStateSignature,
+		len(b) >= 4 && binary.LittleEndian.Uint32(b) == bsnesSerializerSignature:
+		return FormatBSNES
+	default:
+		return FormatUnknown
+	}
+}
+
+// Load reads a save state in any of the supported formats. bsnes does not record the size of the cartridge's SRAM
+// in its states so it must be given, e.g. from the ROM header; it is ignored for the other formats.
+func Load(r io.Reader, sramSize int) (*State, Format, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, FormatUnknown, err
+	}
+
+	f := DetectFormat(b)
+	var st *State
+	switch f {
+	case FormatO2:
+		st, err = ReadO2(bytes.NewReader(b))
+	case FormatSnes9x:
+		st, err = ReadSnes9x(bytes.NewReader(b))
+	case FormatBSNES:
+		st, err = ReadBSNES(bytes.NewReader(b), sramSize)
+	default:
+		err = fmt.Errorf("savestate: unrecognized format")
+	}
+	return st, f, err
+}
@@ -0,0 +1,285 @@
+package savestate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"o2/snes/emulator"
+	"testing"
+)
+
+var testRegisters = emulator.Registers{
+	PC:  0x02_8123,
+	A:   0x1234,
+	X:   0x0056,
+	Y:   0x0078,
+	SP:  0x01F0,
+	D:   0x0300,
+	DBR: 0x7E,
+	P:   0x31,
+}
+
+func makeMemory(n int, seed byte) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i) ^ seed
+	}
+	return b
+}
+
+func makeSnes9x(t *testing.T, compress bool) []byte {
+	t.Helper()
+
+	r := testRegisters
+	reg := make([]byte, snes9xRegSize)
+	reg[0] = byte(r.PC >> 16)
+	reg[1] = r.DBR
+	binary.BigEndian.PutUint16(reg[2:], uint16(r.P))
+	binary.BigEndian.PutUint16(reg[4:], r.A)
+	binary.BigEndian.PutUint16(reg[6:], r.D)
+	binary.BigEndian.PutUint16(reg[8:], r.SP)
+	binary.BigEndian.PutUint16(reg[10:], r.X)
+	binary.BigEndian.PutUint16(reg[12:], r.Y)
+	binary.BigEndian.PutUint16(reg[14:], uint16(r.PC))
+
+	b := &bytes.Buffer{}
+	b.WriteString("#!s9xsnp:0011\n")
+	for _, block := range []struct {
+		name string
+		data []byte
+	}{
+		{"NAM", []byte("test.sfc\x00")},
+		{"CPU", make([]byte, 40)},
+		{"REG", reg},
+		{"VRA", make([]byte, 0x10000)},
+		{"RAM", makeMemory(0x20000, 0x5A)},
+		{"SRA", makeMemory(0x20000, 0xA5)},
+	} {
+		fmt.Fprintf(b, "%s:%06d:", block.name, len(block.data))
+		b.Write(block.data)
+	}
+	if !compress {
+		return b.Bytes()
+	}
+
+	z := &bytes.Buffer{}
+	gz := gzip.NewWriter(z)
+	_, _ = gz.Write(b.Bytes())
+	_ = gz.Close()
+	return z.Bytes()
+}
+
+func TestReadSnes9x(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		b := makeSnes9x(t, compress)
+		if f := DetectFormat(b); f != FormatSnes9x {
+			t.Errorf("DetectFormat = %v", f)
+		}
+
+		st, err := ReadSnes9x(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Registers != testRegisters {
+			t.Errorf("compress=%v: Registers = %v; expected %v", compress, st.Registers, testRegisters)
+		}
+		if !bytes.Equal(st.WRAM, makeMemory(0x20000, 0x5A)) {
+			t.Errorf("compress=%v: WRAM mismatch", compress)
+		}
+		if !bytes.Equal(st.SRAM, makeMemory(0x20000, 0xA5)) {
+			t.Errorf("compress=%v: SRAM mismatch", compress)
+		}
+	}
+}
+
+func TestReadSnes9x_Invalid(t *testing.T) {
+	invalid := map[string]string{
+		"negative size": "#!s9xsnp:0011\nREG:-00001:",
+		"signed size":   "#!s9xsnp:0011\nREG:+00016:",
+		"huge size":     "#!s9xsnp:0011\nSRA:999999:",
+		"truncated":     "#!s9xsnp:0011\nREG:000016:\x00",
+	}
+	for name, b := range invalid {
+		if _, err := ReadSnes9x(bytes.NewReader([]byte(b))); err == nil {
+			t.Errorf("%s: ReadSnes9x should fail", name)
+		}
+	}
+}
+
+// encodeBSNESRLE encodes with literal runs only, which decodeBSNESRLE must accept as well as repeats
+func encodeBSNESRLE(b []byte) []byte {
+	out := make([]byte, 8, len(b)+len(b)/128+9)
+	binary.LittleEndian.PutUint64(out, uint64(len(b)))
+	for len(b) > 0 {
+		n := len(b)
+		if n > 128 {
+			n = 128
+		}
+		out = append(out, byte(n-1))
+		out = append(out, b[:n]...)
+		b = b[n:]
+	}
+	return out
+}
+
+func makeBSNES(sramSize int, wrap bool) []byte {
+	r := testRegisters
+	b := &bytes.Buffer{}
+	header := make([]byte, bsnesSystemHeaderSize)
+	binary.LittleEndian.PutUint32(header, bsnesSerializerSignature)
+	copy(header[8:], "115")
+	b.Write(header)
+	b.Write(make([]byte, bsnesRandomSize))
+	b.Write(makeMemory(sramSize, 0xA5))
+
+	cpu := make([]byte, bsnesWDC65816Size)
+	binary.LittleEndian.PutUint32(cpu[0:], r.PC)
+	binary.LittleEndian.PutUint16(cpu[4:], r.A)
+	binary.LittleEndian.PutUint16(cpu[6:], r.X)
+	binary.LittleEndian.PutUint16(cpu[8:], r.Y)
+	binary.LittleEndian.PutUint16(cpu[12:], r.SP)
+	binary.LittleEndian.PutUint16(cpu[14:], r.D)
+	cpu[16] = r.DBR
+	for i := 0; i < 8; i++ {
+		cpu[17+i] = (r.P >> i) & 1
+	}
+	b.Write(cpu)
+	b.Write(make([]byte, bsnesThreadSize+bsnesPPUCounterSize))
+	b.Write(makeMemory(bsnesWRAMSize, 0x5A))
+	// the rest of the system:
+	b.Write(make([]byte, 0x1000))
+	if !wrap {
+		return b.Bytes()
+	}
+
+	rle := encodeBSNESRLE(b.Bytes())
+	bst := make([]byte, 12, 12+len(rle))
+	binary.LittleEndian.PutUint32(bst[0:], bsnesStateSignature)
+	binary.LittleEndian.PutUint32(bst[4:], uint32(len(rle)))
+	return append(bst, rle...)
+}
+
+func TestReadBSNES(t *testing.T) {
+	for _, wrap := range []bool{false, true} {
+		b := makeBSNES(0x2000, wrap)
+		if f := DetectFormat(b); f != FormatBSNES {
+			t.Errorf("DetectFormat = %v", f)
+		}
+
+		st, err := ReadBSNES(bytes.NewReader(b), 0x2000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Registers != testRegisters {
+			t.Errorf("wrap=%v: Registers = %v; expected %v", wrap, st.Registers, testRegisters)
+		}
+		if !bytes.Equal(st.WRAM, makeMemory(bsnesWRAMSize, 0x5A)) {
+			t.Errorf("wrap=%v: WRAM mismatch", wrap)
+		}
+		if !bytes.Equal(st.SRAM, makeMemory(0x2000, 0xA5)) {
+			t.Errorf("wrap=%v: SRAM mismatch", wrap)
+		}
+	}
+
+	// the wrong SRAM size misaligns the CPU's flags:
+	if _, err := ReadBSNES(bytes.NewReader(makeBSNES(0x2000, false)), 0x800); err == nil {
+		t.Error("expected an error for the wrong SRAM size")
+	}
+	if _, err := ReadBSNES(bytes.NewReader(makeBSNES(0x2000, false)), -1); err == nil {
+		t.Error("expected an error for a negative SRAM size")
+	}
+
+	// a .bst whose RLE block decodes to fewer than 4 bytes:
+	bst := make([]byte, 12)
+	binary.LittleEndian.PutUint32(bst[0:], bsnesStateSignature)
+	rle := []byte{2, 0, 0, 0, 0, 0, 0, 0, 1, 0x42, 0x53}
+	binary.LittleEndian.PutUint32(bst[4:], uint32(len(rle)))
+	if _, err := ReadBSNES(bytes.NewReader(append(bst, rle...)), 0x2000); err == nil {
+		t.Error("expected an error for a short decoded state")
+	}
+}
+
+func TestDecodeBSNESRLE(t *testing.T) {
+	in := []byte{
+		10, 0, 0, 0, 0, 0, 0, 0,
+		// 3 literal bytes:
+		2, 1, 2, 3,
+		// $FF repeated 2+4 times:
+		130, 0xFF,
+		// 1 literal byte:
+		0, 9,
+	}
+	out, err := decodeBSNESRLE(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte{1, 2, 3, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 9}; !bytes.Equal(out, expected) {
+		t.Errorf("decoded = % x; expected % x", out, expected)
+	}
+
+	if _, err = decodeBSNESRLE(in[:len(in)-1]); err == nil {
+		t.Error("expected an error for a truncated block")
+	}
+}
+
+func TestO2_RoundTrip(t *testing.T) {
+	s := &emulator.System{}
+	if err := s.CreateEmulator(); err != nil {
+		t.Fatal(err)
+	}
+	copy(s.WRAM[:], makeMemory(len(s.WRAM), 0x5A))
+	copy(s.SRAM[:], makeMemory(len(s.SRAM), 0xA5))
+	s.SetRegisters(testRegisters)
+
+	b := &bytes.Buffer{}
+	if err := WriteO2(b, Capture(s)); err != nil {
+		t.Fatal(err)
+	}
+
+	st, f, err := Load(bytes.NewReader(b.Bytes()), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != FormatO2 {
+		t.Errorf("format = %v", f)
+	}
+	if st.Registers != testRegisters {
+		t.Errorf("Registers = %v; expected %v", st.Registers, testRegisters)
+	}
+
+	t2 := &emulator.System{}
+	if err = t2.CreateEmulator(); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.Apply(t2); err != nil {
+		t.Fatal(err)
+	}
+	if t2.WRAM != s.WRAM || t2.SRAM != s.SRAM {
+		t.Error("memory mismatch after Apply")
+	}
+	if r := t2.Registers(); r != testRegisters {
+		t.Errorf("Registers after Apply = %v; expected %v", r, testRegisters)
+	}
+}
+
+func TestState_Apply_Emulation(t *testing.T) {
+	s := &emulator.System{}
+	if err := s.CreateEmulator(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := testRegisters
+	r.E = true
+	r.P = 0
+	st := &State{Registers: r, WRAM: make([]byte, len(s.WRAM))}
+	if err := st.Apply(s); err != nil {
+		t.Fatal(err)
+	}
+
+	// emulation mode forces 8-bit index registers and the stack to page 1:
+	actual := s.Registers()
+	if actual.P&0x30 != 0x30 || actual.X != 0x56 || actual.SP != 0x01F0 || actual.A != 0x1234 || !actual.E {
+		t.Errorf("Registers = %v", actual)
+	}
+}
@@ -2,11 +2,13 @@ package emulator
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"o2/snes"
 	"o2/snes/asm"
 	"o2/snes/emulator/bus"
 	"o2/snes/emulator/cpu65c816"
+	"o2/snes/emulator/hwio"
 	"o2/snes/emulator/memory"
 	"o2/util"
 )
@@ -15,6 +17,7 @@ type System struct {
 	// emulated system:
 	Bus *bus.Bus
 	CPU *cpu65c816.CPU
+	HW  *hwio.HW
 
 	ROM  [0x1000000]byte
 	WRAM [0x20000]byte
@@ -123,11 +126,11 @@ func (s *System) CreateEmulator() (err error) {
 
 	// Memory-mapped IO registers:
 	{
-		hwio := &memory.FakeHW{}
+		s.HW = hwio.New(s.Bus, s.CPU)
 		for b := uint32(0); b < 0x70; b++ {
 			bank := b << 16
 			err = s.Bus.Attach(
-				hwio,
+				s.HW,
 				"hwio",
 				bank|0x2000,
 				bank|0x7FFF,
@@ -138,7 +141,7 @@ func (s *System) CreateEmulator() (err error) {
 
 			bank = (b + 0x80) << 16
 			err = s.Bus.Attach(
-				hwio,
+				s.HW,
 				"hwio",
 				bank|0x2000,
 				bank|0x7FFF,
@@ -192,6 +195,27 @@ func MakeTestROM(title string) (rom *snes.ROM, err error) {
 	return
 }
 
+// LoadROM copies the ROM into the system and clears WRAM, SRAM and the hardware registers as if the ROM were just inserted. Only LoROM
+// ROMs of up to 2MiB are supported since that is all CreateEmulator maps.
+func (s *System) LoadROM(rom *snes.ROM) error {
+	if rom.Mapping() != snes.LoROM {
+		return fmt.Errorf("emulator: only LoROM is supported; map mode $%02x", rom.Header.MapMode)
+	}
+	if len(rom.Contents) > 0x40<<15 {
+		return fmt.Errorf("emulator: ROM size %#x is larger than the %#x mapped", len(rom.Contents), 0x40<<15)
+	}
+
+	n := copy(s.ROM[:], rom.Contents)
+	for i := range s.ROM[n:] {
+		s.ROM[n+i] = 0
+	}
+	s.WRAM = [len(s.WRAM)]byte{}
+	s.SRAM = [len(s.SRAM)]byte{}
+	s.HW.Reset()
+
+	return nil
+}
+
 func (s *System) SetupPatch() (err error) {
 	a := asm.Emitter{}
 	// entry point at 0x70_7FFA
@@ -239,6 +263,13 @@ func (s *System) GetPC() uint32 {
 	return uint32(s.CPU.RK)<<16 | uint32(s.CPU.PC)
 }
 
+// Step executes a single CPU instruction and advances the hardware's timing by the cycles it took
+func (s *System) Step() int {
+	nCycles, _ := s.CPU.Step()
+	s.HW.Tick(nCycles)
+	return nCycles
+}
+
 func (s *System) RunUntil(targetPC uint32, maxCycles uint64) bool {
 	shouldLog := s.ShouldLogCPU
 	for cycles := uint64(0); cycles < maxCycles; {
@@ -248,8 +279,7 @@ func (s *System) RunUntil(targetPC uint32, maxCycles uint64) bool {
 		if s.GetPC() == targetPC {
 			break
 		}
-		nCycles, _ := s.CPU.Step()
-		cycles += uint64(nCycles)
+		cycles += uint64(s.Step())
 	}
 
 	return s.GetPC() == targetPC
@@ -1,6 +1,8 @@
 package emulator
 
 import (
+	"bytes"
+	"o2/snes/emulator/hwio"
 	"testing"
 )
 
@@ -193,3 +195,43 @@ func TestSystem_SetupPatch(t *testing.T) {
 		})
 	}
 }
+
+func TestSystem_NMI_DMA(t *testing.T) {
+	s := &System{}
+	if err := s.CreateEmulator(); err != nil {
+		t.Fatal(err)
+	}
+
+	// RESET: SEI : CLC : XCE : SEP #$30 : LDA #$80 : STA $4200 : BRA *
+	copy(s.ROM[0x0000:], []byte{0x78, 0x18, 0xFB, 0xE2, 0x30, 0xA9, 0x80, 0x8D, 0x00, 0x42, 0x80, 0xFE})
+	// NMI: acknowledge, DMA 4 bytes from $7E:1000 to CGRAM, count frames in $00 and RTI
+	copy(s.ROM[0x1000:], []byte{
+		0xAD, 0x10, 0x42, // LDA $4210
+		0xA9, 0x02, 0x8D, 0x00, 0x43, // LDA #$02 : STA $4300
+		0xA9, 0x22, 0x8D, 0x01, 0x43, // LDA #$22 : STA $4301
+		0x9C, 0x02, 0x43, // STZ $4302
+		0xA9, 0x10, 0x8D, 0x03, 0x43, // LDA #$10 : STA $4303
+		0xA9, 0x7E, 0x8D, 0x04, 0x43, // LDA #$7E : STA $4304
+		0xA9, 0x04, 0x8D, 0x05, 0x43, // LDA #$04 : STA $4305
+		0x9C, 0x06, 0x43, // STZ $4306
+		0x9C, 0x21, 0x21, // STZ $2121
+		0xA9, 0x01, 0x8D, 0x0B, 0x42, // LDA #$01 : STA $420B
+		0xE6, 0x00, // INC $00
+		0x40, // RTI
+	})
+	// native NMI vector $9000 and RESET vector $8000:
+	copy(s.ROM[0x7FEA:], []byte{0x00, 0x90})
+	copy(s.ROM[0x7FFC:], []byte{0x00, 0x80})
+
+	copy(s.WRAM[0x1000:], []byte{0x1F, 0x00, 0xE0, 0x03})
+
+	s.CPU.Reset()
+	s.RunUntil(0x1000000, hwio.CyclesPerFrame)
+
+	if actual, expected := s.WRAM[0], uint8(1); actual != expected {
+		t.Errorf("NMI count = %d, expected %d", actual, expected)
+	}
+	if actual, expected := s.HW.CGRAM[0:4], []byte{0x1F, 0x00, 0xE0, 0x03}; !bytes.Equal(actual, expected) {
+		t.Errorf("CGRAM = % x, expected % x", actual, expected)
+	}
+}
@@ -0,0 +1,60 @@
+package exhirom
+
+// BusAddressToPC maps an ExHiROM bus address to an offset into the ROM file; addresses that do not map to ROM
+// return $1000000
+func BusAddressToPC(busAddr uint32) uint32 {
+	bank := (busAddr >> 16) & 0xFF
+	page := busAddr & 0xFFFF
+	if bank == 0x7E || bank == 0x7F {
+		return 0x1000000
+	}
+	// banks $00-$3F and $80-$BF only map ROM in their upper half:
+	if bank&0x40 == 0 && page < 0x8000 {
+		return 0x1000000
+	}
+
+	// banks $80-$FF map the first 4MiB and banks $00-$7D the rest:
+	pcAddr := (bank&0x3F)<<16 | page
+	if bank&0x80 == 0 {
+		pcAddr |= 0x400000
+	}
+	return pcAddr
+}
+
+func BusAddressToPak(busAddr uint32) uint32 {
+	bank := (busAddr >> 16) & 0xFF
+	page := busAddr & 0xFFFF
+	if bank >= 0x7E && bank < 0x80 {
+		wram := (busAddr - 0x7E0000) + 0xF50000
+		return wram
+	}
+	// SRAM is 8KiB per bank at $A0-$BF:6000-7FFF, mirrored at $20-$3F:
+	if bank&0x60 == 0x20 && page >= 0x6000 && page < 0x8000 {
+		sram := (bank&0x1F)<<13 + (page - 0x6000) + 0xE00000
+		return sram
+	}
+	// ROM:
+	if pcAddr := BusAddressToPC(busAddr); pcAddr < 0x1000000 {
+		return pcAddr
+	}
+	return busAddr
+}
+
+func PakAddressToBus(pakAddr uint32) uint32 {
+	// WRAM is easy:
+	if pakAddr >= 0xF50000 && pakAddr < 0xF70000 {
+		return pakAddr - 0xF50000 + 0x7E0000
+	}
+	// SRAM:
+	if pakAddr >= 0xE00000 && pakAddr < 0xE40000 {
+		offs := pakAddr - 0xE00000
+		busAddr := (0xA0+(offs>>13))<<16 + 0x6000 + offs&0x1FFF
+		return busAddr
+	}
+	// ROM access:
+	if pakAddr < 0x400000 {
+		return pakAddr + 0xC00000
+	}
+	// the upper 4MiB are at the same address in banks $40-$7D:
+	return pakAddr
+}
@@ -0,0 +1,62 @@
+package snes
+
+import (
+	"o2/snes/exhirom"
+	"o2/snes/hirom"
+	"o2/snes/lorom"
+)
+
+// Mapping translates between SNES bus addresses, offsets into the ROM file and FX Pak Pro address space for a
+// cartridge's memory map
+type Mapping interface {
+	BusAddressToPC(busAddr uint32) uint32
+	BusAddressToPak(busAddr uint32) uint32
+	PakAddressToBus(pakAddr uint32) uint32
+}
+
+type loROMMapping struct{}
+
+func (loROMMapping) BusAddressToPC(busAddr uint32) uint32  { return lorom.BusAddressToPC(busAddr) }
+func (loROMMapping) BusAddressToPak(busAddr uint32) uint32 { return lorom.BusAddressToPak(busAddr) }
+func (loROMMapping) PakAddressToBus(pakAddr uint32) uint32 { return lorom.PakAddressToBus(pakAddr) }
+
+type hiROMMapping struct{}
+
+func (hiROMMapping) BusAddressToPC(busAddr uint32) uint32  { return hirom.BusAddressToPC(busAddr) }
+func (hiROMMapping) BusAddressToPak(busAddr uint32) uint32 { return hirom.BusAddressToPak(busAddr) }
+func (hiROMMapping) PakAddressToBus(pakAddr uint32) uint32 { return hirom.PakAddressToBus(pakAddr) }
+
+type exHiROMMapping struct{}
+
+func (exHiROMMapping) BusAddressToPC(busAddr uint32) uint32  { return exhirom.BusAddressToPC(busAddr) }
+func (exHiROMMapping) BusAddressToPak(busAddr uint32) uint32 { return exhirom.BusAddressToPak(busAddr) }
+func (exHiROMMapping) PakAddressToBus(pakAddr uint32) uint32 { return exhirom.PakAddressToBus(pakAddr) }
+
+var (
+	// LoROM maps ROM to $00-$7D:8000-FFFF and SRAM to $70-$7D:0000-7FFF
+	LoROM Mapping = loROMMapping{}
+	// HiROM maps ROM to $C0-$FF:0000-FFFF and SRAM to $20-$3F:6000-7FFF
+	HiROM Mapping = hiROMMapping{}
+	// ExHiROM maps the first 4MiB of ROM to $C0-$FF:0000-FFFF, the rest to $40-$7D:0000-FFFF and SRAM to
+	// $A0-$BF:6000-7FFF
+	ExHiROM Mapping = exHiROMMapping{}
+)
+
+// MappingForMode chooses the Mapping for a header's MapMode; unknown modes assume LoROM
+func MappingForMode(mapMode byte) Mapping {
+	// ignore the FastROM bit:
+	switch mapMode &^ 0x10 {
+	case 0x21, 0x2A:
+		return HiROM
+	case 0x25:
+		return ExHiROM
+	default:
+		// includes $22 S-DD1 and $23 SA-1:
+		return LoROM
+	}
+}
+
+// Mapping returns the memory map the ROM's header declares
+func (r *ROM) Mapping() Mapping {
+	return MappingForMode(r.Header.MapMode)
+}
@@ -0,0 +1,92 @@
+package snes
+
+import "testing"
+
+func TestMappingForMode(t *testing.T) {
+	tests := []struct {
+		mapMode byte
+		want    Mapping
+	}{
+		{0x20, LoROM},
+		{0x30, LoROM},
+		{0x23, LoROM},
+		{0x21, HiROM},
+		{0x31, HiROM},
+		{0x25, ExHiROM},
+		{0x35, ExHiROM},
+	}
+	for _, tt := range tests {
+		if got := MappingForMode(tt.mapMode); got != tt.want {
+			t.Errorf("MappingForMode($%02x) = %T, want %T", tt.mapMode, got, tt.want)
+		}
+	}
+}
+
+func TestMapping_BusAddressToPC(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping Mapping
+		busAddr uint32
+		want    uint32
+	}{
+		{"LoROM $00:8000", LoROM, 0x008000, 0x000000},
+		{"LoROM $01:8000", LoROM, 0x018000, 0x008000},
+		{"LoROM $00:7FFF", LoROM, 0x007FFF, 0x1000000},
+		{"HiROM $C0:0000", HiROM, 0xC00000, 0x000000},
+		{"HiROM $C1:2345", HiROM, 0xC12345, 0x012345},
+		{"HiROM $00:FFC0", HiROM, 0x00FFC0, 0x00FFC0},
+		{"HiROM $40:1234", HiROM, 0x401234, 0x001234},
+		{"HiROM $00:7FFF", HiROM, 0x007FFF, 0x1000000},
+		{"HiROM $7E:0000", HiROM, 0x7E0000, 0x1000000},
+		{"ExHiROM $C0:0000", ExHiROM, 0xC00000, 0x000000},
+		{"ExHiROM $80:FFC0", ExHiROM, 0x80FFC0, 0x00FFC0},
+		{"ExHiROM $00:FFC0", ExHiROM, 0x00FFC0, 0x40FFC0},
+		{"ExHiROM $40:0000", ExHiROM, 0x400000, 0x400000},
+		{"ExHiROM $7D:FFFF", ExHiROM, 0x7DFFFF, 0x7DFFFF},
+		{"ExHiROM $7F:0000", ExHiROM, 0x7F0000, 0x1000000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mapping.BusAddressToPC(tt.busAddr); got != tt.want {
+				t.Errorf("BusAddressToPC($%06x) = $%06x, want $%06x", tt.busAddr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapping_Pak(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping Mapping
+		busAddr uint32
+		pakAddr uint32
+	}{
+		{"HiROM WRAM", HiROM, 0x7E0010, 0xF50010},
+		{"HiROM SRAM", HiROM, 0x206000, 0xE00000},
+		{"HiROM SRAM bank 1", HiROM, 0x217FFF, 0xE03FFF},
+		{"HiROM ROM", HiROM, 0xC12345, 0x012345},
+		{"ExHiROM WRAM", ExHiROM, 0x7F1234, 0xF61234},
+		{"ExHiROM SRAM", ExHiROM, 0xA06000, 0xE00000},
+		{"ExHiROM SRAM bank 1", ExHiROM, 0xA173FE, 0xE033FE},
+		{"ExHiROM ROM", ExHiROM, 0xC12345, 0x012345},
+		{"ExHiROM upper ROM", ExHiROM, 0x412345, 0x412345},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mapping.BusAddressToPak(tt.busAddr); got != tt.pakAddr {
+				t.Errorf("BusAddressToPak($%06x) = $%06x, want $%06x", tt.busAddr, got, tt.pakAddr)
+			}
+			if got := tt.mapping.PakAddressToBus(tt.pakAddr); got != tt.busAddr {
+				t.Errorf("PakAddressToBus($%06x) = $%06x, want $%06x", tt.pakAddr, got, tt.busAddr)
+			}
+		})
+	}
+
+	// SRAM mirrors map to the same place:
+	if got := HiROM.BusAddressToPak(0xA06000); got != 0xE00000 {
+		t.Errorf("HiROM.BusAddressToPak($a06000) = $%06x, want $e00000", got)
+	}
+	if got := ExHiROM.BusAddressToPak(0x216000); got != 0xE02000 {
+		t.Errorf("ExHiROM.BusAddressToPak($216000) = $%06x, want $e02000", got)
+	}
+}
@@ -20,7 +20,7 @@ func (d *Driver) DisplayName() string {
 }
 
 func (d *Driver) DisplayDescription() string {
-	return "Connect to a mock SNES device for testing"
+	return "Emulate a headless SNES that runs the booted ROM for testing"
 }
 
 func (d *Driver) Open(desc snes.DeviceDescriptor) (snes.Queue, error) {
@@ -0,0 +1,89 @@
+package mock
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"o2/snes"
+	"o2/snes/emulator/hwio"
+)
+
+// preMain is where games/base installs the preMain routine for LoROM games that the frame hook calls with `JSL`
+const preMain = 0x707FF8
+
+// noFrameHook is a PC the CPU can never reach, used when the booted ROM has no frame hook
+const noFrameHook = 0x1000000
+
+// Boot loads the ROM into the emulated system and resets the CPU. If the ROM is patched for o2 its frame hook is
+// found so that every frame runs the installed update routines the same way the game would on a real SNES.
+func (q *Queue) Boot(rom *snes.ROM) error {
+	defer q.lock.Unlock()
+	q.lock.Lock()
+
+	if err := q.LoadROM(rom); err != nil {
+		return err
+	}
+
+	q.frameHook = findFrameHook(rom)
+	if q.frameHook == noFrameHook {
+		log.Printf("mock: boot '%s': frame hook not found; rom is not patched\n", rom.Name)
+	} else {
+		log.Printf("mock: boot '%s': frame hook at $%06x\n", rom.Name, q.frameHook)
+	}
+
+	q.CPU.Reset()
+	q.booted = true
+	q.halted = false
+
+	return nil
+}
+
+// findFrameHook finds the `JSL` to the patched preMain routine in SRAM that the patcher writes at the frame hook
+func findFrameHook(rom *snes.ROM) uint32 {
+	var jsl [4]byte
+	jsl[0] = 0x22
+	binary.LittleEndian.PutUint16(jsl[1:3], uint16(preMain&0xFFFF))
+	jsl[3] = byte(preMain >> 16)
+
+	c := rom.Contents
+	for i := 0; i+len(jsl) <= len(c); i++ {
+		if c[i] == jsl[0] && c[i+1] == jsl[1] && c[i+2] == jsl[2] && c[i+3] == jsl[3] {
+			// LoROM maps each 32KiB of ROM to $8000-$FFFF of successive banks:
+			return uint32(i>>15)<<16 | 0x8000 | uint32(i&0x7FFF)
+		}
+	}
+
+	return noFrameHook
+}
+
+// frame emulates one frame of the booted ROM: the game's main loop runs up to its frame hook, e.g. while waiting
+// for NMI, and then through the hook which calls the current update routine
+func (q *Queue) frame() {
+	defer q.lock.Unlock()
+	q.lock.Lock()
+
+	if !q.booted {
+		// nothing is running; advance the frame counter so the device still looks alive:
+		q.WRAM[0x1A]++
+		return
+	}
+	if q.halted {
+		return
+	}
+
+	defer func() {
+		// the bus panics when the CPU accesses unmapped memory:
+		if r := recover(); r != nil {
+			log.Printf("mock: halting emulation at $%06x: %v\n", q.GetPC(), r)
+			q.halted = true
+		}
+	}()
+
+	if q.RunUntil(q.frameHook, hwio.CyclesPerFrame) {
+		// step into the hook's JSL and run until it returns:
+		q.Step()
+		if !q.RunUntil(q.frameHook+4, hwio.CyclesPerFrame) {
+			panic(fmt.Errorf("frame hook did not return within %d cycles", hwio.CyclesPerFrame))
+		}
+	}
+}
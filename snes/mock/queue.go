@@ -5,6 +5,7 @@ import (
 	"log"
 	"o2/snes"
 	"o2/snes/emulator"
+	"sync"
 	"time"
 )
 
@@ -14,7 +15,16 @@ type Queue struct {
 
 	closed chan struct{}
 
-	nothing [0x100]byte
+	// lock guards the emulated system between the frame goroutine and the queue's commands:
+	lock sync.Mutex
+
+	// roms are the uploaded ROM files by path:
+	roms map[string][]byte
+	// booted is set once a ROM is booted and halted if its emulation crashed:
+	booted bool
+	halted bool
+	// frameHook is the bus address of the booted ROM's frame hook; noFrameHook if not patched
+	frameHook uint32
 
 	frameTicker *time.Ticker
 }
@@ -29,7 +39,6 @@ func (q *Queue) Closed() <-chan struct{} {
 
 func (q *Queue) Close() error {
 	q.frameTicker.Stop()
-	q.frameTicker = nil
 	close(q.closed)
 	return nil
 }
@@ -41,13 +50,18 @@ func (q *Queue) Init() {
 		return
 	}
 
+	q.roms = make(map[string][]byte)
 	q.closed = make(chan struct{})
+	// 5,369,317.5/89,341.5 ~= 60.0988 frames / sec ~= 16,639,265.605 ns / frame
 	q.frameTicker = time.NewTicker(16_639_265 * time.Nanosecond)
 	go func() {
-		// 5,369,317.5/89,341.5 ~= 60.0988 frames / sec ~= 16,639,265.605 ns / frame
-		for range q.frameTicker.C {
-			// increment frame timer:
-			q.WRAM[0x1A]++
+		for {
+			select {
+			case <-q.frameTicker.C:
+				q.frame()
+			case <-q.closed:
+				return
+			}
 		}
 	}()
 }
@@ -74,6 +88,26 @@ func (q *Queue) MakeWriteCommands(reqs []snes.Write, batchComplete snes.Completi
 	return seq
 }
 
+// memoryAt maps an FX Pak Pro address to the emulated memory backing it, or nil if the address is not backed
+func (q *Queue) memoryAt(address uint32, size uint32) []byte {
+	var mem []byte
+	switch {
+	case address >= 0xF50000 && address < 0xF70000:
+		mem, address = q.WRAM[:], address-0xF50000
+	case address >= 0xE00000 && address < 0xF00000:
+		mem, address = q.SRAM[:], address-0xE00000
+	case address < 0xE00000:
+		mem = q.ROM[:]
+	default:
+		return nil
+	}
+
+	if address+size > uint32(len(mem)) {
+		return nil
+	}
+	return mem[address : address+size]
+}
+
 type readCommand struct {
 	Request snes.Read
 }
@@ -92,15 +126,13 @@ func (r *readCommand) Execute(queue snes.Queue, keepAlive snes.KeepAlive) error
 		return nil
 	}
 
-	var data []byte
-	if r.Request.Address >= 0xF50000 && r.Request.Address < 0xF70000 {
-		// read from wram:
-		o := r.Request.Address - 0xF50000
-		data = q.WRAM[o : o+uint32(r.Request.Size)]
-	} else {
-		// read from nothing:
-		data = q.nothing[0:r.Request.Size]
+	// unmapped addresses read as zeroes:
+	data := make([]byte, r.Request.Size)
+	q.lock.Lock()
+	if mem := q.memoryAt(r.Request.Address, uint32(r.Request.Size)); mem != nil {
+		copy(data, mem)
 	}
+	q.lock.Unlock()
 
 	completed(snes.Response{
 		IsWrite: false,
@@ -117,9 +149,20 @@ type writeCommand struct {
 	Request snes.Write
 }
 
-func (r *writeCommand) Execute(_ snes.Queue, keepAlive snes.KeepAlive) error {
+func (r *writeCommand) Execute(queue snes.Queue, keepAlive snes.KeepAlive) error {
+	q, ok := queue.(*Queue)
+	if !ok {
+		return fmt.Errorf("queue is not of expected internal type")
+	}
+
 	<-time.After(time.Millisecond * 1)
 
+	q.lock.Lock()
+	if mem := q.memoryAt(r.Request.Address, uint32(r.Request.Size)); mem != nil {
+		copy(mem, r.Request.Data)
+	}
+	q.lock.Unlock()
+
 	completed := r.Request.Completion
 	if completed != nil {
 		completed(snes.Response{
@@ -0,0 +1,120 @@
+package mock
+
+import (
+	"o2/games/base"
+	"o2/snes"
+	"testing"
+)
+
+// makePatchedROM builds a LoROM game whose main loop waits for NMI and then calls its frame hook, and patches it
+func makePatchedROM(t *testing.T) *snes.ROM {
+	rom, err := snes.NewROM("test.sfc", make([]byte, 0x10_0000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(addr uint32, b []byte) {
+		if _, err := rom.BusWriter(addr).Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// SEI : CLC : XCE : SEP #$30 : LDA #$81 : STA $4200
+	write(0x008000, []byte{0x78, 0x18, 0xFB, 0xE2, 0x30, 0xA9, 0x81, 0x8D, 0x00, 0x42})
+	// main loop: LDA $12 : BEQ main : STZ $12 : JSL $009000 : BRA main
+	write(0x00800A, []byte{0xA5, 0x12, 0xF0, 0xFC, 0x64, 0x12, 0x22, 0x00, 0x90, 0x00, 0x80, 0xF4})
+	// RTL
+	write(0x009000, []byte{0x6B})
+	// NMI: INC $1A : LDA #$01 : STA $12 : RTI
+	write(0x009100, []byte{0xE6, 0x1A, 0xA9, 0x01, 0x85, 0x12, 0x40})
+
+	rom.EmulatedVectors.RESET = 0x8000
+	rom.NativeVectors.NMI = 0x9100
+	if err = rom.WriteHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	hooks := &base.Hooks{
+		ResetHook: 0x008005,
+		InitHook:  0x00A000,
+		FrameHook: 0x008010,
+	}
+	if err = base.NewPatcher(hooks, rom).Patch(); err != nil {
+		t.Fatal(err)
+	}
+	return rom
+}
+
+func newTestQueue(t *testing.T) *Queue {
+	// no frame ticker so the test runs frames itself:
+	q := &Queue{roms: make(map[string][]byte)}
+	q.BaseInit(driverName, q)
+	if err := q.CreateEmulator(); err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func execute(t *testing.T, q *Queue, seq snes.CommandSequence) {
+	for _, cmd := range seq {
+		if err := cmd.Command.Execute(q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestQueue_Boot(t *testing.T) {
+	q := newTestQueue(t)
+	rom := makePatchedROM(t)
+
+	path, seq := q.MakeUploadROMCommands("o2", "test.sfc", rom.Contents)
+	execute(t, q, seq)
+	execute(t, q, q.MakeBootROMCommands(path))
+
+	if actual, expected := q.frameHook, uint32(0x008010); actual != expected {
+		t.Fatalf("frameHook = $%06x, expected $%06x", actual, expected)
+	}
+
+	for i := 0; i < 3; i++ {
+		q.frame()
+	}
+	if q.halted {
+		t.Fatalf("emulation halted at $%06x", q.GetPC())
+	}
+	// the NMI handler increments the frame counter:
+	if actual, expected := q.WRAM[0x1A], uint8(3); actual != expected {
+		t.Errorf("WRAM[$1A] = %d, expected %d", actual, expected)
+	}
+	// the reset hook installed preMain:
+	if actual, expected := q.SRAM[0x7FF8], uint8(0x20); actual != expected {
+		t.Errorf("SRAM[$7FF8] = $%02x, expected $%02x (JSR)", actual, expected)
+	}
+
+	// write an update routine to SRAM through the queue: LDA #$42 : STA $7E0100 : RTS
+	execute(t, q, q.MakeWriteCommands([]snes.Write{
+		{Address: 0xE07C00, Size: 7, Data: []byte{0xA9, 0x42, 0x8F, 0x00, 0x01, 0x7E, 0x60}},
+	}, nil))
+	q.frame()
+
+	var data []byte
+	execute(t, q, q.MakeReadCommands([]snes.Read{
+		{Address: 0xF50100, Size: 1, Completion: func(rsp snes.Response) { data = rsp.Data }},
+	}, nil))
+	if len(data) != 1 || data[0] != 0x42 {
+		t.Errorf("WRAM[$100] = % x, expected 42", data)
+	}
+}
+
+func TestQueue_Unbooted(t *testing.T) {
+	q := newTestQueue(t)
+	q.frame()
+	q.frame()
+	if actual, expected := q.WRAM[0x1A], uint8(2); actual != expected {
+		t.Errorf("WRAM[$1A] = %d, expected %d", actual, expected)
+	}
+}
+
+func TestPreMain(t *testing.T) {
+	if actual, expected := uint32(preMain), base.LoROMRoutines.PreMain; actual != expected {
+		t.Errorf("preMain = $%06x, expected $%06x", actual, expected)
+	}
+}
@@ -0,0 +1,74 @@
+package mock
+
+import (
+	"fmt"
+	"log"
+	"o2/snes"
+	"strings"
+)
+
+func (q *Queue) MakeUploadROMCommands(folder string, filename string, rom []byte) (path string, cmds snes.CommandSequence) {
+	// let the folder and filename be joined correctly:
+	folder = strings.TrimRight(folder, "/")
+	filename = strings.TrimLeft(filename, "/")
+	path = strings.Join([]string{folder, filename}, "/")
+
+	cmds = snes.CommandSequence{
+		snes.CommandWithCompletion{Command: &uploadCommand{path: path, rom: rom}},
+	}
+
+	return
+}
+
+func (q *Queue) MakeBootROMCommands(path string) snes.CommandSequence {
+	return snes.CommandSequence{
+		snes.CommandWithCompletion{Command: &bootCommand{path: path}},
+	}
+}
+
+type uploadCommand struct {
+	path string
+	rom  []byte
+}
+
+func (c *uploadCommand) Execute(queue snes.Queue, keepAlive snes.KeepAlive) error {
+	q, ok := queue.(*Queue)
+	if !ok {
+		return fmt.Errorf("queue is not of expected internal type")
+	}
+
+	contents := make([]byte, len(c.rom))
+	copy(contents, c.rom)
+
+	q.lock.Lock()
+	q.roms[c.path] = contents
+	q.lock.Unlock()
+
+	log.Printf("mock: uploaded '%s'\n", c.path)
+	return nil
+}
+
+type bootCommand struct {
+	path string
+}
+
+func (c *bootCommand) Execute(queue snes.Queue, keepAlive snes.KeepAlive) error {
+	q, ok := queue.(*Queue)
+	if !ok {
+		return fmt.Errorf("queue is not of expected internal type")
+	}
+
+	q.lock.Lock()
+	contents, ok := q.roms[c.path]
+	q.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("mock: boot: file '%s' not found", c.path)
+	}
+
+	rom, err := snes.NewROM(c.path, contents)
+	if err != nil {
+		return fmt.Errorf("mock: boot: %w", err)
+	}
+
+	return q.Boot(rom)
+}
@@ -0,0 +1,192 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+var bpsMagic = []byte("BPS1")
+
+// BPS actions encoded in the low 2 bits of each action's length:
+const (
+	bpsSourceRead = iota
+	bpsTargetRead
+	bpsSourceCopy
+	bpsTargetCopy
+)
+
+// footerSize is the size of the source, target and patch CRC32s ending BPS and UPS patches
+const footerSize = 12
+
+// footer verifies the patch CRC32 and returns the source and target CRC32s
+func footer(patch []byte) (sourceCRC, targetCRC uint32, err error) {
+	f := patch[len(patch)-footerSize:]
+	sourceCRC = binary.LittleEndian.Uint32(f[0:4])
+	targetCRC = binary.LittleEndian.Uint32(f[4:8])
+	patchCRC := binary.LittleEndian.Uint32(f[8:12])
+	if actual := crc32.ChecksumIEEE(patch[:len(patch)-4]); actual != patchCRC {
+		err = ErrChecksum{Which: "patch", Expected: patchCRC, Actual: actual}
+	}
+	return
+}
+
+// ApplyBPS applies a BPS patch after verifying the patch and source CRC32s and verifies the target CRC32
+func ApplyBPS(source, patch []byte) ([]byte, error) {
+	if len(patch) < len(bpsMagic)+footerSize || !bytes.HasPrefix(patch, bpsMagic) {
+		return nil, ErrUnknownFormat
+	}
+
+	sourceCRC, targetCRC, err := footer(patch)
+	if err != nil {
+		return nil, err
+	}
+	if actual := crc32.ChecksumIEEE(source); actual != sourceCRC {
+		return nil, ErrChecksum{Which: "source", Expected: sourceCRC, Actual: actual}
+	}
+
+	r := &reader{b: patch, o: len(bpsMagic), end: len(patch) - footerSize}
+	sourceSize, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if sourceSize != uint64(len(source)) {
+		return nil, fmt.Errorf("patch: bps: source size %d does not match %d", len(source), sourceSize)
+	}
+	targetSize, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if targetSize > MaxTargetSize {
+		return nil, fmt.Errorf("%w: bps: %d bytes", ErrTooLarge, targetSize)
+	}
+	metadataSize, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = r.bytes(int(metadataSize)); err != nil {
+		return nil, err
+	}
+
+	target := make([]byte, targetSize)
+	var out, sourceRelative, targetRelative int64
+	for !r.done() {
+		var action uint64
+		if action, err = r.varint(); err != nil {
+			return nil, err
+		}
+		length := int64(action>>2) + 1
+		if out+length > int64(len(target)) {
+			return nil, fmt.Errorf("patch: bps: action at target offset %d writes past the end of the target", out)
+		}
+
+		switch action & 3 {
+		case bpsSourceRead:
+			if out+length > int64(len(source)) {
+				return nil, fmt.Errorf("patch: bps: source read at offset %d past the end of the source", out)
+			}
+			copy(target[out:out+length], source[out:out+length])
+		case bpsTargetRead:
+			var data []byte
+			if data, err = r.bytes(int(length)); err != nil {
+				return nil, err
+			}
+			copy(target[out:out+length], data)
+		case bpsSourceCopy, bpsTargetCopy:
+			var v uint64
+			if v, err = r.varint(); err != nil {
+				return nil, err
+			}
+			delta := int64(v >> 1)
+			if v&1 != 0 {
+				delta = -delta
+			}
+
+			if action&3 == bpsSourceCopy {
+				sourceRelative += delta
+				if sourceRelative < 0 || sourceRelative+length > int64(len(source)) {
+					return nil, fmt.Errorf("patch: bps: source copy from offset %d out of range", sourceRelative)
+				}
+				copy(target[out:out+length], source[sourceRelative:sourceRelative+length])
+				sourceRelative += length
+			} else {
+				targetRelative += delta
+				if targetRelative < 0 || targetRelative >= out {
+					return nil, fmt.Errorf("patch: bps: target copy from offset %d out of range", targetRelative)
+				}
+				// copy byte by byte since the ranges may overlap to repeat a pattern:
+				for i := int64(0); i < length; i++ {
+					target[out+i] = target[targetRelative]
+					targetRelative++
+				}
+			}
+		}
+		out += length
+	}
+
+	if out != int64(len(target)) {
+		return nil, fmt.Errorf("patch: bps: actions wrote %d bytes of %d byte target", out, len(target))
+	}
+	if actual := crc32.ChecksumIEEE(target); actual != targetCRC {
+		return nil, ErrChecksum{Which: "target", Expected: targetCRC, Actual: actual}
+	}
+
+	return target, nil
+}
+
+// CreateBPS creates a BPS patch that turns source into target, e.g. an unpatched ROM into the one produced by
+// a games.Patcher. Unchanged bytes are read from the source and changed bytes are stored in the patch.
+func CreateBPS(source, target []byte, metadata string) []byte {
+	w := &bytes.Buffer{}
+	w.Write(bpsMagic)
+	writeVarint(w, uint64(len(source)))
+	writeVarint(w, uint64(len(target)))
+	writeVarint(w, uint64(len(metadata)))
+	w.WriteString(metadata)
+
+	writeAction := func(action int, length int) {
+		writeVarint(w, uint64(length-1)<<2|uint64(action))
+	}
+
+	for out := 0; out < len(target); {
+		// count how many bytes match the source:
+		n := 0
+		for out+n < len(target) && out+n < len(source) && target[out+n] == source[out+n] {
+			n++
+		}
+		if n > 0 {
+			writeAction(bpsSourceRead, n)
+			out += n
+			continue
+		}
+
+		// count how many bytes differ, tolerating short matching runs which cost more as actions than data:
+		for out+n < len(target) {
+			same := 0
+			for out+n+same < len(target) && out+n+same < len(source) && target[out+n+same] == source[out+n+same] && same < 4 {
+				same++
+			}
+			if same == 4 {
+				break
+			}
+			n += same
+			if same == 0 {
+				n++
+			}
+		}
+		writeAction(bpsTargetRead, n)
+		w.Write(target[out : out+n])
+		out += n
+	}
+
+	var crc [4]byte
+	binary.LittleEndian.PutUint32(crc[:], crc32.ChecksumIEEE(source))
+	w.Write(crc[:])
+	binary.LittleEndian.PutUint32(crc[:], crc32.ChecksumIEEE(target))
+	w.Write(crc[:])
+	binary.LittleEndian.PutUint32(crc[:], crc32.ChecksumIEEE(w.Bytes()))
+	w.Write(crc[:])
+
+	return w.Bytes()
+}
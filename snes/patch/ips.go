@@ -0,0 +1,73 @@
+package patch
+
+import (
+	"bytes"
+	"fmt"
+)
+
+var ipsMagic = []byte("PATCH")
+
+// ipsEOF is the record offset that ends an IPS patch
+const ipsEOF = 0x454F46
+
+// ApplyIPS applies an IPS patch; the target grows to fit records written past the end of source and may be
+// truncated by the optional length following the EOF marker
+func ApplyIPS(source, patch []byte) ([]byte, error) {
+	if !bytes.HasPrefix(patch, ipsMagic) {
+		return nil, ErrUnknownFormat
+	}
+	r := &reader{b: patch, o: len(ipsMagic), end: len(patch)}
+
+	target := make([]byte, len(source))
+	copy(target, source)
+
+	for {
+		b, err := r.bytes(3)
+		if err != nil {
+			return nil, err
+		}
+		offset := int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+		if offset == ipsEOF {
+			break
+		}
+
+		if b, err = r.bytes(2); err != nil {
+			return nil, err
+		}
+		size := int(b[0])<<8 | int(b[1])
+
+		var data []byte
+		if size == 0 {
+			// run-length encoded record:
+			if b, err = r.bytes(3); err != nil {
+				return nil, err
+			}
+			size = int(b[0])<<8 | int(b[1])
+			data = make([]byte, size)
+			for i := range data {
+				data[i] = b[2]
+			}
+		} else if data, err = r.bytes(size); err != nil {
+			return nil, err
+		}
+
+		if end := offset + size; end > len(target) {
+			target = append(target, make([]byte, end-len(target))...)
+		}
+		copy(target[offset:], data)
+	}
+
+	// optional truncation extension:
+	if !r.done() {
+		b, err := r.bytes(3)
+		if err != nil {
+			return nil, fmt.Errorf("patch: ips: invalid truncation length: %w", err)
+		}
+		size := int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+		if size < len(target) {
+			target = target[:size]
+		}
+	}
+
+	return target, nil
+}
@@ -0,0 +1,130 @@
+// Package patch applies IPS, BPS and UPS patches to ROM images and creates BPS patches.
+package patch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownFormat = errors.New("patch: unknown patch format")
+	ErrTruncated     = errors.New("patch: patch is truncated")
+	ErrTooLarge      = errors.New("patch: target size too large")
+)
+
+// MaxTargetSize bounds the target size a patch may declare before the target is allocated; the largest SNES ROMs
+// are 8MiB
+const MaxTargetSize = 64 << 20
+
+// ErrChecksum is returned when a CRC32 stored in a BPS or UPS patch does not match
+type ErrChecksum struct {
+	// Which is one of "source", "target" or "patch"
+	Which    string
+	Expected uint32
+	Actual   uint32
+}
+
+func (e ErrChecksum) Error() string {
+	return fmt.Sprintf("patch: %s crc32 mismatch: expected %08x, got %08x", e.Which, e.Expected, e.Actual)
+}
+
+// Format identifies a patch format by its magic bytes
+type Format string
+
+const (
+	FormatIPS Format = "IPS"
+	FormatBPS Format = "BPS"
+	FormatUPS Format = "UPS"
+)
+
+// Detect determines the format of the patch from its magic bytes
+func Detect(patch []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(patch, ipsMagic):
+		return FormatIPS, nil
+	case bytes.HasPrefix(patch, bpsMagic):
+		return FormatBPS, nil
+	case bytes.HasPrefix(patch, upsMagic):
+		return FormatUPS, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Apply applies the patch of any supported format to source and returns the patched copy; source is not modified
+func Apply(source, patch []byte) ([]byte, error) {
+	format, err := Detect(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatIPS:
+		return ApplyIPS(source, patch)
+	case FormatBPS:
+		return ApplyBPS(source, patch)
+	case FormatUPS:
+		return ApplyUPS(source, patch)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// reader reads bytes and the variable-length integers shared by BPS and UPS from a patch
+type reader struct {
+	b []byte
+	o int
+	// end is where the footer starts
+	end int
+}
+
+func (r *reader) done() bool { return r.o >= r.end }
+
+func (r *reader) u8() (uint8, error) {
+	if r.o >= r.end {
+		return 0, ErrTruncated
+	}
+	c := r.b[r.o]
+	r.o++
+	return c, nil
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.o+n > r.end {
+		return nil, ErrTruncated
+	}
+	b := r.b[r.o : r.o+n]
+	r.o += n
+	return b, nil
+}
+
+func (r *reader) varint() (uint64, error) {
+	var data, shift uint64 = 0, 1
+	for {
+		x, err := r.u8()
+		if err != nil {
+			return 0, err
+		}
+		data += uint64(x&0x7F) * shift
+		if x&0x80 != 0 {
+			return data, nil
+		}
+		shift <<= 7
+		data += shift
+		if shift > 1<<56 {
+			return 0, fmt.Errorf("patch: variable-length integer too large")
+		}
+	}
+}
+
+func writeVarint(w *bytes.Buffer, data uint64) {
+	for {
+		x := byte(data & 0x7F)
+		data >>= 7
+		if data == 0 {
+			w.WriteByte(0x80 | x)
+			return
+		}
+		w.WriteByte(x)
+		data--
+	}
+}
@@ -0,0 +1,245 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+)
+
+func testSource() []byte {
+	source := make([]byte, 0x1000)
+	for i := range source {
+		source[i] = byte(i * 7)
+	}
+	return source
+}
+
+func TestVarint(t *testing.T) {
+	for _, v := range []uint64{0, 1, 0x7F, 0x80, 0x3FFF, 0x4000, 0x10_0000, 1<<32 + 5} {
+		w := &bytes.Buffer{}
+		writeVarint(w, v)
+		r := &reader{b: w.Bytes(), end: w.Len()}
+		got, err := r.varint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != v || !r.done() {
+			t.Errorf("varint(%d) = %d", v, got)
+		}
+	}
+}
+
+func TestApplyIPS(t *testing.T) {
+	source := testSource()
+	patch := []byte("PATCH")
+	// write 3 bytes at $000010:
+	patch = append(patch, 0x00, 0x00, 0x10, 0x00, 0x03, 0xAA, 0xBB, 0xCC)
+	// RLE 4 bytes of $EE at $000FFE, growing the target:
+	patch = append(patch, 0x00, 0x0F, 0xFE, 0x00, 0x00, 0x00, 0x04, 0xEE)
+	patch = append(patch, []byte("EOF")...)
+
+	target, err := Apply(source, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(target) != 0x1002 {
+		t.Fatalf("len(target) = $%x, want $1002", len(target))
+	}
+	if !bytes.Equal(target[0x10:0x13], []byte{0xAA, 0xBB, 0xCC}) {
+		t.Errorf("target[$10:$13] = % x", target[0x10:0x13])
+	}
+	if !bytes.Equal(target[0xFFE:], []byte{0xEE, 0xEE, 0xEE, 0xEE}) {
+		t.Errorf("target[$ffe:] = % x", target[0xFFE:])
+	}
+	if source[0x10] != 0x70 {
+		t.Errorf("source was modified")
+	}
+
+	// truncate:
+	patch = append(patch, 0x00, 0x08, 0x00)
+	if target, err = Apply(source, patch); err != nil {
+		t.Fatal(err)
+	}
+	if len(target) != 0x800 {
+		t.Errorf("len(target) = $%x, want $800", len(target))
+	}
+
+	// truncated record:
+	if _, err = Apply(source, []byte("PATCH\x00\x00\x10\x00\x03\xAA")); !errors.Is(err, ErrTruncated) {
+		t.Errorf("err = %v, want ErrTruncated", err)
+	}
+
+	// called directly without the magic:
+	if _, err = ApplyIPS(source, []byte("PATCX\x00\x00\x10\x00\x01\xAAEOF")); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("err = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestBPS_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(b []byte) []byte
+	}{
+		{"same", func(b []byte) []byte { return b }},
+		{"changed", func(b []byte) []byte {
+			b[0] = 0xFF
+			copy(b[0x100:], "o2 was here")
+			b[0x105] = b[0x105-0x100]
+			b[0xFFF] ^= 0xFF
+			return b
+		}},
+		{"grown", func(b []byte) []byte { return append(b, bytes.Repeat([]byte{0x22}, 0x300)...) }},
+		{"shrunk", func(b []byte) []byte { return b[:0x800] }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := testSource()
+			target := tt.modify(testSource())
+
+			patch := CreateBPS(source, target, "o2")
+			if format, _ := Detect(patch); format != FormatBPS {
+				t.Fatalf("Detect() = %v, want BPS", format)
+			}
+
+			got, err := Apply(source, patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, target) {
+				t.Errorf("patched target differs")
+			}
+			if len(patch) > len(target)/2 && tt.name == "changed" {
+				t.Errorf("len(patch) = %d, expected a small patch", len(patch))
+			}
+		})
+	}
+}
+
+func TestApplyBPS_CRC(t *testing.T) {
+	source := testSource()
+	target := testSource()
+	target[0x20] = 0
+	patch := CreateBPS(source, target, "")
+
+	// wrong source:
+	wrong := testSource()
+	wrong[0] = 1
+	var errCRC ErrChecksum
+	if _, err := ApplyBPS(wrong, patch); !errors.As(err, &errCRC) || errCRC.Which != "source" {
+		t.Errorf("err = %v, want source crc32 mismatch", err)
+	}
+
+	// corrupt patch:
+	patch[len(bpsMagic)+4] ^= 0xFF
+	if _, err := ApplyBPS(source, patch); !errors.As(err, &errCRC) || errCRC.Which != "patch" {
+		t.Errorf("err = %v, want patch crc32 mismatch", err)
+	}
+}
+
+func TestApplyBPS_Copy(t *testing.T) {
+	source := []byte("abcdefgh")
+	target := []byte("efgh" + "xy" + "xyxyxy")
+
+	w := &bytes.Buffer{}
+	w.Write(bpsMagic)
+	writeVarint(w, uint64(len(source)))
+	writeVarint(w, uint64(len(target)))
+	writeVarint(w, 0)
+	// SourceCopy 4 bytes from +4:
+	writeVarint(w, 3<<2|bpsSourceCopy)
+	writeVarint(w, 4<<1)
+	// TargetRead "xy":
+	writeVarint(w, 1<<2|bpsTargetRead)
+	w.WriteString("xy")
+	// TargetCopy 6 bytes from 4 repeating "xy":
+	writeVarint(w, 5<<2|bpsTargetCopy)
+	writeVarint(w, 4<<1)
+	writeFooter(w, source, target)
+
+	got, err := ApplyBPS(source, w.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, target) {
+		t.Errorf("target = %q, want %q", got, target)
+	}
+}
+
+func writeFooter(w *bytes.Buffer, source, target []byte) {
+	var crc [4]byte
+	binary.LittleEndian.PutUint32(crc[:], crc32.ChecksumIEEE(source))
+	w.Write(crc[:])
+	binary.LittleEndian.PutUint32(crc[:], crc32.ChecksumIEEE(target))
+	w.Write(crc[:])
+	binary.LittleEndian.PutUint32(crc[:], crc32.ChecksumIEEE(w.Bytes()))
+	w.Write(crc[:])
+}
+
+func TestApplyUPS(t *testing.T) {
+	source := testSource()
+	target := append(testSource(), 0x01, 0x02)
+	target[0x10] ^= 0x5A
+	target[0x11] ^= 0xA5
+
+	w := &bytes.Buffer{}
+	w.Write(upsMagic)
+	writeVarint(w, uint64(len(source)))
+	writeVarint(w, uint64(len(target)))
+	// skip to $10 and XOR 2 bytes:
+	writeVarint(w, 0x10)
+	w.Write([]byte{0x5A, 0xA5, 0x00})
+	// skip to $1000 (after the terminator at $12) and extend:
+	writeVarint(w, 0x1000-0x13)
+	w.Write([]byte{0x01, 0x02, 0x00})
+	writeFooter(w, source, target)
+	patch := w.Bytes()
+
+	got, err := Apply(source, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, target) {
+		t.Errorf("patched target differs")
+	}
+
+	// and in reverse:
+	got, err = Apply(target, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, source) {
+		t.Errorf("reverse patched source differs")
+	}
+}
+
+func TestApply_TooLarge(t *testing.T) {
+	source := testSource()
+
+	w := &bytes.Buffer{}
+	w.Write(bpsMagic)
+	writeVarint(w, uint64(len(source)))
+	writeVarint(w, MaxTargetSize+1)
+	writeVarint(w, 0)
+	writeFooter(w, source, nil)
+	if _, err := Apply(source, w.Bytes()); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("bps: err = %v, want ErrTooLarge", err)
+	}
+
+	// UPS targets cannot be larger than the source plus the patch:
+	w.Reset()
+	w.Write(upsMagic)
+	writeVarint(w, uint64(len(source)))
+	writeVarint(w, uint64(len(source))*2)
+	writeFooter(w, source, nil)
+	if _, err := Apply(source, w.Bytes()); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("ups: err = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestDetect_Unknown(t *testing.T) {
+	if _, err := Apply(testSource(), []byte("NOPE")); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("err = %v, want ErrUnknownFormat", err)
+	}
+}
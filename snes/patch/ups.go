@@ -0,0 +1,83 @@
+package patch
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+)
+
+var upsMagic = []byte("UPS1")
+
+// ApplyUPS applies a UPS patch after verifying the patch CRC32. UPS patches are reversible so source may be
+// either side of the patch; the CRC32 of the other side is verified after applying.
+func ApplyUPS(source, patch []byte) ([]byte, error) {
+	if len(patch) < len(upsMagic)+footerSize || !bytes.HasPrefix(patch, upsMagic) {
+		return nil, ErrUnknownFormat
+	}
+
+	sourceCRC, targetCRC, err := footer(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &reader{b: patch, o: len(upsMagic), end: len(patch) - footerSize}
+	sourceSize, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	targetSize, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+
+	// apply the patch in reverse if given the target:
+	actual := crc32.ChecksumIEEE(source)
+	if actual != sourceCRC {
+		if actual != targetCRC || uint64(len(source)) != targetSize {
+			return nil, ErrChecksum{Which: "source", Expected: sourceCRC, Actual: actual}
+		}
+		sourceSize, targetSize = targetSize, sourceSize
+		sourceCRC, targetCRC = targetCRC, sourceCRC
+	}
+	if sourceSize != uint64(len(source)) {
+		return nil, fmt.Errorf("patch: ups: source size %d does not match %d", len(source), sourceSize)
+	}
+	// every target byte past the end of the source takes a byte of patch data:
+	if targetSize > MaxTargetSize || targetSize > uint64(len(source)+len(patch)) {
+		return nil, fmt.Errorf("%w: ups: %d bytes", ErrTooLarge, targetSize)
+	}
+
+	target := make([]byte, targetSize)
+	copy(target, source)
+
+	pos := uint64(0)
+	for !r.done() {
+		var skip uint64
+		if skip, err = r.varint(); err != nil {
+			return nil, err
+		}
+		pos += skip
+
+		// XOR until a zero byte which also skips a byte:
+		for {
+			var x uint8
+			if x, err = r.u8(); err != nil {
+				return nil, err
+			}
+			if x == 0 {
+				pos++
+				break
+			}
+			if pos < targetSize {
+				target[pos] ^= x
+			}
+			pos++
+		}
+	}
+
+	if actual := crc32.ChecksumIEEE(target); actual != targetCRC {
+		return nil, ErrChecksum{Which: "target", Expected: targetCRC, Actual: actual}
+	}
+
+	return target, nil
+}
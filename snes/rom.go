@@ -5,13 +5,14 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"o2/snes/lorom"
 	"reflect"
 )
 
 type ROM struct {
 	Name     string
 	Contents []byte
+	// CopierHeader is the 512-byte copier header stripped from the start of the ROM file, if any
+	CopierHeader []byte
 
 	HeaderOffset    uint32
 	Header          Header
@@ -117,25 +118,28 @@ type EmulatedVectors struct {
 }
 
 func NewROM(name string, contents []byte) (r *ROM, err error) {
+	// strip the copier header, if any:
+	var copierHeader []byte
+	if HasCopierHeader(contents) {
+		copierHeader = contents[:copierHeaderSize]
+		contents = contents[copierHeaderSize:]
+	}
+
 	if len(contents) < 0x8000 {
 		return nil, fmt.Errorf("ROM file not big enough to contain SNES header")
 	}
 
-	headerOffset := uint32(0x007FB0)
+	// find the most plausible header location for LoROM, HiROM or ExHiROM:
+	headerOffset := ScoreHeaders(contents)[0].Offset
 
 	r = &ROM{
 		Name:         name,
 		Contents:     contents,
+		CopierHeader: copierHeader,
 		HeaderOffset: headerOffset,
 	}
 
-  err = r.ReadHeader()
-
-  // if not valid header for lorom, try hirom
-  if !IsAscii(r.Header.Title[:]){
-    r.HeaderOffset = uint32(0x00FFB0)
-    err = r.ReadHeader()
-  }
+	err = r.ReadHeader()
 	return
 }
 
@@ -258,15 +262,8 @@ func (r *ROM) RAMSize() uint32 {
 	return 1024 << r.Header.RAMSize
 }
 
-// This is all for LoROM mapping:
-
 func (r *ROM) BusAddressToPC(busAddr uint32) uint32 {
-	// TODO: determine based on LoROM/HiROM mapping from header
-  // loosely checking for a type on the rom, only handles ExHiRom (MapMode == 0x35) and LoROM for now
-  if r.Header.MapMode == 0x35 {
-    return (busAddr + 0x400000) % 0x1000000
-  }
-	return lorom.BusAddressToPC(busAddr)
+	return r.Mapping().BusAddressToPC(busAddr)
 }
 
 func (r *ROM) U8(busAddr uint32) uint8 {
@@ -298,21 +295,26 @@ func (alwaysError) Write(p []byte) (n int, err error) {
 
 var alwaysErrorInstance = &alwaysError{}
 
+// busRange maps a bus address to the range of the ROM file up to the end of its bank
+func (r *ROM) busRange(busAddr uint32) (pcStart, pcEnd uint32, ok bool) {
+	mapping := r.Mapping()
+	pcStart = mapping.BusAddressToPC(busAddr)
+	if pcStart >= uint32(len(r.Contents)) {
+		return 0, 0, false
+	}
+
+	pcEnd = mapping.BusAddressToPC(busAddr | 0xFFFF)
+	if pcEnd > uint32(len(r.Contents)) {
+		pcEnd = uint32(len(r.Contents))
+	}
+	return pcStart, pcEnd, true
+}
+
 func (r *ROM) BusReader(busAddr uint32) io.Reader {
-	page := busAddr & 0xFFFF
-	if page < 0x8000 {
+	pcStart, pcEnd, ok := r.busRange(busAddr)
+	if !ok {
 		return alwaysErrorInstance
 	}
-  
-  bank := busAddr >> 16
-	pcStart := (bank << 15) | (page - 0x8000)
-	pcEnd := (bank << 15) | 0x7FFF
-
-  if r.Header.MapMode == 0x35 {
-    pcBank := ((bank + 0x40) % 0x100)
-    pcStart = (pcBank<< 16) | (page)
-    pcEnd = (pcBank << 16) | 0xFFFF  
-  }
 
 	// Return a reader over the ROM contents up to the next bank to prevent accidental overflow:
 	return bytes.NewReader(r.Contents[pcStart:pcEnd])
@@ -339,21 +341,11 @@ func (w *busWriter) Write(p []byte) (n int, err error) {
 }
 
 func (r *ROM) BusWriter(busAddr uint32) io.Writer {
-	page := busAddr & 0xFFFF
-	if page < 0x8000 {
+	pcStart, pcEnd, ok := r.busRange(busAddr)
+	if !ok {
 		return alwaysErrorInstance
 	}
-  
-  bank := busAddr >> 16
-	pcStart := (bank << 15) | (page - 0x8000)
-	pcEnd := (bank << 15) | 0x7FFF
-
-  if r.Header.MapMode == 0x35 {
-    pcBank := ((bank + 0x40) % 0x100)
-    pcStart = (pcBank<< 16) | (page)
-    pcEnd = (pcBank << 16) | 0xFFFF   
-  }
 
-	// Return a reader over the ROM contents up to the next bank to prevent accidental overflow:
+	// Return a writer over the ROM contents up to the next bank to prevent accidental overflow:
 	return &busWriter{r, busAddr, pcStart, pcEnd, 0}
 }
@@ -0,0 +1,234 @@
+package snes
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// copierHeaderSize is the size of the header some copier devices (e.g. SMC) prepend to ROM files
+const copierHeaderSize = 0x200
+
+// HeaderCandidate is a possible location of the SNES header in a ROM file along with how plausible it is
+type HeaderCandidate struct {
+	// Offset is the offset of $FFB0 in the ROM file
+	Offset  uint32
+	Mapping Mapping
+	Score   int
+}
+
+// headerCandidates lists where each memory map puts its header, in order of preference for equal scores
+var headerCandidates = []struct {
+	offset  uint32
+	mapping Mapping
+}{
+	{0x007FB0, LoROM},
+	{0x00FFB0, HiROM},
+	{0x40FFB0, ExHiROM},
+}
+
+// HasCopierHeader determines if the ROM file starts with a 512-byte copier header
+func HasCopierHeader(contents []byte) bool {
+	return len(contents)&0x3FF == copierHeaderSize
+}
+
+// ScoreHeaders scores each possible header location in the ROM file, most plausible first
+func ScoreHeaders(contents []byte) []HeaderCandidate {
+	candidates := make([]HeaderCandidate, 0, len(headerCandidates))
+	for _, c := range headerCandidates {
+		if c.offset+0x50 > uint32(len(contents)) {
+			continue
+		}
+		candidates = append(candidates, HeaderCandidate{
+			Offset:  c.offset,
+			Mapping: c.mapping,
+			Score:   scoreHeader(contents, c.offset, c.mapping),
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates
+}
+
+func scoreHeader(contents []byte, offset uint32, mapping Mapping) (score int) {
+	h := contents[offset : offset+0x50]
+	title := h[0x10:0x25]
+	mapMode := h[0x25] &^ 0x10
+	romSize := h[0x27]
+	ramSize := h[0x28]
+	destination := h[0x29]
+	complement := binary.LittleEndian.Uint16(h[0x2C:])
+	checksum := binary.LittleEndian.Uint16(h[0x2E:])
+	reset := binary.LittleEndian.Uint16(h[0x4C:])
+
+	// the reset vector must point into ROM:
+	if reset < 0x8000 {
+		return -8
+	}
+
+	// the map mode agrees with the location:
+	switch {
+	case mapping == LoROM && (mapMode == 0x20 || mapMode == 0x22 || mapMode == 0x23):
+		score += 4
+	case mapping == HiROM && (mapMode == 0x21 || mapMode == 0x2A):
+		score += 4
+	case mapping == ExHiROM && mapMode == 0x25:
+		score += 4
+	case mapping == HiROM && mapMode == 0x25:
+		// ExHiROM games keep a copy of their header in the HiROM location:
+		score += 2
+	}
+
+	if complement^checksum == 0xFFFF {
+		score += 8
+	}
+	if IsAscii(bytes.TrimRight(title, "\x00")) {
+		score += 4
+	}
+	if romSize >= 0x07 && romSize <= 0x0D {
+		score += 2
+	}
+	if ramSize <= 0x08 {
+		score++
+	}
+	if destination <= byte(RegionOther3) {
+		score++
+	}
+
+	// the first instruction of the reset routine is likely to set up the CPU:
+	pcReset := mapping.BusAddressToPC(uint32(reset))
+	if pcReset < uint32(len(contents)) {
+		switch contents[pcReset] {
+		case 0x78, 0x18, 0x38, 0x9C, 0x4C, 0x5C, 0xC2, 0xE2:
+			// SEI, CLC, SEC, STZ abs, JMP abs, JML long, REP, SEP
+			score += 4
+		case 0x00, 0xFF, 0xDB:
+			// BRK, SBC long,x, STP
+			score -= 4
+		}
+	}
+
+	return
+}
+
+// ComputeChecksum computes the checksum of the ROM contents as it should be stored in the header. ROMs whose
+// size is not a power of two are checksummed as if their remainder were mirrored up to the next power of two.
+func (r *ROM) ComputeChecksum() uint16 {
+	contents := r.Contents
+	size := uint32(len(contents))
+	if size == 0 {
+		return 0
+	}
+
+	// checksum the header as if it had the stored checksum $0000 and its complement $FFFF:
+	o := r.HeaderOffset + 0x2C
+	var stored [4]byte
+	copy(stored[:], contents[o:o+4])
+	copy(contents[o:o+4], []byte{0xFF, 0xFF, 0x00, 0x00})
+	defer copy(contents[o:o+4], stored[:])
+
+	// find the largest power of two that fits:
+	first := uint32(1)
+	for first<<1 <= size {
+		first <<= 1
+	}
+
+	sum := sumBytes(contents[:first])
+	if rest := size - first; rest > 0 {
+		// mirror the remainder until it fills the same size as the first part:
+		restSum := sumBytes(contents[first:])
+		for mirrored := uint32(0); mirrored < first; mirrored += rest {
+			sum += restSum
+		}
+	}
+
+	return uint16(sum)
+}
+
+func sumBytes(b []byte) (sum uint32) {
+	for _, c := range b {
+		sum += uint32(c)
+	}
+	return
+}
+
+// FixChecksum recomputes the checksum and its complement and writes them to the header
+func (r *ROM) FixChecksum() error {
+	checksum := r.ComputeChecksum()
+	r.Header.CheckSum = checksum
+	r.Header.ComplementCheckSum = checksum ^ 0xFFFF
+	return r.WriteHeader()
+}
+
+// ValidationReport describes how well-formed a ROM's header is
+type ValidationReport struct {
+	HeaderOffset uint32
+	Mapping      Mapping
+	// Candidates lists every header location considered, most plausible first
+	Candidates []HeaderCandidate
+
+	// CopierHeaderStripped is set if a 512-byte copier header was removed from the ROM file
+	CopierHeaderStripped bool
+
+	ExpectedChecksum uint16
+	ChecksumValid    bool
+	ComplementValid  bool
+
+	// Problems lists human-readable issues with the header; empty if the ROM is valid
+	Problems []string
+}
+
+func (v *ValidationReport) IsValid() bool { return len(v.Problems) == 0 }
+
+func (v *ValidationReport) String() string {
+	if v.IsValid() {
+		return fmt.Sprintf("header at $%06x is valid", v.HeaderOffset)
+	}
+	return fmt.Sprintf("header at $%06x: %s", v.HeaderOffset, strings.Join(v.Problems, "; "))
+}
+
+// Validate checks the header against the ROM contents
+func (r *ROM) Validate() *ValidationReport {
+	v := &ValidationReport{
+		HeaderOffset:         r.HeaderOffset,
+		Mapping:              r.Mapping(),
+		Candidates:           ScoreHeaders(r.Contents),
+		CopierHeaderStripped: r.CopierHeader != nil,
+		ExpectedChecksum:     r.ComputeChecksum(),
+	}
+
+	for _, c := range v.Candidates {
+		if c.Offset != r.HeaderOffset {
+			continue
+		}
+		if c.Score <= 0 {
+			v.Problems = append(v.Problems, fmt.Sprintf("header score %d is implausible", c.Score))
+		}
+		if c.Mapping != v.Mapping && !(c.Mapping == HiROM && v.Mapping == ExHiROM) {
+			v.Problems = append(v.Problems, fmt.Sprintf("map mode $%02x does not match header location", r.Header.MapMode))
+		}
+	}
+
+	h := &r.Header
+	v.ChecksumValid = h.CheckSum == v.ExpectedChecksum
+	v.ComplementValid = h.ComplementCheckSum^h.CheckSum == 0xFFFF
+	if !v.ChecksumValid {
+		v.Problems = append(v.Problems, fmt.Sprintf("checksum $%04x does not match computed $%04x", h.CheckSum, v.ExpectedChecksum))
+	}
+	if !v.ComplementValid {
+		v.Problems = append(v.Problems, fmt.Sprintf("checksum complement $%04x does not match checksum $%04x", h.ComplementCheckSum, h.CheckSum))
+	}
+
+	size := uint32(len(r.Contents))
+	if declared := r.ROMSize(); h.ROMSize > 0x0D || declared < size || declared >= size<<1 {
+		v.Problems = append(v.Problems, fmt.Sprintf("declared ROM size $%02x does not match file size %d", h.ROMSize, size))
+	}
+	if h.RAMSize > 0x08 {
+		v.Problems = append(v.Problems, fmt.Sprintf("declared SRAM size $%02x is too large", h.RAMSize))
+	}
+
+	return v
+}
@@ -0,0 +1,112 @@
+package snes
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// sampleHiROM makes a 128KiB HiROM image whose reset routine starts with SEI
+func sampleHiROM() []byte {
+	contents := make([]byte, 0x20000)
+	h := contents[0xFFB0:]
+	copy(h[0x10:], "HIROM TEST           ")
+	h[0x25] = 0x31 // HiROM, FastROM
+	h[0x27] = 0x07 // 128KiB
+	h[0x28] = 0x03 // 8KiB SRAM
+	h[0x29] = byte(RegionNorthAmerica)
+	binary.LittleEndian.PutUint16(h[0x4C:], 0x8000)
+	contents[0x8000] = 0x78
+	return contents
+}
+
+func TestNewROM_HiROM(t *testing.T) {
+	rom, err := NewROM("", sampleHiROM())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rom.HeaderOffset != 0xFFB0 {
+		t.Errorf("HeaderOffset = $%06x, want $00ffb0", rom.HeaderOffset)
+	}
+	if rom.Mapping() != HiROM {
+		t.Errorf("Mapping() = %T, want HiROM", rom.Mapping())
+	}
+	if got := rom.U8(0xC08000); got != 0x78 {
+		t.Errorf("U8($c08000) = $%02x, want $78", got)
+	}
+}
+
+func TestNewROM_CopierHeader(t *testing.T) {
+	contents := append(make([]byte, copierHeaderSize), sampleROM()...)
+	rom, err := NewROM("", contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rom.CopierHeader) != copierHeaderSize {
+		t.Errorf("len(CopierHeader) = %d, want %d", len(rom.CopierHeader), copierHeaderSize)
+	}
+	if len(rom.Contents) != 0x10000 {
+		t.Errorf("len(Contents) = $%x, want $10000", len(rom.Contents))
+	}
+	if rom.HeaderOffset != 0x7FB0 {
+		t.Errorf("HeaderOffset = $%06x, want $007fb0", rom.HeaderOffset)
+	}
+	if !rom.Validate().CopierHeaderStripped {
+		t.Errorf("Validate().CopierHeaderStripped = false, want true")
+	}
+}
+
+func TestROM_ComputeChecksum(t *testing.T) {
+	contents := sampleHiROM()
+	rom, err := NewROM("", contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the stored checksum and complement do not contribute to the checksum:
+	want := uint16(0)
+	for i, c := range contents {
+		if uint32(i) >= rom.HeaderOffset+0x2C && uint32(i) < rom.HeaderOffset+0x30 {
+			continue
+		}
+		want += uint16(c)
+	}
+	want += 0xFF + 0xFF
+	if got := rom.ComputeChecksum(); got != want {
+		t.Errorf("ComputeChecksum() = $%04x, want $%04x", got, want)
+	}
+
+	// a 3MiB ROM mirrors its last 1MiB:
+	rom.Contents = append(rom.Contents, make([]byte, 0x300000-len(rom.Contents))...)
+	rom.Contents[0x200000] = 0x01
+	if got := rom.ComputeChecksum(); got != want+2 {
+		t.Errorf("ComputeChecksum() = $%04x, want $%04x", got, want+2)
+	}
+}
+
+func TestROM_FixChecksum(t *testing.T) {
+	rom, err := NewROM("", sampleHiROM())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := rom.Validate()
+	if v.ChecksumValid || v.ComplementValid || v.IsValid() {
+		t.Errorf("Validate() = %s, want invalid checksum", v)
+	}
+
+	if err = rom.FixChecksum(); err != nil {
+		t.Fatal(err)
+	}
+
+	// re-read the header from the contents:
+	if err = rom.ReadHeader(); err != nil {
+		t.Fatal(err)
+	}
+	v = rom.Validate()
+	if !v.IsValid() {
+		t.Errorf("Validate() = %s, want valid", v)
+	}
+	if rom.Header.CheckSum != v.ExpectedChecksum || rom.Header.ComplementCheckSum != ^v.ExpectedChecksum {
+		t.Errorf("checksum = $%04x, complement = $%04x, want $%04x", rom.Header.CheckSum, rom.Header.ComplementCheckSum, v.ExpectedChecksum)
+	}
+}
@@ -0,0 +1,396 @@
+package udpclient
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReliableTransport carries reliable frames over an unreliable datagram transport
+type ReliableTransport interface {
+	// SendData sends a data frame to all peers; base is the oldest sequence the sender has not given up on
+	SendData(channel uint8, seq uint32, base uint32, data []byte) error
+	// SendAck acknowledges receipt of a data frame to the peer that sent it
+	SendAck(peer uint32, channel uint8, seq uint32) error
+}
+
+// ReliableDelivery is a data frame delivered in order from a peer
+type ReliableDelivery struct {
+	Peer    uint32
+	Channel uint8
+	Seq     uint32
+	Data    []byte
+}
+
+// ReliableMode selects the delivery semantics of a channel; both ends must use the same mode
+type ReliableMode uint8
+
+const (
+	// ReliableOrdered frames are acknowledged on receipt, retransmitted until acknowledged and delivered in order
+	ReliableOrdered ReliableMode = iota
+	// ReliableSequenced frames are never retransmitted and only frames newer than the last delivered one are
+	// delivered; the receiver acknowledges them with Ack once it has applied them
+	ReliableSequenced
+)
+
+const (
+	DefaultRetransmitInterval = 250 * time.Millisecond
+	DefaultMaxRetransmits     = 20
+
+	// maximum number of out-of-order frames to hold per peer channel:
+	maxReliableBuffered = 256
+)
+
+type reliablePending struct {
+	data     []byte
+	sentAt   time.Time
+	tries    int
+	awaiting map[uint32]struct{}
+}
+
+type reliableSendChannel struct {
+	next    uint32
+	pending map[uint32]*reliablePending
+}
+
+// base returns the oldest pending sequence, or seq if no older frame is pending
+func (sc *reliableSendChannel) base(seq uint32) uint32 {
+	base := seq
+	for s := range sc.pending {
+		if int32(s-base) < 0 {
+			base = s
+		}
+	}
+	return base
+}
+
+func (sc *reliableSendChannel) dropOlderThan(seq uint32) {
+	for s := range sc.pending {
+		if int32(s-seq) < 0 {
+			delete(sc.pending, s)
+		}
+	}
+}
+
+type reliableRecvKey struct {
+	peer    uint32
+	channel uint8
+}
+
+type reliableRecvChannel struct {
+	started  bool
+	next     uint32
+	buffered map[uint32][]byte
+}
+
+// Reliable adds sequence numbers, acks, retransmits and per-channel ordering on top of an
+// unreliable transport. Data frames are multicast to all known peers and are retransmitted
+// until every peer has acknowledged them or MaxRetransmits is exceeded.
+type Reliable struct {
+	RetransmitInterval time.Duration
+	MaxRetransmits     int
+
+	// OnDelivered is called when all peers have acknowledged a data frame; it must not call back into Reliable
+	OnDelivered func(channel uint8, seq uint32)
+	// OnGivenUp is called when a data frame is dropped without all peers acknowledging it; it must not call back
+	// into Reliable
+	OnGivenUp func(channel uint8, seq uint32)
+
+	transport ReliableTransport
+
+	lock  sync.Mutex
+	peers map[uint32]struct{}
+	modes map[uint8]ReliableMode
+	send  map[uint8]*reliableSendChannel
+	recv  map[reliableRecvKey]*reliableRecvChannel
+}
+
+func NewReliable(transport ReliableTransport) *Reliable {
+	return &Reliable{
+		RetransmitInterval: DefaultRetransmitInterval,
+		MaxRetransmits:     DefaultMaxRetransmits,
+		transport:          transport,
+		peers:              make(map[uint32]struct{}),
+		modes:              make(map[uint8]ReliableMode),
+		send:               make(map[uint8]*reliableSendChannel),
+		recv:               make(map[reliableRecvKey]*reliableRecvChannel),
+	}
+}
+
+// SetMode sets the delivery mode of a channel; channels default to ReliableOrdered
+func (r *Reliable) SetMode(channel uint8, mode ReliableMode) {
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	r.modes[channel] = mode
+}
+
+// Mode returns the delivery mode of a channel
+func (r *Reliable) Mode(channel uint8) ReliableMode {
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	return r.modes[channel]
+}
+
+// SetPeers replaces the set of peers that must acknowledge data frames; departed peers no
+// longer hold up pending frames and their receive state is discarded.
+func (r *Reliable) SetPeers(peers []uint32) {
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	r.peers = make(map[uint32]struct{}, len(peers))
+	for _, p := range peers {
+		r.peers[p] = struct{}{}
+	}
+
+	for key := range r.recv {
+		if _, ok := r.peers[key.peer]; !ok {
+			delete(r.recv, key)
+		}
+	}
+
+	for channel, sc := range r.send {
+		for seq, p := range sc.pending {
+			for peer := range p.awaiting {
+				if _, ok := r.peers[peer]; !ok {
+					delete(p.awaiting, peer)
+				}
+			}
+			r.checkDelivered(channel, sc, seq, p)
+		}
+	}
+}
+
+// Send assigns the next sequence number on the channel to data and transmits it
+func (r *Reliable) Send(channel uint8, data []byte) (seq uint32, err error) {
+	r.lock.Lock()
+
+	sc, ok := r.send[channel]
+	if !ok {
+		sc = &reliableSendChannel{pending: make(map[uint32]*reliablePending)}
+		r.send[channel] = sc
+	}
+
+	seq = sc.next
+	sc.next++
+
+	p := &reliablePending{
+		data:     data,
+		sentAt:   time.Now(),
+		tries:    1,
+		awaiting: make(map[uint32]struct{}, len(r.peers)),
+	}
+	for peer := range r.peers {
+		p.awaiting[peer] = struct{}{}
+	}
+	sc.pending[seq] = p
+	base := sc.base(seq)
+	r.checkDelivered(channel, sc, seq, p)
+
+	r.lock.Unlock()
+
+	err = r.transport.SendData(channel, seq, base, data)
+	return
+}
+
+// NextSequence returns the sequence number the next Send on the channel will assign
+func (r *Reliable) NextSequence(channel uint8) uint32 {
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	sc, ok := r.send[channel]
+	if !ok {
+		return 0
+	}
+	return sc.next
+}
+
+// ReceiveData acknowledges a data frame from peer and returns all frames now deliverable in order; base is the
+// oldest sequence the sender has not given up on, as sent along with the frame
+func (r *Reliable) ReceiveData(peer uint32, channel uint8, seq uint32, base uint32, data []byte) (deliveries []ReliableDelivery, err error) {
+	r.lock.Lock()
+
+	key := reliableRecvKey{peer, channel}
+	rc, ok := r.recv[key]
+	if !ok {
+		rc = &reliableRecvChannel{buffered: make(map[uint32][]byte)}
+		r.recv[key] = rc
+	}
+
+	if int32(seq-base) < 0 {
+		base = seq
+	}
+	if !rc.started {
+		// start from the sender's oldest pending frame so earlier frames lost before this one are still delivered:
+		rc.started = true
+		rc.next = base
+	}
+
+	if r.modes[channel] == ReliableSequenced {
+		// deliver only the newest frame and leave acknowledgement to the caller:
+		if int32(seq-rc.next) >= 0 {
+			deliveries = append(deliveries, ReliableDelivery{peer, channel, seq, data})
+			rc.next = seq + 1
+		}
+		r.lock.Unlock()
+		return
+	}
+
+	// hold frames until they are in order; d < 0 is a duplicate of an already delivered frame that is acked again
+	// below in case our ack was lost:
+	if d := int32(seq - rc.next); d == 0 || (d > 0 && len(rc.buffered) < maxReliableBuffered) {
+		rc.buffered[seq] = data
+	}
+
+	if int32(base-rc.next) > 0 {
+		// the sender gave up on the frames before base so they will never arrive; deliver what we hold of them:
+		var held []uint32
+		for s := range rc.buffered {
+			if int32(s-base) < 0 {
+				held = append(held, s)
+			}
+		}
+		sort.Slice(held, func(i, j int) bool { return int32(held[i]-held[j]) < 0 })
+		for _, s := range held {
+			deliveries = append(deliveries, ReliableDelivery{peer, channel, s, rc.buffered[s]})
+			delete(rc.buffered, s)
+		}
+		rc.next = base
+	}
+
+	// deliver all buffered frames that are now in order:
+	for {
+		b, ok := rc.buffered[rc.next]
+		if !ok {
+			break
+		}
+		delete(rc.buffered, rc.next)
+		deliveries = append(deliveries, ReliableDelivery{peer, channel, rc.next, b})
+		rc.next++
+	}
+
+	r.lock.Unlock()
+
+	err = r.transport.SendAck(peer, channel, seq)
+	return
+}
+
+// Ack acknowledges a frame delivered on a ReliableSequenced channel
+func (r *Reliable) Ack(peer uint32, channel uint8, seq uint32) error {
+	return r.transport.SendAck(peer, channel, seq)
+}
+
+// ReceiveAck records that peer has received the data frame
+func (r *Reliable) ReceiveAck(peer uint32, channel uint8, seq uint32) {
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	sc, ok := r.send[channel]
+	if !ok {
+		return
+	}
+	p, ok := sc.pending[seq]
+	if !ok {
+		return
+	}
+
+	delete(p.awaiting, peer)
+	r.checkDelivered(channel, sc, seq, p)
+}
+
+// Retransmit resends all frames not acknowledged within RetransmitInterval
+func (r *Reliable) Retransmit(now time.Time) (err error) {
+	type resend struct {
+		channel uint8
+		seq     uint32
+		base    uint32
+		data    []byte
+	}
+	var resends []resend
+
+	r.lock.Lock()
+	for channel, sc := range r.send {
+		sequenced := r.modes[channel] == ReliableSequenced
+		for seq, p := range sc.pending {
+			if now.Sub(p.sentAt) < r.RetransmitInterval {
+				continue
+			}
+			if sequenced {
+				// sequenced frames are superseded by newer ones rather than retransmitted:
+				if now.Sub(p.sentAt) >= r.RetransmitInterval*time.Duration(r.MaxRetransmits) {
+					delete(sc.pending, seq)
+					r.givenUp(channel, seq)
+				}
+				continue
+			}
+			if p.tries > r.MaxRetransmits {
+				log.Printf("reliable: channel %d: seq %d: giving up after %d tries\n", channel, seq, p.tries)
+				delete(sc.pending, seq)
+				r.givenUp(channel, seq)
+				continue
+			}
+
+			p.sentAt = now
+			p.tries++
+			resends = append(resends, resend{channel: channel, seq: seq, data: p.data})
+		}
+	}
+	// stamp bases only once frames given up on above are gone:
+	for i := range resends {
+		resends[i].base = r.send[resends[i].channel].base(resends[i].seq)
+	}
+	r.lock.Unlock()
+
+	for _, s := range resends {
+		if err = r.transport.SendData(s.channel, s.seq, s.base, s.data); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Pending returns the number of frames on the channel awaiting acknowledgement
+func (r *Reliable) Pending(channel uint8) int {
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	sc, ok := r.send[channel]
+	if !ok {
+		return 0
+	}
+	return len(sc.pending)
+}
+
+// Reset discards all send and receive state, e.g. after reconnecting
+func (r *Reliable) Reset() {
+	defer r.lock.Unlock()
+	r.lock.Lock()
+
+	r.send = make(map[uint8]*reliableSendChannel)
+	r.recv = make(map[reliableRecvKey]*reliableRecvChannel)
+}
+
+// must be called with lock held
+func (r *Reliable) checkDelivered(channel uint8, sc *reliableSendChannel, seq uint32, p *reliablePending) {
+	if len(p.awaiting) > 0 {
+		return
+	}
+
+	delete(sc.pending, seq)
+	if r.modes[channel] == ReliableSequenced {
+		// older frames can no longer be of use to anyone:
+		sc.dropOlderThan(seq)
+	}
+	if r.OnDelivered != nil {
+		r.OnDelivered(channel, seq)
+	}
+}
+
+// must be called with lock held
+func (r *Reliable) givenUp(channel uint8, seq uint32) {
+	if r.OnGivenUp != nil {
+		r.OnGivenUp(channel, seq)
+	}
+}
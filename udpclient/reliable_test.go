@@ -0,0 +1,280 @@
+package udpclient
+
+import (
+	"testing"
+	"time"
+)
+
+type testFrame struct {
+	peer    uint32
+	channel uint8
+	seq     uint32
+	base    uint32
+	data    []byte
+}
+
+type testTransport struct {
+	data []testFrame
+	acks []testFrame
+}
+
+func (t *testTransport) SendData(channel uint8, seq uint32, base uint32, data []byte) error {
+	t.data = append(t.data, testFrame{channel: channel, seq: seq, base: base, data: data})
+	return nil
+}
+
+func (t *testTransport) SendAck(peer uint32, channel uint8, seq uint32) error {
+	t.acks = append(t.acks, testFrame{peer: peer, channel: channel, seq: seq})
+	return nil
+}
+
+func TestReliable_Ordering(t *testing.T) {
+	tr := &testTransport{}
+	r := NewReliable(tr)
+	r.SetPeers([]uint32{1})
+
+	var delivered []uint32
+	receive := func(seq uint32) {
+		d, err := r.ReceiveData(1, 0, seq, 10, []byte{byte(seq)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, x := range d {
+			delivered = append(delivered, x.Seq)
+		}
+	}
+
+	// the sender's base starts the sequence:
+	receive(10)
+	// out of order frames are held:
+	receive(12)
+	receive(13)
+	if actual, expected := len(delivered), 1; actual != expected {
+		t.Fatalf("delivered = %v, expected %d frames", delivered, expected)
+	}
+	// gap filled releases held frames in order:
+	receive(11)
+	// duplicates are dropped:
+	receive(12)
+
+	expected := []uint32{10, 11, 12, 13}
+	if len(delivered) != len(expected) {
+		t.Fatalf("delivered = %v, expected %v", delivered, expected)
+	}
+	for i := range expected {
+		if delivered[i] != expected[i] {
+			t.Fatalf("delivered = %v, expected %v", delivered, expected)
+		}
+	}
+
+	// every frame is acked, including duplicates:
+	if actual, expected := len(tr.acks), 5; actual != expected {
+		t.Errorf("acks = %d, expected %d", actual, expected)
+	}
+}
+
+func TestReliable_FirstFrameLost(t *testing.T) {
+	tr := &testTransport{}
+	r := NewReliable(tr)
+	r.SetPeers([]uint32{1})
+
+	var delivered []uint32
+	receive := func(seq, base uint32) {
+		d, err := r.ReceiveData(1, 0, seq, base, []byte{byte(seq)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, x := range d {
+			delivered = append(delivered, x.Seq)
+		}
+	}
+
+	// frame 0 is lost so frame 1 arrives first; its base says frame 0 is still coming:
+	receive(1, 0)
+	if len(delivered) != 0 {
+		t.Fatalf("delivered = %v, expected nothing before frame 0", delivered)
+	}
+	// the retransmitted frame 0 is delivered rather than taken for a duplicate:
+	receive(0, 0)
+	receive(2, 0)
+
+	expected := []uint32{0, 1, 2}
+	if len(delivered) != len(expected) {
+		t.Fatalf("delivered = %v, expected %v", delivered, expected)
+	}
+	for i := range expected {
+		if delivered[i] != expected[i] {
+			t.Fatalf("delivered = %v, expected %v", delivered, expected)
+		}
+	}
+
+	// the sender gave up on frame 3; frames held after it are delivered once the base moves past it:
+	receive(5, 3)
+	receive(4, 3)
+	receive(6, 5)
+	expected = []uint32{0, 1, 2, 4, 5, 6}
+	if len(delivered) != len(expected) {
+		t.Fatalf("delivered = %v, expected %v", delivered, expected)
+	}
+	for i := range expected {
+		if delivered[i] != expected[i] {
+			t.Fatalf("delivered = %v, expected %v", delivered, expected)
+		}
+	}
+}
+
+func TestReliable_Base(t *testing.T) {
+	tr := &testTransport{}
+	r := NewReliable(tr)
+	r.SetPeers([]uint32{1})
+
+	s0, _ := r.Send(0, []byte{0})
+	s1, _ := r.Send(0, []byte{1})
+	if actual, expected := tr.data[1].base, s0; actual != expected {
+		t.Errorf("base = %d, expected %d while %d is pending", actual, expected, s0)
+	}
+
+	r.ReceiveAck(1, 0, s0)
+	_ = r.Retransmit(time.Now().Add(r.RetransmitInterval))
+	if actual, expected := tr.data[2].base, s1; actual != expected {
+		t.Errorf("retransmitted base = %d, expected %d once %d was acked", actual, expected, s0)
+	}
+}
+
+func TestReliable_Retransmit(t *testing.T) {
+	tr := &testTransport{}
+	r := NewReliable(tr)
+	r.SetPeers([]uint32{1, 2})
+
+	var deliveredSeq []uint32
+	r.OnDelivered = func(channel uint8, seq uint32) {
+		deliveredSeq = append(deliveredSeq, seq)
+	}
+
+	seq, err := r.Send(3, []byte("item"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := len(tr.data), 1; actual != expected {
+		t.Fatalf("sent = %d, expected %d", actual, expected)
+	}
+
+	// peer 1 acks; peer 2 does not:
+	r.ReceiveAck(1, 3, seq)
+	if actual, expected := r.Pending(3), 1; actual != expected {
+		t.Fatalf("pending = %d, expected %d", actual, expected)
+	}
+
+	// not yet due:
+	_ = r.Retransmit(time.Now())
+	if actual, expected := len(tr.data), 1; actual != expected {
+		t.Fatalf("sent = %d, expected %d", actual, expected)
+	}
+
+	_ = r.Retransmit(time.Now().Add(r.RetransmitInterval))
+	if actual, expected := len(tr.data), 2; actual != expected {
+		t.Fatalf("sent = %d, expected %d", actual, expected)
+	}
+
+	r.ReceiveAck(2, 3, seq)
+	if actual, expected := r.Pending(3), 0; actual != expected {
+		t.Fatalf("pending = %d, expected %d", actual, expected)
+	}
+	if len(deliveredSeq) != 1 || deliveredSeq[0] != seq {
+		t.Errorf("OnDelivered = %v, expected [%d]", deliveredSeq, seq)
+	}
+}
+
+func TestReliable_PeerLeft(t *testing.T) {
+	tr := &testTransport{}
+	r := NewReliable(tr)
+	r.SetPeers([]uint32{1, 2})
+
+	seq, _ := r.Send(0, []byte{1})
+	r.ReceiveAck(1, 0, seq)
+
+	// peer 2 leaves; nobody else is waiting on the frame:
+	r.SetPeers([]uint32{1})
+	if actual, expected := r.Pending(0), 0; actual != expected {
+		t.Errorf("pending = %d, expected %d", actual, expected)
+	}
+}
+
+func TestReliable_GiveUp(t *testing.T) {
+	tr := &testTransport{}
+	r := NewReliable(tr)
+	r.MaxRetransmits = 2
+	r.SetPeers([]uint32{1})
+
+	var givenUp []uint32
+	r.OnGivenUp = func(channel uint8, seq uint32) {
+		givenUp = append(givenUp, seq)
+	}
+
+	_, _ = r.Send(0, []byte{1})
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		now = now.Add(r.RetransmitInterval)
+		_ = r.Retransmit(now)
+	}
+
+	if actual, expected := len(tr.data), 3; actual != expected {
+		t.Errorf("sent = %d, expected %d", actual, expected)
+	}
+	if actual, expected := r.Pending(0), 0; actual != expected {
+		t.Errorf("pending = %d, expected %d", actual, expected)
+	}
+	if actual, expected := len(givenUp), 1; actual != expected {
+		t.Errorf("OnGivenUp called %d times, expected %d", actual, expected)
+	}
+}
+
+func TestReliable_Sequenced(t *testing.T) {
+	tr := &testTransport{}
+	r := NewReliable(tr)
+	r.SetMode(1, ReliableSequenced)
+	r.SetPeers([]uint32{1})
+
+	var delivered []uint32
+	receive := func(seq uint32) {
+		d, err := r.ReceiveData(1, 1, seq, seq, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, x := range d {
+			delivered = append(delivered, x.Seq)
+		}
+	}
+
+	receive(4)
+	receive(6)
+	// older frame arriving late is dropped:
+	receive(5)
+	receive(7)
+
+	expected := []uint32{4, 6, 7}
+	if len(delivered) != len(expected) {
+		t.Fatalf("delivered = %v, expected %v", delivered, expected)
+	}
+	for i := range expected {
+		if delivered[i] != expected[i] {
+			t.Fatalf("delivered = %v, expected %v", delivered, expected)
+		}
+	}
+	// acknowledgement is left to the caller:
+	if actual, expected := len(tr.acks), 0; actual != expected {
+		t.Errorf("acks = %d, expected %d", actual, expected)
+	}
+
+	// sender side: acking a newer frame supersedes older pending frames and nothing is retransmitted:
+	s0, _ := r.Send(1, nil)
+	s1, _ := r.Send(1, nil)
+	_ = r.Retransmit(time.Now().Add(r.RetransmitInterval))
+	if actual, expected := len(tr.data), 2; actual != expected {
+		t.Errorf("sent = %d, expected %d", actual, expected)
+	}
+	r.ReceiveAck(1, 1, s1)
+	if actual, expected := r.Pending(1), 0; actual != expected {
+		t.Errorf("pending = %d, expected %d after ack of %d superseded %d", actual, expected, s1, s0)
+	}
+}
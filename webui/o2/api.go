@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"o2/interfaces"
+	"strings"
+)
+
+// ErrViewNotFound is returned when no view model exists by the requested name
+var ErrViewNotFound = errors.New("view not found")
+
+// EventStream is a Server-Sent Events subscriber to NotifyView updates
+type EventStream struct {
+	q chan ViewModelUpdate
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// viewCollector captures view models sent via NotifyViewTo:
+type viewCollector map[string]interface{}
+
+func (c viewCollector) NotifyView(view string, viewModel interface{}) {
+	c[view] = viewModel
+}
+
+// handles the REST API; see API.md for documentation:
+func (s *WebServer) handleAPI(w http.ResponseWriter, r *http.Request) {
+	if s.commandHandler == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, fmt.Errorf("no view command handler provided"))
+		return
+	}
+
+	// split /api/views/{view}/commands/{command} into parts:
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/"), "/"), "/")
+	if len(parts) == 0 || parts[0] != "views" {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("unknown api path '%s'", r.URL.Path))
+		return
+	}
+
+	switch len(parts) {
+	case 1:
+		// /api/views
+		if r.Method != http.MethodGet {
+			writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		views := viewCollector{}
+		s.commandHandler.NotifyViewTo(views)
+		writeAPIJSON(w, http.StatusOK, views)
+	case 2:
+		// /api/views/{view}
+		if r.Method != http.MethodGet {
+			writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		views := viewCollector{}
+		s.commandHandler.NotifyViewTo(views)
+		viewModel, ok := views[parts[1]]
+		if !ok {
+			writeAPIError(w, http.StatusNotFound, fmt.Errorf("view=%s: %w", parts[1], ErrViewNotFound))
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, viewModel)
+	case 4:
+		// /api/views/{view}/commands/{command}
+		if parts[2] != "commands" {
+			writeAPIError(w, http.StatusNotFound, fmt.Errorf("unknown api path '%s'", r.URL.Path))
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		s.handleAPICommand(w, r, parts[1], parts[3])
+	default:
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("unknown api path '%s'", r.URL.Path))
+	}
+}
+
+func (s *WebServer) handleAPICommand(w http.ResponseWriter, r *http.Request, view, command string) {
+	// any website may make the browser send a simple cross-origin POST, e.g. a text/plain form, without asking
+	// first; so refuse other origins and accept only content types that browsers must preflight:
+	if origin := r.Header.Get("Origin"); origin != "" && !isSameOrigin(origin, r.Host) {
+		writeAPIError(w, http.StatusForbidden, fmt.Errorf("cross-origin request from '%s' refused", origin))
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" && mediaType != "application/octet-stream" {
+		writeAPIError(w, http.StatusUnsupportedMediaType, fmt.Errorf("Content-Type must be application/json or application/octet-stream"))
+		return
+	}
+
+	ce, err := s.commandHandler.CommandFor(view, command)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, err)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("error reading request body: %w", err))
+		return
+	}
+
+	var args interface{}
+	if mediaType == "application/octet-stream" {
+		// same as binary websocket frames; body is passed directly as []byte arg:
+		args = body
+	} else {
+		// same as JSON websocket command requests:
+		args = ce.CreateArgs()
+		if args != nil && len(body) > 0 {
+			if err = json.Unmarshal(body, args); err != nil {
+				writeAPIError(w, http.StatusBadRequest, fmt.Errorf("error deserializing json command args: %w", err))
+				return
+			}
+		}
+	}
+
+	if err = executeAPICommand(ce, args); err != nil {
+		status := http.StatusUnprocessableEntity
+		if errors.Is(err, errInvalidArgs) {
+			status = http.StatusBadRequest
+		}
+		writeAPIError(w, status, fmt.Errorf("view=%s,cmd=%s: %w", view, command, err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var errInvalidArgs = errors.New("invalid args")
+
+// executeAPICommand executes the command, turning a panic into an error before any response is written; commands
+// assume the args type matching their frame type, e.g. binary commands panic on JSON args:
+func executeAPICommand(ce interfaces.Command, args interface{}) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%w: %v", errInvalidArgs, p)
+		}
+	}()
+
+	return ce.Execute(args)
+}
+
+// isSameOrigin reports whether the Origin header names the host the request was sent to:
+func isSameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, host)
+}
+
+// streams all NotifyView updates as Server-Sent Events, starting with the current state of all views:
+func (s *WebServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+	if s.commandHandler == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, fmt.Errorf("no view command handler provided"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	log.Printf("Accepted event stream from %s\n", r.RemoteAddr)
+	defer log.Printf("Closing event stream to %s\n", r.RemoteAddr)
+
+	es := &EventStream{q: make(chan ViewModelUpdate, 10)}
+	s.appendEventStream(es)
+	defer s.removeEventStream(es)
+
+	// start by sending all view models:
+	views := viewCollector{}
+	s.commandHandler.NotifyViewTo(views)
+	for view, viewModel := range views {
+		if err := writeEvent(w, ViewModelUpdate{View: view, ViewModel: viewModel}); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case u := <-es.q:
+			if err := writeEvent(w, u); err != nil {
+				log.Println(err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// NotifyView queues the update for the stream; updates are dropped if the client can't keep up
+func (es *EventStream) NotifyView(view string, viewModel interface{}) {
+	select {
+	case es.q <- ViewModelUpdate{View: view, ViewModel: viewModel}:
+	default:
+	}
+}
+
+func (s *WebServer) appendEventStream(es *EventStream) {
+	s.socketsRw.Lock()
+	defer s.socketsRw.Unlock()
+	s.streams = append(s.streams, es)
+}
+
+func (s *WebServer) removeEventStream(es *EventStream) {
+	s.socketsRw.Lock()
+	defer s.socketsRw.Unlock()
+
+	for i, e := range s.streams {
+		if e == es {
+			s.streams = append(s.streams[:i], s.streams[i+1:]...)
+			break
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, u ViewModelUpdate) (err error) {
+	var b []byte
+	if b, err = json.Marshal(u.ViewModel); err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", u.View, b)
+	return
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println(err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	log.Println(fmt.Errorf("api: %w", err))
+	writeAPIJSON(w, status, apiError{Error: err.Error()})
+}
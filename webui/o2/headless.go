@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"o2/engine"
+	"o2/interfaces"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// how often headless mode checks and restores its SNES and server connections:
+const headlessSuperviseInterval = time.Second * 2
+
+// HeadlessConfig is the JSON config file format for headless mode; flags override its values
+type HeadlessConfig struct {
+	Driver     string `json:"driver"`     // SNES driver name, e.g. "fxpakpro"
+	Device     string `json:"device"`     // SNES device id; empty selects the first detected device
+	ROM        string `json:"rom"`        // path to the ROM file to load
+	Boot       bool   `json:"boot"`       // upload and boot the patched ROM once the SNES is connected
+	HostName   string `json:"hostName"`   // O2 server host[:port]
+	GroupName  string `json:"groupName"`  // group to join
+	PlayerName string `json:"playerName"` // player name to show to others
+	Team       uint8  `json:"team"`       // team number to sync with
+	Password   string `json:"password"`   // optional group password
+	// record each session's network traffic to ~/.o2/recordings for replay with alttpo-replay:
+	RecordSessions bool `json:"recordSessions"`
+}
+
+var (
+	headless       bool
+	headlessConfig string
+	headlessFlags  HeadlessConfig
+	headlessTeam   uint
+)
+
+func init() {
+	flag.BoolVar(&headless, "headless", false, "run without systray or browser, configured by flags or -config")
+	flag.StringVar(&headlessConfig, "config", "", "headless: path to JSON config file")
+	flag.StringVar(&headlessFlags.Driver, "driver", "", "headless: SNES driver name")
+	flag.StringVar(&headlessFlags.Device, "device", "", "headless: SNES device id (default first detected)")
+	flag.StringVar(&headlessFlags.ROM, "rom", "", "headless: path to ROM file")
+	flag.BoolVar(&headlessFlags.Boot, "boot", false, "headless: upload and boot the patched ROM on the SNES")
+	flag.StringVar(&headlessFlags.HostName, "server", "", "headless: O2 server host[:port]")
+	flag.StringVar(&headlessFlags.GroupName, "group", "", "headless: group name")
+	flag.StringVar(&headlessFlags.PlayerName, "player", "", "headless: player name")
+	flag.UintVar(&headlessTeam, "team", 0, "headless: team number")
+	flag.StringVar(&headlessFlags.Password, "password", "", "headless: group password")
+	flag.BoolVar(&headlessFlags.RecordSessions, "record", false, "headless: record sessions to ~/.o2/recordings")
+}
+
+// loadHeadlessConfig reads the -config file, if any, and overrides its values with explicitly set flags
+func loadHeadlessConfig() (cfg HeadlessConfig, err error) {
+	if headlessConfig != "" {
+		var b []byte
+		b, err = ioutil.ReadFile(headlessConfig)
+		if err != nil {
+			return
+		}
+		if err = json.Unmarshal(b, &cfg); err != nil {
+			err = fmt.Errorf("headless: could not json unmarshal config file '%s': %w", headlessConfig, err)
+			return
+		}
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "driver":
+			cfg.Driver = headlessFlags.Driver
+		case "device":
+			cfg.Device = headlessFlags.Device
+		case "rom":
+			cfg.ROM = headlessFlags.ROM
+		case "boot":
+			cfg.Boot = headlessFlags.Boot
+		case "server":
+			cfg.HostName = headlessFlags.HostName
+		case "group":
+			cfg.GroupName = headlessFlags.GroupName
+		case "player":
+			cfg.PlayerName = headlessFlags.PlayerName
+		case "team":
+			cfg.Team = uint8(headlessTeam)
+		case "password":
+			cfg.Password = headlessFlags.Password
+		case "record":
+			cfg.RecordSessions = headlessFlags.RecordSessions
+		}
+	})
+
+	if cfg.Driver == "" {
+		err = fmt.Errorf("headless: SNES driver is required")
+		return
+	}
+	if cfg.ROM == "" {
+		err = fmt.Errorf("headless: ROM path is required")
+		return
+	}
+	return
+}
+
+// statusLogger logs status and connection transitions to stdout and passes all updates along to next:
+type statusLogger struct {
+	next interfaces.ViewNotifier
+	out  *log.Logger
+
+	status          string
+	snesConnected   bool
+	serverConnected bool
+	romLoaded       bool
+}
+
+func newStatusLogger(next interfaces.ViewNotifier) *statusLogger {
+	return &statusLogger{
+		next: next,
+		out:  log.New(os.Stdout, "", log.LstdFlags|log.LUTC),
+	}
+}
+
+func (l *statusLogger) NotifyView(view string, viewModel interface{}) {
+	switch vm := viewModel.(type) {
+	case string:
+		if view == "status" && vm != l.status {
+			l.status = vm
+			l.out.Printf("status: %s\n", vm)
+		}
+	case *engine.SNESViewModel:
+		if vm.IsConnected != l.snesConnected {
+			l.snesConnected = vm.IsConnected
+			l.out.Printf("snes: connected=%v\n", vm.IsConnected)
+		}
+	case *engine.ServerViewModel:
+		if vm.IsConnected != l.serverConnected {
+			l.serverConnected = vm.IsConnected
+			l.out.Printf("server: connected=%v host='%s' group='%s'\n", vm.IsConnected, vm.HostName, vm.GroupName)
+		}
+	case *engine.ROMViewModel:
+		if vm.IsLoaded != l.romLoaded {
+			l.romLoaded = vm.IsLoaded
+			l.out.Printf("rom: loaded=%v title='%s' region=%s version=%s\n", vm.IsLoaded, vm.Title, vm.Region, vm.Version)
+		}
+	}
+
+	if l.next != nil {
+		l.next.NotifyView(view, viewModel)
+	}
+}
+
+// runHeadless drives the view model through the same commands the web UI uses and keeps the SNES and server
+// connections alive; it never returns
+func runHeadless(viewModel *engine.ViewModel, cfg HeadlessConfig) {
+	// load the ROM:
+	contents, err := ioutil.ReadFile(cfg.ROM)
+	if err != nil {
+		log.Fatalf("headless: %v\n", err)
+	}
+	_, name := filepath.Split(cfg.ROM)
+	if err = execute(viewModel, "rom", "name", &engine.ROMNameCommandArgs{Name: name}); err != nil {
+		log.Fatalf("headless: %v\n", err)
+	}
+	if err = execute(viewModel, "rom", "data", contents); err != nil {
+		log.Fatalf("headless: %v\n", err)
+	}
+
+	// configure the server:
+	team := cfg.Team
+	if err = execute(viewModel, "server", "setField", &struct {
+		HostName   *string `json:"hostName"`
+		GroupName  *string `json:"groupName"`
+		Team       *uint8  `json:"team"`
+		PlayerName *string `json:"playerName"`
+		Password   *string `json:"password"`
+
+		RecordSessions *bool `json:"recordSessions"`
+	}{
+		HostName:       nonEmpty(cfg.HostName),
+		GroupName:      nonEmpty(cfg.GroupName),
+		Team:           &team,
+		PlayerName:     nonEmpty(cfg.PlayerName),
+		Password:       &cfg.Password,
+		RecordSessions: &cfg.RecordSessions,
+	}); err != nil {
+		log.Fatalf("headless: %v\n", err)
+	}
+
+	booted := !cfg.Boot
+	for {
+		if !isSNESConnected(viewModel) {
+			connectSNES(viewModel, cfg)
+		}
+
+		if isSNESConnected(viewModel) && !booted {
+			if err = execute(viewModel, "rom", "boot", nil); err != nil {
+				log.Printf("headless: %v\n", err)
+			} else {
+				booted = true
+			}
+		}
+
+		if svm, ok := getViewModel(viewModel, "server").(*engine.ServerViewModel); ok && !svm.IsConnected {
+			if err = execute(viewModel, "server", "connect", nil); err != nil {
+				log.Printf("headless: %v\n", err)
+			}
+		}
+
+		time.Sleep(headlessSuperviseInterval)
+	}
+}
+
+// connectSNES connects to the configured device if it is currently detected
+func connectSNES(viewModel *engine.ViewModel, cfg HeadlessConfig) {
+	svm, ok := getViewModel(viewModel, "snes").(*engine.SNESViewModel)
+	if !ok {
+		return
+	}
+
+	for _, dvm := range svm.Drivers {
+		if dvm.Name != cfg.Driver {
+			continue
+		}
+
+		for _, device := range dvm.Devices {
+			if cfg.Device != "" && device.GetId() != cfg.Device {
+				continue
+			}
+
+			b, err := json.Marshal(device)
+			if err != nil {
+				log.Printf("headless: %v\n", err)
+				return
+			}
+			err = execute(viewModel, "snes", "connect", &engine.ConnectCommandArgs{
+				Driver: dvm.Name,
+				Device: b,
+			})
+			if err != nil {
+				log.Printf("headless: %v\n", err)
+			}
+			return
+		}
+
+		log.Printf("headless: waiting for SNES device '%s' on driver '%s'\n", cfg.Device, cfg.Driver)
+		return
+	}
+
+	log.Fatalf("headless: SNES driver '%s' not found\n", cfg.Driver)
+}
+
+func isSNESConnected(viewModel *engine.ViewModel) bool {
+	svm, ok := getViewModel(viewModel, "snes").(*engine.SNESViewModel)
+	return ok && svm.IsConnected
+}
+
+func getViewModel(viewModel *engine.ViewModel, view string) interface{} {
+	vm, _ := viewModel.GetViewModel(view)
+	return vm
+}
+
+// execute runs a view command with already-typed args, marshaling through JSON for commands that declare
+// their own args type
+func execute(viewModel *engine.ViewModel, view, command string, args interface{}) (err error) {
+	var ce interfaces.Command
+	ce, err = viewModel.CommandFor(view, command)
+	if err != nil {
+		return
+	}
+
+	if _, isBinary := args.([]byte); !isBinary && args != nil {
+		target := ce.CreateArgs()
+		var b []byte
+		if b, err = json.Marshal(args); err != nil {
+			return
+		}
+		if err = json.Unmarshal(b, target); err != nil {
+			return
+		}
+		args = target
+	}
+
+	return ce.Execute(args)
+}
+
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
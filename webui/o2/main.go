@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/skratchdot/open-golang/open"
 	"io"
@@ -27,7 +28,8 @@ import (
 import (
 	_ "o2/games"
 	_ "o2/games/alttp"
-  _ "o2/games/smz3"
+	_ "o2/games/sm"
+	_ "o2/games/smz3"
 )
 
 // build variables set via ldflags by goreleaser:
@@ -74,6 +76,16 @@ func init() {
 func main() {
 	var err error
 
+	flag.Parse()
+
+	var headlessCfg HeadlessConfig
+	if headless {
+		headlessCfg, err = loadHeadlessConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// Parse env vars:
 	listenHost = env.GetOrDefault("O2_WEB_LISTEN_HOST", "0.0.0.0")
 
@@ -94,7 +106,11 @@ func main() {
 	webServer := NewWebServer(listenAddr)
 
 	// inform viewModel of web server and vice versa:
-	viewModel.ProvideViewNotifier(webServer)
+	if headless {
+		viewModel.ProvideViewNotifier(newStatusLogger(webServer))
+	} else {
+		viewModel.ProvideViewNotifier(webServer)
+	}
 	webServer.ProvideViewCommandHandler(viewModel)
 
 	// start the web server:
@@ -105,6 +121,11 @@ func main() {
 	// initialize viewModel now that all dependencies are set up:
 	viewModel.Init()
 
+	if headless {
+		runHeadless(viewModel, headlessCfg)
+		return
+	}
+
 	// start up a systray app (or just open web UI):
 	createSystray()
 }
@@ -29,6 +29,7 @@ type WebServer struct {
 
 	socketsRw sync.RWMutex
 	sockets   []*Socket
+	streams   []*EventStream
 
 	// broadcast channel to all sockets:
 	q chan ViewModelUpdate
@@ -55,6 +56,7 @@ func NewWebServer(listenAddr string) *WebServer {
 		mux:        http.NewServeMux(),
 		socketsRw:  sync.RWMutex{},
 		sockets:    make([]*Socket, 0, 2),
+		streams:    make([]*EventStream, 0, 2),
 		q:          make(chan ViewModelUpdate, 10),
 	}
 
@@ -76,6 +78,10 @@ func NewWebServer(listenAddr string) *WebServer {
 		s.commandHandler.NotifyViewTo(socket)
 	}))
 
+	// REST API and Server-Sent Events stream of view model updates:
+	s.mux.Handle("/api/", http.HandlerFunc(s.handleAPI))
+	s.mux.Handle("/events", http.HandlerFunc(s.handleEvents))
+
 	// download the patched ROM:
 	s.mux.Handle("/rom/patched.smc", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cmd, err := s.commandHandler.CommandFor("rom", "patched")
@@ -103,6 +109,32 @@ func NewWebServer(listenAddr string) *WebServer {
 		http.ServeContent(w, r, romName, time.Now(), bytes.NewReader(rom.Contents))
 	}))
 
+	// download the o2 patch as BPS to apply to the unpatched ROM:
+	s.mux.Handle("/rom/o2.bps", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cmd, err := s.commandHandler.CommandFor("rom", "patchBPS")
+		if err != nil {
+			log.Println(err)
+			http.NotFound(w, r)
+			return
+		}
+
+		var bps []byte
+		err = cmd.Execute(&bps)
+		if err != nil {
+			log.Println(err)
+			http.NotFound(w, r)
+			return
+		}
+		if bps == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Disposition", "attachment; filename=\"o2.bps\"")
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeContent(w, r, "o2.bps", time.Now(), bytes.NewReader(bps))
+	}))
+
 	// access log file:
 	s.mux.Handle("/log.txt", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, logFileName := filepath.Split(logPath)
@@ -184,12 +216,17 @@ func (s *WebServer) handleBroadcast() {
 	for u := range s.q {
 		s.socketsRw.RLock()
 		sockets := s.sockets
+		streams := s.streams
 		s.socketsRw.RUnlock()
 
 		// broadcast to all connected sockets:
 		for _, k := range sockets {
 			k.q <- u
 		}
+		// and event streams:
+		for _, es := range streams {
+			es.NotifyView(u.View, u.ViewModel)
+		}
 	}
 }
 
@@ -1,7 +1,7 @@
 import {GameALTTPViewModel, GameViewProps} from "../viewmodel";
 import {useEffect, useRef, useState} from "preact/hooks";
 import {Fragment} from "preact";
-import {setField} from "../util";
+import {groupByTeam, setField} from "../util";
 
 export function GameViewALTTP({ch, vm}: GameViewProps) {
     const game = vm.game as GameALTTPViewModel;
@@ -20,6 +20,7 @@ export function GameViewALTTP({ch, vm}: GameViewProps) {
     const [syncOverworld, setsyncOverworld] = useState(true);
     const [syncChests, setsyncChests] = useState(true);
     const [syncTunicColor, setsyncTunicColor] = useState(true);
+    const [syncSRAMDelta, setsyncSRAMDelta] = useState(false);
 
     const [notifHistory, setNotifHistory] = useState([] as string[]);
     const historyTextarea = useRef(null);
@@ -45,6 +46,7 @@ export function GameViewALTTP({ch, vm}: GameViewProps) {
         setsyncOverworld(game.syncOverworld);
         setsyncChests(game.syncChests);
         setsyncTunicColor(game.syncTunicColor);
+        setsyncSRAMDelta(game.syncSRAMDelta);
     }, [game]);
 
     useEffect(() => {
@@ -210,6 +212,15 @@ export function GameViewALTTP({ch, vm}: GameViewProps) {
                            onChange={setField.bind(this, sendGameCommand, setsyncChests, "syncChests", getTargetChecked)}
                     />Sync Chests
                 </label>
+
+                <label for="syncSRAMDelta"
+                       title="Send only changed SRAM to save bandwidth; all players need a recent version">
+                    <input type="checkbox"
+                           id="syncSRAMDelta"
+                           checked={syncSRAMDelta}
+                           onChange={setField.bind(this, sendGameCommand, setsyncSRAMDelta, "syncSRAMDelta", getTargetChecked)}
+                    />Delta SRAM
+                </label>
             </div>
         </div>
         <h5 style="grid-row: 1; grid-column: 2">Players</h5>
@@ -220,15 +231,20 @@ export function GameViewALTTP({ch, vm}: GameViewProps) {
             <div style="font-weight: bold">name</div>
             <div style="font-weight: bold">location</div>
             {
-                (vm["game/players"] || []).map((p: any) => (<Fragment key={p.index.toString()}>
-                    <div class="mono" title="Player index">{("0" + p.index.toString(16)).substr(-2)}</div>
-                    <div class="mono" title="Team number">{p.team}</div>
-                    <div style="color: yellow; white-space: nowrap" title="Player name">{p.name}</div>
-                    <div
-                        style={"color: " + (((p.location & 0x10000) != 0) ? "green" : "cyan") + "; white-space: nowrap"}
-                        title="Location">{
-                        ((p.location & 0x10000) != 0) ? p.underworld : p.overworld
-                    }</div>
+                groupByTeam(vm["game/players"] || []).map(([team, players]) => (<Fragment key={"team" + team.toString()}>
+                    <div style={"grid-column: 1 / span 4; font-weight: bold; color: " + (team == vm.server?.team ? "yellow" : "gray")}
+                         title={team == vm.server?.team ? "Your team; items and progress sync only within your team" : "Another team"}
+                    >Team {team}</div>
+                    {players.map((p: any) => (<Fragment key={p.index.toString()}>
+                        <div class="mono" title="Player index">{("0" + p.index.toString(16)).substr(-2)}</div>
+                        <div class="mono" title="Team number">{p.team}</div>
+                        <div style="color: yellow; white-space: nowrap" title="Player name">{p.name}</div>
+                        <div
+                            style={"color: " + (((p.location & 0x10000) != 0) ? "green" : "cyan") + "; white-space: nowrap"}
+                            title="Location">{
+                            ((p.location & 0x10000) != 0) ? p.underworld : p.overworld
+                        }</div>
+                    </Fragment>))}
                 </Fragment>))
             }
         </div>
@@ -1,7 +1,7 @@
 import {GameSMZ3ViewModel, GameViewProps} from "../viewmodel";
 import {useEffect, useRef, useState} from "preact/hooks";
 import {Fragment} from "preact";
-import {setField} from "../util";
+import {groupByTeam, setField} from "../util";
 
 export function GameViewSMZ3({ch, vm}: GameViewProps) {
     const game = vm.game as GameSMZ3ViewModel;
@@ -220,15 +220,20 @@ export function GameViewSMZ3({ch, vm}: GameViewProps) {
             <div style="font-weight: bold">name</div>
             <div style="font-weight: bold">location</div>
             {
-                (vm["game/players"] || []).map((p: any) => (<Fragment key={p.index.toString()}>
-                    <div class="mono" title="Player index">{("0" + p.index.toString(16)).substr(-2)}</div>
-                    <div class="mono" title="Team number">{p.team}</div>
-                    <div style="color: yellow; white-space: nowrap" title="Player name">{p.name}</div>
-                    <div
-                        style={"color: " + (((p.location & 0x10000) != 0) ? "green" : "cyan") + "; white-space: nowrap"}
-                        title="Location">{
-                        ((p.location & 0x10000) != 0) ? p.underworld : p.overworld
-                    }</div>
+                groupByTeam(vm["game/players"] || []).map(([team, players]) => (<Fragment key={"team" + team.toString()}>
+                    <div style={"grid-column: 1 / span 4; font-weight: bold; color: " + (team == vm.server?.team ? "yellow" : "gray")}
+                         title={team == vm.server?.team ? "Your team; items and progress sync only within your team" : "Another team"}
+                    >Team {team}</div>
+                    {players.map((p: any) => (<Fragment key={p.index.toString()}>
+                        <div class="mono" title="Player index">{("0" + p.index.toString(16)).substr(-2)}</div>
+                        <div class="mono" title="Team number">{p.team}</div>
+                        <div style="color: yellow; white-space: nowrap" title="Player name">{p.name}</div>
+                        <div
+                            style={"color: " + (((p.location & 0x10000) != 0) ? "green" : "cyan") + "; white-space: nowrap"}
+                            title="Location">{
+                            ((p.location & 0x10000) != 0) ? p.underworld : p.overworld
+                        }</div>
+                    </Fragment>))}
                 </Fragment>))
             }
         </div>
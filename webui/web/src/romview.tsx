@@ -61,6 +61,10 @@ patched for O2 support. O2 automatically patches your Input ROM for you."
             <label>Version:</label>
             <input style="grid-column-end: span 2" class="mono" readonly value={rom?.region + " " + rom?.version}/>
 
+            <label title="Version of the o2 patch the ROM already had when loaded">o2 Patch:</label>
+            <input style="grid-column-end: span 2" class="mono" readonly value={rom?.patchVersion}
+                   title="Version of the o2 patch the ROM already had when loaded"/>
+
             <label
                 title="Which folder to store the ROM in on the FX Pak Pro when using the Boot command. If blank, 'o2' will be used."
             >Folder:</label>
@@ -13,14 +13,17 @@ function ServerView({ch, server}: ServerProps) {
 
     const [hostName, setHostName] = useState('');
     const [groupName, setGroupName] = useState('');
+    const [password, setPassword] = useState('');
     const [playerName, setPlayerName] = useState('');
     const [team, setTeam] = useState(0);
+    const [recordSessions, setRecordSessions] = useState(false);
 
     useEffect(() => {
         setHostName(server?.hostName);
         setGroupName(server?.groupName);
         setPlayerName(server?.playerName);
         setTeam(server?.team);
+        setRecordSessions(server?.recordSessions);
     }, [server]);
 
     // NOTE: `ch` can be null during app init
@@ -50,6 +53,7 @@ function ServerView({ch, server}: ServerProps) {
 
     const getTargetValueString = (e: Event) => (e.target as HTMLInputElement).value;
     const getTargetValueInt = (e: Event) => parseInt((e.target as HTMLInputElement).value, 10);
+    const getTargetChecked = (e: Event) => (e.target as HTMLInputElement).checked;
     return <div class={"grid collapsible" + (collapsed ? " collapsed" : "")} style="grid-template-columns: 1fr 1fr; min-width: 16em">
         <h5 style="grid-column: 1 / span 2">
             <span data-rh-at="left" data-rh="To play online with other players, connect to a server
@@ -70,6 +74,13 @@ and enter a group name you wish to join. Groups are created on the fly by whoeve
                title="A group name uniquely identifies the group of players you wish to sync items and progress with; max 20 characters, case-insensitive, leading and trailing whitespace are trimmed"
                id="groupName"
                onInput={setField.bind(this, sendServerCommand, setGroupName, "groupName", getTargetValueString)}/>
+        <label for="password">Password:</label>
+        <input type="password"
+               value={password}
+               placeholder={server?.hasPassword ? "(unchanged)" : ""}
+               title="Optional group password; only players with the same password can see or send data in the group"
+               id="password"
+               onInput={setField.bind(this, sendServerCommand, setPassword, "password", getTargetValueString)}/>
 
         <label for="playerName">Player Name:</label>
         <input type="text"
@@ -85,6 +96,15 @@ and enter a group name you wish to join. Groups are created on the fly by whoeve
                title="The team number within the group you wish to sync with; default is 0 to sync with all players, max 255"
                id="team"
                onInput={setField.bind(this, sendServerCommand, setTeam, "team", getTargetValueInt)}/>
+        <label for="recordSessions"
+               style="grid-column: 1 / span 2"
+               title="Record the network traffic of each session to ~/.o2/recordings to replay it later with alttpo-replay">
+            <input type="checkbox"
+                   id="recordSessions"
+                   checked={recordSessions}
+                   onChange={setField.bind(this, sendServerCommand, setRecordSessions, "recordSessions", getTargetChecked)}
+            />Record Sessions
+        </label>
 
         {connectButton()}
     </div>;
@@ -15,3 +15,15 @@ export function setField<T>(
         }
     );
 }
+
+// groups players by team number, ordered by team:
+export function groupByTeam<P extends { team: number }>(players: P[]): [number, P[]][] {
+    const teams = new Map<number, P[]>();
+    for (const p of players) {
+        if (!teams.has(p.team)) {
+            teams.set(p.team, []);
+        }
+        teams.get(p.team).push(p);
+    }
+    return Array.from(teams.entries()).sort((a, b) => a[0] - b[0]);
+}
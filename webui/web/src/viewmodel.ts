@@ -44,6 +44,7 @@ export interface ROMViewModel {
     title: string;
     region: string;
     version: string;
+    patchVersion: string;
 
     folder: string;
     filename: string;
@@ -56,6 +57,8 @@ export interface ServerViewModel {
     groupName: string;
     playerName: string;
     team: number;
+    hasPassword: boolean;
+    recordSessions: boolean;
 }
 
 export interface GameViewModel {
@@ -74,6 +77,7 @@ export interface GameALTTPViewModel extends GameViewModel {
     syncOverworld: boolean;
     syncChests: boolean;
     syncTunicColor: boolean;
+    syncSRAMDelta: boolean;
 }
 
 export interface GameSMZ3ViewModel extends GameViewModel {